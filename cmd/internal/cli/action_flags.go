@@ -21,6 +21,7 @@ var (
 	bindPaths         []string
 	mounts            []string
 	homePath          string
+	homeOptions       []string
 	overlayPath       []string
 	scratchPath       []string
 	workdirPath       string
@@ -30,17 +31,27 @@ var (
 	network           string
 	networkArgs       []string
 	dns               string
+	dnsOptions        []string
+	archVariant       string
+	gpus              string
 	security          []string
 	cgroupsTOMLFile   string
 	containLibsPath   []string
 	fuseMount         []string
 	apptainerEnv      map[string]string
 	apptainerEnvFiles []string
+	apptainerEnvJSON  string
+	unsetEnv          []string
+	labelEnv          []string
+	envPass           []string
 	noMount           []string
+	noBindExpand      bool
 	dmtcpLaunch       string
 	dmtcpRestart      string
+	shmSize           string
 
 	isBoot          bool
+	healthcheck     bool
 	isFakeroot      bool
 	isCleanEnv      bool
 	isCompat        bool
@@ -58,6 +69,12 @@ var (
 	noRocm          bool
 	noUmask         bool
 	disableCache    bool
+	offline         bool
+	ignoreArch      bool
+	cidFile         string
+	containerID     string
+	keepID          bool
+	fakerootRange   string
 
 	netNamespace   bool
 	netnsPath      string
@@ -144,6 +161,17 @@ var actionHomeFlag = cmdline.Flag{
 	Tag:          "<spec>",
 }
 
+// --home-options
+var actionHomeOptionsFlag = cmdline.Flag{
+	ID:           "actionHomeOptionsFlag",
+	Value:        &homeOptions,
+	DefaultValue: []string{},
+	Name:         "home-options",
+	Usage:        "options of the home directory mount: exec or noexec. Can't lift noexec set with 'home mount options' in apptainer.conf.",
+	EnvKeys:      []string{"HOME_OPTIONS"},
+	Tag:          "<opts>",
+}
+
 // -o|--overlay
 var actionOverlayFlag = cmdline.Flag{
 	ID:           "actionOverlayFlag",
@@ -190,6 +218,26 @@ var actionDisableCacheFlag = cmdline.Flag{
 	EnvKeys:      []string{"DISABLE_CACHE"},
 }
 
+// --offline
+var actionOfflineFlag = cmdline.Flag{
+	ID:           "actionOfflineFlag",
+	Value:        &offline,
+	DefaultValue: false,
+	Name:         "offline",
+	Usage:        "do not access the network to retrieve the image, only local images, local transports (oci, oci-archive, docker-archive, docker-daemon) and docker images already in the cache are allowed",
+	EnvKeys:      []string{"OFFLINE"},
+}
+
+// --arch-variant
+var actionArchVariantFlag = cmdline.Flag{
+	ID:           "actionArchVariantFlag",
+	Value:        &archVariant,
+	DefaultValue: "",
+	Name:         "arch-variant",
+	Usage:        "architecture variant (e.g. v6, v7) of the image to pull from an OCI registry, instead of the variant of the host CPU",
+	EnvKeys:      []string{"ARCH_VARIANT"},
+}
+
 // -s|--shell
 var actionShellFlag = cmdline.Flag{
 	ID:           "actionShellFlag",
@@ -268,6 +316,17 @@ var actionDNSFlag = cmdline.Flag{
 	EnvKeys:      []string{"DNS"},
 }
 
+// --dns-option
+var actionDNSOptionFlag = cmdline.Flag{
+	ID:           "actionDNSOptionFlag",
+	Value:        &dnsOptions,
+	DefaultValue: []string{},
+	Name:         "dns-option",
+	Usage:        "resolver option to add in resolv.conf, e.g. ndots:2 (can be specified multiple times)",
+	EnvKeys:      []string{"DNS_OPTION"},
+	Tag:          "<option>",
+}
+
 // --security
 var actionSecurityFlag = cmdline.Flag{
 	ID:           "actionSecurityFlag",
@@ -329,6 +388,48 @@ var actionBootFlag = cmdline.Flag{
 	EnvKeys:      []string{"BOOT"},
 }
 
+// --healthcheck
+var actionHealthcheckFlag = cmdline.Flag{
+	ID:           "actionHealthcheckFlag",
+	Value:        &healthcheck,
+	DefaultValue: false,
+	Name:         "healthcheck",
+	Usage:        "periodically run the image HEALTHCHECK, reporting its status in 'instance list'",
+	EnvKeys:      []string{"HEALTHCHECK"},
+}
+
+// --ignore-arch
+var actionIgnoreArchFlag = cmdline.Flag{
+	ID:           "actionIgnoreArchFlag",
+	Value:        &ignoreArch,
+	DefaultValue: false,
+	Name:         "ignore-arch",
+	Usage:        "run an image built for another architecture, which the host is set up to emulate (e.g. with binfmt_misc and qemu)",
+	EnvKeys:      []string{"IGNORE_ARCH"},
+}
+
+// --container-id
+var actionContainerIDFlag = cmdline.Flag{
+	ID:           "actionContainerIDFlag",
+	Value:        &containerID,
+	DefaultValue: "",
+	Name:         "container-id",
+	Usage:        "set the container id, written to --cidfile, instead of a generated one",
+	Tag:          "<id>",
+	EnvKeys:      []string{"CONTAINER_ID"},
+}
+
+// --cidfile
+var actionCIDFileFlag = cmdline.Flag{
+	ID:           "actionCIDFileFlag",
+	Value:        &cidFile,
+	DefaultValue: "",
+	Name:         "cidfile",
+	Usage:        "write the container id, or instance name, to a file, removed when a non-instance container exits",
+	Tag:          "<path>",
+	EnvKeys:      []string{"CIDFILE"},
+}
+
 // -f|--fakeroot
 var actionFakerootFlag = cmdline.Flag{
 	ID:           "actionFakerootFlag",
@@ -340,6 +441,27 @@ var actionFakerootFlag = cmdline.Flag{
 	EnvKeys:      []string{"FAKEROOT"},
 }
 
+// --keep-id
+var actionKeepIDFlag = cmdline.Flag{
+	ID:           "actionKeepIDFlag",
+	Value:        &keepID,
+	DefaultValue: false,
+	Name:         "keep-id",
+	Usage:        "run container in a user namespace preserving your uid/gid, with the other ids mapped to your subuid/subgid ranges",
+	EnvKeys:      []string{"KEEP_ID"},
+}
+
+// --fakeroot-range
+var actionFakerootRangeFlag = cmdline.Flag{
+	ID:           "actionFakerootRangeFlag",
+	Value:        &fakerootRange,
+	DefaultValue: "",
+	Name:         "fakeroot-range",
+	Usage:        "select the subuid/subgid range mapped with --fakeroot or --keep-id when you have several, by index starting at 0 or as start=<host id>",
+	EnvKeys:      []string{"FAKEROOT_RANGE"},
+	Tag:          "<range>",
+}
+
 // -e|--cleanenv
 var actionCleanEnvFlag = cmdline.Flag{
 	ID:           "actionCleanEnvFlag",
@@ -403,6 +525,16 @@ var actionNvCCLIFlag = cmdline.Flag{
 	EnvKeys:      []string{"NVCCLI"},
 }
 
+// --gpus
+var actionGPUsFlag = cmdline.Flag{
+	ID:           "actionGPUsFlag",
+	Value:        &gpus,
+	DefaultValue: "",
+	Name:         "gpus",
+	Usage:        "NVIDIA GPUs to make available, as 'all', a number of GPUs, or a comma separated list of GPU indexes or UUIDs (implies --nvccli)",
+	EnvKeys:      []string{"GPUS"},
+}
+
 // --rocm flag to automatically bind
 var actionRocmFlag = cmdline.Flag{
 	ID:           "actionRocmFlag",
@@ -454,6 +586,16 @@ var actionNoMountFlag = cmdline.Flag{
 	EnvKeys:      []string{"NO_MOUNT"},
 }
 
+// --no-bind-expand
+var actionNoBindExpandFlag = cmdline.Flag{
+	ID:           "actionNoBindExpandFlag",
+	Value:        &noBindExpand,
+	DefaultValue: false,
+	Name:         "no-bind-expand",
+	Usage:        "do NOT expand host environment variables, e.g. $SCRATCH, in --bind paths",
+	EnvKeys:      []string{"NO_BIND_EXPAND"},
+}
+
 // --no-init
 var actionNoInitFlag = cmdline.Flag{
 	ID:           "actionNoInitFlag",
@@ -617,6 +759,56 @@ var actionEnvFileFlag = cmdline.Flag{
 	EnvKeys:      []string{"ENV_FILE"},
 }
 
+// --env-json
+var actionEnvJSONFlag = cmdline.Flag{
+	ID:           "actionEnvJSONFlag",
+	Value:        &apptainerEnvJSON,
+	DefaultValue: "",
+	Name:         "env-json",
+	Usage:        "pass environment variables from a JSON object, or a file holding one, to contained process",
+	EnvKeys:      []string{"ENV_JSON"},
+}
+
+// --env-pass
+var actionEnvPassFlag = cmdline.Flag{
+	ID:           "actionEnvPassFlag",
+	Value:        &envPass,
+	DefaultValue: []string{},
+	Name:         "env-pass",
+	Usage:        "pass host environment variable to contained process, even with --cleanenv (glob patterns such as SLURM_* are accepted)",
+	EnvKeys:      []string{"ENV_PASS"},
+}
+
+// --unsetenv
+var actionUnsetEnvFlag = cmdline.Flag{
+	ID:           "actionUnsetEnvFlag",
+	Value:        &unsetEnv,
+	DefaultValue: []string{},
+	Name:         "unsetenv",
+	Usage:        "remove environment variable from contained process, including variables set by the image",
+	EnvKeys:      []string{"UNSETENV"},
+}
+
+// --label-env
+var actionLabelEnvFlag = cmdline.Flag{
+	ID:           "actionLabelEnvFlag",
+	Value:        &labelEnv,
+	DefaultValue: []string{},
+	Name:         "label-env",
+	Usage:        "expose image labels matching the given names or patterns (e.g. 'org.opencontainers.image.*') as APPTAINER_LABEL_<NAME> environment variables",
+	EnvKeys:      []string{"LABEL_ENV"},
+}
+
+// --shm-size
+var actionShmSizeFlag = cmdline.Flag{
+	ID:           "actionShmSizeFlag",
+	Value:        &shmSize,
+	DefaultValue: "",
+	Name:         "shm-size",
+	Usage:        "size of the /dev/shm tmpfs of a contained /dev, such as 512m or 2g (base-2 units, bytes without suffix)",
+	EnvKeys:      []string{"SHM_SIZE"},
+}
+
 // --no-umask
 var actionNoUmaskFlag = cmdline.Flag{
 	ID:           "actionNoUmask",
@@ -783,9 +975,8 @@ var actionIgnoreSubuidFlag = cmdline.Flag{
 	Value:        &ignoreSubuid,
 	DefaultValue: false,
 	Name:         "ignore-subuid",
-	Usage:        "ignore entries inside /etc/subuid",
+	Usage:        "ignore entries inside /etc/subuid, with --fakeroot only your uid/gid are mapped, to root",
 	EnvKeys:      []string{"IGNORE_SUBUID"},
-	Hidden:       true,
 }
 
 // --ignore-fakeroot-command
@@ -867,6 +1058,7 @@ func init() {
 		if instanceStartCmd != nil {
 			cmdManager.SetCmdGroup("actions_instance", ExecCmd, ShellCmd, RunCmd, TestCmd, instanceStartCmd, instanceRunCmd)
 			cmdManager.RegisterFlagForCmd(&actionBootFlag, instanceStartCmd, instanceRunCmd)
+			cmdManager.RegisterFlagForCmd(&actionHealthcheckFlag, instanceStartCmd, instanceRunCmd)
 		} else {
 			cmdManager.SetCmdGroup("actions_instance", actionsCmd...)
 		}
@@ -879,6 +1071,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionAllowSetuidFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionAppFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionApplyCgroupsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionArchVariantFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionBindFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCleanEnvFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCompatFlag, actionsInstanceCmd...)
@@ -886,11 +1079,15 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionContainFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainLibsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDisableCacheFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionOfflineFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDNSFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDNSOptionFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDropCapsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionFakerootFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionFuseMountFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionGPUsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionHomeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionHomeOptionsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionHostnameFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionIpcNamespaceFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionKeepPrivsFlag, actionsInstanceCmd...)
@@ -901,6 +1098,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionNetworkFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoHomeFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoMountFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNoBindExpandFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoInitFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoNvidiaFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoRocmFlag, actionsInstanceCmd...)
@@ -932,6 +1130,16 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionEnvFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionEnvFileFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionEnvJSONFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionEnvPassFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionUnsetEnvFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionLabelEnvFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionShmSizeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionIgnoreArchFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionCIDFileFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionContainerIDFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionKeepIDFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionFakerootRangeFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoUmaskFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoEvalFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionBlkioWeightFlag, actionsInstanceCmd...)
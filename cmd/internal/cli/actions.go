@@ -33,6 +33,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/util/uri"
 	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/fs/lock"
+	"github.com/apptainer/apptainer/pkg/util/slice"
 	"github.com/spf13/cobra"
 	"golang.org/x/sys/unix"
 )
@@ -42,17 +43,20 @@ const (
 	shareNSInstancePrefix = "sharens_instance"
 )
 
-func getCacheHandle(cfg cache.Config) *cache.Handle {
+// getCacheHandle returns a cache handle for the cache directory set in the
+// environment, or the default one.
+func getCacheHandle(cfg cache.Config) (*cache.Handle, error) {
 	envKey := env.TrimApptainerKey(cache.DirEnv)
+	roEnvKey := env.TrimApptainerKey(cache.ReadOnlyDirEnv)
 	h, err := cache.New(cache.Config{
-		ParentDir: env.GetenvLegacy(envKey, envKey),
-		Disable:   cfg.Disable,
+		ParentDir:         env.GetenvLegacy(envKey, envKey),
+		Disable:           cfg.Disable,
+		ReadOnlyParentDir: env.GetenvLegacy(roEnvKey, roEnvKey),
 	})
 	if err != nil {
-		sylog.Fatalf("Failed to create an image cache handle: %s", err)
+		return nil, fmt.Errorf("failed to create an image cache handle: %s", err)
 	}
-
-	return h
+	return h, nil
 }
 
 // actionPreRun will run replaceURIWithImage and will also do the proper path unsetting
@@ -65,7 +69,9 @@ func actionPreRun(cmd *cobra.Command, args []string) {
 
 	os.Setenv("IMAGE_ARG", args[0])
 
-	replaceURIWithImage(cmd.Context(), cmd, args)
+	if err := replaceURIWithImage(cmd.Context(), cmd, args); err != nil {
+		sylog.Fatalf("%s", err)
+	}
 
 	// --compat infers other options that give increased OCI / Docker compatibility
 	// Excludes uts/user/net namespaces as these are restrictive for many Apptainer
@@ -82,15 +88,20 @@ func actionPreRun(cmd *cobra.Command, args []string) {
 func handleOCI(ctx context.Context, imgCache *cache.Handle, cmd *cobra.Command, pullFrom string) (string, error) {
 	ociAuth, err := makeOCICredentials(cmd)
 	if err != nil {
-		sylog.Fatalf("While creating Docker credentials: %v", err)
+		return "", fmt.Errorf("while creating docker credentials: %v", err)
 	}
 
+	t, _ := uri.Split(pullFrom)
 	pullOpts := oci.PullOptions{
 		TmpDir:      tmpDir,
 		OciAuth:     ociAuth,
 		DockerHost:  dockerHost,
 		NoHTTPS:     noHTTPS,
+		ArchVariant: archVariant,
 		ReqAuthFile: reqAuthFile,
+		Concurrency: getOCIConcurrency(),
+		Timeout:     getOCITimeout(),
+		Offline:     offline && slice.ContainsString(cachedTransports, t),
 	}
 
 	return oci.Pull(ctx, imgCache, pullFrom, pullOpts)
@@ -101,6 +112,8 @@ func handleOras(ctx context.Context, imgCache *cache.Handle, cmd *cobra.Command,
 	if err != nil {
 		return "", fmt.Errorf("while creating docker credentials: %v", err)
 	}
+	ctx, cancel := ociTimeoutContext(ctx)
+	defer cancel()
 	return oras.Pull(ctx, imgCache, pullFrom, tmpDir, ociAuth, noHTTPS, reqAuthFile)
 }
 
@@ -135,44 +148,100 @@ func handleNet(ctx context.Context, imgCache *cache.Handle, pullFrom string) (st
 	return net.Pull(ctx, imgCache, pullFrom, tmpDir)
 }
 
-func replaceURIWithImage(ctx context.Context, cmd *cobra.Command, args []string) {
+// actionTransports returns the list of image URI transports which can be
+// run by the action commands.
+func actionTransports() []string {
+	transports := []string{uri.Library, uri.Oras, uri.Shub, uri.HTTP, uri.HTTPS}
+	return append(transports, ociimage.SupportedTransports()...)
+}
+
+// unsupportedTransportError returns the error reported when an image URI uses
+// a transport that can't be run, listing the supported transports, with a
+// hint for transports known to other container tools.
+func unsupportedTransportError(t string) error {
+	supported := strings.Join(actionTransports(), ", ")
+	if t == "containers-storage" {
+		return fmt.Errorf("unsupported transport type: %s (supported: %s), export the image with 'podman save --format oci-archive' and use an oci-archive: URI", t, supported)
+	}
+	return fmt.Errorf("unsupported transport type: %s (supported: %s)", t, supported)
+}
+
+// localTransports are the image URI transports which can be handled without
+// network access.
+var localTransports = []string{"docker-archive", "docker-daemon", "oci", "oci-archive"}
+
+// cachedTransports are the image URI transports whose images are retrieved
+// from the cache with --offline, if they were pulled before.
+var cachedTransports = []string{"docker"}
+
+// checkOffline returns an error if retrieving an image with transport t
+// requires network access while running with --offline.
+func checkOffline(t string) error {
+	if !offline || slice.ContainsString(cachedTransports, t) {
+		return nil
+	}
+	local := slice.ContainsString(localTransports, t)
+	if t == "docker-daemon" && dockerHost != "" && !strings.HasPrefix(dockerHost, "unix://") {
+		local = false
+	}
+	if !local {
+		return fmt.Errorf("%s images require network access, which is disabled by --offline: pull the image to a local file first", t)
+	}
+	return nil
+}
+
+// replaceURIWithImage replaces an image URI in args[0] with the path of the
+// image retrieved from it. args are left untouched if args[0] is not a URI.
+func replaceURIWithImage(ctx context.Context, cmd *cobra.Command, args []string) error {
 	// If args[0] is not transport:ref (ex. instance://...) formatted return, not a URI
 	t, _ := uri.Split(args[0])
 	if t == "instance" || t == "" {
-		return
+		return nil
 	}
-
-	var image string
-	var err error
-
-	// Create a cache handle only when we know we are using a URI
-	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
-	if imgCache == nil {
-		sylog.Fatalf("failed to create a new image cache handle")
+	if err := checkOffline(t); err != nil {
+		return err
 	}
 
+	var handle func(imgCache *cache.Handle) (string, error)
+
 	switch t {
 	case uri.Library:
-		image, err = handleLibrary(ctx, imgCache, args[0])
+		handle = func(imgCache *cache.Handle) (string, error) {
+			return handleLibrary(ctx, imgCache, args[0])
+		}
 	case uri.Oras:
-		image, err = handleOras(ctx, imgCache, cmd, args[0])
+		handle = func(imgCache *cache.Handle) (string, error) {
+			return handleOras(ctx, imgCache, cmd, args[0])
+		}
 	case uri.Shub:
-		image, err = handleShub(ctx, imgCache, args[0])
+		handle = func(imgCache *cache.Handle) (string, error) {
+			return handleShub(ctx, imgCache, args[0])
+		}
 	case ociimage.SupportedTransport(t):
-		image, err = handleOCI(ctx, imgCache, cmd, args[0])
-	case uri.HTTP:
-		image, err = handleNet(ctx, imgCache, args[0])
-	case uri.HTTPS:
-		image, err = handleNet(ctx, imgCache, args[0])
+		handle = func(imgCache *cache.Handle) (string, error) {
+			return handleOCI(ctx, imgCache, cmd, args[0])
+		}
+	case uri.HTTP, uri.HTTPS:
+		handle = func(imgCache *cache.Handle) (string, error) {
+			return handleNet(ctx, imgCache, args[0])
+		}
 	default:
-		sylog.Fatalf("Unsupported transport type: %s", t)
+		return unsupportedTransportError(t)
+	}
+
+	// Create a cache handle only when we know we are using a URI
+	imgCache, err := getCacheHandle(cache.Config{Disable: disableCache})
+	if err != nil {
+		return err
 	}
 
+	image, err := handle(imgCache)
 	if err != nil {
-		sylog.Fatalf("Unable to handle %s uri: %v", args[0], err)
+		return fmt.Errorf("unable to handle %s uri: %v", args[0], err)
 	}
 
 	args[0] = image
+	return nil
 }
 
 // ExecCmd represents the exec command
@@ -314,20 +383,28 @@ func launchContainer(cmd *cobra.Command, image string, args []string, instanceNa
 			cmd.Flag(actionHomeFlag.Name).Changed,
 			noHome,
 		),
+		launch.OptHomeOptions(homeOptions),
 		launch.OptMounts(bindPaths, mounts, fuseMount),
 		launch.OptNoMount(noMount),
+		launch.OptNoBindExpand(noBindExpand),
 		launch.OptNvidia(nvidia, nvCCLI),
 		launch.OptNoNvidia(noNvidia),
+		launch.OptGPUs(gpus),
 		launch.OptRocm(rocm),
 		launch.OptNoRocm(noRocm),
 		launch.OptContainLibs(containLibsPath),
 		launch.OptEnv(apptainerEnv, apptainerEnvFiles, isCleanEnv),
+		launch.OptEnvJSON(apptainerEnvJSON),
+		launch.OptEnvPass(envPass),
+		launch.OptUnsetEnv(unsetEnv),
+		launch.OptLabelEnv(labelEnv),
 		launch.OptNoEval(noEval),
 		launch.OptNamespaces(ns),
 		launch.OptNetnsPath(netnsPath),
 		launch.OptNetwork(network, networkArgs),
 		launch.OptHostname(hostname),
 		launch.OptDNS(dns),
+		launch.OptDNSOptions(dnsOptions),
 		launch.OptCaps(addCaps, dropCaps),
 		launch.OptAllowSUID(allowSUID),
 		launch.OptKeepPrivs(keepPrivs),
@@ -339,10 +416,17 @@ func launchContainer(cmd *cobra.Command, image string, args []string, instanceNa
 		launch.OptShellPath(shellPath),
 		launch.OptCwdPath(cwdPath),
 		launch.OptFakeroot(isFakeroot),
+		launch.OptKeepID(keepID),
+		launch.OptFakerootRange(fakerootRange),
 		launch.OptBoot(isBoot),
+		launch.OptHealthcheck(healthcheck),
+		launch.OptIgnoreArch(ignoreArch),
+		launch.OptCIDFile(cidFile),
+		launch.OptContainerID(containerID),
 		launch.OptNoInit(noInit),
 		launch.OptContain(isContained),
 		launch.OptContainAll(isContainAll),
+		launch.OptShmSize(shmSize),
 		launch.OptAppName(appName),
 		launch.OptKeyInfo(ki),
 		launch.OptCacheDisabled(disableCache),
@@ -0,0 +1,129 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func Test_unsupportedTransportError(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport string
+		want      string
+	}{
+		{
+			name:      "ContainersStorage",
+			transport: "containers-storage",
+			want:      "oci-archive:",
+		},
+		{
+			name:      "Unknown",
+			transport: "ftp",
+			want:      "unsupported transport type: ftp (supported: library, oras, shub, http, https, docker,",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := unsupportedTransportError(tt.transport)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func Test_replaceURIWithImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		wantErr bool
+	}{
+		{
+			name: "Path",
+			arg:  "/tmp/image.sif",
+		},
+		{
+			name: "Instance",
+			arg:  "instance://myinstance",
+		},
+		{
+			name:    "UnsupportedTransport",
+			arg:     "ftp://example.com/image.sif",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{tt.arg, "true"}
+			err := replaceURIWithImage(context.Background(), nil, args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if args[0] != tt.arg {
+				t.Errorf("expected args[0] to be left as %q, got %q", tt.arg, args[0])
+			}
+		})
+	}
+}
+
+func Test_checkOffline(t *testing.T) {
+	defer func(o bool, h string) {
+		offline = o
+		dockerHost = h
+	}(offline, dockerHost)
+
+	tests := []struct {
+		name       string
+		offline    bool
+		dockerHost string
+		transport  string
+		wantErr    bool
+	}{
+		{name: "OnlineDocker", transport: "docker"},
+		{name: "OfflineDocker", offline: true, transport: "docker"},
+		{name: "OfflineLibrary", offline: true, transport: "library", wantErr: true},
+		{name: "OfflineOras", offline: true, transport: "oras", wantErr: true},
+		{name: "OfflineHTTPS", offline: true, transport: "https", wantErr: true},
+		{name: "OfflineOCIArchive", offline: true, transport: "oci-archive"},
+		{name: "OfflineDockerArchive", offline: true, transport: "docker-archive"},
+		{name: "OfflineOCI", offline: true, transport: "oci"},
+		{name: "OfflineDaemon", offline: true, transport: "docker-daemon"},
+		{name: "OfflineDaemonSocket", offline: true, dockerHost: "unix:///var/run/docker.sock", transport: "docker-daemon"},
+		{name: "OfflineDaemonTCP", offline: true, dockerHost: "tcp://docker.example.com:2376", transport: "docker-daemon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offline = tt.offline
+			dockerHost = tt.dockerHost
+			err := checkOffline(tt.transport)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A network URI is rejected before any cache or network access, and a
+	// local image path is left untouched.
+	offline = true
+	dockerHost = ""
+	args := []string{"library://alpine:latest"}
+	if err := replaceURIWithImage(context.Background(), nil, args); err == nil || !strings.Contains(err.Error(), "--offline") {
+		t.Errorf("expected offline error for %s, got %v", args[0], err)
+	}
+	args = []string{"/tmp/image.sif"}
+	if err := replaceURIWithImage(context.Background(), nil, args); err != nil || args[0] != "/tmp/image.sif" {
+		t.Errorf("unexpected result for local image: %v, %s", err, args[0])
+	}
+}
@@ -15,7 +15,7 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"math"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -23,10 +23,14 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"text/template"
+	"time"
 
 	"github.com/apptainer/apptainer/docs"
 	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
+	"github.com/apptainer/apptainer/internal/pkg/client/oras"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
 	"github.com/apptainer/apptainer/internal/pkg/plugin"
 	"github.com/apptainer/apptainer/internal/pkg/remote"
 	"github.com/apptainer/apptainer/internal/pkg/remote/endpoint"
@@ -41,6 +45,7 @@ import (
 	keyClient "github.com/apptainer/container-key-client/client"
 	libClient "github.com/apptainer/container-library-client/client"
 	"github.com/google/go-containerregistry/pkg/authn"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -68,6 +73,8 @@ var (
 	tmpDir              string
 	// Optional user requested authentication file for writing/reading OCI registry credentials
 	reqAuthFile string
+	// Number of OCI layers to transfer in parallel, 0 for the apptainer.conf default
+	ociConcurrency int
 )
 
 // apptainer command flags
@@ -270,6 +277,16 @@ var commonAuthFileFlag = cmdline.Flag{
 	EnvKeys:      []string{"AUTH_FILE"},
 }
 
+// --concurrency
+var commonConcurrencyFlag = cmdline.Flag{
+	ID:           "commonConcurrencyFlag",
+	Value:        &ociConcurrency,
+	DefaultValue: 0,
+	Name:         "concurrency",
+	Usage:        "number of OCI layers to transfer in parallel (default from apptainer.conf)",
+	EnvKeys:      []string{"CONCURRENCY"},
+}
+
 func getCurrentUser() *user.User {
 	usr, err := user.Current()
 	if err != nil {
@@ -283,28 +300,28 @@ func addCmdInit(cmdInit func(*cmdline.CommandManager)) {
 }
 
 func setSylogMessageLevel() {
-	var level int
+	var level sylog.MessageLevel
 
 	l, err := strconv.Atoi(env.GetenvLegacy("MESSAGELEVEL", "MESSAGELEVEL"))
 	if err == nil {
-		level = l
+		level = sylog.MessageLevel(l)
 	}
 
 	if debug {
-		level = 5
+		level = sylog.DebugLevel
 		// Propagate debug flag to nested `apptainer` calls.
 		os.Setenv("APPTAINER_DEBUG", "1")
 	} else if verbose {
-		level = 4
+		level = sylog.Verbose3Level
 		os.Setenv("APPTAINER_VERBOSE", "1")
 	} else if quiet {
-		level = -1
+		level = sylog.LogLevel
 		os.Setenv("APPTAINER_QUIET", "1")
 	} else if silent {
-		level = -3
+		level = sylog.ErrorLevel
 		os.Setenv("APPTAINER_SILENT", "1")
 	} else {
-		level = int(math.Max(float64(level), 1))
+		level = max(level, sylog.InfoLevel)
 	}
 
 	color := true
@@ -312,7 +329,7 @@ func setSylogMessageLevel() {
 		color = false
 	}
 
-	sylog.SetLevel(level, color)
+	sylog.SetMessageLevel(level, color)
 }
 
 // handleRemoteConf will make sure your 'remote.yaml' config file
@@ -533,6 +550,7 @@ func persistentPreRun(cmd *cobra.Command, _ []string) error {
 		}
 	}
 	apptainerconf.SetCurrentConfig(config)
+	setProxy(config)
 	// Include the user's PATH for now.
 	// It will be overridden later if using setuid flow.
 	apptainerconf.SetBinaryPath(buildcfg.LIBEXECDIR, true)
@@ -666,7 +684,7 @@ func ExecuteApptainer() {
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	defer func() {
 		signal.Stop(c)
 		cancel()
@@ -674,7 +692,7 @@ func ExecuteApptainer() {
 	go func() {
 		select {
 		case <-c:
-			sylog.Debugf("User requested cancellation with interrupt")
+			sylog.Debugf("User requested cancellation with signal")
 			cancel()
 		case <-ctx.Done():
 		}
@@ -840,6 +858,71 @@ func CheckRootOrUnpriv(cmd *cobra.Command, _ []string) {
 	}
 }
 
+// setProxy sets the proxy settings of config, or of the environment, on the
+// default transports used by the clients retrieving images, so they don't
+// need to be exported in the environment of the container.
+func setProxy(config *apptainerconf.File) {
+	proxy := apptainerconf.ProxyFunc(config)
+	for _, rt := range []http.RoundTripper{http.DefaultTransport, ggcrremote.DefaultTransport} {
+		if t, ok := rt.(*http.Transport); ok {
+			t.Proxy = proxy
+		}
+	}
+}
+
+// getOCIConcurrency returns the number of OCI layers to transfer in parallel,
+// from the --concurrency flag if set, or the 'oci concurrency' directive.
+func getOCIConcurrency() int {
+	if ociConcurrency < 0 {
+		sylog.Fatalf("Invalid --concurrency value: %d", ociConcurrency)
+	}
+	if ociConcurrency > 0 {
+		return ociConcurrency
+	}
+	if conf := apptainerconf.GetCurrentConfig(); conf != nil {
+		return int(conf.OCIConcurrency)
+	}
+	return 0
+}
+
+// getOCITimeout returns the overall deadline for OCI registry operations, as
+// set in apptainer.conf. Zero means no deadline.
+func getOCITimeout() time.Duration {
+	if conf := apptainerconf.GetCurrentConfig(); conf != nil {
+		return time.Duration(conf.OCITimeout) * time.Second
+	}
+	return 0
+}
+
+// ociTimeoutContext returns a copy of ctx which is cancelled after the OCI
+// registry operation timeout, for registry clients that don't take it as an
+// option.
+func ociTimeoutContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return ociimage.TimeoutContext(ctx, getOCITimeout())
+}
+
+// staleTmpDirAge is the age after which temporary OCI layout directories are
+// considered to have been left behind by a killed process.
+const staleTmpDirAge = 24 * time.Hour
+
+// removeStaleOCITmpDirs removes temporary OCI layout directories, older than
+// staleTmpDirAge, that were leaked by earlier pulls or pushes which did not
+// get a chance to clean up after themselves.
+func removeStaleOCITmpDirs() {
+	dir := tmpDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	n, err := fs.RemoveStaleTmpDirs(dir, []string{ociimage.TmpDirPrefix, oras.TmpDirPrefix}, staleTmpDirAge)
+	if err != nil {
+		sylog.Debugf("While removing stale temporary directories from %s: %v", dir, err)
+		return
+	}
+	if n > 0 {
+		sylog.Verbosef("Removed %d stale temporary directories from %s", n, dir)
+	}
+}
+
 // getKeyServerClientOpts returns client options for keyserver access.
 // A "" value for uri will return client options for the current endpoint.
 // A specified uri will return client options for that keyserver.
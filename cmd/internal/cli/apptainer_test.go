@@ -11,12 +11,16 @@ package cli
 
 import (
 	"math/rand"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
 const messageLevelEnv = "APPTAINER_MESSAGELEVEL"
@@ -190,3 +194,39 @@ func TestLogEnvSuite(t *testing.T) {
 		}
 	})
 }
+
+func TestSetProxy(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+
+	transports := []*http.Transport{
+		http.DefaultTransport.(*http.Transport),
+		ggcrremote.DefaultTransport.(*http.Transport),
+	}
+	for _, tr := range transports {
+		defer func(tr *http.Transport, proxy func(*http.Request) (*url.URL, error)) {
+			tr.Proxy = proxy
+		}(tr, tr.Proxy)
+	}
+
+	const proxyURL = "http://proxy.example.com:3128"
+	setProxy(&apptainerconf.File{HTTPSProxy: proxyURL})
+
+	req, err := http.NewRequest(http.MethodGet, "https://registry.example.com/v2/", nil)
+	if err != nil {
+		t.Fatalf("while creating request: %v", err)
+	}
+	for i, tr := range transports {
+		u, err := tr.Proxy(req)
+		if err != nil {
+			t.Fatalf("unexpected error from proxy of transport %d: %v", i, err)
+		}
+		if u == nil || u.String() != proxyURL {
+			t.Errorf("expected proxy %s for transport %d, got %v", proxyURL, i, u)
+		}
+	}
+	if got := os.Getenv("HTTPS_PROXY"); got != "" {
+		t.Errorf("expected HTTPS_PROXY to be left unset, got %q", got)
+	}
+}
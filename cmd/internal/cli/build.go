@@ -49,6 +49,7 @@ var buildArgs struct {
 	writableTmpfs       bool     // For test section only
 	userns              bool     // Enable user namespaces
 	ignoreSubuid        bool     // Ignore /etc/subuid entries (hidden)
+	fakerootIDs         bool     // Preserve OCI file ownership with the fakeroot id range
 	ignoreFakerootCmd   bool     // Ignore fakeroot command (hidden)
 	ignoreUserns        bool     // Ignore user namespace(hidden)
 	remote              bool     // Remote flag(hidden, only for helpful error message)
@@ -257,6 +258,16 @@ var buildIgnoreSubuidFlag = cmdline.Flag{
 	Hidden:       true,
 }
 
+// --fakeroot-ids
+var buildFakerootIDsFlag = cmdline.Flag{
+	ID:           "buildFakerootIDsFlag",
+	Value:        &buildArgs.fakerootIDs,
+	DefaultValue: false,
+	Name:         "fakeroot-ids",
+	Usage:        "with --fakeroot, preserve the ownership of files extracted from OCI images using the full subordinate id range",
+	EnvKeys:      []string{"FAKEROOT_IDS"},
+}
+
 // --ignore-fakeroot-command
 var buildIgnoreFakerootCommand = cmdline.Flag{
 	ID:           "buildIgnoreFakerootCommandFlag",
@@ -354,12 +365,14 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&buildIgnoreSubuidFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildIgnoreFakerootCommand, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildIgnoreUsernsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFakerootIDsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildRemoteFlag, buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&buildVarArgsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildVarArgFileFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildArgUnusedWarn, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&commonConcurrencyFlag, buildCmd)
 	})
 }
 
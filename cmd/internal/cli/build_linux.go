@@ -259,12 +259,12 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string, fa
 		}
 	}
 
-	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
-	if imgCache == nil {
-		sylog.Fatalf("Failed to create an image cache handle")
+	imgCache, err := getCacheHandle(cache.Config{Disable: disableCache})
+	if err != nil {
+		sylog.Fatalf("%v", err)
 	}
 
-	err := checkSections()
+	err = checkSections()
 	if err != nil {
 		sylog.Fatalf("Could not check build sections: %v", err)
 	}
@@ -377,6 +377,9 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string, fa
 				Binds:             buildArgs.bindPaths,
 				Unprivilege:       unprivilege,
 				ReqAuthFile:       reqAuthFile,
+				FakerootIDs:       buildArgs.fakerootIDs,
+				Concurrency:       getOCIConcurrency(),
+				OCITimeout:        getOCITimeout(),
 			},
 		})
 	if err != nil {
@@ -20,6 +20,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/cache"
 	"github.com/apptainer/apptainer/pkg/cmdline"
 	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/docker/go-units"
 	"github.com/spf13/cobra"
 )
 
@@ -27,16 +28,18 @@ func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterFlagForCmd(&cacheCleanTypesFlag, cacheCleanCmd)
 		cmdManager.RegisterFlagForCmd(&cacheCleanDaysFlag, cacheCleanCmd)
+		cmdManager.RegisterFlagForCmd(&cacheCleanMaxSizeFlag, cacheCleanCmd)
 		cmdManager.RegisterFlagForCmd(&cacheCleanDryFlag, cacheCleanCmd)
 		cmdManager.RegisterFlagForCmd(&cacheCleanForceFlag, cacheCleanCmd)
 	})
 }
 
 var (
-	cacheCleanTypes []string
-	cacheCleanDays  int
-	cacheCleanDry   bool
-	cacheCleanForce bool
+	cacheCleanTypes   []string
+	cacheCleanDays    int
+	cacheCleanMaxSize string
+	cacheCleanDry     bool
+	cacheCleanForce   bool
 
 	// -T|--type
 	cacheCleanTypesFlag = cmdline.Flag{
@@ -45,7 +48,7 @@ var (
 		DefaultValue: []string{"all"},
 		Name:         "type",
 		ShortHand:    "T",
-		Usage:        "a list of cache types to clean (possible values: library, oci, shub, blob, net, oras, all)",
+		Usage:        "a list of cache types to clean (possible values: library, oci, shub, blob, net, oras, oci-config, all)",
 	}
 
 	// -D|--days
@@ -58,6 +61,15 @@ var (
 		Usage:        "remove all cache entries older than specified number of days",
 	}
 
+	// --max-size
+	cacheCleanMaxSizeFlag = cmdline.Flag{
+		ID:           "cacheCleanMaxSizeFlag",
+		Value:        &cacheCleanMaxSize,
+		DefaultValue: "",
+		Name:         "max-size",
+		Usage:        "remove the least recently used cache entries until the cache fits in this size (e.g. 10g)",
+	}
+
 	// -n|--dry-run
 	cacheCleanDryFlag = cmdline.Flag{
 		ID:           "cacheCleanDryFlag",
@@ -95,6 +107,15 @@ var (
 )
 
 func cleanCache() error {
+	var maxSize int64
+	if cacheCleanMaxSize != "" {
+		var err error
+		maxSize, err = units.RAMInBytes(cacheCleanMaxSize)
+		if err != nil || maxSize <= 0 {
+			return fmt.Errorf("invalid --max-size %q: must be a positive size", cacheCleanMaxSize)
+		}
+	}
+
 	if cacheCleanDry {
 		fmt.Println("User requested a dry run. Not actually deleting any data!")
 	}
@@ -110,8 +131,11 @@ func cleanCache() error {
 	}
 
 	// create a handle to access the current image cache
-	imgCache := getCacheHandle(cache.Config{})
-	err := apptainer.CleanApptainerCache(imgCache, cacheCleanDry, cacheCleanTypes, cacheCleanDays)
+	imgCache, err := getCacheHandle(cache.Config{})
+	if err != nil {
+		return err
+	}
+	err = apptainer.CleanApptainerCache(imgCache, cacheCleanDry, cacheCleanTypes, cacheCleanDays, maxSize)
 	if err != nil {
 		return fmt.Errorf("could not clean cache: %v", err)
 	}
@@ -22,6 +22,8 @@ func init() {
 		cmdManager.RegisterCmd(CacheCmd)
 		cmdManager.RegisterSubCmd(CacheCmd, cacheCleanCmd)
 		cmdManager.RegisterSubCmd(CacheCmd, CacheListCmd)
+		cmdManager.RegisterSubCmd(CacheCmd, CacheVerifyCmd)
+		cmdManager.RegisterSubCmd(CacheCmd, CacheWarmCmd)
 	})
 }
 
@@ -69,12 +69,12 @@ var CacheListCmd = &cobra.Command{
 
 func cacheListCmd() error {
 	// A get a handle for the current image cache
-	imgCache := getCacheHandle(cache.Config{})
-	if imgCache == nil {
-		sylog.Fatalf("failed to create image cache handle")
+	imgCache, err := getCacheHandle(cache.Config{})
+	if err != nil {
+		return err
 	}
 
-	err := apptainer.ListApptainerCache(imgCache, cacheListTypes, cacheListVerbose)
+	err = apptainer.ListApptainerCache(imgCache, cacheListTypes, cacheListVerbose)
 	if err != nil {
 		sylog.Fatalf("An error occurred while listing cache: %v", err)
 		return err
@@ -0,0 +1,58 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/app/apptainer"
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&cacheVerifyRemoveFlag, CacheVerifyCmd)
+	})
+}
+
+var (
+	cacheVerifyRemove bool
+
+	// --remove
+	cacheVerifyRemoveFlag = cmdline.Flag{
+		ID:           "cacheVerifyRemoveFlag",
+		Value:        &cacheVerifyRemove,
+		DefaultValue: false,
+		Name:         "remove",
+		Usage:        "remove the corrupted blobs from the cache",
+	}
+)
+
+// CacheVerifyCmd is 'apptainer cache verify' and will check the blobs of your
+// local apptainer cache against their digests
+var CacheVerifyCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(0),
+	Run: func(_ *cobra.Command, _ []string) {
+		imgCache, err := getCacheHandle(cache.Config{})
+		if err != nil {
+			sylog.Fatalf("%v", err)
+		}
+		if err := apptainer.VerifyApptainerCache(imgCache, cacheVerifyRemove); err != nil {
+			sylog.Fatalf("%v", err)
+		}
+	},
+
+	Use:     docs.CacheVerifyUse,
+	Short:   docs.CacheVerifyShort,
+	Long:    docs.CacheVerifyLong,
+	Example: docs.CacheVerifyExample,
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/client/oci"
+	"github.com/apptainer/apptainer/pkg/cmdline"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&cacheWarmFileFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&dockerHostFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, CacheWarmCmd)
+		cmdManager.RegisterFlagForCmd(&commonConcurrencyFlag, CacheWarmCmd)
+	})
+}
+
+var (
+	cacheWarmFile string
+
+	// -f|--file
+	cacheWarmFileFlag = cmdline.Flag{
+		ID:           "cacheWarmFileFlag",
+		Value:        &cacheWarmFile,
+		DefaultValue: "",
+		Name:         "file",
+		ShortHand:    "f",
+		Usage:        "read the image references to fetch from a file, one per line",
+	}
+)
+
+// CacheWarmCmd is 'apptainer cache warm' and will fetch the blobs of OCI
+// images into your local apptainer cache
+var CacheWarmCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args: func(_ *cobra.Command, args []string) error {
+		if len(args) == 0 && cacheWarmFile == "" {
+			return fmt.Errorf("requires at least one image reference, or --file")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		refs := args
+		if cacheWarmFile != "" {
+			fileRefs, err := readWarmRefs(cacheWarmFile)
+			if err != nil {
+				sylog.Fatalf("While reading %s: %v", cacheWarmFile, err)
+			}
+			refs = append(refs, fileRefs...)
+		}
+
+		imgCache, err := getCacheHandle(cache.Config{})
+		if err != nil {
+			sylog.Fatalf("%v", err)
+		}
+
+		ociAuth, err := makeOCICredentials(cmd)
+		if err != nil {
+			sylog.Fatalf("While creating Docker credentials: %v", err)
+		}
+
+		opts := oci.PullOptions{
+			TmpDir:      tmpDir,
+			OciAuth:     ociAuth,
+			DockerHost:  dockerHost,
+			NoHTTPS:     noHTTPS,
+			ReqAuthFile: reqAuthFile,
+			Concurrency: getOCIConcurrency(),
+			Timeout:     getOCITimeout(),
+		}
+		if _, err := oci.WarmCache(cmd.Context(), imgCache, refs, opts); err != nil {
+			sylog.Fatalf("While warming the cache: %v", err)
+		}
+	},
+
+	Use:     docs.CacheWarmUse,
+	Short:   docs.CacheWarmShort,
+	Long:    docs.CacheWarmLong,
+	Example: docs.CacheWarmExample,
+}
+
+// readWarmRefs returns the image references listed in the file at path, one
+// per line. Blank lines and lines starting with '#' are ignored.
+func readWarmRefs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var refs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		refs = append(refs, line)
+	}
+	return refs, scanner.Err()
+}
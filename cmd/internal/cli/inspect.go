@@ -23,7 +23,11 @@ import (
 	"strings"
 
 	"github.com/apptainer/apptainer/docs"
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/client/oci"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
 	"github.com/apptainer/apptainer/internal/pkg/util/env"
+	"github.com/apptainer/apptainer/internal/pkg/util/uri"
 	"github.com/apptainer/apptainer/pkg/cmdline"
 	"github.com/apptainer/apptainer/pkg/image"
 	"github.com/apptainer/apptainer/pkg/inspect"
@@ -48,6 +52,8 @@ var (
 	labels      bool
 	deffile     bool
 	jsonfmt     bool
+	inspectOCI  bool
+	ociConfig   bool
 )
 
 // -l|--labels
@@ -158,6 +164,24 @@ var inspectAllFlag = cmdline.Flag{
 	Usage:        "show all available data (imply --json option)",
 }
 
+// --oci
+var inspectOCIFlag = cmdline.Flag{
+	ID:           "inspectOCIFlag",
+	Value:        &inspectOCI,
+	DefaultValue: false,
+	Name:         "oci",
+	Usage:        "inspect an OCI image source URI (docker://, oci-archive:, etc.), without pulling its layers",
+}
+
+// --config
+var inspectConfigFlag = cmdline.Flag{
+	ID:           "inspectConfigFlag",
+	Value:        &ociConfig,
+	DefaultValue: false,
+	Name:         "config",
+	Usage:        "show the OCI image config (ENTRYPOINT, CMD, ENV, USER...) (requires --oci)",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(InspectCmd)
@@ -173,6 +197,16 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&inspectTestFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectAppsListFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectAllFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectOCIFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectConfigFlag, InspectCmd)
+
+		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&dockerHostFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, InspectCmd)
 	})
 }
 
@@ -640,7 +674,15 @@ var InspectCmd = &cobra.Command{
 	Long:    docs.InspectLong,
 	Example: docs.InspectExample,
 
-	Run: func(_ *cobra.Command, args []string) {
+	Run: func(cmd *cobra.Command, args []string) {
+		if inspectOCI {
+			inspectOCIConfig(cmd, args[0])
+			return
+		}
+		if ociConfig {
+			sylog.Fatalf("The --config option requires --oci")
+		}
+
 		img, err := image.Init(args[0], false)
 		if err != nil {
 			sylog.Fatalf("Failed to open image %s: %s", args[0], err)
@@ -768,3 +810,115 @@ var InspectCmd = &cobra.Command{
 	},
 	TraverseChildren: true,
 }
+
+// inspectOCIConfig displays the config of the OCI image at imageURI, which is
+// fetched without pulling or extracting the image layers.
+func inspectOCIConfig(cmd *cobra.Command, imageURI string) {
+	if labels || deffile || helpfile || runscript || startscript || testfile || environment || listApps || allData || appName != "" {
+		sylog.Fatalf("Only --config and --json may be used with --oci")
+	}
+
+	transport, _ := uri.Split(imageURI)
+	if transport == "" && ociimage.IsOCISIF(imageURI) {
+		inspectOCISIF(imageURI)
+		return
+	}
+	if ociimage.SupportedTransport(transport) == "" {
+		sylog.Fatalf("Unsupported transport for --oci: %q", imageURI)
+	}
+
+	ociAuth, err := makeOCICredentials(cmd)
+	if err != nil {
+		sylog.Fatalf("While creating Docker credentials: %v", err)
+	}
+	opts := oci.PullOptions{
+		TmpDir:      tmpDir,
+		OciAuth:     ociAuth,
+		DockerHost:  dockerHost,
+		NoHTTPS:     noHTTPS,
+		ReqAuthFile: reqAuthFile,
+		Timeout:     getOCITimeout(),
+	}
+
+	imgCache, err := getCacheHandle(cache.Config{})
+	if err != nil {
+		sylog.Fatalf("%v", err)
+	}
+	cf, err := oci.InspectConfig(cmd.Context(), imgCache, imageURI, opts)
+	if err != nil {
+		sylog.Fatalf("While inspecting %s: %v", imageURI, err)
+	}
+
+	if jsonfmt {
+		jsonObj, err := json.MarshalIndent(cf.Config, "", "\t")
+		if err != nil {
+			sylog.Fatalf("Could not format image config as JSON")
+		}
+		fmt.Printf("%s\n", string(jsonObj))
+		return
+	}
+
+	entrypoint, _ := json.Marshal(cf.Config.Entrypoint)
+	cmdArgs, _ := json.Marshal(cf.Config.Cmd)
+	fmt.Printf("User: %s\n", cf.Config.User)
+	fmt.Printf("WorkingDir: %s\n", cf.Config.WorkingDir)
+	fmt.Printf("Entrypoint: %s\n", entrypoint)
+	fmt.Printf("Cmd: %s\n", cmdArgs)
+	fmt.Printf("Env:\n")
+	for _, e := range cf.Config.Env {
+		fmt.Printf("  %s\n", e)
+	}
+}
+
+// inspectOCISIF displays the manifest and config metadata of the OCI-SIF image
+// at path, along with the layout of its SIF data objects, as text or in JSON
+// format.
+func inspectOCISIF(path string) {
+	img, err := ociimage.InspectSIF(path)
+	if err != nil {
+		sylog.Fatalf("While inspecting %s: %v", path, err)
+	}
+
+	if jsonfmt {
+		jsonObj, err := json.MarshalIndent(img, "", "\t")
+		if err != nil {
+			sylog.Fatalf("Could not format OCI-SIF metadata as JSON")
+		}
+		fmt.Printf("%s\n", string(jsonObj))
+		return
+	}
+
+	entrypoint, _ := json.Marshal(img.Entrypoint)
+	cmdArgs, _ := json.Marshal(img.Cmd)
+	fmt.Printf("Digest: %s\n", img.Digest)
+	fmt.Printf("Platform: %s/%s", img.OS, img.Architecture)
+	if img.Variant != "" {
+		fmt.Printf("/%s", img.Variant)
+	}
+	fmt.Printf("\n")
+	fmt.Printf("User: %s\n", img.User)
+	fmt.Printf("WorkingDir: %s\n", img.WorkingDir)
+	fmt.Printf("Entrypoint: %s\n", entrypoint)
+	fmt.Printf("Cmd: %s\n", cmdArgs)
+	fmt.Printf("Env:\n")
+	for _, e := range img.Env {
+		fmt.Printf("  %s\n", e)
+	}
+	fmt.Printf("Labels:\n")
+	keys := make([]string, 0, len(img.Labels))
+	for k := range img.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("  %s: %s\n", k, img.Labels[k])
+	}
+	fmt.Printf("Layers:\n")
+	for _, l := range img.Layers {
+		fmt.Printf("  %s (%d bytes)\n", l.Digest, l.Size)
+	}
+	fmt.Printf("Descriptors:\n")
+	for _, d := range img.Descriptors {
+		fmt.Printf("  %d %s %s (offset %d, %d bytes)\n", d.ID, d.DataType, d.Digest, d.Offset, d.Size)
+	}
+}
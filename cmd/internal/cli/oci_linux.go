@@ -26,12 +26,22 @@ var ociBundleFlag = cmdline.Flag{
 	DefaultValue: "",
 	Name:         "bundle",
 	ShortHand:    "b",
-	Required:     true,
-	Usage:        "specify the OCI bundle path (required)",
+	Usage:        "specify the OCI bundle path (required, unless --rootfs is used)",
 	Tag:          "<path>",
 	EnvKeys:      []string{"BUNDLE"},
 }
 
+// --rootfs
+var ociRootfsFlag = cmdline.Flag{
+	ID:           "ociRootfsFlag",
+	Value:        &ociArgs.RootfsPath,
+	DefaultValue: "",
+	Name:         "rootfs",
+	Usage:        "run from an extracted root filesystem, or OCI bundle, directory with a default configuration",
+	Tag:          "<path>",
+	EnvKeys:      []string{"ROOTFS"},
+}
+
 // -s|--sync-socket
 var ociSyncSocketFlag = cmdline.Flag{
 	ID:           "ociSyncSocketFlag",
@@ -54,6 +64,38 @@ var ociCreateEmptyProcessFlag = cmdline.Flag{
 	EnvKeys:      []string{"EMPTY_PROCESS"},
 }
 
+// --init
+var ociInitFlag = cmdline.Flag{
+	ID:           "ociInitFlag",
+	Value:        &ociArgs.Init,
+	DefaultValue: false,
+	Name:         "init",
+	Usage:        "run a minimal init as PID 1, which forwards signals to the container process and reaps zombie processes",
+	EnvKeys:      []string{"INIT"},
+}
+
+// --device
+var ociDeviceFlag = cmdline.Flag{
+	ID:           "ociDeviceFlag",
+	Value:        &ociArgs.Devices,
+	DefaultValue: []string{},
+	Name:         "device",
+	Usage:        "add a host device to the container, as host-path[:container-path][:permissions] where permissions is a combination of r, w and m (default rwm)",
+	Tag:          "<spec>",
+	EnvKeys:      []string{"DEVICE"},
+}
+
+// --security
+var ociSecurityFlag = cmdline.Flag{
+	ID:           "ociSecurityFlag",
+	Value:        &ociArgs.Security,
+	DefaultValue: []string{},
+	Name:         "security",
+	Usage:        "override the security features of the bundle configuration, as seccomp:<profile>, seccomp:unconfined, apparmor:<profile> or selinux:<label>",
+	Tag:          "<feature:arg>",
+	EnvKeys:      []string{"SECURITY"},
+}
+
 // -l|--log-path
 var ociLogPathFlag = cmdline.Flag{
 	ID:           "ociLogPathFlag",
@@ -92,14 +134,25 @@ var ociPidFileFlag = cmdline.Flag{
 var ociKillSignalFlag = cmdline.Flag{
 	ID:           "ociKillSignalFlag",
 	Value:        &ociArgs.KillSignal,
-	DefaultValue: "SIGTERM",
+	DefaultValue: "",
 	Name:         "signal",
 	ShortHand:    "s",
-	Usage:        "signal sent to the container",
+	Usage:        "signal sent to the container (default: the image stop signal, or SIGTERM)",
 	Tag:          "<signal>",
 	EnvKeys:      []string{"SIGNAL"},
 }
 
+// --detach-keys
+var ociDetachKeysFlag = cmdline.Flag{
+	ID:           "ociDetachKeysFlag",
+	Value:        &ociArgs.DetachKeys,
+	DefaultValue: apptainer.DefaultDetachKeys,
+	Name:         "detach-keys",
+	Usage:        "key sequence detaching from the container, leaving it running, as a comma separated list of characters or ctrl-<value> (empty to disable)",
+	Tag:          "<keys>",
+	EnvKeys:      []string{"DETACH_KEYS"},
+}
+
 // -f|--force
 var ociKillForceFlag = cmdline.Flag{
 	ID:           "ociKillForceFlag",
@@ -132,6 +185,57 @@ var ociUpdateFromFileFlag = cmdline.Flag{
 	EnvKeys:      []string{"FROM_FILE"},
 }
 
+// --emulate
+var ociMountEmulateFlag = cmdline.Flag{
+	ID:           "ociMountEmulateFlag",
+	Value:        &ociArgs.Emulate,
+	DefaultValue: false,
+	Name:         "emulate",
+	Usage:        "run an image built for another architecture with the qemu user mode emulator, registered in binfmt_misc",
+	EnvKeys:      []string{"EMULATE"},
+}
+
+// -i|--interactive
+var ociExecInteractiveFlag = cmdline.Flag{
+	ID:           "ociExecInteractiveFlag",
+	Value:        &ociArgs.Interactive,
+	DefaultValue: false,
+	Name:         "interactive",
+	ShortHand:    "i",
+	Usage:        "keep (true) or close (false) standard input of the command",
+}
+
+// --entrypoint
+var ociMountEntrypointFlag = cmdline.Flag{
+	ID:           "ociMountEntrypointFlag",
+	Value:        &ociArgs.Entrypoint,
+	DefaultValue: "",
+	Name:         "entrypoint",
+	Usage:        "replace the image ENTRYPOINT, or reset it with --entrypoint \"\" to run the image CMD on its own",
+}
+
+// -S|--scratch
+var ociMountScratchFlag = cmdline.Flag{
+	ID:           "ociMountScratchFlag",
+	Value:        &ociArgs.ScratchDirs,
+	DefaultValue: []string{},
+	Name:         "scratch",
+	ShortHand:    "S",
+	Usage:        "mount a scratch tmpfs at the given path in the container",
+	Tag:          "<path>",
+}
+
+// -W|--workdir
+var ociMountWorkdirFlag = cmdline.Flag{
+	ID:           "ociMountWorkdirFlag",
+	Value:        &ociArgs.WorkDir,
+	DefaultValue: "",
+	Name:         "workdir",
+	ShortHand:    "W",
+	Usage:        "working directory holding the --scratch directories, rather than tmpfs mounts",
+	Tag:          "<path>",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(OciCmd)
@@ -148,21 +252,32 @@ func init() {
 		cmdManager.RegisterSubCmd(OciCmd, OciResumeCmd)
 		cmdManager.RegisterSubCmd(OciCmd, OciMountCmd)
 		cmdManager.RegisterSubCmd(OciCmd, OciUmountCmd)
+		cmdManager.RegisterSubCmd(OciCmd, OciCommitCmd)
 
 		cmdManager.SetCmdGroup("create_run", OciCreateCmd, OciRunCmd)
 		createRunCmd := cmdManager.GetCmdGroup("create_run")
 
 		cmdManager.RegisterFlagForCmd(&ociBundleFlag, createRunCmd...)
+		cmdManager.RegisterFlagForCmd(&ociRootfsFlag, createRunCmd...)
 		cmdManager.RegisterFlagForCmd(&ociSyncSocketFlag, createRunCmd...)
 		cmdManager.RegisterFlagForCmd(&ociLogPathFlag, createRunCmd...)
 		cmdManager.RegisterFlagForCmd(&ociLogFormatFlag, createRunCmd...)
 		cmdManager.RegisterFlagForCmd(&ociPidFileFlag, createRunCmd...)
+		cmdManager.RegisterFlagForCmd(&ociInitFlag, createRunCmd...)
+		cmdManager.RegisterFlagForCmd(&ociDeviceFlag, createRunCmd...)
+		cmdManager.RegisterFlagForCmd(&ociSecurityFlag, createRunCmd...)
 		cmdManager.RegisterFlagForCmd(&ociCreateEmptyProcessFlag, OciCreateCmd)
 		cmdManager.RegisterFlagForCmd(&ociKillForceFlag, OciKillCmd)
 		cmdManager.RegisterFlagForCmd(&ociKillSignalFlag, OciKillCmd)
+		cmdManager.RegisterFlagForCmd(&ociDetachKeysFlag, OciAttachCmd)
 		cmdManager.RegisterFlagForCmd(&ociKillTimeoutFlag, OciKillCmd)
 		cmdManager.RegisterFlagForCmd(&ociUpdateFromFileFlag, OciUpdateCmd)
 		cmdManager.RegisterFlagForCmd(&ociSyncSocketFlag, OciStateCmd)
+		cmdManager.RegisterFlagForCmd(&ociMountEmulateFlag, OciMountCmd)
+		cmdManager.RegisterFlagForCmd(&ociMountEntrypointFlag, OciMountCmd)
+		cmdManager.RegisterFlagForCmd(&ociMountScratchFlag, OciMountCmd)
+		cmdManager.RegisterFlagForCmd(&ociMountWorkdirFlag, OciMountCmd)
+		cmdManager.RegisterFlagForCmd(&ociExecInteractiveFlag, OciExecCmd)
 	})
 }
 
@@ -278,7 +393,7 @@ var OciAttachCmd = &cobra.Command{
 	DisableFlagsInUseLine: true,
 	PreRun:                CheckRoot,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := apptainer.OciAttach(cmd.Context(), args[0]); err != nil {
+		if err := apptainer.OciAttach(cmd.Context(), args[0], ociArgs.DetachKeys); err != nil {
 			sylog.Fatalf("%s", err)
 		}
 	},
@@ -293,8 +408,9 @@ var OciExecCmd = &cobra.Command{
 	Args:                  cobra.MinimumNArgs(1),
 	DisableFlagsInUseLine: true,
 	PreRun:                CheckRoot,
-	Run: func(_ *cobra.Command, args []string) {
-		if err := apptainer.OciExec(args[0], args[1:]); err != nil { //nolint:staticcheck
+	Run: func(cmd *cobra.Command, args []string) {
+		ociArgs.OverrideInteractive = cmd.Flags().Changed("interactive")
+		if err := apptainer.OciExec(args[0], args[1:], &ociArgs); err != nil { //nolint:staticcheck
 			sylog.Fatalf("%s", err)
 		}
 	},
@@ -357,8 +473,9 @@ var OciMountCmd = &cobra.Command{
 	Args:                  cobra.ExactArgs(2),
 	DisableFlagsInUseLine: true,
 	PreRun:                CheckRoot,
-	Run: func(_ *cobra.Command, args []string) {
-		if err := apptainer.OciMount(args[0], args[1]); err != nil {
+	Run: func(cmd *cobra.Command, args []string) {
+		ociArgs.OverrideEntrypoint = cmd.Flags().Changed("entrypoint")
+		if err := apptainer.OciMount(args[0], args[1], &ociArgs); err != nil {
 			sylog.Fatalf("%s", err)
 		}
 	},
@@ -384,6 +501,22 @@ var OciUmountCmd = &cobra.Command{
 	Example: docs.OciUmountExample,
 }
 
+// OciCommitCmd represents oci commit command.
+var OciCommitCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	PreRun:                CheckRoot,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := apptainer.OciCommit(cmd.Context(), args[0], args[1]); err != nil {
+			sylog.Fatalf("%s", err)
+		}
+	},
+	Use:     docs.OciCommitUse,
+	Short:   docs.OciCommitShort,
+	Long:    docs.OciCommitLong,
+	Example: docs.OciCommitExample,
+}
+
 // OciCmd apptainer oci runtime.
 var OciCmd = &cobra.Command{
 	Run:                   nil,
@@ -181,6 +181,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&pullArchFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullArchVariantFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&commonConcurrencyFlag, PullCmd)
 
 		cmdManager.RegisterFlagForCmd(&pullSandboxFlag, PullCmd)
 	})
@@ -200,9 +201,11 @@ var PullCmd = &cobra.Command{
 func pullRun(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
 
-	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
-	if imgCache == nil {
-		sylog.Fatalf("Failed to create an image cache handle")
+	removeStaleOCITmpDirs()
+
+	imgCache, err := getCacheHandle(cache.Config{Disable: disableCache})
+	if err != nil {
+		sylog.Fatalf("%v", err)
 	}
 
 	pullFrom := args[len(args)-1]
@@ -226,7 +229,7 @@ func pullRun(cmd *cobra.Command, args []string) {
 		pullTo = filepath.Join(pullDir, pullTo)
 	}
 
-	_, err := os.Stat(pullTo)
+	_, err = os.Stat(pullTo)
 	if !os.IsNotExist(err) {
 		// image already exists
 		if !forceOverwrite {
@@ -284,7 +287,9 @@ func pullRun(cmd *cobra.Command, args []string) {
 			sylog.Fatalf("Unable to make docker oci credentials: %s", err)
 		}
 
-		_, err = oras.PullToFile(ctx, imgCache, pullTo, pullFrom, ociAuth, noHTTPS, reqAuthFile, pullSandbox)
+		orasCtx, cancel := ociTimeoutContext(ctx)
+		defer cancel()
+		_, err = oras.PullToFile(orasCtx, imgCache, pullTo, pullFrom, tmpDir, ociAuth, noHTTPS, reqAuthFile, pullSandbox)
 		if err != nil {
 			sylog.Fatalf("While pulling image from oci registry: %v", err)
 		}
@@ -312,6 +317,8 @@ func pullRun(cmd *cobra.Command, args []string) {
 			NoCleanUp:   buildArgs.noCleanUp,
 			Pullarch:    arch,
 			ReqAuthFile: reqAuthFile,
+			Concurrency: getOCIConcurrency(),
+			Timeout:     getOCITimeout(),
 		}
 
 		_, err = oci.PullToFile(ctx, imgCache, pullTo, pullFrom, pullSandbox, pullOpts)
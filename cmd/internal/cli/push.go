@@ -12,10 +12,12 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/apptainer/apptainer/docs"
 	"github.com/apptainer/apptainer/internal/pkg/client/library"
+	"github.com/apptainer/apptainer/internal/pkg/client/oci"
 	"github.com/apptainer/apptainer/internal/pkg/client/oras"
 	"github.com/apptainer/apptainer/internal/pkg/remote/endpoint"
 	"github.com/apptainer/apptainer/internal/pkg/signature"
@@ -35,6 +37,12 @@ var (
 
 	// pushDescription holds a description to be set against a library container
 	pushDescription string
+
+	// pushDigestFile holds the path of a file to write the pushed manifest digest to
+	pushDigestFile string
+
+	// pushAnnotations holds key=value annotations to set on the pushed manifest
+	pushAnnotations []string
 )
 
 // --library
@@ -68,6 +76,25 @@ var pushDescriptionFlag = cmdline.Flag{
 	Usage:        "description for container image (library:// only)",
 }
 
+// --digestfile
+var pushDigestFileFlag = cmdline.Flag{
+	ID:           "pushDigestFileFlag",
+	Value:        &pushDigestFile,
+	DefaultValue: "",
+	Name:         "digestfile",
+	Usage:        "write the digest of the pushed image manifest to the specified file (oras:// only)",
+}
+
+// --annotation
+var pushAnnotationFlag = cmdline.Flag{
+	ID:           "pushAnnotationFlag",
+	Value:        &pushAnnotations,
+	DefaultValue: cmdline.StringArray{}, // to allow commas in annotation values
+	Name:         "annotation",
+	Usage:        "set an annotation on the pushed image manifest, may be repeated (oras:// only)",
+	Tag:          "<key=value>",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(PushCmd)
@@ -75,12 +102,16 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&pushLibraryURIFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&pushAllowUnsignedFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&pushDescriptionFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&pushDigestFileFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&pushAnnotationFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, PushCmd)
 
 		cmdManager.RegisterFlagForCmd(&dockerHostFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&commonConcurrencyFlag, PushCmd)
 	})
 }
 
@@ -91,6 +122,8 @@ var PushCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		file, dest := args[0], args[1]
 
+		removeStaleOCITmpDirs()
+
 		transport, ref := uri.Split(dest)
 		if transport == "" {
 			sylog.Fatalf("bad uri %s", dest)
@@ -168,10 +201,32 @@ var PushCmd = &cobra.Command{
 				sylog.Fatalf("Unable to make docker oci credentials: %s", err)
 			}
 
-			if err := oras.UploadImage(cmd.Context(), file, ref, ociAuth, noHTTPS, reqAuthFile); err != nil {
+			annotations, err := oras.ParseAnnotations(pushAnnotations)
+			if err != nil {
+				sylog.Fatalf("Invalid --annotation: %v", err)
+			}
+
+			ctx, cancel := ociTimeoutContext(cmd.Context())
+			defer cancel()
+			digest, err := oras.UploadImage(ctx, file, ref, ociAuth, noHTTPS, reqAuthFile, getOCIConcurrency(), annotations)
+			if err != nil {
 				sylog.Fatalf("Unable to push image to oci registry: %v", err)
 			}
 			sylog.Infof("Upload complete")
+			fmt.Printf("Pushed %s://%s\n", OrasProtocol, digest.String())
+			if pushDigestFile != "" {
+				if err := os.WriteFile(pushDigestFile, []byte(digest.DigestStr()), 0o644); err != nil {
+					sylog.Fatalf("Unable to write digest file: %v", err)
+				}
+			}
+		case "oci-archive", "docker-archive":
+			if cmd.Flag(pushDescriptionFlag.Name).Changed {
+				sylog.Warningf("Description is not supported for push to %s. Ignoring it.", transport)
+			}
+			if err := oci.ArchiveImage(file, transport, ref, tmpDir); err != nil {
+				sylog.Fatalf("Unable to write image to %s: %v", transport, err)
+			}
+			sylog.Infof("Archive written to %s", ref)
 		case "":
 			sylog.Fatalf("Transport type URI required but not supplied")
 		default:
@@ -191,7 +191,12 @@ Enterprise Performance Computing (EPC)`
 	CacheShort string = `Manage the local cache`
 	CacheLong  string = `
   Manage your local Apptainer cache. You can list/clean using the specific
-  types.`
+  types.
+
+  A pre-populated, read-only cache, for example on a shared filesystem, can be
+  set with APPTAINER_READONLY_CACHEDIR. Entries missing from the local cache
+  are looked up there, while new entries are always written to the local
+  cache. The list and clean commands only act on the local cache.`
 	CacheExample string = `
   All group commands have their own help output:
 
@@ -208,11 +213,18 @@ Enterprise Performance Computing (EPC)`
   APPTAINER_CACHEDIR is not set). By default the entire cache is cleaned, use
   --days and --type flags to override this behavior. Note: if you use Apptainer
   as root, cache will be stored in '/root/.apptainer/.cache', to clean that
-  cache, you will need to run 'cache clean' as root, or with 'sudo'.`
+  cache, you will need to run 'cache clean' as root, or with 'sudo'.
+
+  With --days, entries not used in that many days are removed. With
+  --max-size, the least recently used entries are removed until the cache
+  fits in the given size. Entries that are in use are kept. Images of the
+  OCI blob cache are removed whole, along with the blobs no other cached
+  image uses.`
 	CacheCleanExample string = `
   All group commands have their own help output:
 
   $ apptainer help cache clean --days 30
+  $ apptainer cache clean --days 30 --max-size 20g
   $ apptainer help cache clean --type=library,oci
   $ apptainer cache clean --help`
 
@@ -231,6 +243,39 @@ Enterprise Performance Computing (EPC)`
   $ apptainer help cache list --type=library,oci
   $ apptainer cache list --help`
 
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// Cache Verify
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	CacheVerifyUse   string = `verify`
+	CacheVerifyShort string = `Verify the OCI blobs of your local Apptainer cache`
+	CacheVerifyLong  string = `
+  This will check that the content of each OCI blob in your local cache
+  (stored at $HOME/.apptainer/cache if APPTAINER_CACHEDIR is not set) matches
+  the digest it is stored under. Blobs are stored once per digest, so layers
+  shared by several images are only checked once. The command fails if a
+  corrupted blob is found, unless --remove is given to remove the corrupted
+  blobs, which are then fetched again when needed.`
+	CacheVerifyExample string = `
+  $ apptainer cache verify
+  $ apptainer cache verify --remove`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// Cache Warm
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	CacheWarmUse   string = `warm [warm options...] [<image ref>...]`
+	CacheWarmShort string = `Fetch OCI images into your local Apptainer cache`
+	CacheWarmLong  string = `
+  This will fetch the manifest, config and layer blobs of the given OCI images
+  into your local cache (stored at $HOME/.apptainer/cache if APPTAINER_CACHEDIR
+  is not set), without extracting, converting or running them. Later pulls and
+  runs of these images then reuse the cached layers instead of downloading
+  them. The digest each reference resolved to is recorded in the cache, so
+  the image can be found without network access. The images can be listed in
+  a file with --file, one per line.`
+	CacheWarmExample string = `
+  $ apptainer cache warm docker://alpine:3.20 docker://ubuntu:24.04
+  $ apptainer cache warm --file images.txt`
+
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -705,6 +750,15 @@ Enterprise Performance Computing (EPC)`
   oras:
       oras://registry/namespace/image:tag
 
+  oci-archive:
+      oci-archive:path/to/archive.tar[:tag]
+
+  docker-archive:
+      docker-archive:path/to/archive.tar[:name:tag]
+
+  The archive destinations write the SIF container, as an OCI image which
+  docker or podman can load, to a local tar file for offline transport. The
+  root filesystem of a native SIF container becomes a single image layer.
 
   NOTE: It's always good practice to sign your containers before
   pushing them to the library. An auth token is required to push to the library,
@@ -714,7 +768,10 @@ Enterprise Performance Computing (EPC)`
   $ apptainer push /home/user/my.sif library://user/collection/my.sif:latest
 
   To supported OCI registry
-  $ apptainer push /home/user/my.sif oras://registry/namespace/image:tag`
+  $ apptainer push /home/user/my.sif oras://registry/namespace/image:tag
+
+  To local OCI archive
+  $ apptainer push /home/user/my.sif oci-archive:/tmp/my.tar:latest`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// search
@@ -868,6 +925,13 @@ Enterprise Performance Computing (EPC)`
   Inspect will show you labels, environment variables, apps and scripts associated 
   with the image determined by the flags you pass. By default, they will be shown in 
   plain text. If you would like to list them in json format, you should use the --json flag.
+
+  With the --oci flag, the argument is an OCI image source URI (docker://,
+  docker-archive:, docker-daemon:, oci:, oci-archive:) rather than a local image,
+  and --config shows the image config. Only the manifest and config are fetched,
+  without pulling the image layers. The argument may also be a local OCI-SIF
+  image, for which the architecture, layers, env, entrypoint, labels and SIF
+  data objects are shown.
   `
 	InspectExample string = `
   $ apptainer inspect ubuntu.sif
@@ -918,7 +982,16 @@ Enterprise Performance Computing (EPC)`
 
   To verify you own a single application on your container image, use the --app <appname> flag:
 
-  $ apptainer inspect --app <appname> ubuntu.sif`
+  $ apptainer inspect --app <appname> ubuntu.sif
+
+  To show the ENTRYPOINT, CMD, ENV, USER etc. of an OCI image, without pulling
+  its layers:
+
+  $ apptainer inspect --oci --config docker://alpine
+
+  To show the metadata of an OCI-SIF image as JSON:
+
+  $ apptainer inspect --oci --json alpine.oci.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Test
@@ -968,9 +1041,15 @@ Enterprise Performance Computing (EPC)`
 	OciCreateShort string = `Create a container from a bundle directory (root user only)`
 	OciCreateLong  string = `
   Create invoke create operation to create a container instance from an OCI 
-  bundle directory`
+  bundle directory, or from an extracted root filesystem directory given with
+  --rootfs, which is run with a default configuration starting a shell.`
 	OciCreateExample string = `
-  $ apptainer oci create -b ~/bundle mycontainer`
+  $ apptainer oci create -b ~/bundle mycontainer
+  $ apptainer oci create --rootfs ~/rootfs mycontainer
+
+  Create a container without the default seccomp profile, e.g. to run strace:
+
+  $ apptainer oci create -b ~/bundle --security seccomp:unconfined mycontainer`
 
 	OciStartUse   string = `start <container_ID>`
 	OciStartShort string = `Start container process (root user only)`
@@ -1009,17 +1088,25 @@ Enterprise Performance Computing (EPC)`
 	OciAttachShort string = `Attach console to a running container process (root user only)`
 	OciAttachLong  string = `
   Attach will attach console to a running container process running within 
-  container identified by container ID.`
+  container identified by container ID.
+
+  Typing the --detach-keys sequence, ctrl-p followed by ctrl-q by default,
+  detaches the console and leaves the container running.`
 	OciAttachExample string = `
-  $ apptainer oci attach mycontainer`
+  $ apptainer oci attach mycontainer
+  $ apptainer oci attach --detach-keys ctrl-x mycontainer`
 
-	OciExecUse   string = `exec <container_ID> <command> <args>`
+	OciExecUse   string = `exec [exec options...] <container_ID> <command> <args>`
 	OciExecShort string = `Execute a command within container (root user only)`
 	OciExecLong  string = `
   Exec will execute the provided command/arguments within container identified 
-  by container ID.`
+  by container ID.
+
+  The command keeps the standard streams of the caller, and
+  --interactive=false closes its standard input.`
 	OciExecExample string = `
-  $ apptainer oci exec mycontainer id`
+  $ apptainer oci exec mycontainer id
+  $ apptainer oci exec --interactive=false mycontainer /bin/sh -c 'cat; id'`
 
 	OciRunUse   string = `run -b <bundle_path> [run options...] <container_ID>`
 	OciRunShort string = `Create/start/attach/delete a container from a bundle directory (root user only)`
@@ -1065,9 +1152,27 @@ Enterprise Performance Computing (EPC)`
 	OciMountUse   string = `mount <sif_image> <bundle_path>`
 	OciMountShort string = `Mount create an OCI bundle from SIF image (root user only)`
 	OciMountLong  string = `
-  Mount will mount and create an OCI bundle from a SIF image.`
+  Mount will mount and create an OCI bundle from a SIF image.
+
+  An image built for an architecture which the host can't run is rejected,
+  unless the --emulate option is given. The qemu user mode emulator for the
+  image architecture, e.g. qemu-aarch64-static from qemu-user-static, is then
+  bound into the container at the interpreter path registered in binfmt_misc.
+
+  The --entrypoint option replaces the ENTRYPOINT of an image converted from
+  OCI, and the image CMD is then ignored. As with docker, --entrypoint ""
+  resets the ENTRYPOINT, so that the image CMD runs on its own.
+
+  The --scratch option mounts a scratch tmpfs at each given path in the
+  container, limited to the 'sessiondir max size' set in apptainer.conf. With
+  --workdir, the scratch directories are instead created under the scratch
+  directory of the given working directory, and bound into the container.`
 	OciMountExample string = `
-  $ apptainer oci mount /tmp/example.sif /var/lib/apptainer/bundles/example`
+  $ apptainer oci mount /tmp/example.sif /var/lib/apptainer/bundles/example
+  $ apptainer oci mount --emulate /tmp/arm64.sif /var/lib/apptainer/bundles/arm64
+  $ apptainer oci mount --entrypoint "" /tmp/example.sif /var/lib/apptainer/bundles/example
+  $ apptainer oci mount --scratch /scratch /tmp/example.sif /var/lib/apptainer/bundles/example
+  $ apptainer oci mount -S /scratch -W /data/work /tmp/example.sif /var/lib/apptainer/bundles/example`
 
 	OciUmountUse   string = `umount <bundle_path>`
 	OciUmountShort string = `Umount delete bundle (root user only)`
@@ -1077,6 +1182,22 @@ Enterprise Performance Computing (EPC)`
 	OciUmountExample string = `
   $ apptainer oci umount /var/lib/apptainer/bundles/example`
 
+	OciCommitUse   string = `commit <container_ID> <image_path>`
+	OciCommitShort string = `Commit the changes of a container to a new SIF image (root user only)`
+	OciCommitLong  string = `
+  Commit will write the image of a container created from a bundle mounted
+  with apptainer oci mount to a new SIF image, with the changes recorded in
+  the writable overlay of the bundle applied on top of the source image root
+  filesystem. Deleted files are removed. The container process configuration
+  is saved as the image configuration, so that the new image can be mounted
+  with apptainer oci mount to run the same process. Pause a running container
+  before committing it, for a consistent root filesystem.`
+	OciCommitExample string = `
+  $ apptainer oci pause mycontainer
+  $ apptainer oci commit mycontainer /tmp/snapshot.sif
+  $ apptainer oci resume mycontainer
+  $ apptainer oci mount /tmp/snapshot.sif /var/lib/apptainer/bundles/snapshot`
+
 	ConfigUse   string = `config`
 	ConfigShort string = `Manage various apptainer configuration (root user only)`
 	ConfigLong  string = `
@@ -1092,7 +1213,20 @@ Enterprise Performance Computing (EPC)`
 	ConfigFakerootShort string = `Manage fakeroot user mappings entries (root user only)`
 	ConfigFakerootLong  string = `
   The config fakeroot command allow a root user to add/remove/enable/disable fakeroot
-  user mappings.`
+  user mappings.
+
+  These mappings are also used by the --keep-id action option. With --fakeroot,
+  the user is mapped to root in the container, and the other container ids to
+  its subordinate ids. With --keep-id, the user keeps its uid/gid in the
+  container, so that files it creates are owned by it on the host, and the
+  container ids around it are mapped to its subordinate ids, e.g. for uid 1000
+  with subordinate ids 100000-165535, container ids 0-999 are mapped to
+  100000-100999, and container ids 1001-65536 to 101000-165535.
+
+  Without subordinate ids, only the user uid/gid are mapped in the container,
+  for the most isolation, and no /etc/subuid entry is required: to themselves
+  with --userns, or to root with --fakeroot --ignore-subuid, which is also the
+  fallback of --fakeroot for users without mappings.`
 	ConfigFakerootExample string = `
   To add a fakeroot user mapping for vagrant user:
   $ apptainer config fakeroot --add vagrant
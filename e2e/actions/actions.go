@@ -3093,6 +3093,123 @@ func (c actionTests) relWorkdirScratch(t *testing.T) {
 	}
 }
 
+// actionCIDFile tests that --cidfile holds the container id while a container
+// runs, and is removed when it exits, while it is left for an instance. It
+// also tests that --container-id sets a unique container id.
+func (c actionTests) actionCIDFile(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	testdir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "cidfile-", "")
+	t.Cleanup(func() {
+		if !t.Failed() {
+			cleanup(t)
+		}
+	})
+
+	cidFile := filepath.Join(testdir, "container.id")
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("exec"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--bind", testdir, "--cidfile", cidFile, c.env.ImagePath, "cat", cidFile),
+		e2e.PostRun(func(t *testing.T) {
+			if _, err := os.Stat(cidFile); !os.IsNotExist(err) {
+				t.Errorf("container id file %s not removed on exit: %v", cidFile, err)
+			}
+		}),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.RegexMatch, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`),
+		),
+	)
+
+	requestedID := "cidfile-exec"
+	requestedCIDFile := filepath.Join(testdir, "requested.id")
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("container-id"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--bind", testdir, "--container-id", requestedID, "--cidfile", requestedCIDFile, c.env.ImagePath, "cat", requestedCIDFile),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.ExactMatch, requestedID),
+		),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("container-id invalid"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--container-id", "bad/id", c.env.ImagePath, "true"),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, `invalid container id "bad/id"`),
+		),
+	)
+
+	instanceName := "cidfile"
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("instance start"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs("--cidfile", cidFile, c.env.ImagePath, instanceName),
+		e2e.PostRun(func(t *testing.T) {
+			b, err := os.ReadFile(cidFile)
+			if err != nil {
+				t.Fatalf("while reading container id file: %s", err)
+			}
+			if string(b) != instanceName {
+				t.Errorf("expected container id %s, got %q", instanceName, b)
+			}
+		}),
+		e2e.ExpectExit(0),
+	)
+
+	// an existing file is not overwritten
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("existing"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--cidfile", cidFile, c.env.ImagePath, "true"),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "already exists"),
+		),
+	)
+
+	// the id of a running instance can't be requested
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("container-id collision"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs("--container-id", instanceName, c.env.ImagePath, "true"),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "container id "+instanceName+" is already in use by instance "+instanceName),
+		),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("instance stop"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("instance stop"),
+		e2e.WithArgs(instanceName),
+		e2e.PostRun(func(t *testing.T) {
+			if _, err := os.Stat(cidFile); err != nil {
+				t.Errorf("container id file of instance removed: %s", err)
+			}
+		}),
+		e2e.ExpectExit(0),
+	)
+}
+
 // actionAuth tests run/exec/shell flows that involve authenticated pulls from
 // OCI registries.
 func (c actionTests) actionAuth(t *testing.T) {
@@ -3269,5 +3386,6 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"relWorkdirScratch":            np(c.relWorkdirScratch), // test relative --workdir with --scratch
 		"issue 1868":                   c.issue1868,             // https://github.com/apptainer/apptainer/issues/1868
 		"auth":                         np(c.actionAuth),        // tests action cmds w/authenticated pulls from OCI registries
+		"cidfile":                      np(c.actionCIDFile),     // test --cidfile
 	}
 }
@@ -111,6 +111,22 @@ func (c ctx) apptainerEnv(t *testing.T) {
 			path:  trailingCommaPath,
 			env:   []string{"APPTAINERENV_PATH=" + trailingCommaPath},
 		},
+		{
+			name:  "PrependAndAppendToCustomPath",
+			image: customImage,
+			path:  partialPath + ":" + customPath + ":/bar",
+			env:   []string{"APPTAINERENV_PREPEND_PATH=/foo", "APPTAINERENV_APPEND_PATH=/bar"},
+		},
+		{
+			name:  "OverwriteIgnoresPrependAndAppend",
+			image: customImage,
+			path:  overwrittenPath,
+			env: []string{
+				"APPTAINERENV_PREPEND_PATH=/foo",
+				"APPTAINERENV_PATH=" + overwrittenPath,
+				"APPTAINERENV_APPEND_PATH=/bar",
+			},
+		},
 	}
 
 	for _, tt := range tests {
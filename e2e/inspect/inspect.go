@@ -24,6 +24,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/test/tool/require"
 	"github.com/apptainer/apptainer/pkg/image"
 	"github.com/apptainer/apptainer/pkg/inspect"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
 type ctx struct {
@@ -417,6 +418,51 @@ func (c ctx) apptainerInspect(t *testing.T) {
 	)
 }
 
+// apptainerInspectOCIConfig checks that the config of an OCI image can be
+// inspected directly from a registry.
+func (c ctx) apptainerInspectOCIConfig(t *testing.T) {
+	checkConfig := func(t *testing.T, r *e2e.ApptainerCmdResult) {
+		config := new(v1.Config)
+		if err := json.Unmarshal(r.Stdout, config); err != nil {
+			t.Errorf("unable to parse json output: %s", err)
+			return
+		}
+		if len(config.Cmd) == 0 || config.Cmd[0] != "sh" {
+			t.Errorf("unexpected image CMD %v, expected [sh]", config.Cmd)
+		}
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("json"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("inspect"),
+		e2e.WithArgs("--oci", "--config", "--json", "--no-https", c.env.TestRegistryImage),
+		e2e.ExpectExit(0, checkConfig),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("text"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("inspect"),
+		e2e.WithArgs("--oci", "--config", "--no-https", c.env.TestRegistryImage),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.ContainMatch, `Cmd: ["sh"]`),
+		),
+	)
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("config without oci"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("inspect"),
+		e2e.WithArgs("--config", c.env.TestRegistryImage),
+		e2e.ExpectExit(255),
+	)
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := ctx{
@@ -424,6 +470,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	}
 
 	return testhelper.Tests{
-		"inspect command": c.apptainerInspect,
+		"inspect command":    c.apptainerInspect,
+		"inspect OCI config": c.apptainerInspectOCIConfig,
 	}
 }
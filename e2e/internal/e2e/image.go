@@ -11,6 +11,8 @@ package e2e
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -113,42 +115,14 @@ func EnsureDebianImage(t *testing.T, env TestEnv) {
 			err)
 	}
 
-	out, err := exec.Command("ldd", "--version").Output()
-	if err != nil {
-		t.Fatalf("Error running ldd --version while getting image %q: %+v\n",
-			env.DebianImagePath,
-			err)
-	}
-	outstr := string(out)
-	end := strings.Index(outstr, "\n")
-	if end == -1 {
-		t.Fatalf("No newline in ldd output while getting image %q: %+v\n",
-			env.DebianImagePath,
-			err)
-	}
-	dot := strings.LastIndex(outstr[0:end], ".")
-	if dot == -1 {
-		t.Fatalf("No dot in ldd first line while getting image %q: %+v\n",
-			env.DebianImagePath,
-			err)
-	}
-	lddversion, err := strconv.Atoi(outstr[dot+1 : end])
-	if err != nil {
-		t.Fatalf("Could not convert lddversion (%s) to integer while getting image %q: %+v\n",
-			outstr[dot+1:end],
-			env.DebianImagePath,
-			err)
-	}
-	if lddversion < 17 {
-		t.Fatalf("ldd version (%d) not 17 or older while getting image %q: %+v\n",
-			lddversion,
-			env.DebianImagePath,
-			err)
-	}
-
-	imageSource := "docker://ubuntu:20.04"
-	if lddversion >= 35 {
-		imageSource = "docker://ubuntu:22.04"
+	// ldd --version exits with a non-zero status on musl hosts, while still
+	// printing its version, so the output is parsed whatever the status.
+	out, _ := exec.Command("ldd", "--version").CombinedOutput()
+	imageSource, err := debianImageSource(string(out))
+	if errors.Is(err, errUnsupportedGlibc) {
+		t.Fatalf("While getting image %q: %+v\n", env.DebianImagePath, err)
+	} else if err != nil {
+		t.Logf("Using %s: %+v", imageSource, err)
 	}
 
 	env.RunApptainer(
@@ -163,6 +137,49 @@ func EnsureDebianImage(t *testing.T, env TestEnv) {
 	)
 }
 
+// defaultDebianImageSource is the debian based image used when the host libc
+// can't be identified.
+const defaultDebianImageSource = "docker://ubuntu:20.04"
+
+// errUnsupportedGlibc is returned by debianImageSource for hosts with a glibc
+// older than 2.17, which can't run the e2e debian images.
+var errUnsupportedGlibc = errors.New("glibc older than 2.17 is not supported")
+
+// debianImageSource returns the debian based image to use as the e2e debian
+// image, given the output of ldd --version on the host. Hosts with glibc 2.35
+// or later, or with musl libc, use ubuntu 22.04, and older glibc hosts use
+// ubuntu 20.04. If the output can't be parsed, the default image is returned
+// along with an error. Hosts with glibc older than 2.17 get
+// errUnsupportedGlibc.
+func debianImageSource(lddOutput string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(lddOutput), "musl libc") {
+		return "docker://ubuntu:22.04", nil
+	}
+
+	firstLine, _, _ := strings.Cut(lddOutput, "\n")
+	fields := strings.Fields(firstLine)
+	if len(fields) == 0 {
+		return defaultDebianImageSource, fmt.Errorf("no version found in ldd output %q", firstLine)
+	}
+	version := fields[len(fields)-1]
+	major, minor, ok := strings.Cut(version, ".")
+	if !ok || major != "2" {
+		return defaultDebianImageSource, fmt.Errorf("unexpected glibc version %q in ldd output", version)
+	}
+	lddversion, err := strconv.Atoi(minor)
+	if err != nil {
+		return defaultDebianImageSource, fmt.Errorf("could not convert glibc version %q: %v", version, err)
+	}
+	if lddversion < 17 {
+		return "", fmt.Errorf("%w: found %s", errUnsupportedGlibc, version)
+	}
+
+	if lddversion >= 35 {
+		return "docker://ubuntu:22.04", nil
+	}
+	return "docker://ubuntu:20.04", nil
+}
+
 var orasImageOnce sync.Once
 
 func EnsureORASImage(t *testing.T, env TestEnv) {
@@ -0,0 +1,79 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package e2e
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDebianImageSource(t *testing.T) {
+	tests := []struct {
+		name            string
+		output          string
+		want            string
+		wantErr         bool
+		wantUnsupported bool
+	}{
+		{
+			name:   "GlibcEL8",
+			output: "ldd (GNU libc) 2.28\nCopyright (C) 2018 Free Software Foundation, Inc.\n",
+			want:   "docker://ubuntu:20.04",
+		},
+		{
+			name:   "GlibcUbuntu2204",
+			output: "ldd (Ubuntu GLIBC 2.35-0ubuntu3.8) 2.35\nCopyright (C) 2022 Free Software Foundation, Inc.\n",
+			want:   "docker://ubuntu:22.04",
+		},
+		{
+			name:   "GlibcNoNewline",
+			output: "ldd (GNU libc) 2.39",
+			want:   "docker://ubuntu:22.04",
+		},
+		{
+			name:   "Musl",
+			output: "musl libc (x86_64)\nVersion 1.2.4\nDynamic Program Loader\nUsage: ldd [options] [--] pathname\n",
+			want:   "docker://ubuntu:22.04",
+		},
+		{
+			name:            "GlibcTooOld",
+			output:          "ldd (GNU libc) 2.12\n",
+			want:            "",
+			wantErr:         true,
+			wantUnsupported: true,
+		},
+		{
+			name:    "Empty",
+			output:  "",
+			want:    defaultDebianImageSource,
+			wantErr: true,
+		},
+		{
+			name:    "Unparsable",
+			output:  "ldd: unrecognized option '--version'\n",
+			want:    defaultDebianImageSource,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := debianImageSource(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if errors.Is(err, errUnsupportedGlibc) != tt.wantUnsupported {
+				t.Errorf("unexpected unsupported glibc state, got err=%v, wantUnsupported=%v", err, tt.wantUnsupported)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -12,6 +12,8 @@ package oci
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -147,6 +149,120 @@ func (c ctx) testOciRun(t *testing.T) {
 	)
 }
 
+func (c ctx) testOciInit(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	containerID := randomContainerID(t)
+	bundleDir, umountFn := genericOciMount(t, &c)
+
+	// umount bundle
+	defer umountFn()
+
+	// oci run --init, orphaned processes must be reaped by the init process
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci run"),
+		e2e.WithArgs("--init", "-b", bundleDir, containerID),
+		e2e.ConsoleRun(
+			e2e.ConsoleSendLine("for i in 1 2 3; do (sh -c 'sleep 0.1' &); done"),
+			e2e.ConsoleSendLine("sleep 1"),
+			e2e.ConsoleSendLine("echo zombies=$(grep -l 'State:.*Z' /proc/[0-9]*/status 2>/dev/null | wc -l)"),
+			e2e.ConsoleExpect("zombies=0"),
+			e2e.ConsoleSendLine("exit 3"),
+		),
+		e2e.ExpectExit(3),
+	)
+}
+
+// testOciRunFailure checks that a container that fails to start is reported
+// with the runtime failure exit code, not mistaken for a container exit code.
+func (c ctx) testOciRunFailure(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	containerID := randomContainerID(t)
+	bundleDir, umountFn := genericOciMount(t, &c)
+
+	// umount bundle
+	defer umountFn()
+
+	configJSON := filepath.Join(bundleDir, "config.json")
+	b, err := os.ReadFile(configJSON)
+	if err != nil {
+		t.Fatalf("while reading %s: %s", configJSON, err)
+	}
+	spec := specs.Spec{}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		t.Fatalf("while parsing %s: %s", configJSON, err)
+	}
+	spec.Process.Args = []string{"/does/not/exist"}
+	b, err = json.Marshal(&spec)
+	if err != nil {
+		t.Fatalf("while encoding %s: %s", configJSON, err)
+	}
+	if err := os.WriteFile(configJSON, b, 0o644); err != nil {
+		t.Fatalf("while writing %s: %s", configJSON, err)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci run"),
+		e2e.WithArgs("-b", bundleDir, containerID),
+		e2e.ExpectExit(255),
+	)
+
+	// the failed container must have been deleted
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci state"),
+		e2e.WithArgs(containerID),
+		e2e.ExpectExit(255),
+	)
+}
+
+// testOciRootfs checks that an extracted root filesystem, or OCI bundle,
+// directory is run directly with --rootfs.
+func (c ctx) testOciRootfs(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	bundleDir, umountFn := genericOciMount(t, &c)
+
+	// umount bundle
+	defer umountFn()
+
+	for _, dir := range []string{filepath.Join(bundleDir, "rootfs"), bundleDir} {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(filepath.Base(dir)),
+			e2e.WithProfile(e2e.RootProfile),
+			e2e.WithCommand("oci run"),
+			e2e.WithArgs("--rootfs", dir, randomContainerID(t)),
+			e2e.ConsoleRun(
+				e2e.ConsoleSendLine("hostname"),
+				e2e.ConsoleExpect("apptainer"),
+				e2e.ConsoleSendLine("test -f /.singularity.d/runscript && echo rootfs-ok"),
+				e2e.ConsoleExpect("rootfs-ok"),
+				e2e.ConsoleSendLine("exit"),
+			),
+			e2e.ExpectExit(0),
+		)
+	}
+
+	c.env.RunApptainer(
+		t,
+		e2e.AsSubtest("bundle and rootfs"),
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci run"),
+		e2e.WithArgs("-b", bundleDir, "--rootfs", bundleDir, randomContainerID(t)),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "mutually exclusive"),
+		),
+	)
+}
+
 func (c ctx) testOciAttach(t *testing.T) {
 	e2e.EnsureImage(t, c.env)
 
@@ -187,6 +303,25 @@ func (c ctx) testOciAttach(t *testing.T) {
 		e2e.ExpectExit(0),
 	)
 
+	// detaching leaves the container running
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci attach"),
+		e2e.WithArgs(containerID),
+		e2e.ConsoleRun(
+			e2e.ConsoleSendLine("hostname"),
+			e2e.ConsoleExpect("apptainer"),
+			e2e.ConsoleSend("\x10\x11"),
+		),
+		e2e.PostRun(func(t *testing.T) {
+			if !t.Failed() {
+				c.checkOciState(t, containerID, ociruntime.Running)
+			}
+		}),
+		e2e.ExpectExit(0),
+	)
+
 	c.env.RunApptainer(
 		t,
 		e2e.WithProfile(e2e.RootProfile),
@@ -304,6 +439,45 @@ func (c ctx) testOciBasic(t *testing.T) {
 		e2e.ExpectExit(0),
 	)
 
+	// stdin is passed through for non-interactive exec
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci exec"),
+		e2e.WithArgs(containerID, "cat"),
+		e2e.WithStdin(strings.NewReader("piped data\n")),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.ExactMatch, "piped data"),
+		),
+	)
+
+	// stdin is closed with --interactive=false
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci exec"),
+		e2e.WithArgs("--interactive=false", containerID, "cat"),
+		e2e.WithStdin(strings.NewReader("piped data\n")),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.ExactMatch, ""),
+		),
+	)
+
+	// a terminal requires one on standard input
+	c.env.RunApptainer(
+		t,
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("oci exec"),
+		e2e.WithArgs("-t", containerID, "true"),
+		e2e.WithStdin(strings.NewReader("")),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "the input device is not a TTY"),
+		),
+	)
+
 	c.env.RunApptainer(
 		t,
 		e2e.WithProfile(e2e.RootProfile),
@@ -432,6 +606,9 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 				t.Run("basic", c.testOciBasic)
 				t.Run("attach", c.testOciAttach)
 				t.Run("run", c.testOciRun)
+				t.Run("init", c.testOciInit)
+				t.Run("run failure", c.testOciRunFailure)
+				t.Run("rootfs", c.testOciRootfs)
 				t.Run("help", c.testOciHelp)
 			})),
 	}
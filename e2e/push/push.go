@@ -7,7 +7,8 @@
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
 
-// Package push tests only test the oras transport (and a invalid transport) against a local registry
+// Package push tests test the oras transport against a local registry, the
+// local archive transports, and an invalid transport
 package push
 
 import (
@@ -145,6 +146,57 @@ func (c ctx) testPushCmd(t *testing.T) {
 	}
 }
 
+func (c ctx) testPushArchive(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	archiveDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "push_archive-", "")
+	defer cleanup(t)
+
+	tests := []struct {
+		name       string
+		uri        string
+		archive    string
+		expectExit int
+	}{
+		{
+			name:       "oci-archive",
+			uri:        "oci-archive:" + filepath.Join(archiveDir, "image.oci.tar") + ":test",
+			archive:    filepath.Join(archiveDir, "image.oci.tar"),
+			expectExit: 0,
+		},
+		{
+			name:       "docker-archive",
+			uri:        "docker-archive:" + filepath.Join(archiveDir, "image.docker.tar"),
+			archive:    filepath.Join(archiveDir, "image.docker.tar"),
+			expectExit: 0,
+		},
+		{
+			name:       "non existent directory",
+			uri:        "oci-archive:" + filepath.Join(archiveDir, "missing", "image.oci.tar"),
+			expectExit: 255,
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunApptainer(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("push"),
+			e2e.WithArgs(c.env.ImagePath, tt.uri),
+			e2e.PostRun(func(t *testing.T) {
+				if t.Failed() || tt.archive == "" {
+					return
+				}
+				if _, err := os.Stat(tt.archive); err != nil {
+					t.Errorf("archive %s was not created: %v", tt.archive, err)
+				}
+			}),
+			e2e.ExpectExit(tt.expectExit),
+		)
+	}
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := ctx{
@@ -154,5 +206,6 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	return testhelper.Tests{
 		"invalid transport": c.testInvalidTransport,
 		"oras":              c.testPushCmd,
+		"archive":           c.testPushArchive,
 	}
 }
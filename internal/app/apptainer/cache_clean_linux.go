@@ -12,8 +12,10 @@ package apptainer
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/slice"
 )
@@ -34,8 +36,10 @@ func cleanCache(imgCache *cache.Handle, cacheType string, dryRun bool, days int)
 // provide a summary of what would have been done. If cacheCleanTypes
 // contains something, only clean that type. The special value "all" is
 // interpreted as "all types of entries". If cacheName contains
-// something, clean only cache entries matching that name.
-func CleanApptainerCache(imgCache *cache.Handle, dryRun bool, cacheCleanTypes []string, days int) error {
+// something, clean only cache entries matching that name. If maxSize is
+// greater than zero, or days is, entries are evicted by last use instead,
+// until they are more recent than days and fit in maxSize bytes.
+func CleanApptainerCache(imgCache *cache.Handle, dryRun bool, cacheCleanTypes []string, days int, maxSize int64) error {
 	if imgCache == nil {
 		return errInvalidCacheHandle
 	}
@@ -49,6 +53,14 @@ func CleanApptainerCache(imgCache *cache.Handle, dryRun bool, cacheCleanTypes []
 		cachesToClean = cacheCleanTypes
 	}
 
+	if maxSize > 0 || days > 0 {
+		maxAge := time.Duration(days) * 24 * time.Hour
+		stats, err := imgCache.Clean(cachesToClean, maxAge, maxSize, dryRun)
+		sylog.Infof("Removed %d cache entries (%s), %d entries (%s) left",
+			stats.Removed, fs.FindSize(stats.Freed), stats.Kept, fs.FindSize(stats.Size))
+		return err
+	}
+
 	for _, cacheType := range cachesToClean {
 		sylog.Debugf("Cleaning %s cache...", cacheType)
 		if err := cleanCache(imgCache, cacheType, dryRun, days); err != nil {
@@ -0,0 +1,61 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// VerifyApptainerCache checks the blobs of the OCI blob cache against their
+// digests, reporting each corrupted blob. If remove is set, the corrupted
+// blobs are removed, so that they are fetched again when needed. Otherwise,
+// an error is returned if any blob is corrupted.
+func VerifyApptainerCache(imgCache *cache.Handle, remove bool) error {
+	if imgCache == nil {
+		return errInvalidCacheHandle
+	}
+
+	results, err := imgCache.VerifyOciBlobs()
+	if err != nil {
+		return fmt.Errorf("while verifying blobs: %v", err)
+	}
+
+	corrupted := 0
+	removeErrs := 0
+	for _, r := range results {
+		if r.Err == nil {
+			sylog.Verbosef("Blob %s is valid", r.Digest)
+			continue
+		}
+		sylog.Errorf("Blob %s is corrupted: %v", r.Digest, r.Err)
+		corrupted++
+		if !remove {
+			continue
+		}
+		if err := os.Remove(r.Path); err != nil {
+			sylog.Errorf("Could not remove blob %s: %v", r.Digest, err)
+			removeErrs++
+			continue
+		}
+		sylog.Infof("Removed corrupted blob %s", r.Digest)
+	}
+
+	fmt.Printf("Verified %d blobs, %d corrupted\n", len(results), corrupted)
+	switch {
+	case removeErrs > 0:
+		return fmt.Errorf("failed to remove %d corrupted blobs", removeErrs)
+	case corrupted > 0 && !remove:
+		return fmt.Errorf("%d corrupted blobs found, remove them with 'apptainer cache verify --remove'", corrupted)
+	}
+	return nil
+}
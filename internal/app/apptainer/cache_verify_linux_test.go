@@ -0,0 +1,65 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+)
+
+func TestVerifyApptainerCacheRemove(t *testing.T) {
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		t.Fatalf("while getting cache dir: %v", err)
+	}
+	blobsDir := filepath.Join(layoutDir, "blobs", "sha256")
+	if err := os.MkdirAll(blobsDir, 0o700); err != nil {
+		t.Fatalf("while creating blobs dir: %v", err)
+	}
+	writeBlob := func(content, storedAs string) string {
+		sum := sha256.Sum256([]byte(storedAs))
+		path := filepath.Join(blobsDir, hex.EncodeToString(sum[:]))
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("while writing blob: %v", err)
+		}
+		return path
+	}
+	good := writeBlob("good", "good")
+	corrupt := writeBlob("corrupt", "original")
+
+	if err := VerifyApptainerCache(imgCache, false); err == nil {
+		t.Errorf("expected error for corrupted blob")
+	}
+	if _, err := os.Stat(corrupt); err != nil {
+		t.Errorf("corrupted blob removed without --remove: %v", err)
+	}
+
+	if err := VerifyApptainerCache(imgCache, true); err != nil {
+		t.Errorf("unexpected error removing corrupted blob: %v", err)
+	}
+	if _, err := os.Stat(corrupt); !os.IsNotExist(err) {
+		t.Errorf("corrupted blob not removed: %v", err)
+	}
+	if _, err := os.Stat(good); err != nil {
+		t.Errorf("valid blob removed: %v", err)
+	}
+
+	if err := VerifyApptainerCache(imgCache, false); err != nil {
+		t.Errorf("unexpected error once corrupted blob removed: %v", err)
+	}
+}
@@ -40,6 +40,7 @@ type instanceInfo struct {
 	IP         string `json:"ip"`
 	LogErrPath string `json:"logErrPath"`
 	LogOutPath string `json:"logOutPath"`
+	Health     string `json:"health,omitempty"`
 }
 
 // PrintInstanceList fetches instance list, applying name and
@@ -75,13 +76,30 @@ func PrintInstanceList(w io.Writer, name, user string, formatJSON bool, showLogs
 	}
 
 	if !formatJSON {
-		_, err := fmt.Fprintln(tabWriter, "INSTANCE NAME\tPID\tIP\tIMAGE")
+		// only show the health column when an instance runs a healthcheck
+		showHealth := false
+		for _, i := range ii {
+			if i.Health != "" {
+				showHealth = true
+				break
+			}
+		}
+
+		header := "INSTANCE NAME\tPID\tIP\tIMAGE"
+		if showHealth {
+			header += "\tHEALTH"
+		}
+		_, err := fmt.Fprintln(tabWriter, header)
 		if err != nil {
 			return fmt.Errorf("could not write list header: %v", err)
 		}
 
 		for _, i := range ii {
-			_, err = fmt.Fprintf(tabWriter, "%s\t%d\t%s\t%s\n", i.Name, i.Pid, i.IP, i.Image)
+			if showHealth {
+				_, err = fmt.Fprintf(tabWriter, "%s\t%d\t%s\t%s\t%s\n", i.Name, i.Pid, i.IP, i.Image, i.Health)
+			} else {
+				_, err = fmt.Fprintf(tabWriter, "%s\t%d\t%s\t%s\n", i.Name, i.Pid, i.IP, i.Image)
+			}
 			if err != nil {
 				return fmt.Errorf("could not write instance info: %v", err)
 			}
@@ -97,6 +115,7 @@ func PrintInstanceList(w io.Writer, name, user string, formatJSON bool, showLogs
 		instances[i].IP = ii[i].IP
 		instances[i].LogErrPath = ii[i].LogErrPath
 		instances[i].LogOutPath = ii[i].LogOutPath
+		instances[i].Health = ii[i].Health
 	}
 
 	enc := json.NewEncoder(w)
@@ -12,12 +12,15 @@ package apptainer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	osignal "os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/creack/pty"
@@ -67,7 +70,95 @@ func resize(controlSocket string, oversized bool) {
 	}
 }
 
-func attach(engineConfig *oci.EngineConfig, run bool) error {
+// DefaultDetachKeys is the key sequence detaching from a container console,
+// as with docker attach.
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+// errDetached is returned by a detachReader once the detach keys are read.
+var errDetached = errors.New("detached from container")
+
+// parseDetachKeys returns the bytes of a comma separated sequence of keys,
+// each being a single character or ctrl-<value>, with value a letter or one
+// of @, [, \, ], ^ and _. An empty sequence disables detaching.
+func parseDetachKeys(keys string) ([]byte, error) {
+	if keys == "" {
+		return nil, nil
+	}
+	var seq []byte
+	for _, key := range strings.Split(keys, ",") {
+		if len(key) == 1 {
+			seq = append(seq, key[0])
+			continue
+		}
+		value, ok := strings.CutPrefix(strings.ToLower(key), "ctrl-")
+		if !ok || len(value) != 1 {
+			return nil, fmt.Errorf("invalid detach key %q", key)
+		}
+		switch c := value[0]; {
+		case c >= 'a' && c <= 'z':
+			seq = append(seq, c-'a'+1)
+		case c == '@' || c == '[' || c == '\\' || c == ']' || c == '^' || c == '_':
+			seq = append(seq, c-'@')
+		default:
+			return nil, fmt.Errorf("invalid detach key %q", key)
+		}
+	}
+	return seq, nil
+}
+
+// detachReader reads from r until the detach keys are read, returning
+// errDetached then. Input matching the start of the detach keys is held
+// back, and only passed on if the following input doesn't complete them.
+type detachReader struct {
+	r        io.Reader
+	keys     []byte
+	matched  int
+	pending  []byte
+	detached bool
+}
+
+func (d *detachReader) Read(p []byte) (int, error) {
+	if len(d.keys) == 0 {
+		return d.r.Read(p)
+	}
+	buf := make([]byte, len(p))
+	for len(d.pending) == 0 {
+		if d.detached {
+			return 0, errDetached
+		}
+		n, err := d.r.Read(buf)
+		for _, b := range buf[:n] {
+			if b != d.keys[d.matched] && d.matched > 0 {
+				d.pending = append(d.pending, d.keys[:d.matched]...)
+				d.matched = 0
+			}
+			if b != d.keys[d.matched] {
+				d.pending = append(d.pending, b)
+				continue
+			}
+			d.matched++
+			if d.matched == len(d.keys) {
+				// input following the detach keys is dropped
+				d.detached = true
+				break
+			}
+		}
+		if d.detached {
+			continue
+		}
+		if err != nil {
+			if len(d.pending) > 0 {
+				break
+			}
+			return 0, err
+		}
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+func attach(engineConfig *oci.EngineConfig, run bool, detachKeys []byte) error {
 	var ostate *term.State
 	var conn net.Conn
 	var wg sync.WaitGroup
@@ -121,19 +212,30 @@ func attach(engineConfig *oci.EngineConfig, run bool) error {
 	}()
 
 	if hasTerminal || !run {
+		var detached atomic.Bool
+
 		// Pipe session to bash and visa-versa
 		go func() {
 			io.Copy(os.Stdout, conn)
 			wg.Done()
 		}()
 		go func() {
-			io.Copy(conn, os.Stdin)
+			stdin := &detachReader{r: os.Stdin, keys: detachKeys}
+			if _, err := io.Copy(conn, stdin); errors.Is(err, errDetached) {
+				detached.Store(true)
+				conn.Close()
+			}
 		}()
 		wg.Wait()
 
 		if hasTerminal {
 			fmt.Printf("\r")
-			return term.Restore(0, ostate)
+			if err := term.Restore(0, ostate); err != nil {
+				return err
+			}
+		}
+		if detached.Load() {
+			sylog.Infof("Detached from container, still running")
 		}
 		return nil
 	}
@@ -142,8 +244,13 @@ func attach(engineConfig *oci.EngineConfig, run bool) error {
 	return nil
 }
 
-// OciAttach attaches console to a running container
-func OciAttach(ctx context.Context, containerID string) error {
+// OciAttach attaches console to a running container, until the container
+// process exits or detachKeys are typed.
+func OciAttach(ctx context.Context, containerID string, detachKeys string) error {
+	keys, err := parseDetachKeys(detachKeys)
+	if err != nil {
+		return err
+	}
 	engineConfig, err := getEngineConfig(containerID)
 	if err != nil {
 		return err
@@ -154,5 +261,5 @@ func OciAttach(ctx context.Context, containerID string) error {
 
 	defer exitContainer(ctx, containerID, false)
 
-	return attach(engineConfig, false)
+	return attach(engineConfig, false, keys)
 }
@@ -0,0 +1,89 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseDetachKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    string
+		want    []byte
+		wantErr bool
+	}{
+		{name: "Default", keys: DefaultDetachKeys, want: []byte{0x10, 0x11}},
+		{name: "Empty", keys: "", want: nil},
+		{name: "Char", keys: "a,ctrl-x", want: []byte{'a', 0x18}},
+		{name: "CtrlUpper", keys: "CTRL-P", want: []byte{0x10}},
+		{name: "CtrlSymbols", keys: "ctrl-@,ctrl-[,ctrl-\\,ctrl-],ctrl-^,ctrl-_", want: []byte{0, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}},
+		{name: "BadCtrl", keys: "ctrl-1", wantErr: true},
+		{name: "BadKey", keys: "alt-p", wantErr: true},
+		{name: "EmptyKey", keys: "ctrl-p,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDetachKeys(tt.keys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetachReader(t *testing.T) {
+	keys := []byte{0x10, 0x11}
+
+	tests := []struct {
+		name       string
+		input      []byte
+		want       []byte
+		wantDetach bool
+	}{
+		{name: "NoKeys", input: []byte("ls\n"), want: []byte("ls\n")},
+		{name: "Detach", input: []byte("ls\x10\x11exit\n"), want: []byte("ls"), wantDetach: true},
+		{name: "PartialKeys", input: []byte("a\x10b\n"), want: []byte("a\x10b\n")},
+		{name: "RepeatedFirstKey", input: []byte("\x10\x10\x11"), want: []byte("\x10"), wantDetach: true},
+		{name: "PartialKeysAtEOF", input: []byte("a\x10"), want: []byte("a")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// one byte reads split the detach keys across reads
+			for _, r := range []io.Reader{bytes.NewReader(tt.input), iotest.OneByteReader(bytes.NewReader(tt.input))} {
+				var out bytes.Buffer
+				_, err := io.Copy(&out, &detachReader{r: r, keys: keys})
+				if detached := errors.Is(err, errDetached); detached != tt.wantDetach {
+					t.Errorf("got detached %v (%v), want %v", detached, err, tt.wantDetach)
+				}
+				if !bytes.Equal(out.Bytes(), tt.want) {
+					t.Errorf("got %q, want %q", out.Bytes(), tt.want)
+				}
+			}
+		})
+	}
+
+	// without detach keys, the input is passed on unchanged
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, &detachReader{r: bytes.NewReader(keys)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), keys) {
+		t.Errorf("got %q, want %q", out.Bytes(), keys)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/apptainer/apptainer/internal/pkg/build"
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/client/oci"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	buildtypes "github.com/apptainer/apptainer/pkg/build/types"
+	"github.com/apptainer/apptainer/pkg/ocibundle/tools"
+	"github.com/apptainer/apptainer/pkg/ociruntime"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// OciCommit writes the image of a container, with the changes recorded in the
+// writable overlay of its bundle added as a new layer, to a new SIF image at
+// imagePath. The container process configuration is saved as the image
+// configuration, so that the new image runs the same process.
+func OciCommit(ctx context.Context, containerID, imagePath string) error {
+	engineConfig, err := getEngineConfig(containerID)
+	if err != nil {
+		return err
+	}
+	if engineConfig.State.Status == ociruntime.Running {
+		sylog.Warningf("Container %s is running, pause it to commit a consistent root filesystem", containerID)
+	}
+
+	bundlePath := engineConfig.GetBundlePath()
+	upperDir := tools.OverlayUpperDir(bundlePath)
+	if !fs.IsDir(upperDir) {
+		return fmt.Errorf("container %s has no writable overlay to commit, its bundle must be created with oci mount", containerID)
+	}
+	source := engineConfig.OciConfig.Annotations[ociruntime.AnnotationImage]
+	if source == "" {
+		return fmt.Errorf("container %s has no source image to commit on, its bundle must be created with oci mount", containerID)
+	}
+
+	base, cleanup, err := oci.SIFImage(source, "")
+	if err != nil {
+		return fmt.Errorf("while reading source image %s: %w", source, err)
+	}
+	defer cleanup()
+
+	layer, layerCleanup, err := ociimage.LayerFromOverlay(upperDir, "")
+	if err != nil {
+		return fmt.Errorf("while creating layer from %s: %w", upperDir, err)
+	}
+	defer layerCleanup()
+	img, err := commitImage(base, layer, &engineConfig.OciConfig.Spec)
+	if err != nil {
+		return err
+	}
+
+	sylog.Infof("Committing the changes of container %s on top of %s", containerID, source)
+	return buildCommitSIF(ctx, img, imagePath)
+}
+
+// buildCommitSIF builds img into a new SIF image at imagePath, from a
+// temporary OCI layout, as apptainer build does for an oci: source. The
+// layers are squashed into the root filesystem partition, and the image
+// configuration is recorded for oci mount.
+func buildCommitSIF(ctx context.Context, img v1.Image, imagePath string) error {
+	layoutDir, err := os.MkdirTemp("", "commit-layout-")
+	if err != nil {
+		return fmt.Errorf("could not create temporary directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(layoutDir); err != nil {
+			sylog.Errorf("while removing %q: %v", layoutDir, err)
+		}
+	}()
+	if err := ociimage.OCISourceSink.WriteImage(img, layoutDir, nil); err != nil {
+		return fmt.Errorf("while writing image layout: %w", err)
+	}
+
+	imgCache, err := cache.New(cache.Config{Disable: true})
+	if err != nil {
+		return fmt.Errorf("while creating image cache handle: %w", err)
+	}
+	b, err := build.NewBuild("oci:"+layoutDir, build.Config{
+		Dest:   imagePath,
+		Format: "sif",
+		Opts: buildtypes.Options{
+			NoCache:  true,
+			NoTest:   true,
+			ImgCache: imgCache,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create new build: %w", err)
+	}
+	return b.Full(ctx)
+}
+
+// commitImage returns the base image with layer added on top, configured to
+// run the process of the container spec.
+func commitImage(base v1.Image, layer v1.Layer, spec *specs.Spec) (v1.Image, error) {
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer: layer,
+		History: v1.History{
+			Created:   v1.Time{Time: time.Now()},
+			CreatedBy: "apptainer oci commit",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while adding layer: %w", err)
+	}
+
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("while reading image config: %w", err)
+	}
+	cf = cf.DeepCopy()
+	cf.Config = commitConfig(cf.Config, spec)
+
+	img, err = mutate.ConfigFile(img, cf)
+	if err != nil {
+		return nil, fmt.Errorf("while setting image config: %w", err)
+	}
+	return img, nil
+}
+
+// commitConfig returns the image configuration config, updated to run the
+// process of the container spec. The process args already include the
+// entrypoint, which is reset.
+func commitConfig(config v1.Config, spec *specs.Spec) v1.Config {
+	if spec.Process != nil {
+		config.Entrypoint = nil
+		config.Cmd = spec.Process.Args
+		config.Env = spec.Process.Env
+		config.WorkingDir = ""
+		if spec.Process.Cwd != "/" {
+			config.WorkingDir = spec.Process.Cwd
+		}
+		config.User = ""
+		if u := spec.Process.User; u.UID != 0 || u.GID != 0 {
+			config.User = fmt.Sprintf("%d:%d", u.UID, u.GID)
+		}
+	}
+	if sig := spec.Annotations[ociruntime.AnnotationStopSignal]; sig != "" {
+		config.StopSignal = sig
+	}
+	return config
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apptainer/apptainer/pkg/ociruntime"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestCommitConfig(t *testing.T) {
+	base := v1.Config{
+		Entrypoint: []string{"/docker-entrypoint.sh"},
+		Cmd:        []string{"nginx"},
+		WorkingDir: "/app",
+		User:       "nginx",
+		Labels:     map[string]string{"maintainer": "me"},
+	}
+
+	tests := []struct {
+		name string
+		spec specs.Spec
+		want v1.Config
+	}{
+		{
+			name: "NoProcess",
+			want: base,
+		},
+		{
+			name: "Root",
+			spec: specs.Spec{
+				Process: &specs.Process{
+					Args: []string{"/.singularity.d/actions/run"},
+					Env:  []string{"PATH=/bin"},
+					Cwd:  "/",
+				},
+			},
+			want: v1.Config{
+				Cmd:    []string{"/.singularity.d/actions/run"},
+				Env:    []string{"PATH=/bin"},
+				Labels: base.Labels,
+			},
+		},
+		{
+			name: "UserWorkingDirStopSignal",
+			spec: specs.Spec{
+				Process: &specs.Process{
+					Args: []string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"},
+					Cwd:  "/srv",
+					User: specs.User{UID: 101, GID: 101},
+				},
+				Annotations: map[string]string{ociruntime.AnnotationStopSignal: "SIGQUIT"},
+			},
+			want: v1.Config{
+				Cmd:        []string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"},
+				WorkingDir: "/srv",
+				User:       "101:101",
+				Labels:     base.Labels,
+				StopSignal: "SIGQUIT",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commitConfig(base, &tt.spec)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommitImage(t *testing.T) {
+	base, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("while creating base image: %v", err)
+	}
+	layer, err := random.Layer(64, "")
+	if err != nil {
+		t.Fatalf("while creating layer: %v", err)
+	}
+	spec := &specs.Spec{
+		Process: &specs.Process{Args: []string{"/bin/true"}, Cwd: "/"},
+	}
+
+	img, err := commitImage(base, layer, spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	baseLayers, err := base.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("while reading layers: %v", err)
+	}
+	if len(layers) != len(baseLayers)+1 {
+		t.Fatalf("expected %d layers, got %d", len(baseLayers)+1, len(layers))
+	}
+	for i, l := range append(baseLayers, layer) {
+		want, _ := l.Digest()
+		got, _ := layers[i].Digest()
+		if got != want {
+			t.Errorf("layer %d: expected digest %s, got %s", i, want, got)
+		}
+	}
+
+	cf, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("while reading config: %v", err)
+	}
+	if !reflect.DeepEqual(cf.Config.Cmd, spec.Process.Args) {
+		t.Errorf("expected cmd %v, got %v", spec.Process.Args, cf.Config.Cmd)
+	}
+	if n := len(cf.RootFS.DiffIDs); n != len(layers) {
+		t.Errorf("expected %d diff ids, got %d", len(layers), n)
+	}
+}
@@ -16,10 +16,13 @@ import (
 	"os"
 	"path/filepath"
 
+	ociconfig "github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/oci"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/internal/pkg/util/starter"
 	"github.com/apptainer/apptainer/pkg/runtime/engine/config"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
 )
 
 // OciCreate creates a container from an OCI bundle
@@ -29,9 +32,19 @@ func OciCreate(containerID string, args *OciArgs) error {
 		return fmt.Errorf("%s already exists", containerID)
 	}
 
+	bundlePath := args.BundlePath
+	if args.RootfsPath != "" {
+		if bundlePath != "" {
+			return fmt.Errorf("--bundle and --rootfs options are mutually exclusive")
+		}
+		bundlePath = args.RootfsPath
+	} else if bundlePath == "" {
+		return fmt.Errorf("an OCI bundle (--bundle) or root filesystem (--rootfs) path is required")
+	}
+
 	os.Clearenv()
 
-	absBundle, err := filepath.Abs(args.BundlePath)
+	absBundle, err := filepath.Abs(bundlePath)
 	if err != nil {
 		return fmt.Errorf("failed to determine bundle absolute path: %s", err)
 	}
@@ -47,25 +60,29 @@ func OciCreate(containerID string, args *OciArgs) error {
 	engineConfig.SetLogFormat(args.LogFormat)
 	engineConfig.SetPidFile(args.PidFile)
 
-	// load config.json from bundle path
 	configJSON := filepath.Join(absBundle, "config.json")
-	fb, err := os.Open(configJSON)
-	if err != nil {
-		return fmt.Errorf("oci specification file %q is missing or cannot be read", configJSON)
+	if args.RootfsPath != "" && !fs.IsFile(configJSON) {
+		// the directory is a root filesystem, run it directly as the bundle,
+		// rather than extracting it to a new bundle with oci mount
+		if err := rootfsConfig(generator); err != nil {
+			return err
+		}
+	} else if err := loadConfig(configJSON, generator); err != nil {
+		return err
 	}
 
-	data, err := io.ReadAll(fb)
-	if err != nil {
-		return fmt.Errorf("failed to read OCI specification file %s: %s", configJSON, err)
+	if err := addDevices(generator.Config, args.Devices); err != nil {
+		return err
 	}
-
-	fb.Close()
-
-	if err := json.Unmarshal(data, generator.Config); err != nil {
-		return fmt.Errorf("failed to parse OCI specification file %s: %s", configJSON, err)
+	if err := addSecurity(generator, args.Security); err != nil {
+		return err
+	}
+	if err := addConfigHooks(generator.Config, apptainerconf.GetCurrentConfig()); err != nil {
+		return err
 	}
 
 	engineConfig.EmptyProcess = args.EmptyProcess
+	engineConfig.Init = args.Init
 	engineConfig.SyncSocket = args.SyncSocketPath
 
 	commonConfig := &config.Common{
@@ -83,3 +100,36 @@ func OciCreate(containerID string, args *OciArgs) error {
 		starter.WithStdout(os.Stdout),
 	)
 }
+
+// loadConfig loads the OCI specification from configJSON into generator.
+func loadConfig(configJSON string, generator *generate.Generator) error {
+	fb, err := os.Open(configJSON)
+	if err != nil {
+		return fmt.Errorf("oci specification file %q is missing or cannot be read", configJSON)
+	}
+
+	data, err := io.ReadAll(fb)
+	if err != nil {
+		return fmt.Errorf("failed to read OCI specification file %s: %s", configJSON, err)
+	}
+
+	fb.Close()
+
+	if err := json.Unmarshal(data, generator.Config); err != nil {
+		return fmt.Errorf("failed to parse OCI specification file %s: %s", configJSON, err)
+	}
+	return nil
+}
+
+// rootfsConfig sets generator to the default OCI specification used by oci
+// mount, running a shell, with the bundle directory itself as root
+// filesystem.
+func rootfsConfig(generator *generate.Generator) error {
+	g, err := ociconfig.DefaultConfig()
+	if err != nil {
+		return fmt.Errorf("failed to generate OCI config: %s", err)
+	}
+	g.SetRootPath(".")
+	*generator.Config = *g.Config
+	return nil
+}
@@ -31,7 +31,7 @@ func OciDelete(ctx context.Context, containerID string) error {
 		return fmt.Errorf("cannot delete '%s', the state of the container must be created or stopped", containerID)
 	case ociruntime.Stopped:
 	case ociruntime.Created:
-		if err := OciKill(containerID, "SIGTERM", 2); err != nil {
+		if err := OciKill(containerID, "", 2); err != nil {
 			return err
 		}
 		engineConfig, err = getEngineConfig(containerID)
@@ -0,0 +1,118 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// defaultDevicePerms are the cgroup permissions given to a device when none
+// are specified, as with docker --device.
+const defaultDevicePerms = "rwm"
+
+// parseDevice parses a --device value of the form
+// host-path[:container-path][:permissions], returning the spec entries that
+// create the host device node in the container, and allow its use in the
+// container cgroup.
+func parseDevice(value string) (specs.LinuxDevice, specs.LinuxDeviceCgroup, error) {
+	var (
+		dev  specs.LinuxDevice
+		rule specs.LinuxDeviceCgroup
+	)
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return dev, rule, fmt.Errorf("invalid device %q, expected host-path[:container-path][:permissions]", value)
+	}
+	hostPath := parts[0]
+	containerPath := hostPath
+	perms := defaultDevicePerms
+	switch len(parts) {
+	case 2:
+		// A single extra field is a path if absolute, permissions otherwise.
+		if filepath.IsAbs(parts[1]) {
+			containerPath = parts[1]
+		} else {
+			perms = parts[1]
+		}
+	case 3:
+		containerPath = parts[1]
+		perms = parts[2]
+	}
+
+	if !filepath.IsAbs(hostPath) || !filepath.IsAbs(containerPath) {
+		return dev, rule, fmt.Errorf("invalid device %q, paths must be absolute", value)
+	}
+	if perms == "" || strings.Trim(perms, "rwm") != "" {
+		return dev, rule, fmt.Errorf("invalid device permissions %q, must be a combination of r, w and m", perms)
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(hostPath, &st); err != nil {
+		return dev, rule, fmt.Errorf("while getting information for device %s: %w", hostPath, err)
+	}
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFCHR:
+		dev.Type = "c"
+	case unix.S_IFBLK:
+		dev.Type = "b"
+	default:
+		return dev, rule, fmt.Errorf("%s is not a character or block device", hostPath)
+	}
+
+	major := int64(unix.Major(uint64(st.Rdev)))
+	minor := int64(unix.Minor(uint64(st.Rdev)))
+	mode := os.FileMode(st.Mode &^ unix.S_IFMT)
+	uid := st.Uid
+	gid := st.Gid
+
+	dev.Path = containerPath
+	dev.Major = major
+	dev.Minor = minor
+	dev.FileMode = &mode
+	dev.UID = &uid
+	dev.GID = &gid
+
+	rule = specs.LinuxDeviceCgroup{
+		Allow:  true,
+		Type:   dev.Type,
+		Major:  &major,
+		Minor:  &minor,
+		Access: perms,
+	}
+	return dev, rule, nil
+}
+
+// addDevices adds the host devices given as --device values to spec.
+func addDevices(spec *specs.Spec, devices []string) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+	if spec.Linux.Resources == nil {
+		spec.Linux.Resources = &specs.LinuxResources{}
+	}
+	for _, d := range devices {
+		dev, rule, err := parseDevice(d)
+		if err != nil {
+			return err
+		}
+		spec.Linux.Devices = append(spec.Linux.Devices, dev)
+		spec.Linux.Resources.Devices = append(spec.Linux.Resources.Devices, rule)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// findBlockDevice returns the path of a block device on the host.
+func findBlockDevice(t *testing.T) string {
+	t.Helper()
+
+	entries, err := os.ReadDir("/dev")
+	if err != nil {
+		t.Fatalf("while reading /dev: %v", err)
+	}
+	for _, e := range entries {
+		if e.Type()&os.ModeDevice != 0 && e.Type()&os.ModeCharDevice == 0 {
+			return filepath.Join("/dev", e.Name())
+		}
+	}
+	t.Skip("no block device found in /dev")
+	return ""
+}
+
+func TestAddDevices(t *testing.T) {
+	tests := []struct {
+		name string
+		// device is appended to the path of a host block device if
+		// blockDevice is set, the test being skipped if there is none.
+		device      string
+		blockDevice bool
+		wantType    string
+		wantPath    string
+		wantAccess  string
+		wantErr     bool
+	}{
+		{
+			name:       "CharDevice",
+			device:     "/dev/null",
+			wantType:   "c",
+			wantPath:   "/dev/null",
+			wantAccess: "rwm",
+		},
+		{
+			name:       "CharDeviceContainerPath",
+			device:     "/dev/null:/dev/mynull",
+			wantType:   "c",
+			wantPath:   "/dev/mynull",
+			wantAccess: "rwm",
+		},
+		{
+			name:       "CharDevicePerms",
+			device:     "/dev/null:rw",
+			wantType:   "c",
+			wantPath:   "/dev/null",
+			wantAccess: "rw",
+		},
+		{
+			name:        "BlockDevice",
+			device:      ":/dev/xvda:r",
+			blockDevice: true,
+			wantType:    "b",
+			wantPath:    "/dev/xvda",
+			wantAccess:  "r",
+		},
+		{
+			name:    "NotDevice",
+			device:  "/etc/passwd",
+			wantErr: true,
+		},
+		{
+			name:    "Missing",
+			device:  "/dev/does-not-exist",
+			wantErr: true,
+		},
+		{
+			name:    "BadPerms",
+			device:  "/dev/null:/dev/null:rwx",
+			wantErr: true,
+		},
+		{
+			name:    "RelativePath",
+			device:  "/dev/null:null:rw",
+			wantErr: true,
+		},
+		{
+			name:    "TooManyFields",
+			device:  "/dev/null:/dev/null:rw:extra",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := tt.device
+			if tt.blockDevice {
+				device = findBlockDevice(t) + device
+			}
+
+			spec := &specs.Spec{}
+			err := addDevices(spec, []string{device})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(spec.Linux.Devices) != 1 || len(spec.Linux.Resources.Devices) != 1 {
+				t.Fatalf("expected one device and one cgroup rule, got %+v", spec.Linux)
+			}
+			dev := spec.Linux.Devices[0]
+			rule := spec.Linux.Resources.Devices[0]
+
+			if dev.Type != tt.wantType || dev.Path != tt.wantPath {
+				t.Errorf("expected %s device at %s, got %s device at %s", tt.wantType, tt.wantPath, dev.Type, dev.Path)
+			}
+			if !rule.Allow || rule.Type != tt.wantType || rule.Access != tt.wantAccess {
+				t.Errorf("expected allowed %s rule with access %q, got %+v", tt.wantType, tt.wantAccess, rule)
+			}
+			if rule.Major == nil || rule.Minor == nil || *rule.Major != dev.Major || *rule.Minor != dev.Minor {
+				t.Errorf("cgroup rule does not match device %d:%d", dev.Major, dev.Minor)
+			}
+		})
+	}
+
+	// /dev/null is always character device 1:3.
+	spec := &specs.Spec{}
+	if err := addDevices(spec, []string{"/dev/null"}); err != nil {
+		t.Fatalf("while adding /dev/null: %v", err)
+	}
+	if dev := spec.Linux.Devices[0]; dev.Major != 1 || dev.Minor != 3 {
+		t.Errorf("expected /dev/null to be 1:3, got %d:%d", dev.Major, dev.Minor)
+	}
+}
@@ -17,10 +17,12 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/oci"
 	"github.com/apptainer/apptainer/internal/pkg/util/starter"
 	"github.com/apptainer/apptainer/pkg/ociruntime"
+	"golang.org/x/sys/unix"
 )
 
-// OciExec executes a command in a container
-func OciExec(containerID string, cmdArgs []string) error { //nolint:staticcheck
+// OciExec executes a command in a container. The command keeps the caller's
+// standard streams, unless --interactive=false closes its standard input.
+func OciExec(containerID string, cmdArgs []string, args *OciArgs) error { //nolint:staticcheck
 	commonConfig, err := getCommonConfig(containerID)
 	if err != nil {
 		return fmt.Errorf("%s doesn't exist", containerID)
@@ -38,8 +40,24 @@ func OciExec(containerID string, cmdArgs []string) error { //nolint:staticcheck
 	engineConfig.Exec = true
 	engineConfig.OciConfig.SetProcessArgs(cmdArgs)
 
+	if args.OverrideInteractive && !args.Interactive {
+		if err := nullStdin(); err != nil {
+			return fmt.Errorf("while closing standard input: %s", err)
+		}
+	}
+
 	os.Clearenv()
 
 	procName := fmt.Sprintf("Apptainer OCI %s", containerID)
 	return starter.Exec(procName, commonConfig)
 }
+
+// nullStdin replaces the standard input of the process with /dev/null.
+func nullStdin() error {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return unix.Dup2(int(f.Fd()), int(os.Stdin.Fd()))
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestNullStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if _, err := w.WriteString("piped data\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// run with the pipe as standard input, and restore the original one
+	saved, err := unix.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		unix.Dup2(saved, int(os.Stdin.Fd()))
+		unix.Close(saved)
+	}()
+	if err := unix.Dup2(int(r.Fd()), int(os.Stdin.Fd())); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nullStdin(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		t.Fatalf("while reading standard input: %v", err)
+	}
+	if len(b) != 0 {
+		t.Errorf("got %q from standard input, want nothing", b)
+	}
+}
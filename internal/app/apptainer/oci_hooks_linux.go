@@ -0,0 +1,66 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// configHook checks that path, from the apptainer.conf directive, is an
+// absolute path to an executable file, and returns the corresponding hook.
+func configHook(directive, path string) (specs.Hook, error) {
+	if !filepath.IsAbs(path) {
+		return specs.Hook{}, fmt.Errorf("%s: hook path %q is not absolute", directive, path)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return specs.Hook{}, fmt.Errorf("%s: %w", directive, err)
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm()&0o111 == 0 {
+		return specs.Hook{}, fmt.Errorf("%s: hook %s is not an executable file", directive, path)
+	}
+	return specs.Hook{Path: path, Args: []string{path}}, nil
+}
+
+// addConfigHooks appends the hooks configured in apptainer.conf to spec, in
+// their respective phase, after any hooks defined by the bundle.
+func addConfigHooks(spec *specs.Spec, conf *apptainerconf.File) error {
+	if conf == nil {
+		return nil
+	}
+
+	if spec.Hooks == nil {
+		spec.Hooks = &specs.Hooks{}
+	}
+	phases := []struct {
+		directive string
+		paths     []string
+		hooks     *[]specs.Hook
+	}{
+		{"oci createruntime hooks", conf.OCICreateRuntimeHooks, &spec.Hooks.CreateRuntime},
+		{"oci startcontainer hooks", conf.OCIStartContainerHooks, &spec.Hooks.StartContainer},
+		{"oci poststart hooks", conf.OCIPoststartHooks, &spec.Hooks.Poststart},
+		{"oci poststop hooks", conf.OCIPoststopHooks, &spec.Hooks.Poststop},
+	}
+	for _, p := range phases {
+		for _, path := range p.paths {
+			h, err := configHook(p.directive, path)
+			if err != nil {
+				return err
+			}
+			*p.hooks = append(*p.hooks, h)
+		}
+	}
+	return nil
+}
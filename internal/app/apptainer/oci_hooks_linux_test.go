@@ -0,0 +1,96 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestAddConfigHooks(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "hook")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("while writing hook: %v", err)
+	}
+	notExe := filepath.Join(dir, "data")
+	if err := os.WriteFile(notExe, []byte("data"), 0o644); err != nil {
+		t.Fatalf("while writing file: %v", err)
+	}
+	bundleHook := specs.Hook{Path: "/bundle/hook"}
+
+	tests := []struct {
+		name    string
+		conf    *apptainerconf.File
+		want    *specs.Hooks
+		wantErr bool
+	}{
+		{
+			name: "NoConfig",
+			conf: nil,
+			want: &specs.Hooks{Poststart: []specs.Hook{bundleHook}},
+		},
+		{
+			name: "Phases",
+			conf: &apptainerconf.File{
+				OCICreateRuntimeHooks:  []string{exe},
+				OCIStartContainerHooks: []string{exe},
+				OCIPoststartHooks:      []string{exe},
+				OCIPoststopHooks:       []string{exe, exe},
+			},
+			want: &specs.Hooks{
+				CreateRuntime:  []specs.Hook{{Path: exe, Args: []string{exe}}},
+				StartContainer: []specs.Hook{{Path: exe, Args: []string{exe}}},
+				Poststart:      []specs.Hook{bundleHook, {Path: exe, Args: []string{exe}}},
+				Poststop:       []specs.Hook{{Path: exe, Args: []string{exe}}, {Path: exe, Args: []string{exe}}},
+			},
+		},
+		{
+			name:    "RelativePath",
+			conf:    &apptainerconf.File{OCIPoststartHooks: []string{"hook"}},
+			wantErr: true,
+		},
+		{
+			name:    "Missing",
+			conf:    &apptainerconf.File{OCIPoststopHooks: []string{filepath.Join(dir, "missing")}},
+			wantErr: true,
+		},
+		{
+			name:    "NotExecutable",
+			conf:    &apptainerconf.File{OCICreateRuntimeHooks: []string{notExe}},
+			wantErr: true,
+		},
+		{
+			name:    "Directory",
+			conf:    &apptainerconf.File{OCIStartContainerHooks: []string{dir}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &specs.Spec{Hooks: &specs.Hooks{Poststart: []specs.Hook{bundleHook}}}
+			err := addConfigHooks(spec, tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(spec.Hooks, tt.want) {
+				t.Errorf("expected hooks %+v, got %+v", tt.want, spec.Hooks)
+			}
+		})
+	}
+}
@@ -20,7 +20,21 @@ import (
 	"github.com/apptainer/apptainer/pkg/util/unix"
 )
 
-// OciKill kills container process
+// stopSignal returns the signal to send to the container, which is killSignal
+// if set, or the image stop signal recorded in the container annotations, or
+// SIGTERM.
+func stopSignal(state *ociruntime.State, killSignal string) (syscall.Signal, error) {
+	if killSignal == "" {
+		killSignal = state.Annotations[ociruntime.AnnotationStopSignal]
+	}
+	if killSignal == "" {
+		return syscall.SIGTERM, nil
+	}
+	return signal.Convert(killSignal)
+}
+
+// OciKill kills container process. If killSignal is empty, the image stop
+// signal is sent, or SIGTERM if the image does not define one.
 func OciKill(containerID string, killSignal string, killTimeout int) error {
 	// send signal to the instance
 	state, err := getState(containerID)
@@ -32,13 +46,9 @@ func OciKill(containerID string, killSignal string, killTimeout int) error {
 		return fmt.Errorf("cannot kill '%s', the state of the container must be created or running", containerID)
 	}
 
-	sig := syscall.SIGTERM
-
-	if killSignal != "" {
-		sig, err = signal.Convert(killSignal)
-		if err != nil {
-			return err
-		}
+	sig, err := stopSignal(state, killSignal)
+	if err != nil {
+		return err
 	}
 
 	if killTimeout > 0 {
@@ -0,0 +1,68 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/apptainer/apptainer/pkg/ociruntime"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestStopSignal(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		killSignal  string
+		want        syscall.Signal
+		wantErr     bool
+	}{
+		{
+			name: "Default",
+			want: syscall.SIGTERM,
+		},
+		{
+			name:        "ImageStopSignal",
+			annotations: map[string]string{ociruntime.AnnotationStopSignal: "SIGQUIT"},
+			want:        syscall.SIGQUIT,
+		},
+		{
+			name:        "ImageStopSignalNumber",
+			annotations: map[string]string{ociruntime.AnnotationStopSignal: "3"},
+			want:        syscall.SIGQUIT,
+		},
+		{
+			name:        "ExplicitOverridesImage",
+			annotations: map[string]string{ociruntime.AnnotationStopSignal: "SIGQUIT"},
+			killSignal:  "SIGKILL",
+			want:        syscall.SIGKILL,
+		},
+		{
+			name:        "InvalidImageStopSignal",
+			annotations: map[string]string{ociruntime.AnnotationStopSignal: "SIGBOGUS"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &ociruntime.State{
+				State: specs.State{Annotations: tt.annotations},
+			}
+			sig, err := stopSignal(state, tt.killSignal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if err == nil && sig != tt.want {
+				t.Errorf("expected signal %v, got %v", tt.want, sig)
+			}
+		})
+	}
+}
@@ -25,15 +25,31 @@ import (
 // OciArgs contains CLI arguments
 type OciArgs struct {
 	BundlePath     string
+	RootfsPath     string
 	LogPath        string
 	LogFormat      string
 	SyncSocketPath string
 	PidFile        string
 	FromFile       string
+	Devices        []string
+	Security       []string
 	KillSignal     string
+	DetachKeys     string
 	KillTimeout    uint32
 	EmptyProcess   bool
+	Init           bool
 	ForceKill      bool
+	Emulate        bool
+	// Interactive only applies if OverrideInteractive is set, the caller's
+	// standard input is kept otherwise.
+	Interactive         bool
+	OverrideInteractive bool
+	// Entrypoint replaces the image ENTRYPOINT if OverrideEntrypoint is set.
+	// An empty Entrypoint resets it.
+	Entrypoint         string
+	OverrideEntrypoint bool
+	ScratchDirs        []string
+	WorkDir            string
 }
 
 func getCommonConfig(containerID string) (*config.Common, error) {
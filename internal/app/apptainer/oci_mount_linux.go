@@ -10,12 +10,36 @@
 package apptainer
 
 import (
+	"fmt"
+
 	ocibundle "github.com/apptainer/apptainer/pkg/ocibundle/sif"
+	"github.com/apptainer/apptainer/pkg/util/apptainerconf"
 )
 
 // OciMount mount a SIF image to create an OCI bundle
-func OciMount(image string, bundle string) error {
-	d, err := ocibundle.FromSif(image, bundle, true)
+func OciMount(image string, bundle string, args *OciArgs) error {
+	opts := []ocibundle.Option{ocibundle.OptEmulate(args.Emulate)}
+	if args.OverrideEntrypoint {
+		var entrypoint []string
+		if args.Entrypoint != "" {
+			entrypoint = []string{args.Entrypoint}
+		}
+		opts = append(opts, ocibundle.OptEntrypoint(entrypoint))
+	}
+	if len(args.ScratchDirs) > 0 {
+		var size int64
+		if conf := apptainerconf.GetCurrentConfig(); conf != nil {
+			size = int64(conf.SessiondirMaxSize)
+		}
+		opts = append(opts, ocibundle.OptScratchDirs(args.ScratchDirs, size))
+	}
+	if args.WorkDir != "" {
+		if len(args.ScratchDirs) == 0 {
+			return fmt.Errorf("--workdir requires --scratch, it only holds scratch directories")
+		}
+		opts = append(opts, ocibundle.OptWorkDir(args.WorkDir))
+	}
+	d, err := ocibundle.FromSif(image, bundle, true, opts...)
 	if err != nil {
 		return err
 	}
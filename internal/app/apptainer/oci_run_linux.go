@@ -23,8 +23,10 @@ import (
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 )
 
-// OciRun runs a container (equivalent to create/start/delete)
-func OciRun(ctx context.Context, containerID string, args *OciArgs) error {
+// OciRun runs a container (equivalent to create/start/delete). The process
+// exits with the exit code of the container, unless an error is returned,
+// in which case the runtime itself failed.
+func OciRun(ctx context.Context, containerID string, args *OciArgs) (err error) {
 	dir, err := instance.GetDir(containerID, instance.OciSubDir)
 	if err != nil {
 		return err
@@ -55,7 +57,21 @@ func OciRun(ctx context.Context, containerID string, args *OciArgs) error {
 		return err
 	}
 
-	defer exitContainer(ctx, containerID, true)
+	defer func() {
+		if err == nil {
+			exitContainer(ctx, containerID, true)
+			return
+		}
+		// The container did not run to completion, don't exit with an exit
+		// code it may have recorded, so that the error is reported with the
+		// runtime failure exit code.
+		if _, err := getState(containerID); err != nil {
+			return
+		}
+		if err := OciDelete(ctx, containerID); err != nil {
+			sylog.Warningf("can't delete container %s: %s", containerID, err)
+		}
+	}()
 	defer os.Remove(args.SyncSocketPath)
 
 	go func() {
@@ -99,7 +115,7 @@ func OciRun(ctx context.Context, containerID string, args *OciArgs) error {
 		return err
 	}
 
-	if err := attach(engineConfig, true); err != nil {
+	if err := attach(engineConfig, true, nil); err != nil {
 		// kill container before deletion
 		sylog.Errorf("%s", err)
 		OciKill(containerID, "SIGKILL", 1)
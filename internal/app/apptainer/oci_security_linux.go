@@ -0,0 +1,43 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
+	"github.com/apptainer/apptainer/internal/pkg/security"
+)
+
+// addSecurity applies the --security options, in the <feature>:<arg> format
+// of the action commands, to the configuration of generator. They override
+// the bundle configuration, e.g. seccomp:unconfined removes the default
+// seccomp profile of a bundle created with oci mount.
+func addSecurity(generator *generate.Generator, opts []string) error {
+	for _, opt := range opts {
+		feature, arg, ok := strings.Cut(opt, ":")
+		if !ok || arg == "" {
+			return fmt.Errorf("bad format for security option %q (format is <feature>:<arg>)", opt)
+		}
+		switch feature {
+		case "seccomp":
+			if err := security.SetSeccomp(arg, generator); err != nil {
+				return fmt.Errorf("while applying seccomp profile %s: %s", arg, err)
+			}
+		case "apparmor":
+			generator.SetProcessApparmorProfile(arg)
+		case "selinux":
+			generator.SetProcessSelinuxLabel(arg)
+		default:
+			return fmt.Errorf("unsupported security feature %q, supported features are seccomp, apparmor and selinux", feature)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestAddSecurity(t *testing.T) {
+	newGenerator := func() *generate.Generator {
+		return generate.New(&specs.Spec{
+			Process: &specs.Process{},
+			Linux: &specs.Linux{
+				Seccomp: &specs.LinuxSeccomp{DefaultAction: specs.ActErrno},
+			},
+		})
+	}
+
+	tests := []struct {
+		name        string
+		opts        []string
+		wantSeccomp bool
+		wantAA      string
+		wantSELinux string
+		wantErr     bool
+	}{
+		{name: "None", wantSeccomp: true},
+		{name: "SeccompUnconfined", opts: []string{"seccomp:unconfined"}},
+		{name: "Apparmor", opts: []string{"apparmor:myprofile"}, wantSeccomp: true, wantAA: "myprofile"},
+		{name: "SELinux", opts: []string{"selinux:system_u:system_r:container_t:s0"}, wantSeccomp: true, wantSELinux: "system_u:system_r:container_t:s0"},
+		{name: "Combined", opts: []string{"seccomp:unconfined", "apparmor:myprofile"}, wantAA: "myprofile"},
+		{name: "BadFormat", opts: []string{"seccomp"}, wantErr: true},
+		{name: "Unsupported", opts: []string{"uid:1000"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGenerator()
+			err := addSecurity(g, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, expected error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := g.Config.Linux.Seccomp != nil; got != tt.wantSeccomp {
+				t.Errorf("got seccomp configuration %v, expected %v", got, tt.wantSeccomp)
+			}
+			if g.Config.Process.ApparmorProfile != tt.wantAA {
+				t.Errorf("got apparmor profile %q, expected %q", g.Config.Process.ApparmorProfile, tt.wantAA)
+			}
+			if g.Config.Process.SelinuxLabel != tt.wantSELinux {
+				t.Errorf("got selinux label %q, expected %q", g.Config.Process.SelinuxLabel, tt.wantSELinux)
+			}
+		})
+	}
+}
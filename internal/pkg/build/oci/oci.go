@@ -121,12 +121,15 @@ func (t *ImageReference) newImageSource(ctx context.Context, sys *types.SystemCo
 		return nil, err
 	}
 
-	// First we are fetching into the cache
+	// First we are fetching into the cache, holding log lines while
+	// copy.Image draws progress bars on w
+	stopLog := sylog.StartProgress()
 	_, err = copy.Image(ctx, policyCtx, t.ImageReference, t.source, &copy.Options{
 		ReportWriter:     w,
 		SourceCtx:        sys,
 		RemoveSignatures: true,
 	})
+	stopLog()
 	if err != nil {
 		return nil, err
 	}
@@ -14,17 +14,28 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/cache"
 	"github.com/apptainer/apptainer/internal/pkg/ociimage"
 	"github.com/apptainer/apptainer/internal/pkg/test"
 	buildTypes "github.com/apptainer/apptainer/pkg/build/types"
+	"github.com/containers/image/v5/docker"
 	"github.com/containers/image/v5/oci/layout"
 	"github.com/containers/image/v5/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
 const (
@@ -432,3 +443,97 @@ func TestNewImageSource(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRefDigestProxy(t *testing.T) {
+	// the registry is only reachable through the proxy
+	const registryHost = "registry.invalid"
+
+	reg := registry.New()
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("while creating image: %v", err)
+	}
+	pushRef, err := name.ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/test/image:latest")
+	if err != nil {
+		t.Fatalf("while parsing reference: %v", err)
+	}
+	if err := remote.Write(pushRef, img); err != nil {
+		t.Fatalf("while pushing image: %v", err)
+	}
+	manifest, err := img.RawManifest()
+	if err != nil {
+		t.Fatalf("while getting manifest: %v", err)
+	}
+	want := fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%x", sha256.Sum256(manifest)))))
+
+	var noHead atomic.Bool
+	var proxied, rejected atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host != registryHost {
+			http.Error(w, "unknown host", http.StatusBadGateway)
+			return
+		}
+		if noHead.Load() && r.Method == http.MethodHead && strings.Contains(r.URL.Path, "/manifests/") {
+			rejected.Add(1)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		proxied.Add(1)
+		reg.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatalf("while parsing proxy URL: %v", err)
+	}
+
+	tr := http.DefaultTransport.(*http.Transport)
+	defer func(proxy func(*http.Request) (*url.URL, error)) {
+		tr.Proxy = proxy
+	}(tr.Proxy)
+	tr.Proxy = http.ProxyURL(proxyURL)
+
+	ref, err := docker.ParseReference("//" + registryHost + "/test/image:latest")
+	if err != nil {
+		t.Fatalf("while parsing reference: %v", err)
+	}
+	topts := &ociimage.TransportOptions{Insecure: true}
+
+	tests := []struct {
+		name   string
+		noHead bool
+	}{
+		{
+			name: "Head",
+		},
+		{
+			name:   "Fallback",
+			noHead: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			noHead.Store(tt.noHead)
+			proxied.Store(0)
+			rejected.Store(0)
+
+			digest, err := getRefDigest(context.Background(), ref, topts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if digest != want {
+				t.Errorf("expected digest %s, got %s", want, digest)
+			}
+			if proxied.Load() == 0 {
+				t.Errorf("no request went through the proxy")
+			}
+			if tt.noHead && rejected.Load() == 0 {
+				t.Errorf("the HEAD request didn't reach the proxy")
+			}
+		})
+	}
+}
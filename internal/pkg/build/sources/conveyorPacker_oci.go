@@ -14,6 +14,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -141,6 +142,8 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		AuthFilePath:     ociauth.ChooseAuthFile(cp.b.Opts.ReqAuthFile),
 		UserAgent:        useragent.Value(),
 		TmpDir:           b.TmpDir,
+		Concurrency:      cp.b.Opts.Concurrency,
+		Offline:          cp.b.Opts.OCIOffline,
 	}
 
 	if cp.b.Opts.OCIAuthConfig == nil && cp.b.Opts.DockerAuthConfig != nil {
@@ -151,7 +154,12 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		}
 	}
 
-	dp, err := ociplatform.DefaultPlatform()
+	var dp *v1.Platform
+	if cp.b.Opts.ArchVariant != "" {
+		dp, err = ociplatform.PlatformFromVariant(cp.b.Opts.ArchVariant)
+	} else {
+		dp, err = ociplatform.DefaultPlatform()
+	}
 	if err != nil {
 		return err
 	}
@@ -177,6 +185,9 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		imgCache = cp.b.Opts.ImgCache
 	}
 
+	ctx, cancel := ociimage.TimeoutContext(ctx, cp.b.Opts.OCITimeout)
+	defer cancel()
+
 	// Fetch the image into a temporary containers/image oci layout dir.
 	cp.srcImg, err = ociimage.FetchToLayout(ctx, cp.topts, imgCache, ref, b.TmpDir)
 	if err != nil {
@@ -240,7 +251,16 @@ func (cp *OCIConveyorPacker) insertOCIConfig() error {
 }
 
 func (cp *OCIConveyorPacker) unpackRootfs(ctx context.Context) error {
-	if err := UnpackRootfs(ctx, cp.srcImg, cp.b.RootfsPath); err != nil {
+	if err := UnpackRootfs(ctx, cp.srcImg, cp.b.RootfsPath, cp.b.Opts.FakerootIDs); err != nil {
+		// Don't leave a corrupted layer in the cache for the next build.
+		var diffErr *DiffIDError
+		if errors.As(err, &diffErr) && !cp.b.Opts.NoCache && cp.b.Opts.ImgCache != nil && !cp.b.Opts.ImgCache.IsDisabled() {
+			if evictErr := ociimage.EvictBlob(cp.b.Opts.ImgCache, diffErr.Digest); evictErr != nil {
+				sylog.Warningf("Unable to remove corrupted layer %s from cache: %v", diffErr.Digest, evictErr)
+			} else {
+				sylog.Infof("Removed corrupted layer %s from cache, it will be fetched again by the next build", diffErr.Digest)
+			}
+		}
 		return err
 	}
 
@@ -92,7 +92,7 @@ func (c *YumConveyor) Get(ctx context.Context, b *types.Bundle) (err error) {
 	// Do the install
 	sylog.Debugf("\n\tInstall Command Path: %s\n\tDetected Arch: %s\n\tOSVersion: %s\n\tMirrorURL: %s\n\tUpdateURL: %s\n\tIncludes: %s\n\tSetopt: %s\n", installCommandPath, runtime.GOARCH, c.osversion, c.mirrorurl, c.updateurl, c.include, c.setopt)
 	cmd := exec.CommandContext(ctx, installCommandPath, args...)
-	if sylog.GetLevel() >= int(sylog.VerboseLevel) {
+	if sylog.GetMessageLevel() >= sylog.VerboseLevel {
 		cmd.Stdout = os.Stdout
 	}
 	cmd.Stderr = os.Stderr
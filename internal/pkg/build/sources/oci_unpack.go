@@ -14,17 +14,30 @@
 package sources
 
 import (
+	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	apexlog "github.com/apex/log"
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/fakeroot"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/namespaces"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
 	umocilayer "github.com/opencontainers/umoci/oci/layer"
 	"github.com/opencontainers/umoci/pkg/idtools"
 )
@@ -49,8 +62,153 @@ func isExtractable(img v1.Image) (bool, error) {
 	return false, nil
 }
 
+// DiffIDError reports a layer whose uncompressed content doesn't match the
+// diffID recorded for it in the image configuration, as happens when a
+// cached blob is corrupted.
+type DiffIDError struct {
+	// Digest is the digest of the (compressed) layer blob.
+	Digest v1.Hash
+	// Expected is the diffID from the image configuration.
+	Expected v1.Hash
+	// Got is the digest of the uncompressed content that was read.
+	Got v1.Hash
+}
+
+func (e *DiffIDError) Error() string {
+	return fmt.Sprintf("layer %s is corrupted: uncompressed content has diffID %s, expected %s", e.Digest, e.Got, e.Expected)
+}
+
+// verboseLayersImage wraps a v1.Image so that each layer is reported, with its
+// position in the layer stack and its digest, at verbose level when it is read.
+// As mutate.Extract reads the layers from the top of the stack down, they are
+// reported in that order, which the first message states.
+// The uncompressed content of each layer is also verified against its diffID,
+// with the first mismatch kept for verifyErr.
+type verboseLayersImage struct {
+	v1.Image
+
+	mu  sync.Mutex
+	err error
+}
+
+func (vi *verboseLayersImage) Layers() ([]v1.Layer, error) {
+	layers, err := vi.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	sylog.Verbosef("Unpacking %d layers, from the top of the stack down", len(layers))
+	verbose := make([]v1.Layer, 0, len(layers))
+	for i, l := range layers {
+		verbose = append(verbose, &verboseLayer{Layer: l, index: i + 1, total: len(layers), image: vi})
+	}
+	return verbose, nil
+}
+
+func (vi *verboseLayersImage) setErr(err error) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+	if vi.err == nil {
+		vi.err = err
+	}
+}
+
+// verifyErr returns the first diffID mismatch found in the layers read.
+func (vi *verboseLayersImage) verifyErr() error {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+	return vi.err
+}
+
+type verboseLayer struct {
+	v1.Layer
+	index int
+	total int
+	image *verboseLayersImage
+}
+
+func (vl *verboseLayer) Uncompressed() (io.ReadCloser, error) {
+	digest, err := vl.Digest()
+	if err != nil {
+		return nil, err
+	}
+	sylog.Verbosef("Unpacking layer %d/%d %s", vl.index, vl.total, digest)
+	rc, err := verifiedUncompressed(vl.Layer)
+	if err != nil {
+		return nil, err
+	}
+	rc.onMismatch = vl.image.setErr
+	return rc, nil
+}
+
+// diffIDReader hashes the uncompressed content of a layer as it is read, and
+// compares it with the layer diffID once the end of the content is reached.
+type diffIDReader struct {
+	io.ReadCloser
+	hash       hash.Hash
+	digest     v1.Hash
+	diffID     v1.Hash
+	err        error
+	onMismatch func(error)
+}
+
+// verifiedUncompressed returns the uncompressed content of layer, failing
+// the read that reaches its end if the content doesn't match the diffID.
+func verifiedUncompressed(layer v1.Layer) (*diffIDReader, error) {
+	digest, err := layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, err
+	}
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	return &diffIDReader{
+		ReadCloser: rc,
+		hash:       sha256.New(),
+		digest:     digest,
+		diffID:     diffID,
+	}, nil
+}
+
+func (dr *diffIDReader) Read(p []byte) (int, error) {
+	if dr.err != nil {
+		return 0, dr.err
+	}
+	n, err := dr.ReadCloser.Read(p)
+	dr.hash.Write(p[:n])
+	if err == io.EOF {
+		got := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(dr.hash.Sum(nil))}
+		if dr.diffID.Algorithm == "sha256" && got != dr.diffID {
+			err = &DiffIDError{Digest: dr.digest, Expected: dr.diffID, Got: got}
+			if dr.onMismatch != nil {
+				dr.onMismatch(err)
+			}
+		}
+	}
+	if err != nil {
+		dr.err = err
+	}
+	return n, err
+}
+
+// Close reads the rest of the content, which a tar reader leaves behind
+// after the end of archive marker, so that the layer is always verified.
+func (dr *diffIDReader) Close() error {
+	if dr.err == nil {
+		io.Copy(io.Discard, dr) //nolint:errcheck
+	}
+	return dr.ReadCloser.Close()
+}
+
 // UnpackRootfs extracts all of the layers of the given srcImage into destDir.
-func UnpackRootfs(_ context.Context, srcImage v1.Image, destDir string) (err error) {
+// When fakerootIDs is set and the build runs in a fakeroot user namespace
+// with a subordinate id range, file ownership from the image is preserved
+// across that range instead of being collapsed to the building user.
+func UnpackRootfs(_ context.Context, srcImage v1.Image, destDir string, fakerootIDs bool) (err error) {
 	extractable, err := isExtractable(srcImage)
 	if err != nil {
 		return err
@@ -59,20 +217,194 @@ func UnpackRootfs(_ context.Context, srcImage v1.Image, destDir string) (err err
 		return fmt.Errorf("no extractable OCI/Docker tar layers found in this image")
 	}
 
-	flatTar := mutate.Extract(srcImage)
+	// Layers are read from the top of the stack downwards while flattening, so
+	// that whiteouts are applied. Each is logged at verbose level, and verified
+	// against its diffID, as it is read.
+	verboseImage := &verboseLayersImage{Image: srcImage}
+	flatTar := mutate.Extract(verboseImage)
+
+	unpackOptions, err := umociUnpackOptions(fakerootIDs)
+	if err != nil {
+		return err
+	}
+
+	// Drop the extended attributes we can't restore, rather than leaving
+	// umoci to report each failure.
+	rootfsTar := filterXattrs(flatTar, xattrPermitted)
+	defer rootfsTar.Close()
 
+	// Unpack root filesystem
+	err = umocilayer.UnpackLayer(destDir, rootfsTar, unpackOptions)
+	// A corrupted layer explains any unpacking error, so it is reported first.
+	if verr := verboseImage.verifyErr(); verr != nil {
+		return verr
+	}
+	if err != nil {
+		return fmt.Errorf("error unpacking rootfs: %s", err)
+	}
+
+	// No `--fix-perms` and no sandbox... we are fine
+	return err
+}
+
+// ExtractLayer fetches the image imageURI, and extracts the content of the
+// single layer with digest layerDigest into destDir, without applying the
+// layers below it. This allows inspection of what a given layer adds to the
+// image. Whiteout entries in the layer are applied to destDir as removals.
+func ExtractLayer(ctx context.Context, tOpts *ociimage.TransportOptions, imgCache *cache.Handle, imageURI, layerDigest, destDir string) error {
+	digest, err := v1.NewHash(layerDigest)
+	if err != nil {
+		return fmt.Errorf("invalid layer digest %q: %v", layerDigest, err)
+	}
+
+	var tmpParent string
+	if tOpts != nil {
+		tmpParent = tOpts.TmpDir
+	}
+	tmpDir, err := os.MkdirTemp(tmpParent, "extract-layer-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	img, err := ociimage.FetchToLayout(ctx, tOpts, imgCache, imageURI, tmpDir)
+	if err != nil {
+		return fmt.Errorf("while fetching image: %v", err)
+	}
+
+	layer, err := img.LayerByDigest(digest)
+	if err != nil {
+		return fmt.Errorf("layer %s not found in image %s: %v", digest, imageURI, err)
+	}
+	mt, err := layer.MediaType()
+	if err != nil {
+		return err
+	}
+	if !mt.IsLayer() {
+		return fmt.Errorf("%s is not an extractable OCI/Docker tar layer (media type %s)", digest, mt)
+	}
+
+	rc, err := verifiedUncompressed(layer)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	unpackOptions, err := umociUnpackOptions(false)
+	if err != nil {
+		return err
+	}
+
+	layerTar := filterXattrs(rc, xattrPermitted)
+	defer layerTar.Close()
+
+	sylog.Verbosef("Unpacking layer %s to %s", digest, destDir)
+	if err := umocilayer.UnpackLayer(destDir, layerTar, unpackOptions); err != nil {
+		return fmt.Errorf("error unpacking layer %s: %s", digest, err)
+	}
+	return nil
+}
+
+const paxSchilyXattr = "SCHILY.xattr."
+
+// xattrPermitted reports whether the extended attribute name can be set by
+// the current user when unpacking layers. Unprivileged users can only set
+// user.* attributes. Root in a user namespace can also set security.*
+// attributes, including file capabilities scoped to the namespace, but not
+// trusted.* attributes which require privileges on the host.
+func xattrPermitted(name string) bool {
+	if !namespaces.IsUnprivileged() {
+		return true
+	}
+	if os.Geteuid() == 0 {
+		return !strings.HasPrefix(name, "trusted.")
+	}
+	return strings.HasPrefix(name, "user.")
+}
+
+// filterXattrs returns a copy of the tar stream r with the extended
+// attributes that permitted rejects removed from its entries. Once the
+// stream has been read, a warning lists the attributes that were dropped, so
+// that missing file capabilities in the container can be explained.
+func filterXattrs(r io.Reader, permitted func(string) bool) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		dropped := make(map[string]int)
+		files := 0
+
+		err := func() error {
+			tr := tar.NewReader(r)
+			tw := tar.NewWriter(pw)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				filtered := false
+				for key := range hdr.PAXRecords {
+					name, ok := strings.CutPrefix(key, paxSchilyXattr)
+					if !ok || permitted(name) {
+						continue
+					}
+					delete(hdr.PAXRecords, key)
+					// the tar writer still emits the deprecated Xattrs field
+					delete(hdr.Xattrs, name) //nolint:staticcheck
+					dropped[name]++
+					filtered = true
+				}
+				if filtered {
+					sylog.Debugf("Dropping extended attributes from %s", hdr.Name)
+					files++
+				}
+				if err := tw.WriteHeader(hdr); err != nil {
+					return err
+				}
+				if _, err := io.Copy(tw, tr); err != nil {
+					return err
+				}
+			}
+			// Read what follows the end of archive marker, so that the
+			// source is fully consumed before the copy is closed.
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				return err
+			}
+			return tw.Close()
+		}()
+
+		if files > 0 {
+			names := make([]string, 0, len(dropped))
+			for name := range dropped {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			sylog.Warningf("Extended attributes %s were dropped from %d file(s), as they can't be set by this user: build as root or with --fakeroot to preserve them", strings.Join(names, ", "), files)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+// umociUnpackOptions sets the umoci log level to match sylog, and returns
+// the options to unpack layers as the current user, or across the id range
+// of the current fakeroot user namespace if fakerootIDs is set.
+func umociUnpackOptions(fakerootIDs bool) (*umocilayer.UnpackOptions, error) {
 	var mapOptions umocilayer.MapOptions
 
-	loggerLevel := sylog.GetLevel()
+	loggerLevel := sylog.GetMessageLevel()
 
 	// set the apex log level, for umoci
-	if loggerLevel <= int(sylog.ErrorLevel) {
+	if loggerLevel <= sylog.ErrorLevel {
 		// silent option
 		apexlog.SetLevel(apexlog.ErrorLevel)
-	} else if loggerLevel <= int(sylog.LogLevel) {
+	} else if loggerLevel <= sylog.LogLevel {
 		// quiet option
 		apexlog.SetLevel(apexlog.WarnLevel)
-	} else if loggerLevel < int(sylog.DebugLevel) {
+	} else if loggerLevel < sylog.DebugLevel {
 		// verbose option(s) or default
 		apexlog.SetLevel(apexlog.InfoLevel)
 	} else {
@@ -80,6 +412,18 @@ func UnpackRootfs(_ context.Context, srcImage v1.Image, destDir string) (err err
 		apexlog.SetLevel(apexlog.DebugLevel)
 	}
 
+	if fakerootIDs && namespaces.IsUnprivileged() {
+		mapOptions, err := fakerootMapOptions("/proc/self/uid_map", "/proc/self/gid_map")
+		if err == nil && os.Geteuid() != 0 {
+			err = fmt.Errorf("not running in a fakeroot user namespace")
+		}
+		if err == nil {
+			sylog.Debugf("unpacking with fakeroot id mappings %v / %v", mapOptions.UIDMappings, mapOptions.GIDMappings)
+			return &umocilayer.UnpackOptions{MapOptions: mapOptions}, nil
+		}
+		sylog.Warningf("Unable to preserve file ownership: %s", err)
+	}
+
 	// Allow unpacking as non-root
 	if namespaces.IsUnprivileged() {
 		sylog.Debugf("setting umoci rootless mode")
@@ -87,26 +431,78 @@ func UnpackRootfs(_ context.Context, srcImage v1.Image, destDir string) (err err
 
 		uidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Geteuid()))
 		if err != nil {
-			return fmt.Errorf("error parsing uidmap: %s", err)
+			return nil, fmt.Errorf("error parsing uidmap: %s", err)
 		}
 		mapOptions.UIDMappings = append(mapOptions.UIDMappings, uidMap)
 
 		gidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Getegid()))
 		if err != nil {
-			return fmt.Errorf("error parsing gidmap: %s", err)
+			return nil, fmt.Errorf("error parsing gidmap: %s", err)
 		}
 		mapOptions.GIDMappings = append(mapOptions.GIDMappings, gidMap)
 	}
 
-	// Unpack root filesystem
-	unpackOptions := umocilayer.UnpackOptions{MapOptions: mapOptions}
-	err = umocilayer.UnpackLayer(destDir, flatTar, &unpackOptions)
+	return &umocilayer.UnpackOptions{MapOptions: mapOptions}, nil
+}
+
+// fakerootMapOptions returns the umoci options to unpack layers as root
+// inside a fakeroot user namespace, with each id mapped in the namespace,
+// as listed in uidMapPath and gidMapPath, owning its files. It fails when
+// only root is mapped, as there is no range to preserve ownership across.
+func fakerootMapOptions(uidMapPath, gidMapPath string) (umocilayer.MapOptions, error) {
+	var mapOptions umocilayer.MapOptions
+
+	uidMaps, err := namespaceIDMappings(uidMapPath)
 	if err != nil {
-		return fmt.Errorf("error unpacking rootfs: %s", err)
+		return mapOptions, err
 	}
+	gidMaps, err := namespaceIDMappings(gidMapPath)
+	if err != nil {
+		return mapOptions, err
+	}
+	mapOptions.UIDMappings = uidMaps
+	mapOptions.GIDMappings = gidMaps
 
-	// No `--fix-perms` and no sandbox... we are fine
-	return err
+	return mapOptions, nil
+}
+
+// namespaceIDMappings reads a user namespace uid_map or gid_map file, and
+// returns identity mappings for the ids it maps into the namespace.
+func namespaceIDMappings(path string) ([]specs.LinuxIDMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []specs.LinuxIDMapping
+	var total uint64
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed entry %q in %s", line, path)
+		}
+		containerID, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed entry %q in %s: %s", line, path, err)
+		}
+		size, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed entry %q in %s: %s", line, path, err)
+		}
+		if uint32(size) == ^uint32(0) {
+			return nil, fmt.Errorf("not running in a user namespace")
+		}
+		mappings = append(mappings, specs.LinuxIDMapping{
+			ContainerID: uint32(containerID),
+			HostID:      uint32(containerID),
+			Size:        uint32(size),
+		})
+		total += size
+	}
+	if total <= 1 {
+		return nil, fmt.Errorf("no subordinate id range is mapped in the user namespace, see %s", fakeroot.SubUIDFile)
+	}
+	return mappings, nil
 }
 
 // FixPerms will work through the rootfs of this bundle, making sure that all
@@ -0,0 +1,404 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestVerboseLayersImage(t *testing.T) {
+	img, err := random.Image(64, 3)
+	if err != nil {
+		t.Fatalf("while creating random image: %v", err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("while getting layers: %v", err)
+	}
+
+	var want []string
+	for i, l := range layers {
+		digest, err := l.Digest()
+		if err != nil {
+			t.Fatalf("while getting layer digest: %v", err)
+		}
+		want = append(want, fmt.Sprintf("Unpacking layer %d/%d %s", i+1, len(layers), digest))
+	}
+	summary := fmt.Sprintf("Unpacking %d layers, from the top of the stack down", len(layers))
+
+	oldLevel := sylog.GetLevel()
+	defer sylog.SetLevel(oldLevel, true)
+
+	tests := []struct {
+		name      string
+		level     int
+		expectLog bool
+	}{
+		{name: "info", level: int(sylog.InfoLevel), expectLog: false},
+		{name: "verbose", level: int(sylog.VerboseLevel), expectLog: true},
+		{name: "debug", level: int(sylog.DebugLevel), expectLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			oldWriter := sylog.SetWriter(&buf)
+			defer sylog.SetWriter(oldWriter)
+			sylog.SetLevel(tt.level, true)
+
+			rc := mutate.Extract(&verboseLayersImage{Image: img})
+			if _, err := io.Copy(io.Discard, rc); err != nil {
+				t.Fatalf("while extracting image: %v", err)
+			}
+			rc.Close()
+
+			out := buf.String()
+			for _, msg := range append([]string{summary}, want...) {
+				if got := strings.Contains(out, msg); got != tt.expectLog {
+					t.Errorf("expected message %q logged: %v, got: %v", msg, tt.expectLog, got)
+				}
+			}
+			if !tt.expectLog {
+				return
+			}
+			// the summary comes first, then the layers from the top down
+			prev := strings.Index(out, summary)
+			for i := len(want) - 1; i >= 0; i-- {
+				pos := strings.Index(out, want[i])
+				if pos < prev {
+					t.Errorf("expected message %q logged after the layers above it", want[i])
+				}
+				prev = pos
+			}
+		})
+	}
+}
+
+// tarLayer returns a layer holding a single regular file.
+func tarLayer(t *testing.T, name, content string) v1.Layer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("while writing tar header: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("while writing tar content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("while closing tar: %v", err)
+	}
+
+	b := buf.Bytes()
+	l, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	})
+	if err != nil {
+		t.Fatalf("while creating layer: %v", err)
+	}
+	return l
+}
+
+func TestExtractLayer(t *testing.T) {
+	bottom := tarLayer(t, "bottom.txt", "bottom")
+	top := tarLayer(t, "top.txt", "top")
+	img, err := mutate.AppendLayers(empty.Image, bottom, top)
+	if err != nil {
+		t.Fatalf("while creating image: %v", err)
+	}
+
+	layoutDir := t.TempDir()
+	lp, err := layout.Write(layoutDir, empty.Index)
+	if err != nil {
+		t.Fatalf("while creating layout: %v", err)
+	}
+	if err := lp.AppendImage(img); err != nil {
+		t.Fatalf("while writing image to layout: %v", err)
+	}
+
+	topDigest, err := top.Digest()
+	if err != nil {
+		t.Fatalf("while getting layer digest: %v", err)
+	}
+
+	tOpts := &ociimage.TransportOptions{TmpDir: t.TempDir()}
+	destDir := t.TempDir()
+	if err := ExtractLayer(context.Background(), tOpts, nil, "oci:"+layoutDir, topDigest.String(), destDir); err != nil {
+		t.Fatalf("while extracting layer: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(destDir, "top.txt"))
+	if err != nil {
+		t.Fatalf("top layer content not extracted: %v", err)
+	}
+	if string(content) != "top" {
+		t.Errorf("expected content %q, got %q", "top", content)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "bottom.txt")); !os.IsNotExist(err) {
+		t.Errorf("bottom layer content unexpectedly extracted")
+	}
+
+	missing := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("0", 64)}
+	if err := ExtractLayer(context.Background(), tOpts, nil, "oci:"+layoutDir, missing.String(), t.TempDir()); err == nil {
+		t.Errorf("expected error extracting layer not in image")
+	}
+	if err := ExtractLayer(context.Background(), tOpts, nil, "oci:"+layoutDir, "invalid", t.TempDir()); err == nil {
+		t.Errorf("expected error for invalid digest")
+	}
+}
+
+func TestFakerootMapOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		idMap     string
+		expectErr bool
+		expect    []specs.LinuxIDMapping
+	}{
+		{
+			name:  "subordinate range",
+			idMap: "         0       1000          1\n         1     100000      65536\n",
+			expect: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 0, Size: 1},
+				{ContainerID: 1, HostID: 1, Size: 65536},
+			},
+		},
+		{
+			name:      "root mapped only",
+			idMap:     "         0       1000          1\n",
+			expectErr: true,
+		},
+		{
+			name:      "host namespace",
+			idMap:     "         0          0 4294967295\n",
+			expectErr: true,
+		},
+		{
+			name:      "malformed",
+			idMap:     "0 1000\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idMap := filepath.Join(t.TempDir(), "id_map")
+			if err := os.WriteFile(idMap, []byte(tt.idMap), 0o644); err != nil {
+				t.Fatalf("while writing id map: %v", err)
+			}
+
+			mapOptions, err := fakerootMapOptions(idMap, idMap)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got mappings %v", mapOptions.UIDMappings)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mapOptions.Rootless {
+				t.Errorf("unexpected rootless mode with fakeroot mappings")
+			}
+			if !reflect.DeepEqual(mapOptions.UIDMappings, tt.expect) {
+				t.Errorf("expected uid mappings %v, got %v", tt.expect, mapOptions.UIDMappings)
+			}
+			if !reflect.DeepEqual(mapOptions.GIDMappings, tt.expect) {
+				t.Errorf("expected gid mappings %v, got %v", tt.expect, mapOptions.GIDMappings)
+			}
+		})
+	}
+}
+
+func TestFilterXattrs(t *testing.T) {
+	// a v2 security.capability value granting cap_net_raw
+	capability := string([]byte{0, 0, 0, 2, 0, 32, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{
+			Name:     "ping",
+			Mode:     0o755,
+			Typeflag: tar.TypeReg,
+			Format:   tar.FormatPAX,
+			PAXRecords: map[string]string{
+				paxSchilyXattr + "security.capability": capability,
+				paxSchilyXattr + "user.comment":        "ping",
+			},
+		},
+		{
+			Name:     "plain",
+			Mode:     0o644,
+			Typeflag: tar.TypeReg,
+		},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("while writing tar header: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("while closing tar: %v", err)
+	}
+
+	oldLevel := sylog.GetLevel()
+	defer sylog.SetLevel(oldLevel, true)
+	sylog.SetLevel(int(sylog.InfoLevel), true)
+
+	tests := []struct {
+		name        string
+		permitted   func(string) bool
+		expectXattr map[string]string
+		expectWarn  bool
+	}{
+		{
+			name:      "all permitted",
+			permitted: func(string) bool { return true },
+			expectXattr: map[string]string{
+				"security.capability": capability,
+				"user.comment":        "ping",
+			},
+		},
+		{
+			name:      "user only",
+			permitted: func(name string) bool { return strings.HasPrefix(name, "user.") },
+			expectXattr: map[string]string{
+				"user.comment": "ping",
+			},
+			expectWarn: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logBuf bytes.Buffer
+			oldWriter := sylog.SetWriter(&logBuf)
+			defer sylog.SetWriter(oldWriter)
+
+			rc := filterXattrs(bytes.NewReader(buf.Bytes()), tt.permitted)
+			defer rc.Close()
+
+			xattrs := make(map[string]map[string]string)
+			tr := tar.NewReader(rc)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("while reading filtered tar: %v", err)
+				}
+				xattrs[hdr.Name] = make(map[string]string)
+				for key, value := range hdr.PAXRecords {
+					if name, ok := strings.CutPrefix(key, paxSchilyXattr); ok {
+						xattrs[hdr.Name][name] = value
+					}
+				}
+			}
+			// the warning is emitted before the stream is closed
+			if _, err := io.Copy(io.Discard, rc); err != nil {
+				t.Fatalf("while reading filtered tar: %v", err)
+			}
+
+			if !reflect.DeepEqual(xattrs["ping"], tt.expectXattr) {
+				t.Errorf("expected xattrs %q on ping, got %q", tt.expectXattr, xattrs["ping"])
+			}
+			if len(xattrs["plain"]) != 0 {
+				t.Errorf("unexpected xattrs %q on plain", xattrs["plain"])
+			}
+
+			warned := strings.Contains(logBuf.String(), "security.capability were dropped from 1 file(s)")
+			if warned != tt.expectWarn {
+				t.Errorf("expected warning: %v, got log %q", tt.expectWarn, logBuf.String())
+			}
+		})
+	}
+}
+
+func TestUnpackRootfsCorruptedLayer(t *testing.T) {
+	good := tarLayer(t, "good.txt", "good")
+	img, err := mutate.AppendLayers(empty.Image, good)
+	if err != nil {
+		t.Fatalf("while creating image: %v", err)
+	}
+
+	layoutDir := t.TempDir()
+	lp, err := layout.Write(layoutDir, empty.Index)
+	if err != nil {
+		t.Fatalf("while creating layout: %v", err)
+	}
+	if err := lp.AppendImage(img); err != nil {
+		t.Fatalf("while writing image to layout: %v", err)
+	}
+
+	// Replace the cached blob of the layer with different, but still
+	// readable, content, as a corrupted cache entry would be.
+	digest, err := good.Digest()
+	if err != nil {
+		t.Fatalf("while getting layer digest: %v", err)
+	}
+	bad := tarLayer(t, "bad.txt", "bad")
+	rc, err := bad.Compressed()
+	if err != nil {
+		t.Fatalf("while reading layer: %v", err)
+	}
+	badBlob, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("while reading layer: %v", err)
+	}
+	blobPath := filepath.Join(layoutDir, "blobs", digest.Algorithm, digest.Hex)
+	if err := os.WriteFile(blobPath, badBlob, 0o644); err != nil {
+		t.Fatalf("while corrupting layer blob: %v", err)
+	}
+
+	imgDigest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("while getting image digest: %v", err)
+	}
+	srcImg, err := lp.Image(imgDigest)
+	if err != nil {
+		t.Fatalf("while reading image from layout: %v", err)
+	}
+
+	err = UnpackRootfs(context.Background(), srcImg, t.TempDir(), false)
+	var diffErr *DiffIDError
+	if !errors.As(err, &diffErr) {
+		t.Fatalf("expected diffID error, got: %v", err)
+	}
+	if diffErr.Digest != digest {
+		t.Errorf("expected corrupted layer %s, got %s", digest, diffErr.Digest)
+	}
+}
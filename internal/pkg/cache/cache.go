@@ -23,6 +23,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/pkg/syfs"
 	"github.com/apptainer/apptainer/pkg/sylog"
+	"golang.org/x/sys/unix"
 )
 
 var errInvalidCacheType = errors.New("invalid cache type")
@@ -33,6 +34,10 @@ const (
 	DirEnv = "APPTAINER_CACHEDIR"
 	// DisableEnv specifies whether the image should be used
 	DisableEnv = "APPTAINER_DISABLE_CACHE"
+	// ReadOnlyDirEnv specifies the environment variable which can set the
+	// parent directory of a read-only cache, searched for entries that are
+	// missing from the writable cache
+	ReadOnlyDirEnv = "APPTAINER_READONLY_CACHEDIR"
 	// SubDirName specifies the name of the directory relative to the
 	// ParentDir specified when the cache is created.
 	// By default the cache will be placed at "~/.apptainer/cache" which
@@ -51,6 +56,8 @@ const (
 	OrasCacheType = "oras"
 	// NetCacheType specifies the cache holds images pulled from http(s) internet sources
 	NetCacheType = "net"
+	// OciConfigCacheType specifies the cache holds OCI image configs, keyed by manifest digest
+	OciConfigCacheType = "oci-config"
 )
 
 var (
@@ -61,6 +68,7 @@ var (
 		ShubCacheType,
 		OrasCacheType,
 		NetCacheType,
+		OciConfigCacheType,
 	}
 	// OciCacheTypes specifies the OCI cache types.
 	OciCacheTypes = []string{
@@ -75,6 +83,10 @@ type Config struct {
 	ParentDir string
 	// Disable specifies whether the user request the cache to be disabled by default.
 	Disable bool
+	// ReadOnlyParentDir specifies the location of a pre-populated cache, which
+	// is searched when an entry is not found in the cache at ParentDir. It is
+	// never written to.
+	ReadOnlyParentDir string
 }
 
 // Handle is an structure representing the image cache, it's location and subdirectories
@@ -87,6 +99,9 @@ type Handle struct {
 	// deleted as opposed to the parent directory that is potentially managed
 	// (passed in) by the user.
 	rootDir string
+	// readOnlyRootDir is the root directory of the read-only cache, if any,
+	// laid out as rootDir.
+	readOnlyRootDir string
 	// If the cache is disabled
 	disabled bool
 }
@@ -105,6 +120,18 @@ func (h *Handle) GetOciCacheDir(cacheType string) (cacheDir string, err error) {
 	return h.getCacheTypeDir(cacheType), nil
 }
 
+// GetReadOnlyOciCacheDir returns the directory of the read-only cache for an
+// OCI cache type, or an empty string if no read-only cache is in use.
+func (h *Handle) GetReadOnlyOciCacheDir(cacheType string) (cacheDir string, err error) {
+	if !stringInSlice(cacheType, OciCacheTypes) {
+		return "", errInvalidCacheType
+	}
+	if h.readOnlyRootDir == "" {
+		return "", nil
+	}
+	return path.Join(h.readOnlyRootDir, cacheType), nil
+}
+
 // GetEntry returns a cache Entry for a specified file cache type and hash
 func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 	if h.disabled {
@@ -138,6 +165,17 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 		return nil, fmt.Errorf("could not check for cache entry '%s': %v", e.Path, err)
 	}
 
+	// Fall back to an entry of the read-only cache, which is used in place
+	if !pathExists && h.readOnlyRootDir != "" {
+		roPath := filepath.Join(h.readOnlyRootDir, cacheType, hash)
+		if fs.IsFile(roPath) {
+			sylog.Debugf("Using read-only cache entry: %s", roPath)
+			e.Path = roPath
+			e.Exists = true
+			return e, nil
+		}
+	}
+
 	if !pathExists {
 		e.Exists = false
 		f, err := fs.MakeTmpFile(cacheDir, "tmp_", 0o700)
@@ -157,6 +195,12 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 		return nil, fmt.Errorf("path '%s' exists but is not a file", e.Path)
 	}
 
+	// Record the use of the entry for Clean, as access times are not always
+	// updated on read.
+	if err := os.Chtimes(e.Path, time.Now(), time.Time{}); err != nil {
+		sylog.Debugf("Could not update access time of %s: %v", e.Path, err)
+	}
+
 	// It exists in the cache and it's a file. Caller can use the Path directly
 	e.Exists = true
 	return e, nil
@@ -205,6 +249,42 @@ func (h *Handle) CleanCache(cacheType string, dryRun bool, days int) (err error)
 	return err
 }
 
+// ociBlobLockFile is the lock file of the OCI blob cache layout. Writers of
+// the layout hold a shared lock on it, and Clean an exclusive one.
+const ociBlobLockFile = "layout.lock"
+
+// lockOciBlobCache locks the OCI blob cache layout with the flock operation
+// how, and returns a function releasing the lock.
+func (h *Handle) lockOciBlobCache(how int) (func(), error) {
+	dir := h.getCacheTypeDir(OciBlobCacheType)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	fd, err := unix.Open(filepath.Join(dir, ociBlobLockFile), unix.O_CREAT|unix.O_RDONLY|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(fd, how); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return func() {
+		unix.Flock(fd, unix.LOCK_UN)
+		unix.Close(fd)
+	}, nil
+}
+
+// LockOciBlobCache takes a shared lock on the OCI blob cache layout, so that
+// Clean doesn't evict blobs while images are written to it. The returned
+// function releases the lock.
+func (h *Handle) LockOciBlobCache() (func(), error) {
+	unlock, err := h.lockOciBlobCache(unix.LOCK_SH)
+	if err != nil {
+		return nil, fmt.Errorf("while locking OCI blob cache: %w", err)
+	}
+	return unlock, nil
+}
+
 // IsDisabled returns true if the cache is disabled
 func (h *Handle) IsDisabled() bool {
 	return h.disabled
@@ -267,6 +347,16 @@ func New(cfg Config) (h *Handle, err error) {
 		return h, nil
 	}
 
+	// The read-only cache is optional, and only searched if it exists
+	if cfg.ReadOnlyParentDir != "" {
+		roRootDir := path.Join(cfg.ReadOnlyParentDir, SubDirName)
+		if fs.IsDir(roRootDir) {
+			h.readOnlyRootDir = roRootDir
+		} else {
+			sylog.Warningf("Ignoring read-only cache - %s is not a directory", roRootDir)
+		}
+	}
+
 	// Initialize the root directory of the cache
 	rootDir := path.Join(parentDir, SubDirName)
 	h.rootDir = rootDir
@@ -0,0 +1,108 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetEntryReadOnly(t *testing.T) {
+	roParent := t.TempDir()
+	roDir := filepath.Join(roParent, SubDirName, LibraryCacheType)
+	if err := os.MkdirAll(roDir, 0o755); err != nil {
+		t.Fatalf("while creating read-only cache: %v", err)
+	}
+	for _, hash := range []string{"shared", "both"} {
+		if err := os.WriteFile(filepath.Join(roDir, hash), []byte("ro"), 0o644); err != nil {
+			t.Fatalf("while populating read-only cache: %v", err)
+		}
+	}
+
+	h, err := New(Config{ParentDir: t.TempDir(), ReadOnlyParentDir: roParent})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	if h.IsDisabled() {
+		t.Fatalf("unexpected disabled cache")
+	}
+	rwDir, err := h.GetFileCacheDir(LibraryCacheType)
+	if err != nil {
+		t.Fatalf("while getting cache dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rwDir, "both"), []byte("rw"), 0o644); err != nil {
+		t.Fatalf("while populating writable cache: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		hash         string
+		expectExists bool
+		expectPath   string
+	}{
+		{
+			name:         "read-only only",
+			hash:         "shared",
+			expectExists: true,
+			expectPath:   filepath.Join(roDir, "shared"),
+		},
+		{
+			name:         "writable takes precedence",
+			hash:         "both",
+			expectExists: true,
+			expectPath:   filepath.Join(rwDir, "both"),
+		},
+		{
+			name:         "missing",
+			hash:         "missing",
+			expectExists: false,
+			expectPath:   filepath.Join(rwDir, "missing"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := h.GetEntry(LibraryCacheType, tt.hash)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer e.CleanTmp()
+
+			if e.Exists != tt.expectExists {
+				t.Errorf("expected exists %v, got %v", tt.expectExists, e.Exists)
+			}
+			if e.Path != tt.expectPath {
+				t.Errorf("expected path %s, got %s", tt.expectPath, e.Path)
+			}
+			// New entries are always created in the writable cache
+			if !e.Exists && !strings.HasPrefix(e.TmpPath, rwDir+string(filepath.Separator)) {
+				t.Errorf("expected temporary path in %s, got %s", rwDir, e.TmpPath)
+			}
+		})
+	}
+}
+
+func TestNewReadOnlyMissing(t *testing.T) {
+	h, err := New(Config{ParentDir: t.TempDir(), ReadOnlyParentDir: filepath.Join(t.TempDir(), "missing")})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	dir, err := h.GetReadOnlyOciCacheDir(OciBlobCacheType)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir != "" {
+		t.Errorf("expected no read-only cache, got %s", dir)
+	}
+	if _, err := h.GetReadOnlyOciCacheDir(LibraryCacheType); err == nil {
+		t.Errorf("expected error for file cache type")
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/apptainer/apptainer/pkg/util/fs/lock"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"golang.org/x/sys/unix"
+)
+
+// CleanStats summarizes the entries evicted by Clean.
+type CleanStats struct {
+	// Removed is the number of entries evicted.
+	Removed int
+	// Freed is the size in bytes of the entries evicted.
+	Freed int64
+	// Kept is the number of entries left in the cache.
+	Kept int
+	// Size is the size in bytes of the entries left in the cache.
+	Size int64
+}
+
+// cleanEntry is a file of the cache, or an image of the OCI blob cache,
+// considered for eviction.
+type cleanEntry struct {
+	path     string
+	size     int64
+	lastUsed time.Time
+	// image is set for an image of the OCI blob cache, which is evicted
+	// along with the blobs no other image references. size is then the size
+	// of these blobs.
+	image *blobImage
+}
+
+// blobLayout is the OCI layout of the OCI blob cache, with the number of
+// images referencing each blob.
+type blobLayout struct {
+	path layout.Path
+	refs map[v1.Hash]int
+	// shared is the size of the blobs referenced by more than one image.
+	shared int64
+}
+
+// blobPath returns the path of the blob with the given digest.
+func (l *blobLayout) blobPath(h v1.Hash) string {
+	return filepath.Join(string(l.path), "blobs", h.Algorithm, h.Hex)
+}
+
+// blobImage is an image of the OCI blob cache, with the blobs of its
+// manifest, config and layers.
+type blobImage struct {
+	layout *blobLayout
+	digest v1.Hash
+	blobs  []v1.Hash
+}
+
+// remove removes the image from the index of its layout, then the blobs that
+// no other image references, and returns their size. In dryRun mode, the
+// layout is left untouched.
+func (i *blobImage) remove(dryRun bool) (int64, error) {
+	if !dryRun {
+		if err := i.layout.path.RemoveDescriptors(match.Digests(i.digest)); err != nil {
+			return 0, err
+		}
+	}
+	var freed int64
+	for _, h := range i.blobs {
+		i.layout.refs[h]--
+		if i.layout.refs[h] > 0 {
+			continue
+		}
+		path := i.layout.blobPath(h)
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return freed, err
+			}
+		}
+		freed += fi.Size()
+	}
+	return freed, nil
+}
+
+// blobImages returns the images recorded in the index of the OCI blob cache
+// layout at dir, as entries evicted with their blobs.
+func blobImages(dir string) ([]cleanEntry, *blobLayout, error) {
+	bl := &blobLayout{refs: make(map[v1.Hash]int)}
+
+	lp, err := layout.FromPath(dir)
+	if err != nil {
+		// no image was written yet, all blobs are unreferenced
+		return nil, bl, nil
+	}
+	bl.path = lp
+	ii, err := lp.ImageIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	im, err := ii.IndexManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var images []*blobImage
+	seen := make(map[v1.Hash]bool)
+	for _, desc := range im.Manifests {
+		if seen[desc.Digest] {
+			continue
+		}
+		seen[desc.Digest] = true
+
+		img := &blobImage{layout: bl, digest: desc.Digest}
+		blobs := []v1.Hash{desc.Digest}
+		if m, err := blobManifest(bl.blobPath(desc.Digest)); err == nil {
+			blobs = append(blobs, m.Config.Digest)
+			for _, l := range m.Layers {
+				blobs = append(blobs, l.Digest)
+			}
+		} else {
+			sylog.Debugf("Could not read manifest of cached image %s: %v", desc.Digest, err)
+		}
+		inImage := make(map[v1.Hash]bool)
+		for _, h := range blobs {
+			if !inImage[h] {
+				inImage[h] = true
+				img.blobs = append(img.blobs, h)
+				bl.refs[h]++
+			}
+		}
+		images = append(images, img)
+	}
+
+	var entries []cleanEntry
+	sharedSeen := make(map[v1.Hash]bool)
+	for _, img := range images {
+		e := cleanEntry{path: bl.blobPath(img.digest), image: img}
+		for _, h := range img.blobs {
+			fi, err := os.Stat(bl.blobPath(h))
+			if err != nil {
+				continue
+			}
+			if bl.refs[h] == 1 {
+				e.size += fi.Size()
+			} else if !sharedSeen[h] {
+				sharedSeen[h] = true
+				bl.shared += fi.Size()
+			}
+			if used := lastUsed(fi); used.After(e.lastUsed) {
+				e.lastUsed = used
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, bl, nil
+}
+
+// blobManifest reads the image manifest stored in the blob at path.
+func blobManifest(path string) (*v1.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return v1.ParseManifest(f)
+}
+
+// lastUsed returns the time a cache file was last used, which is its access
+// time unless the file was modified later, as relatime mounts only update the
+// access time once per day.
+func lastUsed(fi fs.FileInfo) time.Time {
+	used := fi.ModTime()
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		atime := time.Unix(st.Atim.Sec, st.Atim.Nsec)
+		if atime.After(used) {
+			used = atime
+		}
+	}
+	return used
+}
+
+// cleanEntries returns the files of the cache type cacheType that can be
+// evicted. Temporary files of entries being created, and the lock files of
+// downloads, are not returned. The images of the OCI blob cache are returned
+// as single entries, the blobs they reference are not returned on their own.
+func (h *Handle) cleanEntries(cacheType string) ([]cleanEntry, error) {
+	var entries []cleanEntry
+	var bl *blobLayout
+
+	dir := h.getCacheTypeDir(cacheType)
+	if cacheType == OciBlobCacheType {
+		var err error
+		entries, bl, err = blobImages(dir)
+		if err != nil {
+			return nil, fmt.Errorf("while reading OCI blob cache index: %w", err)
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, "tmp_") || strings.HasSuffix(name, ".lock") {
+			return nil
+		}
+		if bl != nil {
+			// The layout files of the OCI blob cache are needed by every
+			// image, and the blobs of an image are evicted with it.
+			if filepath.Dir(path) == dir {
+				return nil
+			}
+			algorithm := filepath.Base(filepath.Dir(path))
+			if bl.refs[v1.Hash{Algorithm: algorithm, Hex: name}] > 0 {
+				return nil
+			}
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, cleanEntry{path: path, size: fi.Size(), lastUsed: lastUsed(fi)})
+		return nil
+	})
+	return entries, err
+}
+
+// inUse reports whether the cache file at path is locked, either directly or,
+// for a partial download, through its lock file.
+func inUse(path string) bool {
+	lockPath := path
+	if _, err := os.Stat(path + ".lock"); err == nil {
+		lockPath = path + ".lock"
+	}
+	fd, acquired, err := lock.TryExclusive(lockPath)
+	if err != nil {
+		sylog.Debugf("Could not check whether %s is in use: %v", path, err)
+		return false
+	}
+	if acquired {
+		lock.Release(fd)
+	}
+	return !acquired
+}
+
+// Clean evicts entries of the given cache types that were last used longer
+// than maxAge ago, then the least recently used remaining entries until they
+// fit in maxSize bytes. A zero maxAge or maxSize disables the corresponding
+// limit. Entries in use are kept. Images of the OCI blob cache are evicted
+// whole, removing them from its index with the blobs no other image
+// references, and the OCI blob cache is skipped while images are written to
+// it. In dryRun mode, the entries that would be evicted are only reported.
+func (h *Handle) Clean(cacheTypes []string, maxAge time.Duration, maxSize int64, dryRun bool) (CleanStats, error) {
+	var stats CleanStats
+
+	if h.disabled {
+		return stats, nil
+	}
+
+	var entries []cleanEntry
+	for _, cacheType := range cacheTypes {
+		if cacheType == OciBlobCacheType {
+			// Blobs written by a pull are not referenced by any image until
+			// the pull completes, so the blob cache is left alone meanwhile.
+			unlock, err := h.lockOciBlobCache(unix.LOCK_EX | unix.LOCK_NB)
+			if errors.Is(err, unix.EWOULDBLOCK) {
+				sylog.Infof("Skipping %s cache: images are being written to it", cacheType)
+				continue
+			} else if err != nil {
+				return stats, fmt.Errorf("while locking %s cache: %v", cacheType, err)
+			}
+			defer unlock()
+		}
+		e, err := h.cleanEntries(cacheType)
+		if err != nil {
+			return stats, fmt.Errorf("while listing %s cache entries: %v", cacheType, err)
+		}
+		entries = append(entries, e...)
+	}
+
+	// Least recently used first
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastUsed.Before(entries[j].lastUsed)
+	})
+
+	var total int64
+	layouts := make(map[*blobLayout]bool)
+	for _, e := range entries {
+		total += e.size
+		if e.image != nil && !layouts[e.image.layout] {
+			layouts[e.image.layout] = true
+			total += e.image.layout.shared
+		}
+	}
+
+	errCount := 0
+	now := time.Now()
+	for _, e := range entries {
+		expired := maxAge > 0 && now.Sub(e.lastUsed) > maxAge
+		oversize := maxSize > 0 && total > maxSize
+		if !expired && !oversize {
+			stats.Kept++
+			continue
+		}
+		if e.image == nil && inUse(e.path) {
+			sylog.Debugf("Keeping %s: in use", e.path)
+			stats.Kept++
+			continue
+		}
+
+		if e.image != nil {
+			sylog.Infof("Removing cached OCI image: %s (last used %s)", e.image.digest, e.lastUsed.Format(time.RFC3339))
+			freed, err := e.image.remove(dryRun)
+			total -= freed
+			stats.Freed += freed
+			if err != nil {
+				sylog.Errorf("Could not remove cached OCI image '%s': %v", e.image.digest, err)
+				errCount++
+				stats.Kept++
+				continue
+			}
+			stats.Removed++
+			continue
+		}
+
+		sylog.Infof("Removing cache entry: %s (last used %s)", e.path, e.lastUsed.Format(time.RFC3339))
+		if !dryRun {
+			if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+				sylog.Errorf("Could not remove cache entry '%s': %v", e.path, err)
+				errCount++
+				stats.Kept++
+				continue
+			}
+		}
+		stats.Removed++
+		stats.Freed += e.size
+		total -= e.size
+	}
+	stats.Size = total
+
+	if errCount > 0 {
+		return stats, fmt.Errorf("failed to remove %d cache entries", errCount)
+	}
+	return stats, nil
+}
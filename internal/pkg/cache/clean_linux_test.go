@@ -0,0 +1,258 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apptainer/apptainer/pkg/util/fs/lock"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+// cacheFile creates a file of size bytes at path, last used age ago.
+func cacheFile(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("while creating %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o600); err != nil {
+		t.Fatalf("while creating %s: %v", path, err)
+	}
+	used := time.Now().Add(-age)
+	if err := os.Chtimes(path, used, used); err != nil {
+		t.Fatalf("while setting times of %s: %v", path, err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	const day = 24 * time.Hour
+
+	tests := []struct {
+		name         string
+		maxAge       time.Duration
+		maxSize      int64
+		dryRun       bool
+		expectKept   []string
+		expectFreed  int64
+		expectRemove int
+	}{
+		{
+			name:         "age",
+			maxAge:       5 * day,
+			expectKept:   []string{"library/new", "library/mid", "library/locked", "blob/blobs/sha256/new"},
+			expectFreed:  300,
+			expectRemove: 2,
+		},
+		{
+			name:         "size",
+			maxSize:      250,
+			expectKept:   []string{"library/new", "library/locked", "blob/blobs/sha256/new"},
+			expectFreed:  400,
+			expectRemove: 3,
+		},
+		{
+			name:         "age and size",
+			maxAge:       5 * day,
+			maxSize:      300,
+			expectKept:   []string{"library/new", "library/locked", "blob/blobs/sha256/new"},
+			expectFreed:  400,
+			expectRemove: 3,
+		},
+		{
+			name:         "dry run",
+			maxSize:      250,
+			dryRun:       true,
+			expectKept:   []string{"library/new", "library/mid", "library/old", "library/locked", "blob/blobs/sha256/new", "blob/blobs/sha256/old"},
+			expectFreed:  400,
+			expectRemove: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := New(Config{ParentDir: t.TempDir()})
+			if err != nil {
+				t.Fatalf("while creating cache: %v", err)
+			}
+
+			// 650 bytes in evictable entries, from the most to the least
+			// recently used: new, blob new, mid, blob old, locked, old.
+			cacheFile(t, filepath.Join(h.rootDir, "library", "new"), 100, 0)
+			cacheFile(t, filepath.Join(h.rootDir, "blob", "blobs", "sha256", "new"), 50, day)
+			cacheFile(t, filepath.Join(h.rootDir, "library", "mid"), 100, 2*day)
+			cacheFile(t, filepath.Join(h.rootDir, "library", "locked"), 100, 10*day)
+			cacheFile(t, filepath.Join(h.rootDir, "blob", "blobs", "sha256", "old"), 150, 9*day)
+			cacheFile(t, filepath.Join(h.rootDir, "library", "old"), 150, 20*day)
+			// never evicted
+			if _, err := layout.Write(filepath.Join(h.rootDir, "blob"), empty.Index); err != nil {
+				t.Fatalf("while creating blob cache layout: %v", err)
+			}
+			cacheFile(t, filepath.Join(h.rootDir, "library", "tmp_123"), 10, 30*day)
+
+			fd, err := lock.Exclusive(filepath.Join(h.rootDir, "library", "locked"))
+			if err != nil {
+				t.Fatalf("while locking entry: %v", err)
+			}
+			defer lock.Release(fd)
+
+			stats, err := h.Clean([]string{LibraryCacheType, OciBlobCacheType}, tt.maxAge, tt.maxSize, tt.dryRun)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stats.Removed != tt.expectRemove || stats.Freed != tt.expectFreed {
+				t.Errorf("expected %d entries (%d bytes) removed, got %d (%d bytes)", tt.expectRemove, tt.expectFreed, stats.Removed, stats.Freed)
+			}
+
+			kept := append([]string{"blob/index.json", "library/tmp_123"}, tt.expectKept...)
+			for _, name := range kept {
+				if _, err := os.Stat(filepath.Join(h.rootDir, name)); err != nil {
+					t.Errorf("expected %s to be kept: %v", name, err)
+				}
+			}
+			entries, err := h.cleanEntries(LibraryCacheType)
+			if err != nil {
+				t.Fatalf("while listing entries: %v", err)
+			}
+			blobs, err := h.cleanEntries(OciBlobCacheType)
+			if err != nil {
+				t.Fatalf("while listing entries: %v", err)
+			}
+			if n := len(entries) + len(blobs); n != len(tt.expectKept) {
+				t.Errorf("expected %d entries left, got %d", len(tt.expectKept), n)
+			}
+		})
+	}
+}
+
+// blobDigests returns the digests of the manifest, config and layers of img.
+func blobDigests(t *testing.T, img v1.Image) []v1.Hash {
+	t.Helper()
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digests := []v1.Hash{digest, m.Config.Digest}
+	for _, l := range m.Layers {
+		digests = append(digests, l.Digest)
+	}
+	return digests
+}
+
+func TestCleanOCIImages(t *testing.T) {
+	const day = 24 * time.Hour
+
+	h, err := New(Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	dir := filepath.Join(h.rootDir, OciBlobCacheType)
+	lp, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatalf("while creating blob cache layout: %v", err)
+	}
+
+	// The new image shares the layer of the old one.
+	oldImg, err := random.Image(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, err := random.Layer(100, "application/vnd.oci.image.layer.v1.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newImg, err := mutate.AppendLayers(oldImg, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, img := range []v1.Image{oldImg, newImg} {
+		if err := lp.AppendImage(img); err != nil {
+			t.Fatalf("while writing image: %v", err)
+		}
+	}
+	blobPath := func(h v1.Hash) string {
+		return filepath.Join(dir, "blobs", h.Algorithm, h.Hex)
+	}
+	age := func(h v1.Hash, d time.Duration) {
+		used := time.Now().Add(-d)
+		if err := os.Chtimes(blobPath(h), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldBlobs := blobDigests(t, oldImg)
+	newBlobs := blobDigests(t, newImg)
+	for _, h := range oldBlobs {
+		age(h, 10*day)
+	}
+	for _, h := range newBlobs[:2] {
+		age(h, day)
+	}
+	age(newBlobs[len(newBlobs)-1], day)
+	// a blob left by an interrupted pull
+	orphan := filepath.Join(dir, "blobs", "sha256", "orphan")
+	cacheFile(t, orphan, 10, 10*day)
+
+	// Nothing is evicted while an image is written to the cache.
+	unlock, err := h.LockOciBlobCache()
+	if err != nil {
+		t.Fatalf("while locking blob cache: %v", err)
+	}
+	stats, err := h.Clean([]string{OciBlobCacheType}, 5*day, 0, false)
+	unlock()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Removed != 0 {
+		t.Errorf("expected no entries removed while locked, got %d", stats.Removed)
+	}
+
+	stats, err = h.Clean([]string{OciBlobCacheType}, 5*day, 0, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Removed != 2 {
+		t.Errorf("expected old image and orphan blob removed, got %d entries", stats.Removed)
+	}
+
+	// The old image is removed from the index, the new one is complete.
+	ii, err := lp.ImageIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im, err := ii.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(im.Manifests) != 1 || im.Manifests[0].Digest != newBlobs[0] {
+		t.Fatalf("expected only image %s in index, got %v", newBlobs[0], im.Manifests)
+	}
+	for _, h := range newBlobs {
+		if _, err := os.Stat(blobPath(h)); err != nil {
+			t.Errorf("expected blob %s of the kept image: %v", h, err)
+		}
+	}
+	for _, h := range oldBlobs[:2] {
+		if _, err := os.Stat(blobPath(h)); !os.IsNotExist(err) {
+			t.Errorf("expected blob %s of the evicted image to be removed: %v", h, err)
+		}
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("expected orphan blob to be removed: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// VerifyResult is the outcome of checking a blob of the OCI blob cache.
+type VerifyResult struct {
+	// Digest is the digest the blob is stored under, e.g. sha256:<hex>.
+	Digest string
+	// Path is the location of the blob.
+	Path string
+	// Err is nil if the content of the blob matches its digest.
+	Err error
+}
+
+// blobHashes are the digest algorithms of the blobs stored in the OCI layout.
+var blobHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// VerifyOciBlobs checks that the content of every blob of the OCI blob cache
+// matches the digest it is stored under. Blobs are stored in an OCI layout,
+// content-addressed by digest, so that the layers shared by several images are
+// stored once.
+func (h *Handle) VerifyOciBlobs() ([]VerifyResult, error) {
+	if h.disabled {
+		return nil, nil
+	}
+
+	var results []VerifyResult
+
+	blobsDir := filepath.Join(h.getCacheTypeDir(OciBlobCacheType), "blobs")
+	err := filepath.WalkDir(blobsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		algorithm := filepath.Base(filepath.Dir(path))
+		result := VerifyResult{
+			Digest: algorithm + ":" + d.Name(),
+			Path:   path,
+		}
+		result.Err = verifyBlob(path, algorithm, d.Name())
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// verifyBlob checks that the content of the file at path has the digest
+// algorithm:hexDigest.
+func verifyBlob(path, algorithm, hexDigest string) error {
+	newHash, ok := blobHashes[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != hexDigest {
+		return fmt.Errorf("content has digest %s:%s", algorithm, got)
+	}
+	return nil
+}
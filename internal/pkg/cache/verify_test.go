@@ -0,0 +1,61 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyOciBlobs(t *testing.T) {
+	h, err := New(Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+
+	blobsDir := filepath.Join(h.getCacheTypeDir(OciBlobCacheType), "blobs", "sha256")
+	if err := os.MkdirAll(blobsDir, 0o700); err != nil {
+		t.Fatalf("while creating blobs dir: %v", err)
+	}
+	writeBlob := func(content, storedAs string) string {
+		sum := sha256.Sum256([]byte(storedAs))
+		name := hex.EncodeToString(sum[:])
+		if err := os.WriteFile(filepath.Join(blobsDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("while writing blob: %v", err)
+		}
+		return "sha256:" + name
+	}
+	good := writeBlob("good", "good")
+	corrupt := writeBlob("corrupt", "original")
+
+	results, err := h.VerifyOciBlobs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 blobs checked, got %d", len(results))
+	}
+	for _, r := range results {
+		switch r.Digest {
+		case good:
+			if r.Err != nil {
+				t.Errorf("unexpected error for %s: %v", r.Digest, r.Err)
+			}
+		case corrupt:
+			if r.Err == nil {
+				t.Errorf("expected error for corrupted blob %s", r.Digest)
+			}
+		default:
+			t.Errorf("unexpected blob %s", r.Digest)
+		}
+	}
+}
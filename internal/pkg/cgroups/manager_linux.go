@@ -39,6 +39,8 @@ type Manager struct {
 	group string
 	// Are we using systemd?
 	systemd bool
+	// Are we using rootless cgroups?
+	rootless bool
 	// The underlying runc/libcontainer/cgroups manager
 	cgroup lccgroups.Manager
 }
@@ -126,6 +128,13 @@ func (m *Manager) UpdateFromSpec(resources *specs.LinuxResources) (err error) {
 		return ErrUninitialized
 	}
 
+	checkResources(resources, m.rootless)
+	return m.setResources(resources)
+}
+
+// setResources applies resources, already adapted to the cgroups available
+// to the manager by checkResources, to the existing managed cgroup.
+func (m *Manager) setResources(resources *specs.LinuxResources) error {
 	spec := &specs.Spec{
 		Linux: &specs.Linux{
 			CgroupsPath: m.group,
@@ -133,12 +142,10 @@ func (m *Manager) UpdateFromSpec(resources *specs.LinuxResources) (err error) {
 		},
 	}
 
-	uid := os.Geteuid()
-
 	opts := &lcspecconv.CreateOpts{
 		CgroupName:       m.group,
 		UseSystemdCgroup: false,
-		RootlessCgroups:  uid != 0,
+		RootlessCgroups:  m.rootless,
 		Spec:             spec,
 	}
 
@@ -286,6 +293,8 @@ func newManager(resources *specs.LinuxResources, group string, systemd bool) (ma
 		}
 	}
 
+	checkResources(resources, rootless)
+
 	spec := &specs.Spec{
 		Linux: &specs.Linux{
 			CgroupsPath: group,
@@ -333,9 +342,10 @@ func newManager(resources *specs.LinuxResources, group string, systemd bool) (ma
 	}
 
 	mgr := Manager{
-		group:   group,
-		systemd: systemd,
-		cgroup:  cgroup,
+		group:    group,
+		systemd:  systemd,
+		rootless: rootless,
+		cgroup:   cgroup,
 	}
 	return &mgr, nil
 }
@@ -373,7 +383,9 @@ func NewManagerWithSpec(spec *specs.LinuxResources, pid int, group string, syste
 	if err := mgr.cgroup.Apply(pid); err != nil {
 		return nil, err
 	}
-	if err := mgr.UpdateFromSpec(spec); err != nil {
+	// newManager has already checked the resources against the available
+	// controllers, so only apply them.
+	if err := mgr.setResources(spec); err != nil {
 		return nil, err
 	}
 
@@ -429,9 +441,10 @@ func GetManagerForGroup(group string) (manager *Manager, err error) {
 	}
 
 	mgr := Manager{
-		group:   group,
-		systemd: false,
-		cgroup:  cgroup,
+		group:    group,
+		systemd:  false,
+		rootless: os.Geteuid() != 0,
+		cgroup:   cgroup,
 	}
 	return &mgr, nil
 }
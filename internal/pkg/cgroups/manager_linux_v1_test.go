@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
@@ -43,6 +44,10 @@ func TestCgroupsV1(t *testing.T) {
 			name:     "UpdateUnified",
 			testFunc: testUpdateUnifiedV1,
 		},
+		{
+			name:     "Controllers",
+			testFunc: testControllersV1,
+		},
 		{
 			name:     "AddProc",
 			testFunc: testAddProcV1,
@@ -157,6 +162,42 @@ func testUpdateUnifiedV1(t *testing.T, systemd bool) {
 	}
 }
 
+// testControllersV1 checks that limits are written to the files of the
+// right cgroups v1 controllers.
+func testControllersV1(t *testing.T, systemd bool) {
+	cmd := exec.Command("/bin/cat", "/dev/zero")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("While starting test process: %v", err)
+	}
+	pid := cmd.Process.Pid
+	group := filepath.Join("/apptainer", strconv.Itoa(pid))
+	if systemd {
+		group = "system.slice:apptainer:" + strconv.Itoa(pid)
+	}
+
+	shares := uint64(512)
+	resources := &specs.LinuxResources{
+		Memory: &specs.LinuxMemory{Limit: Int64ptr(256 * 1024 * 1024)},
+		CPU:    &specs.LinuxCPU{Shares: &shares},
+		Pids:   &specs.LinuxPids{Limit: 64},
+	}
+	manager, err := NewManagerWithSpec(resources, pid, group, systemd)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Process.Wait()
+		t.Fatalf("While creating new cgroup: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Process.Wait()
+		manager.Destroy()
+	}()
+
+	ensureInt(t, filepath.Join(manager.cgroup.Path("memory"), "memory.limit_in_bytes"), 256*1024*1024)
+	ensureInt(t, filepath.Join(manager.cgroup.Path("cpu"), "cpu.shares"), 512)
+	ensureInt(t, filepath.Join(manager.cgroup.Path("pids"), "pids.max"), 64)
+}
+
 func testAddProcV1(t *testing.T, systemd bool) {
 	pid, manager, cleanup := testManager(t, systemd)
 
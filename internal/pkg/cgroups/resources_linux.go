@@ -0,0 +1,126 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+	lccgroups "github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// delegationGuidance explains how to delegate controllers to users with
+// systemd.
+const delegationGuidance = "To enforce them, delegate the controller to users with systemd, " +
+	"e.g. with 'Delegate=cpu cpuset io memory pids' in the [Service] section of " +
+	"/etc/systemd/system/user@.service.d/delegate.conf, then run 'systemctl daemon-reload' and log in again"
+
+// checkResources adapts resources to the limitations of the host cgroups,
+// warning about the limits which can't be applied: those of controllers not
+// mounted on a cgroups v1 hierarchy, or not delegated to the user by systemd
+// for rootless cgroups. The run isn't failed, the limits are not enforced.
+func checkResources(resources *specs.LinuxResources, rootless bool) {
+	var warnings []string
+	if !lccgroups.IsCgroup2UnifiedMode() {
+		warnings = adaptV1Resources(resources, v1Mounted, v1SwapAccounting())
+	} else if rootless {
+		delegated, err := delegatedControllers(os.Geteuid())
+		if err != nil {
+			sylog.Debugf("Could not check cgroup controllers delegated to the user: %s", err)
+			return
+		}
+		warnings = adaptRootlessResources(resources, delegated)
+	}
+	for _, w := range warnings {
+		sylog.Warningf("%s", w)
+	}
+}
+
+// delegatedControllers returns the cgroups v2 controllers delegated by
+// systemd to the user manager of uid, which creates rootless cgroups.
+func delegatedControllers(uid int) ([]string, error) {
+	path := filepath.Join("/sys/fs/cgroup/user.slice",
+		fmt.Sprintf("user-%d.slice", uid),
+		fmt.Sprintf("user@%d.service", uid),
+		"cgroup.controllers",
+	)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(b)), nil
+}
+
+// v2Controllers returns the cgroups v2 controllers applying the limits of
+// resources.
+func v2Controllers(r *specs.LinuxResources) []string {
+	var controllers []string
+	for _, c := range v1Controllers(r) {
+		switch c {
+		case "blkio":
+			controllers = append(controllers, "io")
+		case "net_cls", "net_prio":
+			// no cgroups v2 controller
+		default:
+			controllers = append(controllers, c)
+		}
+	}
+	return controllers
+}
+
+// adaptRootlessResources returns warnings about the limits of resources
+// which can't be applied with rootless cgroups, as their controller isn't in
+// delegated, and removes them from resources so the run doesn't fail.
+func adaptRootlessResources(r *specs.LinuxResources, delegated []string) []string {
+	var warnings []string
+	for _, c := range v2Controllers(r) {
+		found := false
+		for _, d := range delegated {
+			if d == c {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("The %s cgroup controller is not delegated to the user, its limits will not be enforced. %s", c, delegationGuidance))
+		clearLimits(r, c)
+	}
+	return warnings
+}
+
+// clearLimits removes the limits applied by the cgroups v2 controller from
+// resources.
+func clearLimits(r *specs.LinuxResources, controller string) {
+	switch controller {
+	case "memory":
+		r.Memory = nil
+	case "cpu":
+		if r.CPU != nil {
+			r.CPU = &specs.LinuxCPU{Cpus: r.CPU.Cpus, Mems: r.CPU.Mems}
+		}
+	case "cpuset":
+		if r.CPU != nil {
+			r.CPU.Cpus, r.CPU.Mems = "", ""
+		}
+	case "pids":
+		r.Pids = nil
+	case "io":
+		r.BlockIO = nil
+	case "hugetlb":
+		r.HugepageLimits = nil
+	case "rdma":
+		r.Rdma = nil
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"strings"
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestAdaptRootlessResources(t *testing.T) {
+	shares := uint64(512)
+	newResources := func() *specs.LinuxResources {
+		return &specs.LinuxResources{
+			Memory:  &specs.LinuxMemory{Limit: Int64ptr(1 << 20)},
+			CPU:     &specs.LinuxCPU{Shares: &shares, Cpus: "0"},
+			Pids:    &specs.LinuxPids{Limit: 64},
+			BlockIO: &specs.LinuxBlockIO{},
+		}
+	}
+
+	t.Run("AllDelegated", func(t *testing.T) {
+		r := newResources()
+		warnings := adaptRootlessResources(r, []string{"cpuset", "cpu", "io", "memory", "pids"})
+		if len(warnings) != 0 {
+			t.Errorf("unexpected warnings: %q", warnings)
+		}
+		if r.Memory == nil || r.CPU.Shares == nil || r.CPU.Cpus == "" || r.Pids == nil || r.BlockIO == nil {
+			t.Errorf("unexpected limits removed: %+v", r)
+		}
+	})
+
+	// the systemd default, delegating only memory and pids
+	t.Run("MemoryPidsDelegated", func(t *testing.T) {
+		r := newResources()
+		warnings := adaptRootlessResources(r, []string{"memory", "pids"})
+		if len(warnings) != 3 {
+			t.Fatalf("expected 3 warnings, got %q", warnings)
+		}
+		for i, c := range []string{"cpu", "cpuset", "io"} {
+			if !strings.Contains(warnings[i], "The "+c+" cgroup controller is not delegated") ||
+				!strings.Contains(warnings[i], "Delegate=") {
+				t.Errorf("unexpected warning for %s controller: %q", c, warnings[i])
+			}
+		}
+		if r.Memory == nil || r.Pids == nil {
+			t.Errorf("delegated limits removed: %+v", r)
+		}
+		if r.CPU.Shares != nil || r.CPU.Cpus != "" || r.BlockIO != nil {
+			t.Errorf("limits not delegated kept: %+v", r)
+		}
+	})
+
+	// no delegation at all, e.g. the --memory limit of a rootless user
+	t.Run("NoDelegation", func(t *testing.T) {
+		r := &specs.LinuxResources{Memory: &specs.LinuxMemory{Limit: Int64ptr(1 << 20)}}
+		warnings := adaptRootlessResources(r, nil)
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "memory cgroup controller is not delegated to the user, its limits will not be enforced") {
+			t.Errorf("unexpected warnings: %q", warnings)
+		}
+		if r.Memory != nil {
+			t.Errorf("memory limit not removed")
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"fmt"
+	"path/filepath"
+
+	lccgroups "github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// v1MemorySwapLimit is the cgroups v1 memory controller file limiting memory
+// and swap usage, only present when swap accounting is enabled.
+const v1MemorySwapLimit = "memory.memsw.limit_in_bytes"
+
+// v1Mounted returns whether the cgroups v1 controller is mounted.
+func v1Mounted(controller string) bool {
+	_, err := lccgroups.FindCgroupMountpoint("", controller)
+	return err == nil
+}
+
+// v1SwapAccounting returns whether swap accounting is enabled for the
+// cgroups v1 memory controller, i.e. the swapaccount=1 kernel parameter is
+// set on kernels disabling it by default.
+func v1SwapAccounting() bool {
+	mnt, err := lccgroups.FindCgroupMountpoint("", "memory")
+	if err != nil {
+		return false
+	}
+	return lccgroups.PathExists(filepath.Join(mnt, v1MemorySwapLimit))
+}
+
+// v1Controllers returns the cgroups v1 controllers applying the limits of
+// resources.
+func v1Controllers(r *specs.LinuxResources) []string {
+	var controllers []string
+	if r.Memory != nil {
+		controllers = append(controllers, "memory")
+	}
+	if c := r.CPU; c != nil {
+		if c.Shares != nil || c.Quota != nil || c.Burst != nil || c.Period != nil ||
+			c.RealtimeRuntime != nil || c.RealtimePeriod != nil || c.Idle != nil {
+			controllers = append(controllers, "cpu")
+		}
+		if c.Cpus != "" || c.Mems != "" {
+			controllers = append(controllers, "cpuset")
+		}
+	}
+	if r.Pids != nil {
+		controllers = append(controllers, "pids")
+	}
+	if r.BlockIO != nil {
+		controllers = append(controllers, "blkio")
+	}
+	if len(r.HugepageLimits) > 0 {
+		controllers = append(controllers, "hugetlb")
+	}
+	if n := r.Network; n != nil {
+		if n.ClassID != nil {
+			controllers = append(controllers, "net_cls")
+		}
+		if len(n.Priorities) > 0 {
+			controllers = append(controllers, "net_prio")
+		}
+	}
+	if len(r.Rdma) > 0 {
+		controllers = append(controllers, "rdma")
+	}
+	return controllers
+}
+
+// adaptV1Resources returns warnings about the limits of resources which
+// can't be applied on a cgroups v1 hierarchy, where mounted reports whether
+// a controller is mounted, and swapAccounting whether memory controller
+// accounts swap usage. The limits of a controller which isn't mounted are
+// ignored by the cgroups v1 managers, and a swap limit without swap
+// accounting makes them fail, so it is removed from resources.
+func adaptV1Resources(r *specs.LinuxResources, mounted func(string) bool, swapAccounting bool) []string {
+	var warnings []string
+	for _, c := range v1Controllers(r) {
+		if !mounted(c) {
+			warnings = append(warnings, fmt.Sprintf("The cgroups v1 %s controller is not mounted, its limits will not be applied", c))
+		}
+	}
+	if r.Memory != nil && r.Memory.Swap != nil && mounted("memory") && !swapAccounting {
+		warnings = append(warnings, "Swap accounting is not enabled for the cgroups v1 memory controller (swapaccount=1 kernel parameter), the memory swap limit will not be applied")
+		r.Memory.Swap = nil
+	}
+	return warnings
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"reflect"
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestV1Controllers(t *testing.T) {
+	shares := uint64(512)
+	classID := uint32(1)
+	r := &specs.LinuxResources{
+		Memory:         &specs.LinuxMemory{Limit: Int64ptr(1 << 20)},
+		CPU:            &specs.LinuxCPU{Shares: &shares, Cpus: "0"},
+		Pids:           &specs.LinuxPids{Limit: 64},
+		HugepageLimits: []specs.LinuxHugepageLimit{{Pagesize: "2MB", Limit: 1 << 21}},
+		Network:        &specs.LinuxNetwork{ClassID: &classID},
+	}
+	want := []string{"memory", "cpu", "cpuset", "pids", "hugetlb", "net_cls"}
+	if got := v1Controllers(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected controllers %v, got %v", want, got)
+	}
+	if got := v1Controllers(&specs.LinuxResources{CPU: &specs.LinuxCPU{Mems: "0"}}); !reflect.DeepEqual(got, []string{"cpuset"}) {
+		t.Errorf("expected cpuset controller only, got %v", got)
+	}
+}
+
+func TestAdaptV1Resources(t *testing.T) {
+	mountedExcept := func(missing ...string) func(string) bool {
+		return func(c string) bool {
+			for _, m := range missing {
+				if c == m {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	tests := []struct {
+		name           string
+		resources      *specs.LinuxResources
+		mounted        func(string) bool
+		swapAccounting bool
+		wantWarnings   int
+		wantSwap       bool
+	}{
+		{
+			name:           "AllMounted",
+			resources:      &specs.LinuxResources{Memory: &specs.LinuxMemory{Limit: Int64ptr(1 << 20), Swap: Int64ptr(1 << 21)}, Pids: &specs.LinuxPids{Limit: 64}},
+			mounted:        mountedExcept(),
+			swapAccounting: true,
+			wantSwap:       true,
+		},
+		{
+			name:           "PidsNotMounted",
+			resources:      &specs.LinuxResources{Pids: &specs.LinuxPids{Limit: 64}},
+			mounted:        mountedExcept("pids"),
+			swapAccounting: true,
+			wantWarnings:   1,
+		},
+		{
+			name:         "NoSwapAccounting",
+			resources:    &specs.LinuxResources{Memory: &specs.LinuxMemory{Limit: Int64ptr(1 << 20), Swap: Int64ptr(1 << 21)}},
+			mounted:      mountedExcept(),
+			wantWarnings: 1,
+		},
+		{
+			name:         "NoSwapLimit",
+			resources:    &specs.LinuxResources{Memory: &specs.LinuxMemory{Limit: Int64ptr(1 << 20)}},
+			mounted:      mountedExcept(),
+			wantWarnings: 0,
+		},
+		{
+			name:         "MemoryNotMounted",
+			resources:    &specs.LinuxResources{Memory: &specs.LinuxMemory{Limit: Int64ptr(1 << 20), Swap: Int64ptr(1 << 21)}},
+			mounted:      mountedExcept("memory"),
+			wantWarnings: 1,
+			wantSwap:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := adaptV1Resources(tt.resources, tt.mounted, tt.swapAccounting)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("expected %d warnings, got %q", tt.wantWarnings, warnings)
+			}
+			hasSwap := tt.resources.Memory != nil && tt.resources.Memory.Swap != nil
+			if hasSwap != tt.wantSwap {
+				t.Errorf("expected swap limit %v, got %v", tt.wantSwap, hasSwap)
+			}
+		})
+	}
+}
@@ -14,8 +14,10 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -26,11 +28,78 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/pkg/sylog"
 	useragent "github.com/apptainer/apptainer/pkg/util/user-agent"
+	"github.com/opencontainers/go-digest"
 )
 
 // Timeout for an image pull in seconds - could be a large download...
 const pullTimeout = 1800
 
+// maxRedirects is the maximum number of redirects followed for a pull.
+const maxRedirects = 10
+
+// httpClient is the client used to retrieve images. It follows at most
+// maxRedirects redirects, and refuses redirects from https to http.
+var httpClient = &http.Client{
+	Timeout:       pullTimeout * time.Second,
+	CheckRedirect: checkRedirect,
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if via[0].URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect from %s to insecure %s", via[0].URL.Redacted(), req.URL.Redacted())
+	}
+	sylog.Debugf("Following redirect to %s", req.URL.Redacted())
+	return nil
+}
+
+// splitChecksum splits an optional #<algorithm>=<hex> fragment, such as
+// #sha256=<hex> or #sha512=<hex>, from netURL, returning the URL without it
+// and the expected digest of the image, if any.
+func splitChecksum(netURL string) (string, digest.Digest, error) {
+	u, err := url.Parse(netURL)
+	if err != nil {
+		return "", "", fmt.Errorf("while parsing %s: %v", netURL, err)
+	}
+	if u.Fragment == "" {
+		return netURL, "", nil
+	}
+	alg, encoded, ok := strings.Cut(u.Fragment, "=")
+	if !ok {
+		return "", "", fmt.Errorf("unsupported URL fragment %q, expected <algorithm>=<hex>", u.Fragment)
+	}
+	d, err := digest.Parse(alg + ":" + strings.ToLower(encoded))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid checksum %q: %v", u.Fragment, err)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), d, nil
+}
+
+var errChecksumMismatch = errors.New("image checksum mismatch")
+
+// verifyChecksum checks that the digest of the file at path, computed with
+// the algorithm of expected, matches expected.
+func verifyChecksum(path string, expected digest.Digest) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	got, err := expected.Algorithm().FromReader(f)
+	if err != nil {
+		return fmt.Errorf("while computing checksum of %s: %v", path, err)
+	}
+	if got != expected {
+		return fmt.Errorf("%w: expected %s, got %s", errChecksumMismatch, expected, got)
+	}
+	return nil
+}
+
 // IsNetPullRef returns true if the provided string is a valid url
 // reference for a pull operation.
 func IsNetPullRef(netRef string) bool {
@@ -39,25 +108,26 @@ func IsNetPullRef(netRef string) bool {
 }
 
 // DownloadImage will retrieve an image from an http(s) URI,
-// saving it into the specified file
+// saving it into the specified file. If the URI has a #<algorithm>=<hex>
+// fragment, such as #sha256=<hex>, the downloaded image must match this
+// checksum.
 func DownloadImage(ctx context.Context, filePath string, netURL string) error {
 	if !IsNetPullRef(netURL) {
 		return fmt.Errorf("not a valid url reference: %s", netURL)
 	}
+	imageURL, checksum, err := splitChecksum(netURL)
+	if err != nil {
+		return err
+	}
 	if filePath == "" {
-		refParts := strings.Split(netURL, "/")
+		refParts := strings.Split(imageURL, "/")
 		filePath = refParts[len(refParts)-1]
 		sylog.Infof("Download filename not provided. Downloading to: %s\n", filePath)
 	}
 
-	url := netURL
-	sylog.Debugf("Pulling from URL: %s\n", url)
-
-	httpClient := &http.Client{
-		Timeout: pullTimeout * time.Second,
-	}
+	sylog.Debugf("Pulling from URL: %s\n", imageURL)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
 	if err != nil {
 		return err
 	}
@@ -108,6 +178,17 @@ func DownloadImage(ctx context.Context, filePath string, netURL string) error {
 
 	sylog.Debugf("Download complete\n")
 
+	if checksum != "" {
+		if err := verifyChecksum(filePath, checksum); err != nil {
+			out.Close()
+			if err := os.Remove(filePath); err != nil {
+				sylog.Errorf("Error while removing downloaded image: %v", err)
+			}
+			return err
+		}
+		sylog.Verbosef("Image checksum verified: %s", checksum)
+	}
+
 	return nil
 }
 
@@ -119,11 +200,16 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 	// effectively result in no caching.
 	imageDate := time.Now().String()
 
-	req, err := http.NewRequest("HEAD", pullFrom, nil)
+	imageURL, checksum, err := splitChecksum(pullFrom)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, imageURL, nil)
 	if err != nil {
 		sylog.Fatalf("Error constructing http request: %v\n", err)
 	}
-	res, err := http.DefaultClient.Do(req)
+	res, err := httpClient.Do(req)
 	if err != nil {
 		sylog.Fatalf("Error making http request: %v\n", err)
 	}
@@ -136,7 +222,7 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 	}
 
 	h := sha256.New()
-	h.Write([]byte(pullFrom + imageDate))
+	h.Write([]byte(imageURL + imageDate))
 	hash := hex.EncodeToString(h.Sum(nil))
 	sylog.Debugf("Image hash for cache is: %s", hash)
 
@@ -168,6 +254,11 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 
 		} else {
 			sylog.Verbosef("Using image from cache")
+			if checksum != "" {
+				if err := verifyChecksum(cacheEntry.Path, checksum); err != nil {
+					return "", err
+				}
+			}
 		}
 
 		imagePath = cacheEntry.Path
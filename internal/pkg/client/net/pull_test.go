@@ -0,0 +1,222 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package net
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	useragent "github.com/apptainer/apptainer/pkg/util/user-agent"
+	"github.com/opencontainers/go-digest"
+)
+
+func TestMain(m *testing.M) {
+	useragent.InitValue("apptainer", "v0.1.0-30-g67692d50f-dirty")
+
+	os.Exit(m.Run())
+}
+
+func TestSplitChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", sha256.Size)
+	sum512 := strings.Repeat("cd", sha512.Size)
+
+	tests := []struct {
+		name         string
+		url          string
+		wantURL      string
+		wantChecksum digest.Digest
+		wantErr      bool
+	}{
+		{
+			name:    "NoChecksum",
+			url:     "https://example.com/image.sif",
+			wantURL: "https://example.com/image.sif",
+		},
+		{
+			name:         "Checksum",
+			url:          "https://example.com/image.sif?x=1#sha256=" + sum,
+			wantURL:      "https://example.com/image.sif?x=1",
+			wantChecksum: digest.Digest("sha256:" + sum),
+		},
+		{
+			name:         "UppercaseChecksum",
+			url:          "https://example.com/image.sif#sha256=" + strings.ToUpper(sum),
+			wantURL:      "https://example.com/image.sif",
+			wantChecksum: digest.Digest("sha256:" + sum),
+		},
+		{
+			name:         "SHA512Checksum",
+			url:          "https://example.com/image.sif#sha512=" + sum512,
+			wantURL:      "https://example.com/image.sif",
+			wantChecksum: digest.Digest("sha512:" + sum512),
+		},
+		{
+			name:    "ShortChecksum",
+			url:     "https://example.com/image.sif#sha256=abcd",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidHex",
+			url:     "https://example.com/image.sif#sha256=" + strings.Repeat("zz", sha256.Size),
+			wantErr: true,
+		},
+		{
+			name:    "UnsupportedAlgorithm",
+			url:     "https://example.com/image.sif#md5=" + strings.Repeat("ab", 16),
+			wantErr: true,
+		},
+		{
+			name:    "UnknownFragment",
+			url:     "https://example.com/image.sif#section",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, checksum, err := splitChecksum(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if url != tt.wantURL {
+				t.Errorf("expected url %q, got %q", tt.wantURL, url)
+			}
+			if checksum != tt.wantChecksum {
+				t.Errorf("expected checksum %q, got %q", tt.wantChecksum, checksum)
+			}
+		})
+	}
+}
+
+func TestDownloadImageChecksum(t *testing.T) {
+	content := []byte("not really a SIF image")
+	sum := sha256.Sum256(content)
+	checksum := "sha256=" + hex.EncodeToString(sum[:])
+	sum512 := sha512.Sum512(content)
+	checksum512 := "sha512=" + hex.EncodeToString(sum512[:])
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/image.sif", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(content)
+	})
+	mux.Handle("/redirect.sif", http.RedirectHandler("/image.sif", http.StatusFound))
+	mux.HandleFunc("/loop.sif", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop.sif", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		checksum string
+		wantErr  bool
+		mismatch bool
+	}{
+		{
+			name: "NoChecksum",
+			path: "/image.sif",
+		},
+		{
+			name:     "MatchingChecksum",
+			path:     "/image.sif",
+			checksum: checksum,
+		},
+		{
+			name:     "MatchingSHA512Checksum",
+			path:     "/image.sif",
+			checksum: checksum512,
+		},
+		{
+			name:     "MismatchingChecksum",
+			path:     "/image.sif",
+			checksum: "sha256=" + strings.Repeat("0", 2*sha256.Size),
+			wantErr:  true,
+			mismatch: true,
+		},
+		{
+			name:     "MismatchingSHA512Checksum",
+			path:     "/image.sif",
+			checksum: "sha512=" + strings.Repeat("0", 2*sha512.Size),
+			wantErr:  true,
+			mismatch: true,
+		},
+		{
+			name:     "Redirect",
+			path:     "/redirect.sif",
+			checksum: checksum,
+		},
+		{
+			name:    "RedirectLoop",
+			path:    "/loop.sif",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "image.sif")
+			u := srv.URL + tt.path
+			if tt.checksum != "" {
+				u += "#" + tt.checksum
+			}
+
+			err := DownloadImage(context.Background(), dest, u)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if errors.Is(err, errChecksumMismatch) != tt.mismatch {
+				t.Errorf("expected checksum mismatch %v, got %v", tt.mismatch, err)
+			}
+			if tt.mismatch {
+				if _, err := os.Stat(dest); !os.IsNotExist(err) {
+					t.Errorf("expected image with bad checksum to be removed")
+				}
+			}
+			if err != nil {
+				return
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatalf("while reading downloaded image: %v", err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloaded image does not match source")
+			}
+		})
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	newReq := func(u string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	via := []*http.Request{newReq("https://example.com/image.sif")}
+	if err := checkRedirect(newReq("https://mirror.example.com/image.sif"), via); err != nil {
+		t.Errorf("unexpected error for https redirect: %v", err)
+	}
+	if err := checkRedirect(newReq("http://mirror.example.com/image.sif"), via); err == nil {
+		t.Errorf("expected error for https to http redirect")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/apptainer/apptainer/internal/pkg/image/unpacker"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	"github.com/apptainer/apptainer/pkg/image"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// ArchiveImage writes the SIF image at path to a local oci-archive or
+// docker-archive at ref, as an OCI image which docker or podman can load.
+func ArchiveImage(path, transport, ref, tmpDir string) error {
+	img, cleanup, err := SIFImage(path, tmpDir)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return ociimage.WriteArchive(img, transport, ref, tmpDir)
+}
+
+// SIFImage returns the SIF image at path as an OCI image. The OCI image of an
+// OCI-SIF image is returned unchanged. The root filesystem of a native SIF
+// image is extracted below tmpDir and returned as a single layer, with the OCI
+// configuration recorded in the image, if any. The cleanup function removes
+// the layer file written below tmpDir, once the image is no longer used.
+func SIFImage(path, tmpDir string) (v1.Image, func(), error) {
+	if ociimage.IsOCISIF(path) {
+		img, err := ociimage.ImageFromSIF(path)
+		return img, func() {}, err
+	}
+
+	img, err := image.Init(path, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open image %s: %w", path, err)
+	}
+	defer img.File.Close()
+
+	if img.Type != image.SIF {
+		return nil, nil, fmt.Errorf("%s is not a SIF image", path)
+	}
+	part, err := img.GetRootFsPartition()
+	if err != nil {
+		return nil, nil, fmt.Errorf("while getting root filesystem in SIF %s: %w", path, err)
+	}
+	if part.Type != image.SQUASHFS {
+		return nil, nil, fmt.Errorf("unsupported image fs type: %v", part.Type)
+	}
+
+	config, err := sifImageConfig(img)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rootfs, err := os.MkdirTemp(tmpDir, "archive-rootfs-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create temporary directory: %w", err)
+	}
+	// the root filesystem is only needed to write the layer
+	defer func() {
+		if err := os.RemoveAll(rootfs); err != nil {
+			sylog.Errorf("while removing %q: %v", rootfs, err)
+		}
+	}()
+
+	reader, err := image.NewPartitionReader(img, "", 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read root filesystem: %w", err)
+	}
+	sylog.Debugf("Extracting root filesystem of %s to %s", path, rootfs)
+	if err := unpacker.NewSquashfs().ExtractAll(reader, rootfs); err != nil {
+		return nil, nil, fmt.Errorf("root filesystem extraction failed: %w", err)
+	}
+
+	layer, cleanup, err := ociimage.LayerFromDir(rootfs, tmpDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("while creating image layer: %w", err)
+	}
+	oi, err := rootfsImage(layer, img.Architecture, config)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return oi, cleanup, nil
+}
+
+// sifImageConfig returns the OCI image configuration recorded in the native
+// SIF image img, or an empty configuration if there is none.
+func sifImageConfig(img *image.Image) (v1.Config, error) {
+	var config v1.Config
+
+	reader, err := image.NewSectionReader(img, image.SIFDescOCIConfigJSON, -1)
+	if err == image.ErrNoSection {
+		return config, nil
+	} else if err != nil {
+		return config, fmt.Errorf("failed to read %s section: %w", image.SIFDescOCIConfigJSON, err)
+	}
+	if err := json.NewDecoder(reader).Decode(&config); err != nil {
+		return config, fmt.Errorf("failed to decode %s: %w", image.SIFDescOCIConfigJSON, err)
+	}
+	return config, nil
+}
+
+// rootfsImage returns a single layer OCI image for the linux/arch platform,
+// with the given configuration.
+func rootfsImage(layer v1.Layer, arch string, config v1.Config) (v1.Image, error) {
+	base := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	base = mutate.ConfigMediaType(base, types.OCIConfigJSON)
+	base, err := mutate.ConfigFile(base, &v1.ConfigFile{
+		OS:           "linux",
+		Architecture: arch,
+		Config:       config,
+		RootFS:       v1.RootFS{Type: "layers"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while setting image configuration: %w", err)
+	}
+	img, err := mutate.AppendLayers(base, layer)
+	if err != nil {
+		return nil, fmt.Errorf("while adding image layer: %w", err)
+	}
+	return img, nil
+}
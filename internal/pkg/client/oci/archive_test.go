@@ -0,0 +1,84 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/google/go-containerregistry/pkg/v1/validate"
+)
+
+func TestRootfsImage(t *testing.T) {
+	rootfs := t.TempDir()
+	if err := os.WriteFile(filepath.Join(rootfs, "hello"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layer, cleanup, err := ociimage.LayerFromDir(rootfs, t.TempDir())
+	if err != nil {
+		t.Fatalf("while creating layer: %v", err)
+	}
+	defer cleanup()
+
+	config := v1.Config{
+		Entrypoint: []string{"/bin/cat"},
+		Cmd:        []string{"/hello"},
+	}
+	img, err := rootfsImage(layer, "arm64", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mt, err := img.MediaType(); err != nil || mt != types.OCIManifestSchema1 {
+		t.Errorf("got media type %q (%v), want %q", mt, err, types.OCIManifestSchema1)
+	}
+
+	// the image must survive a round trip through a docker-archive
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	if err := ociimage.WriteArchive(img, "docker-archive", archive, t.TempDir()); err != nil {
+		t.Fatalf("while writing archive: %v", err)
+	}
+	loaded, err := tarball.ImageFromPath(archive, nil)
+	if err != nil {
+		t.Fatalf("while reading archive: %v", err)
+	}
+	if err := validate.Image(loaded); err != nil {
+		t.Errorf("archived image is not a valid image: %v", err)
+	}
+
+	cf, err := loaded.ConfigFile()
+	if err != nil {
+		t.Fatalf("while reading config: %v", err)
+	}
+	if cf.OS != "linux" || cf.Architecture != "arm64" {
+		t.Errorf("got platform %s/%s, want linux/arm64", cf.OS, cf.Architecture)
+	}
+	if !reflect.DeepEqual(cf.Config.Entrypoint, config.Entrypoint) {
+		t.Errorf("got entrypoint %v, want %v", cf.Config.Entrypoint, config.Entrypoint)
+	}
+	layers, err := loaded.Layers()
+	if err != nil {
+		t.Fatalf("while reading layers: %v", err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("got %d layers, want 1", len(layers))
+	}
+	want, err := layer.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := layers[0].Digest(); err != nil || got != want {
+		t.Errorf("got layer digest %s (%v), want %s", got, err, want)
+	}
+}
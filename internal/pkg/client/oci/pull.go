@@ -12,16 +12,22 @@ package oci
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/apptainer/apptainer/internal/pkg/build"
 	"github.com/apptainer/apptainer/internal/pkg/build/oci"
 	"github.com/apptainer/apptainer/internal/pkg/cache"
 	"github.com/apptainer/apptainer/internal/pkg/client"
 	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	"github.com/apptainer/apptainer/internal/pkg/ociplatform"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/internal/pkg/util/ociauth"
 	buildtypes "github.com/apptainer/apptainer/pkg/build/types"
@@ -39,11 +45,24 @@ type PullOptions struct {
 	NoCleanUp   bool
 	Pullarch    string
 	ReqAuthFile string
+	Concurrency int
+	// Timeout is the deadline for resolving and fetching an image from its
+	// registry, not including its conversion to SIF. No deadline is set if it
+	// is zero.
+	Timeout time.Duration
+	// ArchVariant selects the CPU variant (e.g. v6, v7) of the host
+	// architecture to pull, in place of the variant of the host CPU. It is
+	// ignored if Pullarch is set.
+	ArchVariant string
+	// Offline returns the SIF image cached for the digest the image URI last
+	// resolved to, or converts the image cached for it in the OCI blob cache,
+	// without network access. An error is returned if neither is cached.
+	Offline bool
 }
 
 // transportOptions maps PullOptions to OCI image transport options
-func transportOptions(opts PullOptions) *ociimage.TransportOptions {
-	return &ociimage.TransportOptions{
+func transportOptions(opts PullOptions) (*ociimage.TransportOptions, error) {
+	to := &ociimage.TransportOptions{
 		AuthConfig:       opts.OciAuth,
 		AuthFilePath:     ociauth.ChooseAuthFile(opts.ReqAuthFile),
 		Insecure:         opts.NoHTTPS,
@@ -51,7 +70,16 @@ func transportOptions(opts PullOptions) *ociimage.TransportOptions {
 		UserAgent:        useragent.Value(),
 		DockerDaemonHost: opts.DockerHost,
 		Platform:         v1.Platform{},
+		Concurrency:      opts.Concurrency,
 	}
+	if opts.ArchVariant != "" {
+		plat, err := ociplatform.PlatformFromVariant(opts.ArchVariant)
+		if err != nil {
+			return nil, err
+		}
+		to.Platform = *plat
+	}
+	return to, nil
 }
 
 // pull will build a SIF image into the cache if directTo="", or a specific file if directTo is set.
@@ -61,7 +89,10 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 	// can have three possible values true/false and undefined, so we left it as undefined instead
 	// of forcing it to false in order to delegate decision to /etc/containers/registries.conf:
 	// https://github.com/apptainer/singularity/issues/5172
-	to := transportOptions(opts)
+	to, err := transportOptions(opts)
+	if err != nil {
+		return "", err
+	}
 	if opts.Pullarch != "" {
 		if arch, ok := oci.ArchMap[opts.Pullarch]; ok {
 			to.Platform = v1.Platform{
@@ -73,9 +104,28 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 			return "", fmt.Errorf("failed to parse the arch value: %s, should be one of %v", opts.Pullarch, keys)
 		}
 	}
-	hash, err := oci.ImageDigest(ctx, pullFrom, to)
-	if err != nil {
-		return "", fmt.Errorf("failed to get checksum for %s: %s", pullFrom, err)
+	platform := to.Platform
+	var hash string
+	if opts.Offline {
+		hash, err = offlineDigest(ctx, imgCache, pullFrom, platform)
+		if err != nil {
+			return "", fmt.Errorf("%s is not in the cache and can't be pulled offline: %v", pullFrom, err)
+		}
+	} else {
+		rctx, cancel := ociimage.TimeoutContext(ctx, opts.Timeout)
+		hash, err = oci.ImageDigest(rctx, pullFrom, to)
+		cancel()
+		if err != nil && ociimage.IsUnreachable(err) {
+			if cachedHash, cerr := offlineDigest(ctx, imgCache, pullFrom, platform); cerr == nil {
+				sylog.Warningf("Unable to reach the registry for %s, using the cached image", pullFrom)
+				sylog.Debugf("While resolving %s: %v", pullFrom, err)
+				hash, err = cachedHash, nil
+				opts.Offline = true
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get checksum for %s: %s", pullFrom, err)
+		}
 	}
 
 	if directTo != "" {
@@ -107,11 +157,37 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 			sylog.Infof("Using cached SIF image")
 		}
 		imagePath = cacheEntry.Path
+
+		if err := recordDigest(imgCache, refDigestKey(pullFrom, platform), hash); err != nil {
+			sylog.Debugf("Unable to record digest %s for %s: %v", hash, pullFrom, err)
+		}
 	}
 
 	return imagePath, nil
 }
 
+// offlineDigest returns, without network access, the digest keying the SIF
+// image cached for pullFrom and platform. This is the digest pullFrom last
+// resolved to if it was recorded, or otherwise one derived from the image
+// recorded for pullFrom in the OCI blob cache, which is converted offline.
+func offlineDigest(ctx context.Context, imgCache *cache.Handle, pullFrom string, platform v1.Platform) (string, error) {
+	if imgCache == nil || imgCache.IsDisabled() {
+		return "", fmt.Errorf("the cache is disabled")
+	}
+	if hash, err := cachedDigest(imgCache, refDigestKey(pullFrom, platform)); err == nil {
+		return hash, nil
+	}
+	img, err := ociimage.CachedRefImage(ctx, imgCache, pullFrom, platform)
+	if err != nil {
+		return "", err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(digest.Hex+platform.Architecture+platform.Variant))), nil
+}
+
 // convertOciToSIF will convert an OCI source into a SIF using the build routines
 func convertOciToSIF(ctx context.Context, imgCache *cache.Handle, image, cachedImgPath string, opts PullOptions) error {
 	if imgCache == nil {
@@ -133,7 +209,11 @@ func convertOciToSIF(ctx context.Context, imgCache *cache.Handle, image, cachedI
 				DockerDaemonHost: opts.DockerHost,
 				ImgCache:         imgCache,
 				Arch:             opts.Pullarch,
+				ArchVariant:      opts.ArchVariant,
 				ReqAuthFile:      opts.ReqAuthFile,
+				Concurrency:      opts.Concurrency,
+				OCITimeout:       opts.Timeout,
+				OCIOffline:       opts.Offline,
 			},
 		},
 	)
@@ -153,11 +233,16 @@ func Pull(ctx context.Context, imgCache *cache.Handle, pullFrom string, opts Pul
 		if err != nil {
 			return "", fmt.Errorf("unable to create tmp file: %v", err)
 		}
+		file.Close()
 		directTo = file.Name()
 		sylog.Infof("Downloading library image to tmp cache: %s", directTo)
 	}
 
-	return pull(ctx, imgCache, directTo, pullFrom, opts)
+	imagePath, err = pull(ctx, imgCache, directTo, pullFrom, opts)
+	if err != nil && directTo != "" {
+		os.Remove(directTo)
+	}
+	return imagePath, err
 }
 
 // PullToFile will build a SIF image from the specified oci URI and place it at the specified dest
@@ -192,3 +277,139 @@ func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom st
 
 	return pullTo, nil
 }
+
+// InspectConfig returns the config of the OCI image at imageURI, without
+// pulling or extracting its layers. The config of an image in a registry is
+// cached in imgCache, keyed by manifest digest, so that only the manifest
+// digest is requested when the same image is inspected again. The last
+// digest imageURI resolved to for the platform is also recorded, so that its
+// cached config is returned when the registry can't be reached.
+func InspectConfig(ctx context.Context, imgCache *cache.Handle, imageURI string, opts PullOptions) (*v1.ConfigFile, error) {
+	ctx, cancel := ociimage.TimeoutContext(ctx, opts.Timeout)
+	defer cancel()
+
+	to, err := transportOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, _, _ := strings.Cut(imageURI, ":")
+	if imgCache == nil || imgCache.IsDisabled() || transport != "docker" {
+		return ociimage.InspectImage(ctx, to, imageURI)
+	}
+
+	key := refDigestKey(imageURI, to.Platform)
+	hash, resolveErr := oci.ImageDigest(ctx, imageURI, to)
+	if resolveErr != nil {
+		unreachable := ociimage.IsUnreachable(resolveErr)
+		resolveErr = fmt.Errorf("failed to get checksum for %s: %s", imageURI, resolveErr)
+		if !unreachable {
+			return nil, resolveErr
+		}
+		cachedHash, err := cachedDigest(imgCache, key)
+		if err != nil {
+			sylog.Debugf("No cached digest for %s: %v", imageURI, err)
+			return nil, resolveErr
+		}
+		sylog.Warningf("Unable to reach the registry for %s, using the config cached for digest %s", imageURI, cachedHash)
+		sylog.Debugf("While resolving %s: %v", imageURI, resolveErr)
+		hash = cachedHash
+	} else if err := recordDigest(imgCache, key, hash); err != nil {
+		sylog.Debugf("Unable to record digest %s for %s: %v", hash, imageURI, err)
+	}
+
+	cacheEntry, err := imgCache.GetEntry(cache.OciConfigCacheType, hash)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check if %v exists in cache: %v", hash, err)
+	}
+	defer cacheEntry.CleanTmp()
+
+	if cacheEntry.Exists {
+		cf, err := readConfigFile(cacheEntry.Path)
+		if err == nil {
+			sylog.Debugf("Using cached image config %s", cacheEntry.Path)
+			return cf, nil
+		}
+		sylog.Debugf("Ignoring invalid cached image config %s: %v", cacheEntry.Path, err)
+	}
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	cf, err := ociimage.InspectImage(ctx, to, imageURI)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(cf)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cacheEntry.TmpPath, b, 0o600); err != nil {
+		return nil, err
+	}
+	if err := cacheEntry.Finalize(); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// refDigestKey returns the key of the oci-config cache entry holding the
+// last digest imageURI resolved to for platform.
+func refDigestKey(imageURI string, platform v1.Platform) string {
+	sum := sha256.Sum256([]byte(imageURI + "@" + platform.Architecture + "/" + platform.Variant))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordDigest records in the oci-config cache of imgCache the digest hash
+// under key, replacing any previously recorded digest.
+func recordDigest(imgCache *cache.Handle, key, hash string) error {
+	cacheDir, err := imgCache.GetFileCacheDir(cache.OciConfigCacheType)
+	if err != nil {
+		return err
+	}
+	f, err := fs.MakeTmpFile(cacheDir, "tmp_", 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(hash); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(cacheDir, key))
+}
+
+// cachedDigest returns the digest recorded under key in the oci-config cache
+// of imgCache.
+func cachedDigest(imgCache *cache.Handle, key string) (string, error) {
+	entry, err := imgCache.GetEntry(cache.OciConfigCacheType, key)
+	if err != nil {
+		return "", err
+	}
+	defer entry.CleanTmp()
+	if !entry.Exists {
+		return "", fmt.Errorf("no digest recorded")
+	}
+	b, err := os.ReadFile(entry.Path)
+	if err != nil {
+		return "", err
+	}
+	hash := strings.TrimSpace(string(b))
+	if hash == "" {
+		return "", fmt.Errorf("empty digest recorded")
+	}
+	return hash, nil
+}
+
+// readConfigFile reads an OCI image config from path.
+func readConfigFile(path string) (*v1.ConfigFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return v1.ParseConfigFile(f)
+}
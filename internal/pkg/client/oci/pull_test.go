@@ -0,0 +1,258 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	useragent "github.com/apptainer/apptainer/pkg/util/user-agent"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestMain(m *testing.M) {
+	useragent.InitValue("apptainer", "v0.1.0-30-g67692d50f-dirty")
+
+	os.Exit(m.Run())
+}
+
+func TestTransportOptions(t *testing.T) {
+	opts := PullOptions{
+		TmpDir:      "/tmp/pull",
+		DockerHost:  "unix:///var/run/docker.sock",
+		NoHTTPS:     true,
+		Concurrency: 7,
+	}
+
+	to, err := transportOptions(opts)
+	if err != nil {
+		t.Fatalf("while getting transport options: %v", err)
+	}
+	if to.Concurrency != opts.Concurrency {
+		t.Errorf("expected concurrency %d, got %d", opts.Concurrency, to.Concurrency)
+	}
+	if to.TmpDir != opts.TmpDir {
+		t.Errorf("expected tmpdir %q, got %q", opts.TmpDir, to.TmpDir)
+	}
+	if to.DockerDaemonHost != opts.DockerHost {
+		t.Errorf("expected docker host %q, got %q", opts.DockerHost, to.DockerDaemonHost)
+	}
+	if !to.Insecure {
+		t.Errorf("expected insecure transport with NoHTTPS")
+	}
+}
+
+func TestTransportOptionsArchVariant(t *testing.T) {
+	opts := PullOptions{
+		ArchVariant: "v6",
+	}
+
+	to, err := transportOptions(opts)
+	if err != nil {
+		t.Fatalf("while getting transport options: %v", err)
+	}
+	sc := to.SystemContext()
+	if sc.ArchitectureChoice != runtime.GOARCH {
+		t.Errorf("expected architecture %q, got %q", runtime.GOARCH, sc.ArchitectureChoice)
+	}
+	if sc.VariantChoice != opts.ArchVariant {
+		t.Errorf("expected variant %q, got %q", opts.ArchVariant, sc.VariantChoice)
+	}
+}
+
+func TestInspectConfigTimeout(t *testing.T) {
+	// A registry which never answers, until the client gives up.
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	opts := PullOptions{
+		TmpDir:  t.TempDir(),
+		NoHTTPS: true,
+		Timeout: 200 * time.Millisecond,
+	}
+	imageURI := "docker://" + strings.TrimPrefix(srv.URL, "http://") + "/test/image:latest"
+
+	errCh := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		_, err := InspectConfig(context.Background(), nil, imageURI, opts)
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatalf("expected error from stalled registry")
+		}
+		if elapsed := time.Since(start); elapsed < opts.Timeout {
+			t.Errorf("operation aborted after %v, before the %v deadline", elapsed, opts.Timeout)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatalf("operation was not aborted at the %v deadline", opts.Timeout)
+	}
+}
+
+func TestInspectConfigCache(t *testing.T) {
+	// A registry which counts the blob requests, i.e. config fetches as
+	// inspect never retrieves layers. It can also deny all requests.
+	var blobRequests atomic.Int32
+	var deny atomic.Bool
+	reg := registry.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if deny.Load() {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			blobRequests.Add(1)
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/test/cached:latest"
+	r, err := name.ParseReference(ref, name.Insecure)
+	if err != nil {
+		t.Fatalf("while parsing reference: %v", err)
+	}
+	push := func(entrypoint string) v1.Config {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("while creating random image: %v", err)
+		}
+		config := v1.Config{Entrypoint: []string{entrypoint}}
+		if img, err = mutate.Config(img, config); err != nil {
+			t.Fatalf("while setting image config: %v", err)
+		}
+		if err := remote.Write(r, img); err != nil {
+			t.Fatalf("while pushing image: %v", err)
+		}
+		return config
+	}
+
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	opts := PullOptions{TmpDir: t.TempDir(), NoHTTPS: true}
+	imageURI := "docker://" + ref
+
+	inspect := func(want v1.Config, wantBlobRequests int32) {
+		t.Helper()
+		blobRequests.Store(0)
+		cf, err := InspectConfig(context.Background(), imgCache, imageURI, opts)
+		if err != nil {
+			t.Fatalf("while inspecting image: %v", err)
+		}
+		if !reflect.DeepEqual(cf.Config, want) {
+			t.Errorf("expected config %+v, got %+v", want, cf.Config)
+		}
+		if got := blobRequests.Load(); got != wantBlobRequests {
+			t.Errorf("expected %d config fetches, got %d", wantBlobRequests, got)
+		}
+	}
+
+	first := push("/bin/first")
+	inspect(first, 1)
+	// The second inspect must be served from the cache.
+	inspect(first, 0)
+
+	// The tag now resolves to a new digest, so the config is fetched again.
+	second := push("/bin/second")
+	inspect(second, 1)
+
+	// The digest is recorded for the platform it was resolved for.
+	if _, err := cachedDigest(imgCache, refDigestKey(imageURI, v1.Platform{})); err != nil {
+		t.Errorf("no digest recorded for %s: %v", imageURI, err)
+	}
+	if _, err := cachedDigest(imgCache, refDigestKey(imageURI, v1.Platform{Architecture: "s390x"})); err == nil {
+		t.Errorf("digest of %s recorded for another platform", imageURI)
+	}
+
+	// An error returned by the registry is not hidden by the cache.
+	deny.Store(true)
+	if _, err := InspectConfig(context.Background(), imgCache, imageURI, opts); err == nil {
+		t.Errorf("expected error inspecting an image the registry denies access to")
+	}
+	deny.Store(false)
+
+	// Once the registry can't be reached, the config cached for the last
+	// digest the tag resolved to is returned.
+	srv.Close()
+	inspect(second, 0)
+
+	// An image never inspected can't be inspected offline.
+	otherURI := "docker://" + strings.TrimPrefix(srv.URL, "http://") + "/test/other:latest"
+	if _, err := InspectConfig(context.Background(), imgCache, otherURI, opts); err == nil {
+		t.Errorf("expected error inspecting uncached image offline")
+	}
+}
+
+func TestPullOffline(t *testing.T) {
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	// No registry listens on this address, so any network access fails.
+	imageURI := "docker://127.0.0.1:1/test/image:latest"
+	opts := PullOptions{TmpDir: t.TempDir(), NoHTTPS: true, Offline: true}
+
+	if _, err := Pull(context.Background(), imgCache, imageURI, opts); err == nil {
+		t.Fatalf("expected error pulling an uncached image offline")
+	}
+
+	// Cache a SIF for the digest the image last resolved to.
+	hash := "0123456789abcdef"
+	entry, err := imgCache.GetEntry(cache.OciTempCacheType, hash)
+	if err != nil {
+		t.Fatalf("while creating cache entry: %v", err)
+	}
+	if err := os.WriteFile(entry.TmpPath, []byte("sif"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := entry.Finalize(); err != nil {
+		t.Fatalf("while finalizing cache entry: %v", err)
+	}
+	if err := recordDigest(imgCache, refDigestKey(imageURI, v1.Platform{}), hash); err != nil {
+		t.Fatalf("while recording digest: %v", err)
+	}
+
+	path, err := Pull(context.Background(), imgCache, imageURI, opts)
+	if err != nil {
+		t.Fatalf("unexpected error pulling a cached image offline: %v", err)
+	}
+	if path != entry.Path {
+		t.Errorf("expected cached image %s, got %s", entry.Path, path)
+	}
+
+	// The digest is recorded per platform.
+	opts.Pullarch = "arm64"
+	if runtime.GOARCH == "arm64" {
+		opts.Pullarch = "amd64"
+	}
+	if _, err := Pull(context.Background(), imgCache, imageURI, opts); err == nil {
+		t.Errorf("expected error pulling an image cached for another platform offline")
+	}
+}
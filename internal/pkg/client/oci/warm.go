@@ -0,0 +1,57 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// WarmCache fetches the manifest, config and layer blobs of the OCI images
+// refs into the blob cache of imgCache, without extracting or converting
+// them, so that later pulls and runs of the images don't download their
+// layers again. The digest each ref resolved to is recorded in the cache, so
+// that ociimage.CachedRefImage finds the image without network access. It
+// returns the manifest digest of each image, in the order of refs.
+func WarmCache(ctx context.Context, imgCache *cache.Handle, refs []string, opts PullOptions) ([]v1.Hash, error) {
+	if imgCache == nil || imgCache.IsDisabled() {
+		return nil, fmt.Errorf("the cache is disabled, there is nothing to warm")
+	}
+
+	to, err := transportOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make([]v1.Hash, 0, len(refs))
+	for _, ref := range refs {
+		sylog.Infof("Fetching %s into the cache", ref)
+		fctx, cancel := ociimage.TimeoutContext(ctx, opts.Timeout)
+		img, err := ociimage.FetchToLayout(fctx, to, imgCache, ref, opts.TmpDir)
+		cancel()
+		if err != nil {
+			return digests, fmt.Errorf("while fetching %s: %w", ref, err)
+		}
+		digest, err := img.Digest()
+		if err != nil {
+			return digests, fmt.Errorf("while getting digest of %s: %w", ref, err)
+		}
+		if err := ociimage.RecordCachedRef(imgCache, ref, img); err != nil {
+			return digests, fmt.Errorf("while recording digest of %s: %w", ref, err)
+		}
+		sylog.Infof("Cached %s as %s", ref, digest)
+		digests = append(digests, digest)
+	}
+	return digests, nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/internal/pkg/ociimage"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestWarmCache(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+
+	var refs []string
+	var want []v1.Hash
+	for _, tag := range []string{"first", "second"} {
+		ref := strings.TrimPrefix(srv.URL, "http://") + "/test/warm:" + tag
+		r, err := name.ParseReference(ref, name.Insecure)
+		if err != nil {
+			t.Fatalf("while parsing reference: %v", err)
+		}
+		img, err := random.Image(256, 2)
+		if err != nil {
+			t.Fatalf("while creating random image: %v", err)
+		}
+		if err := remote.Write(r, img); err != nil {
+			t.Fatalf("while pushing image: %v", err)
+		}
+		digest, err := img.Digest()
+		if err != nil {
+			t.Fatalf("while getting image digest: %v", err)
+		}
+		refs = append(refs, "docker://"+ref)
+		want = append(want, digest)
+	}
+
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	opts := PullOptions{TmpDir: t.TempDir(), NoHTTPS: true}
+
+	got, err := WarmCache(context.Background(), imgCache, refs, opts)
+	if err != nil {
+		t.Fatalf("while warming cache: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d digests, got %d", len(want), len(got))
+	}
+
+	// With the registry gone, the images must be complete in the cache.
+	srv.Close()
+
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		t.Fatalf("while getting cache dir: %v", err)
+	}
+	lp, err := layout.FromPath(layoutDir)
+	if err != nil {
+		t.Fatalf("while opening cache layout: %v", err)
+	}
+	for i, digest := range want {
+		if got[i] != digest {
+			t.Errorf("expected digest %s for %s, got %s", digest, refs[i], got[i])
+		}
+		img, err := lp.Image(digest)
+		if err != nil {
+			t.Fatalf("image %s not in cache: %v", refs[i], err)
+		}
+		layers, err := img.Layers()
+		if err != nil {
+			t.Fatalf("while getting layers of %s: %v", refs[i], err)
+		}
+		for _, l := range layers {
+			rc, err := l.Compressed()
+			if err != nil {
+				t.Fatalf("layer of %s not in cache: %v", refs[i], err)
+			}
+			_, err = io.Copy(io.Discard, rc)
+			rc.Close()
+			if err != nil {
+				t.Errorf("while reading cached layer of %s: %v", refs[i], err)
+			}
+		}
+	}
+
+	// The tags must resolve to the warmed digests from the cache alone.
+	for i, ref := range refs {
+		img, err := ociimage.CachedRefImage(context.Background(), imgCache, ref, v1.Platform{})
+		if err != nil {
+			t.Fatalf("while resolving %s offline: %v", ref, err)
+		}
+		digest, err := img.Digest()
+		if err != nil {
+			t.Fatalf("while getting digest of %s: %v", ref, err)
+		}
+		if digest != want[i] {
+			t.Errorf("expected %s to resolve to %s, got %s", ref, want[i], digest)
+		}
+	}
+	if _, err := ociimage.CachedRefImage(context.Background(), imgCache, "docker://"+strings.TrimPrefix(srv.URL, "http://")+"/test/warm:missing", v1.Platform{}); err == nil {
+		t.Errorf("expected error resolving a tag that was not warmed")
+	}
+
+	if _, err := WarmCache(context.Background(), nil, refs, opts); err == nil {
+		t.Errorf("expected error without cache")
+	}
+}
@@ -33,8 +33,14 @@ import (
 	"golang.org/x/term"
 )
 
-// DownloadImage downloads a SIF image specified by an oci reference to a file using the included credentials
-func DownloadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) error {
+// TmpDirPrefix is the name prefix of the temporary OCI layout directories
+// used to stage ORAS image downloads.
+const TmpDirPrefix = "oras-tmp-"
+
+// DownloadImage downloads a SIF image specified by an oci reference to a file using the included credentials.
+// The image is staged in a temporary OCI layout created under tmpDir, or the system default location
+// if tmpDir is empty.
+func DownloadImage(ctx context.Context, path, ref, tmpDir string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) error {
 	rt := client.NewRoundTripper(ctx, nil)
 	im, err := remoteImage(ctx, ref, ociAuth, noHTTPS, rt, reqAuthFile)
 	if err != nil {
@@ -54,6 +60,7 @@ func DownloadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthCon
 		return err
 	}
 	if len(manifest.Layers) != 1 {
+		rt.ProgressShutdown()
 		return fmt.Errorf("ORAS SIF image should have a single layer, found %d", len(manifest.Layers))
 	}
 	layer := manifest.Layers[0]
@@ -64,17 +71,17 @@ func DownloadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthCon
 	}
 
 	// Retrieve image to a temporary OCI layout
-	tmpDir, err := os.MkdirTemp("", "oras-tmp-")
+	layoutDir, err := os.MkdirTemp(tmpDir, TmpDirPrefix)
 	if err != nil {
 		rt.ProgressShutdown()
 		return err
 	}
 	defer func() {
-		if err := os.RemoveAll(tmpDir); err != nil {
-			sylog.Errorf("while removing %q: %v", tmpDir, err)
+		if err := os.RemoveAll(layoutDir); err != nil {
+			sylog.Errorf("while removing %q: %v", layoutDir, err)
 		}
 	}()
-	tmpLayout, err := layout.Write(tmpDir, empty.Index)
+	tmpLayout, err := layout.Write(layoutDir, empty.Index)
 	if err != nil {
 		rt.ProgressShutdown()
 		return err
@@ -114,11 +121,14 @@ func DownloadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthCon
 }
 
 // UploadImage uploads the image specified by path and pushes it to the provided oci reference,
-// it will use credentials if supplied
-func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string) error {
+// it will use credentials if supplied. At most concurrency blobs are uploaded in parallel,
+// unless it is less than 1, in which case the go-containerregistry default applies.
+// Any annotations supplied are set on the pushed manifest. On success, the pushed manifest
+// is returned as a digest reference in the destination repository.
+func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfig, noHTTPS bool, reqAuthFile string, concurrency int, annotations map[string]string) (name.Digest, error) {
 	// ensure that are uploading a SIF
 	if err := ensureSIF(path); err != nil {
-		return err
+		return name.Digest{}, err
 	}
 
 	ref = strings.TrimPrefix(ref, "oras://")
@@ -131,12 +141,15 @@ func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfi
 	}
 	ir, err := name.ParseReference(ref, opts...)
 	if err != nil {
-		return err
+		return name.Digest{}, err
 	}
 
 	im, err := NewImageFromSIF(path, SifLayerMediaTypeV1)
 	if err != nil {
-		return err
+		return name.Digest{}, err
+	}
+	if len(annotations) > 0 {
+		im.manifest.Annotations = annotations
 	}
 
 	remoteOpts := []remote.Option{
@@ -144,6 +157,9 @@ func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfi
 		remote.WithUserAgent(useragent.Value()),
 		remote.WithContext(ctx),
 	}
+	if concurrency > 0 {
+		remoteOpts = append(remoteOpts, remote.WithJobs(concurrency))
+	}
 	if term.IsTerminal(2) {
 		pb := &client.DownloadProgressBar{}
 		progChan := make(chan v1.Update, 1)
@@ -172,7 +188,44 @@ func UploadImage(ctx context.Context, path, ref string, ociAuth *authn.AuthConfi
 		}()
 		remoteOpts = append(remoteOpts, remote.WithProgress(progChan))
 	}
-	return remote.Write(ir, im, remoteOpts...)
+	if err := remote.Write(ir, im, remoteOpts...); err != nil {
+		return name.Digest{}, err
+	}
+	return manifestDigest(ir, im)
+}
+
+// ParseAnnotations parses a list of key=value strings into a map of manifest
+// annotations. Keys must be non-empty and may not contain whitespace. Values
+// may be empty.
+func ParseAnnotations(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	annotations := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("annotation %q is not in key=value format", spec)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("annotation %q has an empty key", spec)
+		}
+		if strings.ContainsAny(key, " \t\n") {
+			return nil, fmt.Errorf("annotation key %q contains whitespace", key)
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
+// manifestDigest returns a reference to the manifest of im, by digest, in the
+// repository of ref.
+func manifestDigest(ref name.Reference, im v1.Image) (name.Digest, error) {
+	hash, err := im.Digest()
+	if err != nil {
+		return name.Digest{}, err
+	}
+	return ref.Context().Digest(hash.String()), nil
 }
 
 // ensureSIF checks for a SIF image at filepath and returns an error if it is not, or an error is encountered
@@ -0,0 +1,192 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oras
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apptainer/sif/v2/pkg/sif"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// createSIF creates a minimal SIF file, holding a single generic data object,
+// in a temporary directory.
+func createSIF(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.sif")
+	di, err := sif.NewDescriptorInput(sif.DataGeneric, bytes.NewReader([]byte("apptainer")))
+	if err != nil {
+		t.Fatalf("failed to get DescriptorInput: %v", err)
+	}
+	fp, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(di))
+	if err != nil {
+		t.Fatalf("failed to create SIF: %v", err)
+	}
+	if err := fp.UnloadContainer(); err != nil {
+		t.Fatalf("failed to unload SIF: %v", err)
+	}
+	return path
+}
+
+// testRegistry starts an in-memory OCI registry, returning its host:port.
+func testRegistry(t *testing.T) string {
+	t.Helper()
+
+	s := httptest.NewServer(registry.New())
+	t.Cleanup(s.Close)
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func TestUploadImageDigest(t *testing.T) {
+	sifPath := createSIF(t)
+	ref := testRegistry(t) + "/test/image:latest"
+
+	digest, err := UploadImage(context.Background(), sifPath, "oras://"+ref, nil, true, "", 0, nil)
+	if err != nil {
+		t.Fatalf("while uploading image: %v", err)
+	}
+
+	ir, err := name.ParseReference(ref, name.Insecure)
+	if err != nil {
+		t.Fatalf("while parsing reference: %v", err)
+	}
+	desc, err := remote.Head(ir)
+	if err != nil {
+		t.Fatalf("while fetching pushed manifest: %v", err)
+	}
+
+	if digest.DigestStr() != desc.Digest.String() {
+		t.Errorf("expected digest %s, got %s", desc.Digest, digest.DigestStr())
+	}
+	if digest.Context().String() != ir.Context().String() {
+		t.Errorf("expected repository %s, got %s", ir.Context(), digest.Context())
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "none",
+			specs: nil,
+			want:  nil,
+		},
+		{
+			name:  "valid",
+			specs: []string{"org.opencontainers.image.source=https://example.com/repo", "empty="},
+			want: map[string]string{
+				"org.opencontainers.image.source": "https://example.com/repo",
+				"empty":                           "",
+			},
+		},
+		{
+			name:  "value with equals",
+			specs: []string{"key=a=b"},
+			want:  map[string]string{"key": "a=b"},
+		},
+		{
+			name:    "no separator",
+			specs:   []string{"key"},
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			specs:   []string{"=value"},
+			wantErr: true,
+		},
+		{
+			name:    "whitespace in key",
+			specs:   []string{"my key=value"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAnnotations(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUploadImageAnnotations(t *testing.T) {
+	sifPath := createSIF(t)
+	ref := testRegistry(t) + "/test/annotated:latest"
+	annotations := map[string]string{
+		"org.opencontainers.image.source": "https://example.com/repo",
+	}
+
+	if _, err := UploadImage(context.Background(), sifPath, "oras://"+ref, nil, true, "", 0, annotations); err != nil {
+		t.Fatalf("while uploading image: %v", err)
+	}
+
+	ir, err := name.ParseReference(ref, name.Insecure)
+	if err != nil {
+		t.Fatalf("while parsing reference: %v", err)
+	}
+	im, err := remote.Image(ir)
+	if err != nil {
+		t.Fatalf("while fetching pushed image: %v", err)
+	}
+	manifest, err := im.Manifest()
+	if err != nil {
+		t.Fatalf("while reading pushed manifest: %v", err)
+	}
+	if !reflect.DeepEqual(manifest.Annotations, annotations) {
+		t.Errorf("expected annotations %v, got %v", annotations, manifest.Annotations)
+	}
+}
+
+func TestDownloadImageTmpDir(t *testing.T) {
+	sifPath := createSIF(t)
+	ref := "oras://" + testRegistry(t) + "/test/tmpdir:latest"
+
+	if _, err := UploadImage(context.Background(), sifPath, ref, nil, true, "", 0, nil); err != nil {
+		t.Fatalf("while uploading image: %v", err)
+	}
+
+	// The temporary layout must be created under the requested tmpDir, so a
+	// missing tmpDir is an error.
+	missingDir := filepath.Join(t.TempDir(), "missing")
+	dest := filepath.Join(t.TempDir(), "image.sif")
+	if err := DownloadImage(context.Background(), dest, ref, missingDir, nil, true, ""); err == nil {
+		t.Errorf("expected error downloading with non-existent tmpdir %s", missingDir)
+	}
+
+	tmpDir := t.TempDir()
+	if err := DownloadImage(context.Background(), dest, ref, tmpDir, nil, true, ""); err != nil {
+		t.Fatalf("while downloading image: %v", err)
+	}
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("while reading tmpdir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected temporary layout to be removed from %s, found %d entries", tmpDir, len(entries))
+	}
+}
@@ -57,7 +57,7 @@ type ProgressCallback func(int64, io.Reader, io.Writer) error
 
 // ProgressBarCallback returns a progress bar callback unless e.g. --quiet or lower loglevel is set
 func ProgressBarCallback(ctx context.Context) ProgressCallback {
-	if sylog.GetLevel() <= -1 {
+	if sylog.GetMessageLevel() <= sylog.LogLevel {
 		// If we don't need a bar visible, we just copy data through the callback func
 		return func(_ int64, r io.Reader, w io.Writer) error {
 			_, err := CopyWithContext(ctx, w, r)
@@ -67,6 +67,8 @@ func ProgressBarCallback(ctx context.Context) ProgressCallback {
 
 	return func(totalSize int64, r io.Reader, w io.Writer) error {
 		p, bar := initProgressBar(totalSize) //nolint:contextcheck
+		stopLog := sylog.StartProgress()
+		defer stopLog()
 
 		// create proxy reader
 		bodyProgress := bar.ProxyReader(r)
@@ -110,16 +112,18 @@ func CopyWithContext(ctx context.Context, dst io.Writer, src io.Reader) (written
 
 // DownloadProgressBar is a progress bar that implements the container-library-client ProgressBar interface.
 type DownloadProgressBar struct {
-	bar *mpb.Bar
-	p   *mpb.Progress
+	bar     *mpb.Bar
+	p       *mpb.Progress
+	stopLog func()
 }
 
 func (dpb *DownloadProgressBar) Init(contentLength int64) {
-	if sylog.GetLevel() <= -1 {
+	if sylog.GetMessageLevel() <= sylog.LogLevel {
 		// we don't need a bar visible
 		return
 	}
 	dpb.p, dpb.bar = initProgressBar(contentLength)
+	dpb.stopLog = sylog.StartProgress()
 }
 
 func (dpb *DownloadProgressBar) ProxyReader(r io.Reader) io.ReadCloser {
@@ -138,6 +142,7 @@ func (dpb *DownloadProgressBar) Abort(drop bool) {
 		return
 	}
 	dpb.bar.Abort(drop)
+	dpb.stopLog()
 }
 
 func (dpb *DownloadProgressBar) Wait() {
@@ -145,6 +150,7 @@ func (dpb *DownloadProgressBar) Wait() {
 		return
 	}
 	dpb.p.Wait()
+	dpb.stopLog()
 }
 
 // UploadProgressBar is a progress bar that implements the scs-library-client UploadCallback interface.
@@ -152,15 +158,17 @@ type UploadProgressBar struct {
 	progress *mpb.Progress
 	bar      *mpb.Bar
 	r        io.Reader
+	stopLog  func()
 }
 
 func (upb *UploadProgressBar) InitUpload(totalSize int64, r io.Reader) {
-	if sylog.GetLevel() <= -1 {
+	if sylog.GetMessageLevel() <= sylog.LogLevel {
 		// we don't need a bar visible
 		upb.r = r
 		return
 	}
 	upb.progress, upb.bar = initProgressBar(totalSize)
+	upb.stopLog = sylog.StartProgress()
 	upb.r = upb.bar.ProxyReader(r)
 }
 
@@ -173,6 +181,7 @@ func (upb *UploadProgressBar) Terminate() {
 		return
 	}
 	upb.bar.Abort(true)
+	upb.stopLog()
 }
 
 func (upb *UploadProgressBar) Finish() {
@@ -181,4 +190,5 @@ func (upb *UploadProgressBar) Finish() {
 	}
 	// wait for our bar to complete and flush
 	upb.progress.Wait()
+	upb.stopLog()
 }
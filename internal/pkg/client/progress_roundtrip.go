@@ -26,6 +26,8 @@ type RoundTripper struct {
 	p     *mpb.Progress
 	bars  []*mpb.Bar
 	sizes []int64
+	// stopLog ends the sylog progress region started with the bars.
+	stopLog func()
 }
 
 // NewRoundTripper wraps inner (or http.DefaultTransport if inner is nil) with
@@ -46,8 +48,9 @@ func NewRoundTripper(ctx context.Context, inner http.RoundTripper) *RoundTripper
 		inner: inner,
 	}
 
-	if term.IsTerminal(2) && sylog.GetLevel() >= 0 {
+	if term.IsTerminal(2) && sylog.GetMessageLevel() > sylog.LogLevel {
 		rt.p = mpb.NewWithContext(ctx)
+		rt.stopLog = sylog.StartProgress()
 	}
 
 	return &rt
@@ -82,6 +85,7 @@ func (t *RoundTripper) ProgressComplete() {
 func (t *RoundTripper) ProgressWait() {
 	if t.p != nil {
 		t.p.Wait()
+		t.stopLog()
 	}
 }
 
@@ -89,5 +93,6 @@ func (t *RoundTripper) ProgressWait() {
 func (t *RoundTripper) ProgressShutdown() {
 	if t.p != nil {
 		t.p.Shutdown()
+		t.stopLog()
 	}
 }
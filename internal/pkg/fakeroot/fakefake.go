@@ -25,8 +25,10 @@ import (
 	"github.com/apptainer/apptainer/pkg/sylog"
 )
 
-// exec the command effectively under unshare -r or unshare -rm
-func UnshareRootMapped(args []string, includeMountNamespace bool) error {
+// rootMappedCmd returns the command args set to run in a root-mapped user
+// namespace, mapping only the user uid/gid to root, without any subordinate
+// id range.
+func rootMappedCmd(args []string, includeMountNamespace bool) *osExec.Cmd {
 	cmd := osExec.Command(args[0], args[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -42,6 +44,12 @@ func UnshareRootMapped(args []string, includeMountNamespace bool) error {
 	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{
 		{ContainerID: 0, HostID: syscall.Getgid(), Size: 1},
 	}
+	return cmd
+}
+
+// exec the command effectively under unshare -r or unshare -rm
+func UnshareRootMapped(args []string, includeMountNamespace bool) error {
+	cmd := rootMappedCmd(args, includeMountNamespace)
 	sylog.Debugf("Executing %s in root-mapped unprivileged user namespace", args[0])
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("error re-executing in root-mapped unprivileged user namespace: %v", err)
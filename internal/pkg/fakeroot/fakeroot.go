@@ -20,6 +20,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/apptainer/apptainer/internal/pkg/util/bin"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
 	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/fs/lock"
@@ -358,11 +359,102 @@ func (c *Config) GetUserEntry(username string) (*Entry, error) {
 var (
 	getPwUID = user.GetPwUID
 	getPwNam = user.GetPwNam
+	findBin  = bin.FindBin
 )
 
+// RangeSelector selects one of the subordinate id ranges of a user, rather
+// than the range picked by GetUserEntry, for users with several ranges.
+type RangeSelector struct {
+	// Index is the position of the range among the ranges of the user,
+	// starting at 0. Ranges are counted in the order of the configuration
+	// file, skipping disabled and invalid lines. It is only used if Start
+	// is 0.
+	Index int
+	// Start selects the range starting at this host id, if not 0.
+	Start uint32
+}
+
+// ParseRangeSelector parses a range selection, either the index of the range
+// among the ranges of the user, such as "1", or its host start id, such as
+// "start=200000".
+func ParseRangeSelector(s string) (RangeSelector, error) {
+	if v, ok := strings.CutPrefix(s, "start="); ok {
+		start, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || start == 0 {
+			return RangeSelector{}, fmt.Errorf("invalid range start %q", v)
+		}
+		return RangeSelector{Start: uint32(start)}, nil
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return RangeSelector{}, fmt.Errorf("invalid range selection %q: must be an index or start=<id>", s)
+	}
+	return RangeSelector{Index: index}, nil
+}
+
+// String returns the range selection in the format of ParseRangeSelector.
+func (r RangeSelector) String() string {
+	if r.Start != 0 {
+		return fmt.Sprintf("start=%d", r.Start)
+	}
+	return strconv.Itoa(r.Index)
+}
+
+// SelectUserEntry returns the entry of a user selected by sel, and returns
+// an error if there is no such entry, or if its range is too small.
+func (c *Config) SelectUserEntry(username string, sel RangeSelector) (*Entry, error) {
+	u, err := c.getUserFn(username)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve user information for %s: %s", username, err)
+	}
+
+	entries, err := c.getMappingEntries(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up mapping entries for user %s: %w", username, err)
+	}
+
+	index := 0
+	for _, entry := range entries {
+		if entry.invalid {
+			continue
+		}
+		// a disabled range is only matched by its start, for the caller to
+		// report it as disabled
+		if entry.disabled && sel.Start == 0 {
+			continue
+		}
+		if (sel.Start != 0 && entry.Start == sel.Start) || (sel.Start == 0 && index == sel.Index) {
+			if entry.Count < validRangeCount {
+				return nil, fmt.Errorf(
+					"mapping entry %s for user %s in %s has a %w %d",
+					sel, username, c.file.Name(), errRangeTooLow, validRangeCount,
+				)
+			}
+			return entry, nil
+		}
+		index++
+	}
+
+	return nil, fmt.Errorf("%w %s in %s for %s", errNoMappingEntry, sel, c.file.Name(), username)
+}
+
 // GetIDRange determines UID/GID mappings based on configuration
 // file provided in path.
 func GetIDRange(path string, uid uint32) (*specs.LinuxIDMapping, error) {
+	return getIDRange(path, uid, func(c *Config, username string) (*Entry, error) {
+		return c.GetUserEntry(username)
+	})
+}
+
+// GetSelectedIDRange determines UID/GID mappings based on configuration
+// file provided in path, with the range of the user selected by sel.
+func GetSelectedIDRange(path string, uid uint32, sel RangeSelector) (*specs.LinuxIDMapping, error) {
+	return getIDRange(path, uid, func(c *Config, username string) (*Entry, error) {
+		return c.SelectUserEntry(username, sel)
+	})
+}
+
+func getIDRange(path string, uid uint32, entryFn func(*Config, string) (*Entry, error)) (*specs.LinuxIDMapping, error) {
 	config, err := GetConfig(path, false, getPwNam)
 	if err != nil {
 		return nil, err
@@ -373,7 +465,7 @@ func GetIDRange(path string, uid uint32) (*specs.LinuxIDMapping, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve user with UID %d: %s", uid, err)
 	}
-	e, err := config.GetUserEntry(userinfo.Name)
+	e, err := entryFn(config, userinfo.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -387,6 +479,56 @@ func GetIDRange(path string, uid uint32) (*specs.LinuxIDMapping, error) {
 	}, nil
 }
 
+// KeepIDMappings returns the user namespace mappings preserving the host
+// id inside the container, as the --userns=keep-id option of podman, with
+// the subordinate range subRange, as returned by GetIDRange, mapped to the
+// container ids around it: container ids below id are mapped to the start of
+// the range, and container ids above id to the rest of it. With fakeroot,
+// the host id is mapped to the container root instead.
+func KeepIDMappings(id uint32, subRange *specs.LinuxIDMapping) []specs.LinuxIDMapping {
+	var mappings []specs.LinuxIDMapping
+
+	below := subRange.Size
+	if id < below {
+		below = id
+	}
+	if below > 0 {
+		mappings = append(mappings, specs.LinuxIDMapping{
+			ContainerID: 0,
+			HostID:      subRange.HostID,
+			Size:        below,
+		})
+	}
+	mappings = append(mappings, specs.LinuxIDMapping{
+		ContainerID: id,
+		HostID:      id,
+		Size:        1,
+	})
+	if above := subRange.Size - below; above > 0 {
+		mappings = append(mappings, specs.LinuxIDMapping{
+			ContainerID: id + 1,
+			HostID:      subRange.HostID + below,
+			Size:        above,
+		})
+	}
+	return mappings
+}
+
+// CheckIDMapBinaries checks that the newuidmap and newgidmap binaries, which
+// map the subordinate ids of SubUIDFile and SubGIDFile in an unprivileged
+// installation, are available. The error names the missing binary and the
+// package providing it.
+func CheckIDMapBinaries() error {
+	for _, name := range []string{"newuidmap", "newgidmap"} {
+		if _, err := findBin(name); err != nil {
+			return fmt.Errorf("%s was not found, it is required to map the subordinate ids of your user in %s and %s: "+
+				"install the uidmap package (Debian, Ubuntu) or shadow-utils (RHEL, Fedora, SUSE)",
+				name, SubUIDFile, SubGIDFile)
+		}
+	}
+	return nil
+}
+
 // IsUIDMapped returns true if the given uid is mapped in SubUIDFile
 // and otherwise it returns false
 func IsUIDMapped(uid uint32) bool {
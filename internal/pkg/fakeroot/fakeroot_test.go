@@ -13,11 +13,13 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
+	"github.com/apptainer/apptainer/internal/pkg/util/bin"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -215,6 +217,171 @@ root:100000:65536
 	}
 }
 
+func TestParseRangeSelector(t *testing.T) {
+	tests := []struct {
+		sel     string
+		want    RangeSelector
+		wantErr bool
+	}{
+		{sel: "0", want: RangeSelector{Index: 0}},
+		{sel: "2", want: RangeSelector{Index: 2}},
+		{sel: "start=200000", want: RangeSelector{Start: 200000}},
+		{sel: "-1", wantErr: true},
+		{sel: "first", wantErr: true},
+		{sel: "start=0", wantErr: true},
+		{sel: "start=", wantErr: true},
+		{sel: "start=4294967296", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRangeSelector(tt.sel)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error for %q: %v", tt.sel, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("got %+v for %q, want %+v", got, tt.sel, tt.want)
+		}
+		if got.String() != tt.sel {
+			t.Errorf("got string %q, want %q", got.String(), tt.sel)
+		}
+	}
+}
+
+func TestGetSelectedIDRange(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	getPwUID = getPwUIDMock
+	getPwNam = getPwNamMock
+	defer func() {
+		getPwUID = user.GetPwUID
+		getPwNam = user.GetPwNam
+	}()
+
+	f, err := fs.MakeTmpFile("", "subid-", 0o700)
+	if err != nil {
+		t.Fatalf("failed to create temporary file")
+	}
+	defer os.Remove(f.Name())
+
+	subIDContent := `
+root:100000:65536
+1:200000:65536
+!1:250000:65536
+1:-1:65536
+1:300000:131072
+1:400000:1
+1:500000:65536
+!2:600000:65536
+`
+	f.WriteString(subIDContent)
+	f.Close()
+
+	tests := []struct {
+		name     string
+		uid      uint32
+		sel      RangeSelector
+		wantHost uint32
+		wantSize uint32
+		wantErr  bool
+	}{
+		{
+			name:     "RootFirst",
+			uid:      0,
+			sel:      RangeSelector{Index: 0},
+			wantHost: 100000,
+			wantSize: 65536,
+		},
+		{
+			name:     "First",
+			uid:      1,
+			sel:      RangeSelector{Index: 0},
+			wantHost: 200000,
+			wantSize: 65536,
+		},
+		{
+			name:     "SecondSkipsDisabledAndInvalid",
+			uid:      1,
+			sel:      RangeSelector{Index: 1},
+			wantHost: 300000,
+			wantSize: 131072,
+		},
+		{
+			name:    "TooSmall",
+			uid:     1,
+			sel:     RangeSelector{Index: 2},
+			wantErr: true,
+		},
+		{
+			name:     "Last",
+			uid:      1,
+			sel:      RangeSelector{Index: 3},
+			wantHost: 500000,
+			wantSize: 65536,
+		},
+		{
+			name:    "IndexOutOfRange",
+			uid:     1,
+			sel:     RangeSelector{Index: 4},
+			wantErr: true,
+		},
+		{
+			name:     "Start",
+			uid:      1,
+			sel:      RangeSelector{Start: 500000},
+			wantHost: 500000,
+			wantSize: 65536,
+		},
+		{
+			name:    "StartTooSmall",
+			uid:     1,
+			sel:     RangeSelector{Start: 400000},
+			wantErr: true,
+		},
+		{
+			name:    "StartDisabled",
+			uid:     1,
+			sel:     RangeSelector{Start: 250000},
+			wantErr: true,
+		},
+		{
+			name:    "StartNotFound",
+			uid:     1,
+			sel:     RangeSelector{Start: 100000},
+			wantErr: true,
+		},
+		{
+			name:    "Disabled",
+			uid:     2,
+			sel:     RangeSelector{Index: 0},
+			wantErr: true,
+		},
+		{
+			name:    "NoUser",
+			uid:     8,
+			sel:     RangeSelector{Index: 0},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idRange, err := GetSelectedIDRange(f.Name(), tt.uid, tt.sel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if idRange.ContainerID != 1 || idRange.HostID != tt.wantHost || idRange.Size != tt.wantSize {
+				t.Errorf("got mapping %+v, want host ID %d and size %d", idRange, tt.wantHost, tt.wantSize)
+			}
+		})
+	}
+}
+
 func getUserFn(username string) (*user.User, error) {
 	var prefix string
 
@@ -497,6 +664,65 @@ func testEditEntry(t *testing.T, config *Config) {
 	}
 }
 
+func TestKeepIDMappings(t *testing.T) {
+	subRange := &specs.LinuxIDMapping{ContainerID: 1, HostID: 100000, Size: 65536}
+
+	tests := []struct {
+		name     string
+		id       uint32
+		subRange *specs.LinuxIDMapping
+		expected []specs.LinuxIDMapping
+	}{
+		{
+			name:     "UID1000",
+			id:       1000,
+			subRange: subRange,
+			expected: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 100000, Size: 1000},
+				{ContainerID: 1000, HostID: 1000, Size: 1},
+				{ContainerID: 1001, HostID: 101000, Size: 64536},
+			},
+		},
+		{
+			name:     "UID1",
+			id:       1,
+			subRange: subRange,
+			expected: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 100000, Size: 1},
+				{ContainerID: 1, HostID: 1, Size: 1},
+				{ContainerID: 2, HostID: 100001, Size: 65535},
+			},
+		},
+		{
+			name:     "UIDAboveRange",
+			id:       70000,
+			subRange: subRange,
+			expected: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 100000, Size: 65536},
+				{ContainerID: 70000, HostID: 70000, Size: 1},
+			},
+		},
+		{
+			name:     "UIDEqualRangeSize",
+			id:       65536,
+			subRange: subRange,
+			expected: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 100000, Size: 65536},
+				{ContainerID: 65536, HostID: 65536, Size: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings := KeepIDMappings(tt.id, tt.subRange)
+			if !reflect.DeepEqual(mappings, tt.expected) {
+				t.Errorf("expected mappings %+v, got %+v", tt.expected, mappings)
+			}
+		})
+	}
+}
+
 func TestConfig(t *testing.T) {
 	test.DropPrivilege(t)
 	defer test.ResetPrivilege(t)
@@ -518,3 +744,65 @@ func TestConfig(t *testing.T) {
 	testGetUserEntry(t, config)
 	testEditEntry(t, config)
 }
+
+func TestRootMappedCmd(t *testing.T) {
+	var out bytes.Buffer
+	cmd := rootMappedCmd([]string{"/bin/cat", "/proc/self/uid_map", "/proc/self/gid_map"}, false)
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		t.Skipf("user namespace not available: %v", err)
+	}
+
+	// The kernel reports exactly one entry per map, the user id mapped to
+	// root, without any subordinate id range.
+	expected := [][]string{
+		{"0", strconv.Itoa(os.Getuid()), "1"},
+		{"0", strconv.Itoa(os.Getgid()), "1"},
+	}
+	var maps [][]string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		maps = append(maps, strings.Fields(line))
+	}
+	if !reflect.DeepEqual(maps, expected) {
+		t.Errorf("expected uid and gid maps %v, got %v", expected, maps)
+	}
+}
+
+func TestCheckIDMapBinaries(t *testing.T) {
+	defer func() {
+		findBin = bin.FindBin
+	}()
+
+	tests := []struct {
+		name    string
+		missing string
+	}{
+		{name: "Available"},
+		{name: "NoNewuidmap", missing: "newuidmap"},
+		{name: "NoNewgidmap", missing: "newgidmap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findBin = func(name string) (string, error) {
+				if name == tt.missing {
+					return "", fmt.Errorf("%s not found", name)
+				}
+				return "/usr/bin/" + name, nil
+			}
+			err := CheckIDMapBinaries()
+			if tt.missing == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("unexpected success with %s missing", tt.missing)
+			}
+			if !strings.HasPrefix(err.Error(), tt.missing+" was not found") || !strings.Contains(err.Error(), "uidmap package") {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// HealthStarting is the health status of an instance whose healthcheck
+	// has not yet succeeded.
+	HealthStarting = "starting"
+	// HealthHealthy is the health status of an instance whose last
+	// healthcheck succeeded.
+	HealthHealthy = "healthy"
+	// HealthUnhealthy is the health status of an instance whose healthcheck
+	// has failed Retries consecutive times.
+	HealthUnhealthy = "unhealthy"
+)
+
+// Default healthcheck settings, matching those used by Docker.
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 30 * time.Second
+	defaultHealthRetries  = 3
+)
+
+// Healthcheck describes a command run periodically inside an instance to
+// check that it is healthy.
+type Healthcheck struct {
+	// Args is the command run in the instance. A shell-form healthcheck is
+	// run with /bin/sh -c.
+	Args []string
+	// Interval is the time between two checks.
+	Interval time.Duration
+	// Timeout is the time after which a check is considered to have failed.
+	Timeout time.Duration
+	// StartPeriod is the time after the instance starts during which failed
+	// checks are not counted towards Retries.
+	StartPeriod time.Duration
+	// Retries is the number of consecutive failures after which the instance
+	// is reported as unhealthy.
+	Retries int
+}
+
+// imageHealthConfig holds the Docker specific HEALTHCHECK of an image config.
+type imageHealthConfig struct {
+	Healthcheck *struct {
+		Test        []string      `json:",omitempty"`
+		Interval    time.Duration `json:",omitempty"`
+		Timeout     time.Duration `json:",omitempty"`
+		StartPeriod time.Duration `json:",omitempty"`
+		Retries     int           `json:",omitempty"`
+	} `json:",omitempty"`
+}
+
+// ParseHealthcheck reads the HEALTHCHECK from an OCI image config, as stored
+// in a SIF image. A nil Healthcheck is returned if the image does not define
+// one, or explicitly disables it. Shell-form (CMD-SHELL) healthchecks are run
+// with /bin/sh -c, as Docker does.
+func ParseHealthcheck(r io.Reader) (*Healthcheck, error) {
+	var config imageHealthConfig
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return nil, fmt.Errorf("while decoding image config: %w", err)
+	}
+
+	hc := config.Healthcheck
+	if hc == nil || len(hc.Test) == 0 {
+		return nil, nil
+	}
+
+	var args []string
+	switch hc.Test[0] {
+	case "NONE":
+		return nil, nil
+	case "CMD":
+		if len(hc.Test) < 2 {
+			return nil, errors.New("healthcheck CMD has no command")
+		}
+		args = hc.Test[1:]
+	case "CMD-SHELL":
+		if len(hc.Test) != 2 || hc.Test[1] == "" {
+			return nil, errors.New("healthcheck CMD-SHELL must have a single command")
+		}
+		args = []string{"/bin/sh", "-c", hc.Test[1]}
+	default:
+		return nil, fmt.Errorf("unknown healthcheck test type %q", hc.Test[0])
+	}
+
+	h := &Healthcheck{
+		Args:        args,
+		Interval:    hc.Interval,
+		Timeout:     hc.Timeout,
+		StartPeriod: hc.StartPeriod,
+		Retries:     hc.Retries,
+	}
+	if h.Interval <= 0 {
+		h.Interval = defaultHealthInterval
+	}
+	if h.Timeout <= 0 {
+		h.Timeout = defaultHealthTimeout
+	}
+	if h.Retries <= 0 {
+		h.Retries = defaultHealthRetries
+	}
+	return h, nil
+}
+
+// RunHealthcheck runs probe with the healthcheck command every Interval,
+// until ctx is cancelled. Each probe is given a context that expires after
+// Timeout. The update function is called with the initial HealthStarting
+// status, and then each time the status changes.
+func RunHealthcheck(ctx context.Context, h *Healthcheck, probe func(ctx context.Context, args []string) error, update func(status string)) {
+	status := HealthStarting
+	update(status)
+
+	start := time.Now()
+	failures := 0
+
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, h.Timeout)
+		err := probe(probeCtx, h.Args)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		newStatus := status
+		if err == nil {
+			failures = 0
+			newStatus = HealthHealthy
+		} else if time.Since(start) >= h.StartPeriod {
+			failures++
+			if failures >= h.Retries {
+				newStatus = HealthUnhealthy
+			}
+		}
+
+		if newStatus != status {
+			status = newStatus
+			update(status)
+		}
+	}
+}
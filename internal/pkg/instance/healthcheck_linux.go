@@ -0,0 +1,33 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package instance
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// ProbeCommand returns the command running a healthcheck probe with args in
+// the instance name, through `apptainer exec` with the apptainer binary and
+// the exec options opts. The command runs in its own process group, which is
+// killed as a whole when ctx is done, as killing the apptainer process alone
+// would leave the probe running in the instance.
+func ProbeCommand(ctx context.Context, apptainer, name string, opts, args []string) *exec.Cmd {
+	cmdArgs := append([]string{"exec"}, opts...)
+	cmdArgs = append(cmdArgs, "instance://"+name)
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.CommandContext(ctx, apptainer, cmdArgs...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return cmd
+}
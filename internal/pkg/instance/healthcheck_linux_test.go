@@ -0,0 +1,86 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package instance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProbeCommand(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	pidFile := filepath.Join(dir, "pid")
+
+	// A fake apptainer, recording its arguments, whose probe outlives it
+	// unless its whole process group is killed.
+	apptainer := filepath.Join(dir, "apptainer")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" > " + argsFile + "\n" +
+		"sleep 60 &\n" +
+		"echo $! > " + pidFile + "\n" +
+		"wait\n"
+	if err := os.WriteFile(apptainer, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	opts := []string{"--security", "uid:1000"}
+	cmd := ProbeCommand(ctx, apptainer, "test", opts, []string{"/bin/check", "--quick"})
+	if err := cmd.Run(); err == nil {
+		t.Fatalf("expected error from probe killed on timeout")
+	}
+
+	b, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("while reading arguments: %v", err)
+	}
+	want := []string{"exec", "--security", "uid:1000", "instance://test", "/bin/check", "--quick"}
+	if got := strings.Fields(string(b)); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected arguments %v, got %v", want, got)
+	}
+
+	b, err = os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("while reading probe pid: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		t.Fatalf("while parsing probe pid: %v", err)
+	}
+	// the killed probe may not be reaped yet
+	for i := 0; i < 100; i++ {
+		if err := syscall.Kill(pid, 0); err == syscall.ESRCH || isZombie(pid) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	syscall.Kill(pid, syscall.SIGKILL)
+	t.Errorf("probe process %d still running after timeout", pid)
+}
+
+// isZombie returns whether the process pid has exited, without having been
+// reaped yet.
+func isZombie(pid int) bool {
+	b, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// the state follows the command name, which is in parentheses
+	fields := strings.Fields(string(b[strings.LastIndexByte(string(b), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
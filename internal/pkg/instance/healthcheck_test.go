@@ -0,0 +1,204 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package instance
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseHealthcheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    *Healthcheck
+		wantErr bool
+	}{
+		{
+			name:   "NoHealthcheck",
+			config: `{"Cmd":["/bin/sh"]}`,
+			want:   nil,
+		},
+		{
+			name:   "None",
+			config: `{"Healthcheck":{"Test":["NONE"]}}`,
+			want:   nil,
+		},
+		{
+			name:   "ExecFormDefaults",
+			config: `{"Healthcheck":{"Test":["CMD","/bin/check","--quick"]}}`,
+			want: &Healthcheck{
+				Args:     []string{"/bin/check", "--quick"},
+				Interval: defaultHealthInterval,
+				Timeout:  defaultHealthTimeout,
+				Retries:  defaultHealthRetries,
+			},
+		},
+		{
+			name:   "ExecFormSettings",
+			config: `{"Healthcheck":{"Test":["CMD","/bin/check"],"Interval":5000000000,"Timeout":1000000000,"StartPeriod":10000000000,"Retries":5}}`,
+			want: &Healthcheck{
+				Args:        []string{"/bin/check"},
+				Interval:    5 * time.Second,
+				Timeout:     time.Second,
+				StartPeriod: 10 * time.Second,
+				Retries:     5,
+			},
+		},
+		{
+			name:   "ShellForm",
+			config: `{"Healthcheck":{"Test":["CMD-SHELL","curl -f http://localhost/ || exit 1"]}}`,
+			want: &Healthcheck{
+				Args:     []string{"/bin/sh", "-c", "curl -f http://localhost/ || exit 1"},
+				Interval: defaultHealthInterval,
+				Timeout:  defaultHealthTimeout,
+				Retries:  defaultHealthRetries,
+			},
+		},
+		{
+			name:    "ShellFormNoCommand",
+			config:  `{"Healthcheck":{"Test":["CMD-SHELL"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "NoCommand",
+			config:  `{"Healthcheck":{"Test":["CMD"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "UnknownType",
+			config:  `{"Healthcheck":{"Test":["BOGUS","/bin/check"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidJSON",
+			config:  `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHealthcheck(strings.NewReader(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// statusRecorder records the health status updates from RunHealthcheck.
+type statusRecorder struct {
+	mu       sync.Mutex
+	statuses []string
+}
+
+func (r *statusRecorder) update(status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, status)
+}
+
+func (r *statusRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.statuses...)
+}
+
+func TestRunHealthcheck(t *testing.T) {
+	errProbe := errors.New("probe failed")
+
+	tests := []struct {
+		name        string
+		results     []error
+		startPeriod time.Duration
+		want        []string
+	}{
+		{
+			name:    "Healthy",
+			results: []error{nil},
+			want:    []string{HealthStarting, HealthHealthy},
+		},
+		{
+			name:    "Unhealthy",
+			results: []error{errProbe, errProbe},
+			want:    []string{HealthStarting, HealthUnhealthy},
+		},
+		{
+			name:    "FailureBelowRetries",
+			results: []error{nil, errProbe, nil},
+			want:    []string{HealthStarting, HealthHealthy},
+		},
+		{
+			name:    "Recovers",
+			results: []error{errProbe, errProbe, nil},
+			want:    []string{HealthStarting, HealthUnhealthy, HealthHealthy},
+		},
+		{
+			name:        "StartPeriod",
+			results:     []error{errProbe, errProbe, errProbe},
+			startPeriod: time.Hour,
+			want:        []string{HealthStarting},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Healthcheck{
+				Args:        []string{"/bin/check"},
+				Interval:    time.Millisecond,
+				Timeout:     time.Second,
+				StartPeriod: tt.startPeriod,
+				Retries:     2,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls := 0
+			probe := func(_ context.Context, args []string) error {
+				if !reflect.DeepEqual(args, h.Args) {
+					t.Errorf("expected probe args %v, got %v", h.Args, args)
+				}
+				if calls == len(tt.results) {
+					cancel()
+					return nil
+				}
+				err := tt.results[calls]
+				calls++
+				return err
+			}
+
+			rec := &statusRecorder{}
+			done := make(chan struct{})
+			go func() {
+				RunHealthcheck(ctx, h, probe, rec.update)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("timed out waiting for healthcheck to finish")
+			}
+
+			if got := rec.get(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected statuses %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
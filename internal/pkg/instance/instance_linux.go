@@ -56,6 +56,7 @@ type File struct {
 	LogOutPath  string `json:"logOutPath"`
 	Checkpoint  string `json:"checkpoint"`
 	ShareNSMode bool   `json:"sharensMode"`
+	Health      string `json:"health,omitempty"`
 }
 
 // ProcName returns process name based on instance name
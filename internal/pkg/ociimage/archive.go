@@ -0,0 +1,153 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// ociRefNameAnnotation is the index annotation holding the tag of an image in
+// an OCI layout.
+const ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// WriteArchive writes img to a local archive file, for the oci-archive or
+// docker-archive transport. ref takes the same form as the transport
+// reference on the command line:
+//
+//	oci-archive:    <path>[:tag]
+//	docker-archive: <path>[:name[:tag]]
+//
+// An oci-archive is created by writing an OCI layout into a temporary
+// directory below tmpDir, which is then packed into a tar file.
+func WriteArchive(img v1.Image, transport, ref, tmpDir string) error {
+	switch transport {
+	case "oci-archive":
+		refParts := strings.SplitN(ref, ":", 2)
+		tag := ""
+		if len(refParts) == 2 {
+			tag = refParts[1]
+		}
+		return writeOCIArchive(img, refParts[0], tag, tmpDir)
+	case "docker-archive":
+		refParts := strings.SplitN(ref, ":", 2)
+		// Only supports writing a single image per tarball.
+		dstRef := name.MustParseReference("image")
+		if len(refParts) == 2 {
+			var err error
+			dstRef, err = name.ParseReference(refParts[1])
+			if err != nil {
+				return fmt.Errorf("invalid docker-archive reference %q: %w", refParts[1], err)
+			}
+		}
+		return tarball.WriteToFile(refParts[0], dstRef, img)
+	default:
+		return errUnsupportedTransport
+	}
+}
+
+// writeOCIArchive writes img, optionally tagged with tag, as the single image
+// in an OCI layout tar archive at dst.
+func writeOCIArchive(img v1.Image, dst, tag, tmpDir string) error {
+	layoutDir, err := os.MkdirTemp(tmpDir, TmpDirPrefix)
+	if err != nil {
+		return fmt.Errorf("could not create temporary oci directory: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(layoutDir); err != nil {
+			sylog.Errorf("while removing %q: %v", layoutDir, err)
+		}
+	}()
+
+	lp, err := layout.Write(layoutDir, empty.Index)
+	if err != nil {
+		return err
+	}
+	var opts []layout.Option
+	if tag != "" {
+		opts = append(opts, layout.WithAnnotations(map[string]string{ociRefNameAnnotation: tag}))
+	}
+	if err := lp.AppendImage(img, opts...); err != nil {
+		return err
+	}
+
+	sylog.Debugf("Packing OCI layout %q into archive %q", layoutDir, dst)
+	return packArchive(layoutDir, dst)
+}
+
+// packArchive creates an uncompressed tar file at dst from the content of the
+// src directory. Entries are relative to src, with no ownership information.
+func packArchive(src, dst string) (err error) {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	tw := tar.NewWriter(f)
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if !info.Mode().IsDir() && !info.Mode().IsRegular() {
+			return fmt.Errorf("%s: unexpected file type in OCI layout", path)
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		r, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(tw, r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/validate"
+)
+
+func TestWriteArchive(t *testing.T) {
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatalf("while creating random image: %v", err)
+	}
+	wantDigest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("while getting image digest: %v", err)
+	}
+
+	t.Run("oci-archive", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		archive := filepath.Join(tmpDir, "image.tar")
+		if err := WriteArchive(img, "oci-archive", archive+":mytag", tmpDir); err != nil {
+			t.Fatalf("while writing oci-archive: %v", err)
+		}
+
+		extractDir := t.TempDir()
+		if err := extractArchive(archive, extractDir, nil); err != nil {
+			t.Fatalf("while extracting oci-archive: %v", err)
+		}
+		lp, err := layout.FromPath(extractDir)
+		if err != nil {
+			t.Fatalf("while reading extracted layout: %v", err)
+		}
+		ii, err := lp.ImageIndex()
+		if err != nil {
+			t.Fatalf("while reading layout index: %v", err)
+		}
+		im, err := ii.IndexManifest()
+		if err != nil {
+			t.Fatalf("while reading layout index manifest: %v", err)
+		}
+		if len(im.Manifests) != 1 {
+			t.Fatalf("expected 1 image in layout, found %d", len(im.Manifests))
+		}
+		if im.Manifests[0].Digest != wantDigest {
+			t.Errorf("expected digest %s, got %s", wantDigest, im.Manifests[0].Digest)
+		}
+		if tag := im.Manifests[0].Annotations[ociRefNameAnnotation]; tag != "mytag" {
+			t.Errorf("expected tag %q, got %q", "mytag", tag)
+		}
+
+		archived, err := lp.Image(wantDigest)
+		if err != nil {
+			t.Fatalf("while reading image from layout: %v", err)
+		}
+		if err := validate.Image(archived); err != nil {
+			t.Errorf("archived image is not a valid OCI image: %v", err)
+		}
+	})
+
+	t.Run("docker-archive", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "image.tar")
+		if err := WriteArchive(img, "docker-archive", archive+":myimage:mytag", ""); err != nil {
+			t.Fatalf("while writing docker-archive: %v", err)
+		}
+
+		tarImg, err := tarball.ImageFromPath(archive, nil)
+		if err != nil {
+			t.Fatalf("while reading docker-archive: %v", err)
+		}
+		if err := validate.Image(tarImg); err != nil {
+			t.Errorf("archived image is not a valid image: %v", err)
+		}
+		gotLayers, err := tarImg.Layers()
+		if err != nil {
+			t.Fatalf("while reading docker-archive layers: %v", err)
+		}
+		if len(gotLayers) != 2 {
+			t.Errorf("expected 2 layers, found %d", len(gotLayers))
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		if err := WriteArchive(img, "docker", "example.com/image", ""); err == nil {
+			t.Errorf("expected error for unsupported transport")
+		}
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// cacheRefName returns the fully qualified name of the registry image URI
+// imageURI, as recorded in the OCI blob cache index.
+func cacheRefName(imageURI string) (string, error) {
+	srcType, srcRef, err := URItoSourceSinkRef(imageURI)
+	if err != nil {
+		return "", err
+	}
+	if srcType != RegistrySourceSink {
+		return "", fmt.Errorf("%s is not a registry image reference", imageURI)
+	}
+	ref, ok := srcType.Reference(srcRef, nil)
+	if !ok {
+		return "", fmt.Errorf("invalid registry reference: %s", srcRef)
+	}
+	return ref.Name(), nil
+}
+
+// RecordCachedRef records, in the index of the OCI blob cache, that the
+// registry image URI imageURI resolved to img, which must already be in the
+// cache. Any image previously recorded for imageURI is replaced.
+func RecordCachedRef(imgCache *cache.Handle, imageURI string, img v1.Image) error {
+	if imgCache == nil || imgCache.IsDisabled() {
+		return fmt.Errorf("undefined image cache")
+	}
+	refName, err := cacheRefName(imageURI)
+	if err != nil {
+		return err
+	}
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return err
+	}
+	unlock, err := imgCache.LockOciBlobCache()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	lp, err := layout.FromPath(layoutDir)
+	if err != nil {
+		return err
+	}
+	annotations := map[string]string{imgspecv1.AnnotationRefName: refName}
+	return lp.ReplaceImage(img, match.Name(refName), layout.WithAnnotations(annotations))
+}
+
+// CachedRefImage returns the image recorded for the registry image URI
+// imageURI by RecordCachedRef, from the writable or the read-only OCI blob
+// cache, without network access. An error is returned if no complete image
+// satisfying platform is recorded for imageURI. Empty platform fields match
+// any value.
+func CachedRefImage(ctx context.Context, imgCache *cache.Handle, imageURI string, platform v1.Platform) (v1.Image, error) {
+	if imgCache == nil || imgCache.IsDisabled() {
+		return nil, fmt.Errorf("undefined image cache")
+	}
+	refName, err := cacheRefName(imageURI)
+	if err != nil {
+		return nil, err
+	}
+
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return nil, err
+	}
+	roLayoutDir, err := imgCache.GetReadOnlyOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{layoutDir, roLayoutDir} {
+		if dir == "" {
+			continue
+		}
+		digest, err := refDigest(dir, refName)
+		if err != nil {
+			sylog.Debugf("No image recorded for %s in %s: %v", refName, dir, err)
+			continue
+		}
+		img, err := layoutImage(ctx, dir, digest)
+		if err != nil {
+			sylog.Debugf("Image recorded for %s in %s is incomplete: %v", refName, dir, err)
+			continue
+		}
+		cf, err := img.ConfigFile()
+		if err != nil {
+			sylog.Debugf("Image recorded for %s in %s has no readable config: %v", refName, dir, err)
+			continue
+		}
+		p := cf.Platform()
+		if p == nil {
+			p = &v1.Platform{}
+		}
+		if !p.Satisfies(platform) {
+			sylog.Debugf("Image recorded for %s in %s is not for platform %s", refName, dir, platform)
+			continue
+		}
+		sylog.Debugf("Using image %s recorded for %s in %s", digest, refName, dir)
+		return img, nil
+	}
+	return nil, fmt.Errorf("no image cached for %s", refName)
+}
+
+// refDigest returns the digest of the image recorded for refName in the
+// index of the OCI layout at layoutDir.
+func refDigest(layoutDir, refName string) (v1.Hash, error) {
+	lp, err := layout.FromPath(layoutDir)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	ii, err := lp.ImageIndex()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	im, err := ii.IndexManifest()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	matches := match.Name(refName)
+	for i := len(im.Manifests) - 1; i >= 0; i-- {
+		if matches(im.Manifests[i]) {
+			return im.Manifests[i].Digest, nil
+		}
+	}
+	return v1.Hash{}, fmt.Errorf("no image recorded for %s", refName)
+}
@@ -14,8 +14,10 @@ import (
 	"bufio"
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -26,10 +28,18 @@ import (
 	"github.com/apptainer/apptainer/pkg/sylog"
 	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 )
 
+// TmpDirPrefix is the name prefix of the temporary OCI layout directories
+// created while fetching and archiving images.
+const TmpDirPrefix = "temp-oci-"
+
 // cachedImage will ensure that the provided v1.Image is present in the Apptainer
 // OCI cache layout dir, and return a new v1.Image pointing to the cached copy.
+// An image already complete in the writable cache is used from there, then
+// one complete in the read-only cache, if any. Otherwise the image is written
+// to the writable cache.
 func cachedImage(ctx context.Context, imgCache *cache.Handle, srcImg v1.Image) (v1.Image, error) {
 	if imgCache == nil || imgCache.IsDisabled() {
 		return nil, fmt.Errorf("undefined image cache")
@@ -44,6 +54,27 @@ func cachedImage(ctx context.Context, imgCache *cache.Handle, srcImg v1.Image) (
 	if err != nil {
 		return nil, err
 	}
+	if img, err := layoutImage(ctx, layoutDir, digest); err == nil {
+		sylog.Debugf("Using image %s from cache %s", digest, layoutDir)
+		return img, nil
+	}
+
+	roLayoutDir, err := imgCache.GetReadOnlyOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return nil, err
+	}
+	if roLayoutDir != "" {
+		if img, err := layoutImage(ctx, roLayoutDir, digest); err == nil {
+			sylog.Debugf("Using image %s from read-only cache %s", digest, roLayoutDir)
+			return img, nil
+		}
+	}
+
+	unlock, err := imgCache.LockOciBlobCache()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	cachedRef := layoutDir + "@" + digest.String()
 	sylog.Debugf("Caching image to %s", cachedRef)
@@ -54,34 +85,107 @@ func cachedImage(ctx context.Context, imgCache *cache.Handle, srcImg v1.Image) (
 	return OCISourceSink.Image(ctx, cachedRef, nil, nil)
 }
 
+// layoutImage returns the image with the given digest from the OCI layout at
+// layoutDir, if the layout holds its manifest, config and all of its layers.
+func layoutImage(ctx context.Context, layoutDir string, digest v1.Hash) (v1.Image, error) {
+	img, err := OCISourceSink.Image(ctx, layoutDir+"@"+digest.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	blobs := []v1.Descriptor{manifest.Config}
+	blobs = append(blobs, manifest.Layers...)
+	for _, desc := range blobs {
+		path := filepath.Join(layoutDir, "blobs", desc.Digest.Algorithm, desc.Digest.Hex)
+		if fi, err := os.Stat(path); err != nil || fi.Size() != desc.Size {
+			return nil, fmt.Errorf("blob %s of image %s missing from %s", desc.Digest, digest, layoutDir)
+		}
+	}
+	return img, nil
+}
+
+// resumableCachedImage wraps srcImg, pulled from the registry reference
+// srcRef, so that interrupted layer downloads are resumed from partial files
+// kept in the OCI blob cache. An image already complete in the cache is
+// returned unchanged, without authenticating to the registry again.
+func resumableCachedImage(ctx context.Context, imgCache *cache.Handle, srcImg v1.Image, srcRef string, tOpts *TransportOptions, rt http.RoundTripper) (v1.Image, error) {
+	ref, ok := RegistrySourceSink.Reference(srcRef, tOpts)
+	if !ok {
+		return nil, fmt.Errorf("invalid registry reference: %s", srcRef)
+	}
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return nil, err
+	}
+	roLayoutDir, err := imgCache.GetReadOnlyOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := srcImg.Digest()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{layoutDir, roLayoutDir} {
+		if dir == "" {
+			continue
+		}
+		if _, err := layoutImage(ctx, dir, digest); err == nil {
+			return srcImg, nil
+		}
+	}
+	return resumeImage(ctx, srcImg, ref, layoutDir, tOpts, rt)
+}
+
+// EvictBlob removes the blob with the given digest from the OCI blob cache, so
+// that it is fetched again by the next pull of an image referencing it.
+func EvictBlob(imgCache *cache.Handle, digest v1.Hash) error {
+	if imgCache == nil || imgCache.IsDisabled() {
+		return fmt.Errorf("undefined image cache")
+	}
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(layoutDir, "blobs", digest.Algorithm, digest.Hex)
+	sylog.Debugf("Removing blob %s from cache", path)
+	return os.Remove(path)
+}
+
+// IsUnreachable reports whether err shows that a registry could not be
+// reached, as opposed to an error returned by the registry, such as an
+// authentication failure or a missing image.
+func IsUnreachable(err error) bool {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr)
+}
+
 // FetchToLayout will fetch the OCI image specified by imageRef to an OCI layout
 // and return a v1.Image referencing it. If imgCache is non-nil, and enabled,
 // the image will be fetched into Apptainer's cache - which is a multi-image
 // OCI layout. If the cache is disabled, the image will be fetched into a
 // subdirectory of the provided tmpDir. The caller is responsible for cleaning
-// up tmpDir.
+// up tmpDir. A registry image is recorded in the cache for its tag, which is
+// used in place of the registry when it can't be reached, or with
+// tOpts.Offline.
 func FetchToLayout(ctx context.Context, tOpts *TransportOptions, imgCache *cache.Handle, imageURI, tmpDir string) (ggcrv1.Image, error) {
 	// oci-archive - Perform a tar extraction first, and handle as an oci layout.
 	if strings.HasPrefix(imageURI, "oci-archive:") {
-		var tmpDir string
-		tmpDir, err := os.MkdirTemp(tOpts.TmpDir, "temp-oci-")
+		layoutURI, layoutDir, err := ociArchiveToLayout(imageURI, tOpts.TmpDir, false)
 		if err != nil {
-			return nil, fmt.Errorf("could not create temporary oci directory: %v", err)
-		}
-		defer os.RemoveAll(tmpDir)
-
-		// oci-archive:<path>[:tag]
-		refParts := strings.SplitN(imageURI, ":", 3)
-		sylog.Debugf("Extracting oci-archive %q to %q", refParts[1], tmpDir)
-		err = extractArchive(refParts[1], tmpDir)
-		if err != nil {
-			return nil, fmt.Errorf("error extracting the OCI archive file: %v", err)
-		}
-		// We may or may not have had a ':tag' in the source to handle
-		imageURI = "oci:" + tmpDir
-		if len(refParts) == 3 {
-			imageURI = imageURI + ":" + refParts[2]
+			return nil, err
 		}
+		defer os.RemoveAll(layoutDir)
+		imageURI = layoutURI
 	}
 
 	srcType, srcRef, err := URItoSourceSinkRef(imageURI)
@@ -89,15 +193,48 @@ func FetchToLayout(ctx context.Context, tOpts *TransportOptions, imgCache *cache
 		return nil, err
 	}
 
+	useCache := imgCache != nil && !imgCache.IsDisabled()
+	var platform v1.Platform
+	if tOpts != nil {
+		platform = tOpts.Platform
+	}
+	if srcType == RegistrySourceSink && tOpts != nil && tOpts.Offline {
+		if !useCache {
+			return nil, fmt.Errorf("%s can't be retrieved offline with the cache disabled", imageURI)
+		}
+		img, err := CachedRefImage(ctx, imgCache, imageURI, platform)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not in the cache and can't be fetched offline: %w", imageURI, err)
+		}
+		return img, nil
+	}
+
 	rt := progressClient.NewRoundTripper(ctx, nil)
 
 	srcImg, err := srcType.Image(ctx, srcRef, tOpts, rt)
 	if err != nil {
 		rt.ProgressShutdown()
+		if srcType == RegistrySourceSink && useCache && IsUnreachable(err) {
+			if img, cerr := CachedRefImage(ctx, imgCache, imageURI, platform); cerr == nil {
+				sylog.Warningf("Unable to reach the registry for %s, using the image cached for it", imageURI)
+				sylog.Debugf("While fetching %s: %v", imageURI, err)
+				return img, nil
+			}
+		}
 		return nil, err
 	}
+	if srcType == RegistrySourceSink && useCache {
+		srcImg, err = resumableCachedImage(ctx, imgCache, srcImg, srcRef, tOpts, rt)
+		if err != nil {
+			rt.ProgressShutdown()
+			return nil, err
+		}
+	}
+	if tOpts != nil {
+		srcImg = limitImage(srcImg, tOpts.Concurrency)
+	}
 
-	if imgCache != nil && !imgCache.IsDisabled() {
+	if useCache {
 		// Ensure the image is cached, and return reference to the cached image.
 		cachedImg, err := cachedImage(ctx, imgCache, srcImg)
 		if err != nil {
@@ -106,12 +243,20 @@ func FetchToLayout(ctx context.Context, tOpts *TransportOptions, imgCache *cache
 		}
 		rt.ProgressComplete()
 		rt.ProgressWait()
+		// Record the image for the tag, so that it can be used when the
+		// registry is unreachable.
+		if srcType == RegistrySourceSink {
+			if err := RecordCachedRef(imgCache, imageURI, cachedImg); err != nil {
+				sylog.Debugf("Unable to record cached image for %s: %v", imageURI, err)
+			}
+		}
 		return cachedImg, nil
 	}
 
 	// No cache - write to layout directory provided
 	tmpLayout, err := os.MkdirTemp(tmpDir, "layout-")
 	if err != nil {
+		rt.ProgressShutdown()
 		return nil, err
 	}
 	sylog.Debugf("Copying %q to temporary layout at %q", srcRef, tmpLayout)
@@ -125,11 +270,114 @@ func FetchToLayout(ctx context.Context, tOpts *TransportOptions, imgCache *cache
 	return OCISourceSink.Image(ctx, tmpLayout, tOpts, nil)
 }
 
+// ociArchiveToLayout extracts the oci-archive:<path>[:tag] imageURI into a new
+// temporary directory under tmpParent. It returns the equivalent oci: URI for
+// the extracted layout, and the directory, which the caller must remove. With
+// metadataOnly, the image layers are not extracted, leaving a layout which
+// only holds the indexes, manifests and configs of the images.
+func ociArchiveToLayout(imageURI, tmpParent string, metadataOnly bool) (layoutURI, layoutDir string, err error) {
+	layoutDir, err = os.MkdirTemp(tmpParent, TmpDirPrefix)
+	if err != nil {
+		return "", "", fmt.Errorf("could not create temporary oci directory: %v", err)
+	}
+
+	// oci-archive:<path>[:tag]
+	refParts := strings.SplitN(imageURI, ":", 3)
+	sylog.Debugf("Extracting oci-archive %q to %q", refParts[1], layoutDir)
+	extract := func(src, dst string) error {
+		return extractArchive(src, dst, nil)
+	}
+	if metadataOnly {
+		extract = extractArchiveMetadata
+	}
+	if err := extract(refParts[1], layoutDir); err != nil {
+		os.RemoveAll(layoutDir)
+		return "", "", fmt.Errorf("error extracting the OCI archive file: %v", err)
+	}
+	// We may or may not have had a ':tag' in the source to handle
+	layoutURI = "oci:" + layoutDir
+	if len(refParts) == 3 {
+		layoutURI = layoutURI + ":" + refParts[2]
+	}
+	return layoutURI, layoutDir, nil
+}
+
+// extractArchiveMetadata extracts the OCI layout archive src to dst, leaving
+// out the image layers. The layout files are extracted first, then the blobs
+// referenced by the index, with one pass over the archive for each level of
+// indexes, manifests and configs.
+func extractArchiveMetadata(src string, dst string) error {
+	err := extractArchive(src, dst, func(name string) bool {
+		return !strings.HasPrefix(name, "blobs/")
+	})
+	if err != nil {
+		return err
+	}
+	index, err := os.Open(filepath.Join(dst, "index.json"))
+	if err != nil {
+		return err
+	}
+	im, err := v1.ParseIndexManifest(index)
+	index.Close()
+	if err != nil {
+		return fmt.Errorf("while parsing index.json: %w", err)
+	}
+
+	extracted := make(map[string]bool)
+	descs := im.Manifests
+	for len(descs) > 0 {
+		wanted := make(map[string]v1.Descriptor)
+		for _, d := range descs {
+			name := filepath.Join("blobs", d.Digest.Algorithm, d.Digest.Hex)
+			if !extracted[name] {
+				extracted[name] = true
+				wanted[name] = d
+			}
+		}
+		if len(wanted) == 0 {
+			break
+		}
+		err := extractArchive(src, dst, func(name string) bool {
+			_, ok := wanted[name]
+			return ok
+		})
+		if err != nil {
+			return err
+		}
+
+		descs = nil
+		for name, d := range wanted {
+			if !d.MediaType.IsIndex() && !d.MediaType.IsImage() {
+				continue
+			}
+			f, err := os.Open(filepath.Join(dst, name))
+			if err != nil {
+				// a missing blob is reported when reading the layout
+				continue
+			}
+			if d.MediaType.IsIndex() {
+				im, err := v1.ParseIndexManifest(f)
+				if err == nil {
+					descs = append(descs, im.Manifests...)
+				}
+			} else {
+				m, err := v1.ParseManifest(f)
+				if err == nil {
+					descs = append(descs, m.Config)
+				}
+			}
+			f.Close()
+		}
+	}
+	return nil
+}
+
 // Perform a dumb tar(gz) extraction with no chown, id remapping etc.
 // This is needed for non-root handling of `oci-archive` as the extraction
 // by containers/archive is failing when uid/gid don't match local machine
-// and we're not root
-func extractArchive(src string, dst string) error {
+// and we're not root. If keep is not nil, only the regular files for which
+// it returns true, given their cleaned path in the archive, are extracted.
+func extractArchive(src string, dst string, keep func(name string) bool) error {
 	f, err := os.Open(src)
 	if err != nil {
 		return err
@@ -187,6 +435,12 @@ func extractArchive(src string, dst string) error {
 			}
 		// if it's a file create it
 		case tar.TypeReg:
+			if keep != nil && !keep(filepath.Clean(header.Name)) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
 			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
 			if err != nil {
 				return err
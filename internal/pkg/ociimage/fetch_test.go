@@ -0,0 +1,268 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/cache"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestCachedImageSharedLayers(t *testing.T) {
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+
+	base, err := random.Layer(256, types.DockerLayer)
+	if err != nil {
+		t.Fatalf("while creating base layer: %v", err)
+	}
+	var images []v1.Image
+	for i := 0; i < 2; i++ {
+		top, err := random.Layer(256, types.DockerLayer)
+		if err != nil {
+			t.Fatalf("while creating layer: %v", err)
+		}
+		img, err := mutate.AppendLayers(empty.Image, base, top)
+		if err != nil {
+			t.Fatalf("while creating image: %v", err)
+		}
+		images = append(images, img)
+	}
+
+	for _, img := range images {
+		if _, err := cachedImage(context.Background(), imgCache, img); err != nil {
+			t.Fatalf("while caching image: %v", err)
+		}
+	}
+
+	// The shared base layer is stored once: 3 layers, 2 configs and 2
+	// manifests.
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		t.Fatalf("while getting cache dir: %v", err)
+	}
+	blobs, err := os.ReadDir(filepath.Join(layoutDir, "blobs", "sha256"))
+	if err != nil {
+		t.Fatalf("while reading blobs: %v", err)
+	}
+	if len(blobs) != 7 {
+		t.Errorf("expected 7 blobs in cache, got %d", len(blobs))
+	}
+
+	results, err := imgCache.VerifyOciBlobs()
+	if err != nil {
+		t.Fatalf("while verifying blobs: %v", err)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("blob %s: %v", r.Digest, r.Err)
+		}
+	}
+}
+
+func TestCachedImageReadOnly(t *testing.T) {
+	roParent := t.TempDir()
+	roCache, err := cache.New(cache.Config{ParentDir: roParent})
+	if err != nil {
+		t.Fatalf("while creating read-only cache: %v", err)
+	}
+
+	var images []v1.Image
+	for i := 0; i < 2; i++ {
+		img, err := random.Image(256, 1)
+		if err != nil {
+			t.Fatalf("while creating image: %v", err)
+		}
+		if _, err := cachedImage(context.Background(), roCache, img); err != nil {
+			t.Fatalf("while caching image: %v", err)
+		}
+		images = append(images, img)
+	}
+	roLayoutDir, err := roCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		t.Fatalf("while getting cache dir: %v", err)
+	}
+
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir(), ReadOnlyParentDir: roParent})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	layoutDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		t.Fatalf("while getting cache dir: %v", err)
+	}
+
+	// The second image is also complete in the writable cache.
+	if err := OCISourceSink.WriteImage(images[1], layoutDir, nil); err != nil {
+		t.Fatalf("while writing image: %v", err)
+	}
+
+	// The first image is used from the read-only cache, without being
+	// copied to the writable cache.
+	img, err := cachedImage(context.Background(), imgCache, images[0])
+	if err != nil {
+		t.Fatalf("while getting cached image: %v", err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("while getting layers: %v", err)
+	}
+	layer, err := layers[0].Digest()
+	if err != nil {
+		t.Fatalf("while getting layer digest: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(layoutDir, "blobs", layer.Algorithm, layer.Hex)); err == nil {
+		t.Errorf("layer %s of read-only cached image copied to the writable cache", layer)
+	}
+
+	// The second image is used from the writable cache, so it remains
+	// readable once the read-only cache is gone.
+	img, err = cachedImage(context.Background(), imgCache, images[1])
+	if err != nil {
+		t.Fatalf("while getting cached image: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(roLayoutDir, "blobs")); err != nil {
+		t.Fatalf("while removing read-only blobs: %v", err)
+	}
+	layers, err = img.Layers()
+	if err != nil {
+		t.Fatalf("while getting layers: %v", err)
+	}
+	rc, err := layers[0].Compressed()
+	if err != nil {
+		t.Fatalf("image not used from the writable cache: %v", err)
+	}
+	rc.Close()
+}
+
+func TestFetchToLayoutCachedRef(t *testing.T) {
+	// A registry which can be made to deny all requests.
+	var deny atomic.Bool
+	reg := registry.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if deny.Load() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/test/fetch:latest"
+	r, err := name.ParseReference(ref, name.Insecure)
+	if err != nil {
+		t.Fatalf("while parsing reference: %v", err)
+	}
+	img, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatalf("while creating random image: %v", err)
+	}
+	if err := remote.Write(r, img); err != nil {
+		t.Fatalf("while pushing image: %v", err)
+	}
+	want, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	imageURI := "docker://" + ref
+	tOpts := &TransportOptions{Insecure: true, TmpDir: t.TempDir()}
+	fetch := func() (v1.Hash, error) {
+		img, err := FetchToLayout(context.Background(), tOpts, imgCache, imageURI, t.TempDir())
+		if err != nil {
+			return v1.Hash{}, err
+		}
+		return img.Digest()
+	}
+
+	if got, err := fetch(); err != nil || got != want {
+		t.Fatalf("expected image %s, got %s (%v)", want, got, err)
+	}
+
+	// An error returned by the registry is reported, even with a cached image.
+	deny.Store(true)
+	if _, err := fetch(); err == nil {
+		t.Errorf("expected error from registry denying access")
+	}
+
+	// The cached image is used when the registry can't be reached, or offline.
+	srv.Close()
+	if got, err := fetch(); err != nil || got != want {
+		t.Errorf("expected cached image %s with unreachable registry, got %s (%v)", want, got, err)
+	}
+	tOpts.Offline = true
+	if got, err := fetch(); err != nil || got != want {
+		t.Errorf("expected cached image %s offline, got %s (%v)", want, got, err)
+	}
+
+	// Only images cached for the platform are used.
+	tOpts.Platform = v1.Platform{OS: "linux", Architecture: "s390x"}
+	if _, err := fetch(); err == nil {
+		t.Errorf("expected error fetching an image cached for another platform offline")
+	}
+	tOpts.Platform = v1.Platform{}
+	imageURI = "docker://" + strings.TrimPrefix(srv.URL, "http://") + "/test/fetch:missing"
+	if _, err := fetch(); err == nil {
+		t.Errorf("expected error fetching an uncached image offline")
+	}
+}
+
+func TestResumableCachedImageComplete(t *testing.T) {
+	imgCache, err := cache.New(cache.Config{ParentDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("while creating cache: %v", err)
+	}
+	img, err := random.Image(256, 2)
+	if err != nil {
+		t.Fatalf("while creating random image: %v", err)
+	}
+
+	// A registry which can't be reached, as no request is needed for an image
+	// complete in the cache.
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/test/resume:latest"
+	tOpts := &TransportOptions{Insecure: true}
+
+	if _, err := resumableCachedImage(context.Background(), imgCache, img, ref, tOpts, nil); err == nil {
+		t.Fatalf("expected error preparing the download of an uncached image")
+	}
+
+	if _, err := cachedImage(context.Background(), imgCache, img); err != nil {
+		t.Fatalf("while caching image: %v", err)
+	}
+	got, err := resumableCachedImage(context.Background(), imgCache, img, ref, tOpts, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != img {
+		t.Errorf("expected the cached image to be returned unchanged")
+	}
+}
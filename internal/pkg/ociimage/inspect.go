@@ -0,0 +1,54 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// InspectImage retrieves the config of the OCI image specified by imageURI,
+// holding its ENTRYPOINT, CMD, ENV, USER etc. Only the manifest and config are
+// fetched from a registry - the image layers are not downloaded or extracted.
+func InspectImage(ctx context.Context, tOpts *TransportOptions, imageURI string) (*v1.ConfigFile, error) {
+	// oci-archive - Extract the index, manifests and configs, without the
+	// layers, and handle as an oci layout.
+	if strings.HasPrefix(imageURI, "oci-archive:") {
+		var tmpParent string
+		if tOpts != nil {
+			tmpParent = tOpts.TmpDir
+		}
+		layoutURI, layoutDir, err := ociArchiveToLayout(imageURI, tmpParent, true)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(layoutDir)
+		imageURI = layoutURI
+	}
+
+	srcType, srcRef, err := URItoSourceSinkRef(imageURI)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := srcType.Image(ctx, srcRef, tOpts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("while retrieving image config: %w", err)
+	}
+	return cf, nil
+}
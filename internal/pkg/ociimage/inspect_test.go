@@ -0,0 +1,139 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestInspectImage(t *testing.T) {
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatalf("while creating random image: %v", err)
+	}
+	wantConfig := v1.Config{
+		Entrypoint: []string{"/bin/entrypoint"},
+		Cmd:        []string{"--help"},
+		Env:        []string{"PATH=/usr/bin:/bin", "FOO=bar"},
+		User:       "1000:1000",
+		WorkingDir: "/app",
+	}
+	img, err = mutate.Config(img, wantConfig)
+	if err != nil {
+		t.Fatalf("while setting image config: %v", err)
+	}
+
+	s := httptest.NewServer(registry.New())
+	t.Cleanup(s.Close)
+	ref := strings.TrimPrefix(s.URL, "http://") + "/test/inspect:latest"
+	r, err := name.ParseReference(ref, name.Insecure)
+	if err != nil {
+		t.Fatalf("while parsing reference: %v", err)
+	}
+	if err := remote.Write(r, img); err != nil {
+		t.Fatalf("while pushing image: %v", err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	if err := WriteArchive(img, "oci-archive", archive, ""); err != nil {
+		t.Fatalf("while writing oci-archive: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		imageURI string
+	}{
+		{
+			name:     "docker",
+			imageURI: "docker://" + ref,
+		},
+		{
+			name:     "oci-archive",
+			imageURI: "oci-archive:" + archive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tOpts := &TransportOptions{Insecure: true, TmpDir: t.TempDir()}
+			cf, err := InspectImage(context.Background(), tOpts, tt.imageURI)
+			if err != nil {
+				t.Fatalf("while inspecting image: %v", err)
+			}
+			if !reflect.DeepEqual(cf.Config, wantConfig) {
+				t.Errorf("expected config %+v, got %+v", wantConfig, cf.Config)
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		if _, err := InspectImage(context.Background(), nil, "library://alpine"); err == nil {
+			t.Errorf("expected error for unsupported transport")
+		}
+	})
+}
+
+func TestOCIArchiveMetadataToLayout(t *testing.T) {
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatalf("while creating random image: %v", err)
+	}
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	if err := WriteArchive(img, "oci-archive", archive, ""); err != nil {
+		t.Fatalf("while writing oci-archive: %v", err)
+	}
+
+	_, layoutDir, err := ociArchiveToLayout("oci-archive:"+archive, t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	blobPath := func(h v1.Hash) string {
+		return filepath.Join(layoutDir, "blobs", h.Algorithm, h.Hex)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := img.ConfigName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []v1.Hash{digest, config} {
+		if _, err := os.Stat(blobPath(h)); err != nil {
+			t.Errorf("expected blob %s to be extracted: %v", h, err)
+		}
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range layers {
+		h, err := l.Digest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(blobPath(h)); !os.IsNotExist(err) {
+			t.Errorf("expected layer %s not to be extracted, got %v", h, err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// whiteoutPrefix is the file name prefix marking a deleted path in an
+	// OCI layer.
+	whiteoutPrefix = ".wh."
+	// whiteoutOpaque is the file marking a directory of an OCI layer as
+	// opaque, hiding the content of the directory in lower layers.
+	whiteoutOpaque = whiteoutPrefix + whiteoutPrefix + ".opq"
+	// paxSchilyXattr is the prefix of the PAX records holding the extended
+	// attributes of a tar entry.
+	paxSchilyXattr = "SCHILY.xattr."
+)
+
+// opaqueXattrs are the extended attributes marking an overlayfs opaque
+// directory, the user namespace one being used by overlays mounted with
+// userxattr.
+var opaqueXattrs = []string{"trusted.overlay.opaque", "user.overlay.opaque"}
+
+// LayerFromDir returns an OCI layer holding the content of dir. The layer is
+// written once to a temporary file in tmpDir, which the returned cleanup
+// function removes when the layer is no longer used.
+func LayerFromDir(dir, tmpDir string) (v1.Layer, func(), error) {
+	return layerFromDir(dir, tmpDir, false)
+}
+
+// LayerFromOverlay returns an OCI layer holding the changes recorded in the
+// overlayfs upper directory upperDir, written to a temporary file in tmpDir
+// as with LayerFromDir. Overlayfs whiteouts, character devices with device
+// number 0/0, and opaque directories are converted to OCI layer whiteouts.
+func LayerFromOverlay(upperDir, tmpDir string) (v1.Layer, func(), error) {
+	return layerFromDir(upperDir, tmpDir, true)
+}
+
+// layerFromDir writes the content of dir to a temporary tar file in tmpDir,
+// so that the digest and content of the layer are read from the same
+// snapshot of dir, which may still be modified.
+func layerFromDir(dir, tmpDir string, overlay bool) (v1.Layer, func(), error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.CreateTemp(tmpDir, "layer-*.tar")
+	if err != nil {
+		return nil, nil, fmt.Errorf("while creating layer file: %w", err)
+	}
+	cleanup := func() {
+		if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+			sylog.Errorf("while removing %q: %v", f.Name(), err)
+		}
+	}
+
+	err = writeDirTar(f, dir, overlay)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("while writing layer of %s: %w", dir, err)
+	}
+
+	layer, err := tarball.LayerFromFile(f.Name(), tarball.WithMediaType(types.OCILayer))
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return layer, cleanup, nil
+}
+
+// fileID identifies a file by its device and inode numbers.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// writeDirTar writes the content of dir as a tar stream to w, with entry
+// names relative to dir. Hard links are written as tar links to the first
+// name of the file, and extended attributes as PAX records. Overlayfs
+// whiteouts are converted if overlay is set.
+func writeDirTar(w io.Writer, dir string, overlay bool) error {
+	tw := tar.NewWriter(w)
+	links := make(map[fileID]string)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		var st unix.Stat_t
+		if err := unix.Lstat(p, &st); err != nil {
+			return fmt.Errorf("while getting %s status: %w", p, err)
+		}
+		if overlay && d.Type()&fs.ModeCharDevice != 0 && st.Rdev == 0 {
+			return writeWhiteout(tw, path.Join(path.Dir(name), whiteoutPrefix+path.Base(name)))
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if d.Type()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("while creating tar header for %s: %w", p, err)
+		}
+		hdr.Name = name
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		// ownership is kept by ids only, as the names are host specific
+		hdr.Uname, hdr.Gname = "", ""
+		// access and change times are not recorded, as they change when the
+		// files are read
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		if hdr.PAXRecords, err = xattrRecords(p); err != nil {
+			return fmt.Errorf("while reading %s extended attributes: %w", p, err)
+		}
+
+		id := fileID{dev: uint64(st.Dev), ino: st.Ino}
+		if d.Type().IsRegular() && st.Nlink > 1 {
+			if first, ok := links[id]; ok {
+				hdr.Typeflag = tar.TypeLink
+				hdr.Linkname = first
+				hdr.Size = 0
+				return tw.WriteHeader(hdr)
+			}
+			links[id] = name
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if overlay && d.IsDir() && isOpaqueDir(p) {
+			return writeWhiteout(tw, path.Join(name, whiteoutOpaque))
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// a file growing while it is read is truncated to the size recorded
+		// in its header, rather than corrupting the tar stream
+		_, err = io.CopyN(tw, f, hdr.Size)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// xattrRecords returns the extended attributes of p as tar PAX records,
+// except the private ones of overlayfs.
+func xattrRecords(p string) (map[string]string, error) {
+	size, err := unix.Llistxattr(p, nil)
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	} else if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Llistxattr(p, buf); err != nil {
+		return nil, err
+	}
+
+	var records map[string]string
+	for _, attr := range strings.Split(string(buf[:size]), "\x00") {
+		if attr == "" || strings.HasPrefix(attr, "trusted.overlay.") || strings.HasPrefix(attr, "user.overlay.") {
+			continue
+		}
+		vsize, err := unix.Lgetxattr(p, attr, nil)
+		if errors.Is(err, unix.ENODATA) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		value := make([]byte, vsize)
+		if vsize, err = unix.Lgetxattr(p, attr, value); err != nil {
+			return nil, err
+		}
+		if records == nil {
+			records = make(map[string]string)
+		}
+		records[paxSchilyXattr+attr] = string(value[:vsize])
+	}
+	return records, nil
+}
+
+// writeWhiteout writes the empty whiteout file name to tw.
+func writeWhiteout(tw *tar.Writer, name string) error {
+	return tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o600,
+	})
+}
+
+// isOpaqueDir reports whether dir is marked as opaque in an overlayfs upper
+// directory.
+func isOpaqueDir(dir string) bool {
+	buf := make([]byte, 1)
+	for _, attr := range opaqueXattrs {
+		n, err := unix.Lgetxattr(dir, attr, buf)
+		if err == nil && n == 1 && buf[0] == 'y' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"golang.org/x/sys/unix"
+)
+
+// layerEntries returns the entries of the tar stream of layer, mapped to
+// their content for regular files and link target for symlinks and hard
+// links.
+func layerEntries(t *testing.T, layer v1.Layer) map[string]string {
+	t.Helper()
+
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		t.Fatalf("while opening layer: %v", err)
+	}
+	defer rc.Close()
+
+	entries := make(map[string]string)
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("while reading layer: %v", err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatalf("while reading %s: %v", hdr.Name, err)
+			}
+			entries[hdr.Name] = string(b)
+		case tar.TypeSymlink:
+			entries[hdr.Name] = "-> " + hdr.Linkname
+		case tar.TypeLink:
+			entries[hdr.Name] = "=> " + hdr.Linkname
+		default:
+			entries[hdr.Name] = ""
+		}
+	}
+	return entries
+}
+
+func TestLayerFromDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "etc", "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "etc", "hostname"), []byte("container\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("etc/hostname", filepath.Join(dir, "hostname")); err != nil {
+		t.Fatal(err)
+	}
+
+	layer, cleanup, err := LayerFromDir(dir, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+
+	want := map[string]string{
+		"etc/":         "",
+		"etc/empty/":   "",
+		"etc/hostname": "container\n",
+		"hostname":     "-> etc/hostname",
+	}
+	if got := layerEntries(t, layer); !reflect.DeepEqual(got, want) {
+		t.Errorf("got layer entries %v, want %v", got, want)
+	}
+
+	// the layer can be read again, to compute digests and to write it
+	d1, err := layer.Digest()
+	if err != nil {
+		t.Fatalf("while computing digest: %v", err)
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		t.Fatalf("while opening layer: %v", err)
+	}
+	defer rc.Close()
+	d2, _, err := v1.SHA256(rc)
+	if err != nil {
+		t.Fatalf("while hashing layer: %v", err)
+	}
+	if d1 != d2 {
+		t.Errorf("got digest %s, want %s", d2, d1)
+	}
+
+	if _, _, err := LayerFromDir(filepath.Join(dir, "missing"), t.TempDir()); err == nil {
+		t.Errorf("unexpected success for a missing directory")
+	}
+}
+
+func TestLayerFromDirLinksXattrs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a"), []byte("content\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(dir, "a"), filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	xattrs := true
+	if err := unix.Lsetxattr(filepath.Join(dir, "a"), "user.test", []byte("value"), 0); err != nil {
+		t.Logf("extended attributes not supported: %v", err)
+		xattrs = false
+	}
+
+	layer, cleanup, err := LayerFromDir(dir, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+
+	// the second name of the file is a link to the first one
+	want := map[string]string{
+		"a": "content\n",
+		"b": "=> a",
+	}
+	if got := layerEntries(t, layer); !reflect.DeepEqual(got, want) {
+		t.Errorf("got layer entries %v, want %v", got, want)
+	}
+
+	if !xattrs {
+		return
+	}
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		t.Fatalf("while opening layer: %v", err)
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			t.Fatalf("file a not found in layer")
+		}
+		if err != nil {
+			t.Fatalf("while reading layer: %v", err)
+		}
+		if hdr.Name != "a" {
+			continue
+		}
+		if got := hdr.PAXRecords["SCHILY.xattr.user.test"]; got != "value" {
+			t.Errorf("got user.test extended attribute %q, want %q", got, "value")
+		}
+		break
+	}
+}
+
+func TestLayerFromOverlay(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("overlay mount requires root")
+	}
+
+	dir := t.TempDir()
+	lower := filepath.Join(dir, "lower")
+	upper := filepath.Join(dir, "upper")
+	work := filepath.Join(dir, "work")
+	merged := filepath.Join(dir, "merged")
+	for _, d := range []string{
+		filepath.Join(lower, "etc"),
+		filepath.Join(lower, "var", "cache", "apt"),
+		filepath.Join(lower, "usr", "bin"),
+		upper, work, merged,
+	} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"etc/hostname", "etc/motd", "var/cache/apt/pkgcache.bin", "usr/bin/true"} {
+		if err := os.WriteFile(filepath.Join(lower, f), []byte("lower\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := "lowerdir=" + lower + ",upperdir=" + upper + ",workdir=" + work
+	if err := unix.Mount("overlay", merged, "overlay", 0, opts); err != nil {
+		t.Skipf("overlay mount not supported: %v", err)
+	}
+	mounted := true
+	defer func() {
+		if mounted {
+			unix.Unmount(merged, unix.MNT_DETACH)
+		}
+	}()
+
+	// modify, add and delete a file, and replace a directory
+	if err := os.WriteFile(filepath.Join(merged, "etc", "hostname"), []byte("upper\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(merged, "etc", "added"), []byte("added\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(merged, "etc", "motd")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(merged, "var", "cache")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(merged, "var", "cache"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unix.Unmount(merged, 0); err != nil {
+		t.Fatalf("while unmounting overlay: %v", err)
+	}
+	mounted = false
+
+	layer, cleanup, err := LayerFromOverlay(upper, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+
+	// usr/ and var/cache/apt/ are unchanged, and must not be in the layer
+	want := map[string]string{
+		"etc/":                   "",
+		"etc/added":              "added\n",
+		"etc/hostname":           "upper\n",
+		"etc/.wh.motd":           "",
+		"var/":                   "",
+		"var/cache/":             "",
+		"var/cache/.wh..wh..opq": "",
+	}
+	if got := layerEntries(t, layer); !reflect.DeepEqual(got, want) {
+		t.Errorf("got layer entries %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"io"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// limitedImage wraps a v1.Image so that no more than cap(sem) of its layers
+// are read at the same time. go-containerregistry writes all layers of an
+// image to a layout concurrently, so this bounds the number of parallel
+// layer downloads from a remote source.
+type limitedImage struct {
+	v1.Image
+	sem chan struct{}
+}
+
+// limitImage returns img wrapped so that at most n layers are read
+// concurrently. If n is less than 1, img is returned unchanged.
+func limitImage(img v1.Image, n int) v1.Image {
+	if n < 1 {
+		return img
+	}
+	return &limitedImage{
+		Image: img,
+		sem:   make(chan struct{}, n),
+	}
+}
+
+func (li *limitedImage) Layers() ([]v1.Layer, error) {
+	layers, err := li.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	limited := make([]v1.Layer, 0, len(layers))
+	for _, l := range layers {
+		limited = append(limited, &limitedLayer{Layer: l, sem: li.sem})
+	}
+	return limited, nil
+}
+
+func (li *limitedImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	l, err := li.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedLayer{Layer: l, sem: li.sem}, nil
+}
+
+func (li *limitedImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	l, err := li.Image.LayerByDiffID(h)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedLayer{Layer: l, sem: li.sem}, nil
+}
+
+// limitedLayer holds a slot in sem from the time one of its readers is opened
+// until it is closed.
+type limitedLayer struct {
+	v1.Layer
+	sem chan struct{}
+}
+
+func (ll *limitedLayer) Compressed() (io.ReadCloser, error) {
+	return ll.acquire(ll.Layer.Compressed)
+}
+
+func (ll *limitedLayer) Uncompressed() (io.ReadCloser, error) {
+	return ll.acquire(ll.Layer.Uncompressed)
+}
+
+func (ll *limitedLayer) acquire(open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	ll.sem <- struct{}{}
+	rc, err := open()
+	if err != nil {
+		<-ll.sem
+		return nil, err
+	}
+	return &limitedReadCloser{ReadCloser: rc, sem: ll.sem}, nil
+}
+
+type limitedReadCloser struct {
+	io.ReadCloser
+	sem  chan struct{}
+	once sync.Once
+}
+
+func (lrc *limitedReadCloser) Close() error {
+	err := lrc.ReadCloser.Close()
+	lrc.once.Do(func() { <-lrc.sem })
+	return err
+}
@@ -0,0 +1,69 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestLimitImage(t *testing.T) {
+	img, err := random.Image(64, 8)
+	if err != nil {
+		t.Fatalf("while creating random image: %v", err)
+	}
+
+	if got := limitImage(img, 0); got != img {
+		t.Errorf("expected image to be unchanged with no limit")
+	}
+
+	const limit = 2
+	layers, err := limitImage(img, limit).Layers()
+	if err != nil {
+		t.Fatalf("while getting layers: %v", err)
+	}
+
+	var open, maxOpen int32
+	var wg sync.WaitGroup
+	for _, l := range layers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rc, err := l.Compressed()
+			if err != nil {
+				t.Errorf("while opening layer: %v", err)
+				return
+			}
+			n := atomic.AddInt32(&open, 1)
+			for {
+				m := atomic.LoadInt32(&maxOpen)
+				if n <= m || atomic.CompareAndSwapInt32(&maxOpen, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			_, _ = io.Copy(io.Discard, rc)
+			atomic.AddInt32(&open, -1)
+			rc.Close()
+		}()
+	}
+	wg.Wait()
+
+	if maxOpen > limit {
+		t.Errorf("expected at most %d concurrent layer reads, got %d", limit, maxOpen)
+	}
+	if maxOpen < 1 {
+		t.Errorf("no layer was read")
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/ociauth"
+	"github.com/apptainer/apptainer/pkg/sylog"
+	"github.com/apptainer/apptainer/pkg/util/fs/lock"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// partialDir is the directory, below the OCI blob cache, that holds partially
+// downloaded layer blobs.
+const partialDir = "partial"
+
+// blobLockTimeout bounds the wait for another process downloading the same
+// blob. The kernel releases the lock if its holder dies, so this only guards
+// against a holder that stops making progress.
+var blobLockTimeout = 30 * time.Minute
+
+// blobLockPoll is the interval between attempts to take a blob lock.
+const blobLockPoll = 100 * time.Millisecond
+
+// blobFetcher opens the blob identified by digest, starting at offset. If the
+// source does not honor the offset, resumed is false and the returned content
+// starts at the beginning of the blob.
+type blobFetcher func(ctx context.Context, digest v1.Hash, offset int64) (rc io.ReadCloser, resumed bool, err error)
+
+// rangeGet performs a GET request for u, asking for content from offset
+// onwards when offset is greater than zero.
+func rangeGet(ctx context.Context, client *http.Client, u string, offset int64) (io.ReadCloser, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, offset > 0, nil
+	case http.StatusOK:
+		return resp.Body, false, nil
+	default:
+		resp.Body.Close()
+		return nil, false, fmt.Errorf("unexpected status fetching %s: %s", u, resp.Status)
+	}
+}
+
+// registryBlobFetcher returns a blobFetcher that retrieves blobs from the
+// repository of ref, authenticating as configured in tOpts.
+func registryBlobFetcher(ctx context.Context, ref name.Reference, tOpts *TransportOptions, rt http.RoundTripper) (blobFetcher, error) {
+	repo := ref.Context()
+
+	var ociAuth *authn.AuthConfig
+	var authFile string
+	if tOpts != nil {
+		ociAuth = tOpts.AuthConfig
+		authFile = tOpts.AuthFilePath
+	}
+	auth, err := ociauth.Authenticator(ociAuth, authFile, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	tr, err := transport.NewWithContext(ctx, repo.Registry, auth, rt, []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: tr}
+
+	return func(ctx context.Context, digest v1.Hash, offset int64) (io.ReadCloser, bool, error) {
+		u := url.URL{
+			Scheme: repo.Scheme(),
+			Host:   repo.RegistryStr(),
+			Path:   fmt.Sprintf("/v2/%s/blobs/%s", repo.RepositoryStr(), digest),
+		}
+		return rangeGet(ctx, client, u.String(), offset)
+	}, nil
+}
+
+// downloadBlob downloads the blob identified by digest and size to path. If
+// path already holds the start of the blob, from an earlier interrupted
+// download, only the remaining content is fetched. On failure, the content
+// retrieved so far is left at path so that a later call can resume.
+func downloadBlob(ctx context.Context, fetch blobFetcher, digest v1.Hash, size int64, path string) error {
+	var offset int64
+	if fi, err := os.Stat(path); err == nil {
+		offset = fi.Size()
+	}
+	if offset > size {
+		sylog.Debugf("Discarding oversized partial download %s", path)
+		offset = 0
+	}
+
+	if offset < size {
+		rc, resumed, err := fetch(ctx, digest, offset)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if !resumed {
+			flags |= os.O_TRUNC
+			offset = 0
+		}
+		if offset > 0 {
+			sylog.Debugf("Resuming download of %s at offset %d of %d", digest, offset, size)
+		}
+
+		f, err := os.OpenFile(path, flags, 0o644)
+		if err != nil {
+			return err
+		}
+		_, copyErr := io.Copy(f, io.LimitReader(rc, size-offset))
+		if err := f.Close(); err != nil && copyErr == nil {
+			copyErr = err
+		}
+		if copyErr != nil {
+			return fmt.Errorf("while downloading %s: %w", digest, copyErr)
+		}
+	}
+
+	return verifyBlob(path, digest, size)
+}
+
+// verifyBlob checks that the file at path has the expected size and digest,
+// removing it if the content is not as expected.
+func verifyBlob(path string, digest v1.Hash, size int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("incomplete download of %s: got %d of %d bytes", digest, n, size)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != digest.Hex {
+		os.Remove(path)
+		return fmt.Errorf("digest mismatch for %s: got sha256:%s", digest, got)
+	}
+	return nil
+}
+
+// resumableImage wraps a v1.Image so that its layers are downloaded to a
+// partial file, keyed by digest, before being read. Downloads interrupted by
+// a network failure resume from the partial file on the next attempt.
+type resumableImage struct {
+	v1.Image
+	download func(digest v1.Hash, size int64, path string) error
+	dir      string
+}
+
+// resumeImage wraps img, pulled from ref, so that layer downloads can be
+// resumed from partial files stored below the blobCacheDir.
+func resumeImage(ctx context.Context, img v1.Image, ref name.Reference, blobCacheDir string, tOpts *TransportOptions, rt http.RoundTripper) (v1.Image, error) {
+	fetch, err := registryBlobFetcher(ctx, ref, tOpts, rt)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(blobCacheDir, partialDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	download := func(digest v1.Hash, size int64, path string) error {
+		return downloadBlob(ctx, fetch, digest, size, path)
+	}
+	return &resumableImage{Image: img, download: download, dir: dir}, nil
+}
+
+func (ri *resumableImage) wrap(l v1.Layer) v1.Layer {
+	return &resumableLayer{Layer: l, image: ri}
+}
+
+func (ri *resumableImage) Layers() ([]v1.Layer, error) {
+	layers, err := ri.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	resumable := make([]v1.Layer, 0, len(layers))
+	for _, l := range layers {
+		resumable = append(resumable, ri.wrap(l))
+	}
+	return resumable, nil
+}
+
+func (ri *resumableImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	l, err := ri.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return ri.wrap(l), nil
+}
+
+func (ri *resumableImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	l, err := ri.Image.LayerByDiffID(h)
+	if err != nil {
+		return nil, err
+	}
+	return ri.wrap(l), nil
+}
+
+type resumableLayer struct {
+	v1.Layer
+	image *resumableImage
+}
+
+// Compressed downloads the layer blob to its partial file, then returns a
+// reader for the completed file. The partial file is removed once it has been
+// read to the end and closed, and the blob lock is held until then, so that
+// another pull can't truncate or append to the file while it is read.
+func (rl *resumableLayer) Compressed() (_ io.ReadCloser, err error) {
+	digest, err := rl.Digest()
+	if err != nil {
+		return nil, err
+	}
+	size, err := rl.Size()
+	if err != nil {
+		return nil, err
+	}
+	if digest.Algorithm != "sha256" {
+		return rl.Layer.Compressed()
+	}
+
+	// Concurrent pulls of the same image must not append to the same partial
+	// file, so the download is serialized by a lock per blob.
+	path := filepath.Join(rl.image.dir, digest.Algorithm+"-"+digest.Hex)
+	fd, err := lockBlob(digest, path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			lock.Release(fd)
+		}
+	}()
+
+	// Another pull may have completed while we waited, in which case the blob
+	// is already in the cache layout.
+	cached := filepath.Join(filepath.Dir(rl.image.dir), "blobs", digest.Algorithm, digest.Hex)
+	if fi, err := os.Stat(cached); err == nil && fi.Size() == size {
+		sylog.Debugf("Using blob %s cached by another pull", digest)
+		lock.Release(fd)
+		return os.Open(cached)
+	}
+
+	if err := rl.image.download(digest, size, path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &partialReadCloser{File: f, lockFd: fd}, nil
+}
+
+// lockBlob takes an exclusive lock on the lock file of the partial download
+// at path, waiting up to blobLockTimeout while another process holds it.
+func lockBlob(digest v1.Hash, path string) (int, error) {
+	lockPath := path + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDONLY, 0o644)
+	if err != nil {
+		return -1, err
+	}
+	f.Close()
+
+	deadline := time.Now().Add(blobLockTimeout)
+	for waited := false; ; waited = true {
+		fd, acquired, err := lock.TryExclusive(lockPath)
+		if err != nil {
+			return -1, fmt.Errorf("while locking %s: %w", lockPath, err)
+		}
+		if acquired {
+			return fd, nil
+		}
+		if !waited {
+			sylog.Infof("Waiting for another pull of %s to complete", digest)
+		}
+		if time.Now().After(deadline) {
+			return -1, fmt.Errorf("timed out after %s waiting for another pull of %s", blobLockTimeout, digest)
+		}
+		time.Sleep(blobLockPoll)
+	}
+}
+
+// partialReadCloser removes the underlying file on Close, if it was read to
+// EOF, then releases the blob lock held by lockFd.
+type partialReadCloser struct {
+	*os.File
+	lockFd int
+	eof    bool
+}
+
+func (p *partialReadCloser) Read(b []byte) (int, error) {
+	n, err := p.File.Read(b)
+	if errors.Is(err, io.EOF) {
+		p.eof = true
+	}
+	return n, err
+}
+
+func (p *partialReadCloser) Close() error {
+	err := p.File.Close()
+	if p.eof {
+		if err := os.Remove(p.Name()); err != nil {
+			sylog.Debugf("while removing %s: %v", p.Name(), err)
+		}
+	}
+	if err := lock.Release(p.lockFd); err != nil {
+		sylog.Debugf("while releasing lock of %s: %v", p.Name(), err)
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apptainer/apptainer/pkg/util/fs/lock"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestDownloadBlobResume(t *testing.T) {
+	blob := make([]byte, 1<<20)
+	if _, err := rand.Read(blob); err != nil {
+		t.Fatalf("while generating blob: %v", err)
+	}
+	sum := sha256.Sum256(blob)
+	digest := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+	size := int64(len(blob))
+	truncateAt := size / 3
+
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// First attempt - advertise the full blob, but drop the
+			// connection part way through.
+			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+			w.WriteHeader(http.StatusOK)
+			w.Write(blob[:truncateAt])
+			return
+		}
+		http.ServeContent(w, r, "blob", time.Time{}, bytes.NewReader(blob))
+	}))
+	defer srv.Close()
+
+	fetch := func(ctx context.Context, _ v1.Hash, offset int64) (io.ReadCloser, bool, error) {
+		return rangeGet(ctx, srv.Client(), srv.URL, offset)
+	}
+	path := filepath.Join(t.TempDir(), "sha256-"+digest.Hex)
+
+	if err := downloadBlob(context.Background(), fetch, digest, size, path); err == nil {
+		t.Fatalf("expected error from truncated download")
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("partial download was not kept: %v", err)
+	}
+	if fi.Size() != truncateAt {
+		t.Fatalf("expected %d bytes in partial download, found %d", truncateAt, fi.Size())
+	}
+
+	if err := downloadBlob(context.Background(), fetch, digest, size, path); err != nil {
+		t.Fatalf("while resuming download: %v", err)
+	}
+	if want := "bytes=" + strconv.FormatInt(truncateAt, 10) + "-"; ranges[1] != want {
+		t.Errorf("expected resumed request with range %q, got %q", want, ranges[1])
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("while reading downloaded blob: %v", err)
+	}
+	if !bytes.Equal(got, blob) {
+		t.Errorf("downloaded blob does not match source")
+	}
+}
+
+func TestDownloadBlobNoRangeSupport(t *testing.T) {
+	blob := []byte("a blob served without range support")
+	sum := sha256.Sum256(blob)
+	digest := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(blob)
+	}))
+	defer srv.Close()
+
+	fetch := func(ctx context.Context, _ v1.Hash, offset int64) (io.ReadCloser, bool, error) {
+		return rangeGet(ctx, srv.Client(), srv.URL, offset)
+	}
+	path := filepath.Join(t.TempDir(), "sha256-"+digest.Hex)
+	// Stale partial content, which must be discarded as the server always
+	// returns the full blob.
+	if err := os.WriteFile(path, blob[:10], 0o644); err != nil {
+		t.Fatalf("while writing partial blob: %v", err)
+	}
+
+	if err := downloadBlob(context.Background(), fetch, digest, int64(len(blob)), path); err != nil {
+		t.Fatalf("while downloading blob: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("while reading downloaded blob: %v", err)
+	}
+	if !bytes.Equal(got, blob) {
+		t.Errorf("downloaded blob does not match source")
+	}
+}
+
+func TestDownloadBlobDigestMismatch(t *testing.T) {
+	blob := []byte("unexpected content")
+	digest := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(make([]byte, sha256.Size))}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(blob)
+	}))
+	defer srv.Close()
+
+	fetch := func(ctx context.Context, _ v1.Hash, offset int64) (io.ReadCloser, bool, error) {
+		return rangeGet(ctx, srv.Client(), srv.URL, offset)
+	}
+	path := filepath.Join(t.TempDir(), "sha256-"+digest.Hex)
+
+	if err := downloadBlob(context.Background(), fetch, digest, int64(len(blob)), path); err == nil {
+		t.Fatalf("expected digest mismatch error")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected corrupt download to be removed")
+	}
+}
+
+// blobLayer is a v1.Layer that only knows the digest and size of its blob.
+type blobLayer struct {
+	v1.Layer
+	digest v1.Hash
+	size   int64
+}
+
+func (bl *blobLayer) Digest() (v1.Hash, error) { return bl.digest, nil }
+func (bl *blobLayer) Size() (int64, error)     { return bl.size, nil }
+
+func TestResumableLayerConcurrentPulls(t *testing.T) {
+	blob := make([]byte, 1<<20)
+	if _, err := rand.Read(blob); err != nil {
+		t.Fatalf("while generating blob: %v", err)
+	}
+	sum := sha256.Sum256(blob)
+	digest := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+	size := int64(len(blob))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Write slowly, so that pulls overlap.
+		for i := 0; i < len(blob); i += len(blob) / 8 {
+			w.Write(blob[i : i+len(blob)/8])
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	fetch := func(ctx context.Context, _ v1.Hash, offset int64) (io.ReadCloser, bool, error) {
+		return rangeGet(ctx, srv.Client(), srv.URL, offset)
+	}
+
+	var active, maxActive atomic.Int32
+	dir := filepath.Join(t.TempDir(), partialDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatalf("while creating partial dir: %v", err)
+	}
+	ri := &resumableImage{
+		download: func(digest v1.Hash, size int64, path string) error {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				m := maxActive.Load()
+				if n <= m || maxActive.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			return downloadBlob(context.Background(), fetch, digest, size, path)
+		},
+		dir: dir,
+	}
+
+	const pulls = 4
+	var wg sync.WaitGroup
+	errs := make(chan error, pulls)
+	for i := 0; i < pulls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rc, err := ri.wrap(&blobLayer{digest: digest, size: size}).Compressed()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer rc.Close()
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(got, blob) {
+				errs <- fmt.Errorf("pulled blob does not match source")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := maxActive.Load(); n != 1 {
+		t.Errorf("expected downloads of the same blob to be serialized, got %d concurrent downloads", n)
+	}
+}
+
+func TestResumableLayerHoldsLockUntilClose(t *testing.T) {
+	blob := []byte("layer content")
+	sum := sha256.Sum256(blob)
+	digest := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+	size := int64(len(blob))
+
+	dir := filepath.Join(t.TempDir(), partialDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatalf("while creating partial dir: %v", err)
+	}
+	ri := &resumableImage{
+		download: func(_ v1.Hash, _ int64, path string) error {
+			return os.WriteFile(path, blob, 0o644)
+		},
+		dir: dir,
+	}
+	lockPath := filepath.Join(dir, digest.Algorithm+"-"+digest.Hex) + ".lock"
+
+	rc, err := ri.wrap(&blobLayer{digest: digest, size: size}).Compressed()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := io.ReadAll(rc); err != nil {
+		t.Fatalf("while reading layer: %v", err)
+	}
+
+	fd, acquired, err := lock.TryExclusive(lockPath)
+	if err != nil {
+		t.Fatalf("while locking %s: %v", lockPath, err)
+	}
+	if acquired {
+		lock.Release(fd)
+		t.Fatalf("blob lock was released before the partial file was closed")
+	}
+
+	if err := rc.Close(); err != nil {
+		t.Fatalf("while closing layer: %v", err)
+	}
+	fd, acquired, err = lock.TryExclusive(lockPath)
+	if err != nil {
+		t.Fatalf("while locking %s: %v", lockPath, err)
+	}
+	if !acquired {
+		t.Fatalf("blob lock was not released on close")
+	}
+	lock.Release(fd)
+}
@@ -0,0 +1,264 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apptainer/sif/v2/pkg/sif"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// SIFDescriptor describes a data object of an OCI-SIF image.
+type SIFDescriptor struct {
+	ID       uint32 `json:"id"`
+	DataType string `json:"dataType"`
+	Digest   string `json:"digest,omitempty"`
+	Offset   int64  `json:"offset"`
+	Size     int64  `json:"size"`
+}
+
+// SIFImage is the metadata of the OCI image held in an OCI-SIF image, along
+// with the layout of its SIF data objects.
+type SIFImage struct {
+	Digest       string            `json:"digest"`
+	Architecture string            `json:"architecture"`
+	OS           string            `json:"os"`
+	Variant      string            `json:"variant,omitempty"`
+	Layers       []v1.Descriptor   `json:"layers"`
+	Env          []string          `json:"env,omitempty"`
+	Entrypoint   []string          `json:"entrypoint,omitempty"`
+	Cmd          []string          `json:"cmd,omitempty"`
+	WorkingDir   string            `json:"workingDir,omitempty"`
+	User         string            `json:"user,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Descriptors  []SIFDescriptor   `json:"descriptors"`
+}
+
+// IsOCISIF reports whether the file at path is a SIF image holding an OCI
+// image, rather than a native SIF image.
+func IsOCISIF(path string) bool {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return false
+	}
+	defer f.UnloadContainer()
+
+	_, err = f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+	return err == nil
+}
+
+// InspectSIF returns the manifest and config metadata of the OCI image held in
+// the OCI-SIF image at path, and the layout of its SIF data objects. The image
+// layers are not read.
+func InspectSIF(path string) (*SIFImage, error) {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer f.UnloadContainer()
+
+	md, mb, cb, err := readSIFImage(f, path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := v1.ParseManifest(bytes.NewReader(mb))
+	if err != nil {
+		return nil, fmt.Errorf("while parsing manifest: %w", err)
+	}
+	cf, err := v1.ParseConfigFile(bytes.NewReader(cb))
+	if err != nil {
+		return nil, fmt.Errorf("while parsing config: %w", err)
+	}
+
+	img := &SIFImage{
+		Digest:       md.Digest.String(),
+		Architecture: cf.Architecture,
+		OS:           cf.OS,
+		Variant:      cf.Variant,
+		Layers:       m.Layers,
+		Env:          cf.Config.Env,
+		Entrypoint:   cf.Config.Entrypoint,
+		Cmd:          cf.Config.Cmd,
+		WorkingDir:   cf.Config.WorkingDir,
+		User:         cf.Config.User,
+		Labels:       cf.Config.Labels,
+	}
+
+	descriptors, err := f.GetDescriptors()
+	if err != nil {
+		return nil, fmt.Errorf("while listing SIF descriptors: %w", err)
+	}
+	for _, d := range descriptors {
+		sd := SIFDescriptor{
+			ID:       d.ID(),
+			DataType: d.DataType().String(),
+			Offset:   d.Offset(),
+			Size:     d.Size(),
+		}
+		if digest, err := d.OCIBlobDigest(); err == nil {
+			sd.Digest = digest.String()
+		}
+		img.Descriptors = append(img.Descriptors, sd)
+	}
+
+	return img, nil
+}
+
+// readSIFImage returns the manifest descriptor, the raw manifest and the raw
+// config of the single OCI image held in the OCI-SIF image f, loaded from
+// path.
+func readSIFImage(f *sif.FileImage, path string) (v1.Descriptor, []byte, []byte, error) {
+	rootIndex, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+	if err != nil {
+		return v1.Descriptor{}, nil, nil, fmt.Errorf("%s is not an OCI-SIF image: %w", path, err)
+	}
+	ix, err := v1.ParseIndexManifest(rootIndex.GetReader())
+	if err != nil {
+		return v1.Descriptor{}, nil, nil, fmt.Errorf("while parsing root index: %w", err)
+	}
+	if len(ix.Manifests) != 1 {
+		return v1.Descriptor{}, nil, nil, fmt.Errorf("expected a single image in OCI-SIF, found %d", len(ix.Manifests))
+	}
+	md := ix.Manifests[0]
+
+	mb, err := sifBlob(f, md.Digest)
+	if err != nil {
+		return v1.Descriptor{}, nil, nil, fmt.Errorf("while reading manifest: %w", err)
+	}
+	m, err := v1.ParseManifest(bytes.NewReader(mb))
+	if err != nil {
+		return v1.Descriptor{}, nil, nil, fmt.Errorf("while parsing manifest: %w", err)
+	}
+	cb, err := sifBlob(f, m.Config.Digest)
+	if err != nil {
+		return v1.Descriptor{}, nil, nil, fmt.Errorf("while reading config: %w", err)
+	}
+	return md, mb, cb, nil
+}
+
+// ImageFromSIF returns the OCI image held in the OCI-SIF image at path. The
+// manifest and config are read immediately, the layers when they are used.
+func ImageFromSIF(path string) (v1.Image, error) {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer f.UnloadContainer()
+
+	md, mb, cb, err := readSIFImage(f, path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := v1.ParseManifest(bytes.NewReader(mb))
+	if err != nil {
+		return nil, fmt.Errorf("while parsing manifest: %w", err)
+	}
+	mediaType := md.MediaType
+	if m.MediaType != "" {
+		mediaType = m.MediaType
+	}
+
+	return partial.CompressedToImage(&sifImageCore{
+		path:      path,
+		manifest:  m,
+		rawConfig: cb,
+		rawMan:    mb,
+		mediaType: mediaType,
+	})
+}
+
+// sifImageCore implements partial.CompressedImageCore for an image held in
+// an OCI-SIF image.
+type sifImageCore struct {
+	path      string
+	manifest  *v1.Manifest
+	rawConfig []byte
+	rawMan    []byte
+	mediaType types.MediaType
+}
+
+func (c *sifImageCore) RawConfigFile() ([]byte, error) {
+	return c.rawConfig, nil
+}
+
+func (c *sifImageCore) MediaType() (types.MediaType, error) {
+	return c.mediaType, nil
+}
+
+func (c *sifImageCore) RawManifest() ([]byte, error) {
+	return c.rawMan, nil
+}
+
+func (c *sifImageCore) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	for _, l := range c.manifest.Layers {
+		if l.Digest == h {
+			return &sifLayer{path: c.path, desc: l}, nil
+		}
+	}
+	if c.manifest.Config.Digest == h {
+		return &sifLayer{path: c.path, desc: c.manifest.Config}, nil
+	}
+	return nil, fmt.Errorf("blob %s not found in %s", h, c.path)
+}
+
+// sifLayer is a blob of an OCI-SIF image, read from the SIF file when opened.
+type sifLayer struct {
+	path string
+	desc v1.Descriptor
+}
+
+func (l *sifLayer) Digest() (v1.Hash, error) {
+	return l.desc.Digest, nil
+}
+
+func (l *sifLayer) Size() (int64, error) {
+	return l.desc.Size, nil
+}
+
+func (l *sifLayer) MediaType() (types.MediaType, error) {
+	return l.desc.MediaType, nil
+}
+
+func (l *sifLayer) Compressed() (io.ReadCloser, error) {
+	f, err := sif.LoadContainerFromPath(l.path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF: %w", err)
+	}
+	d, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIBlob), sif.WithOCIBlobDigest(l.desc.Digest))
+	if err != nil {
+		f.UnloadContainer()
+		return nil, fmt.Errorf("blob %s: %w", l.desc.Digest, err)
+	}
+	return &sifBlobReader{Reader: d.GetReader(), f: f}, nil
+}
+
+// sifBlobReader reads a blob of a SIF file, which is unloaded on Close.
+type sifBlobReader struct {
+	io.Reader
+	f *sif.FileImage
+}
+
+func (r *sifBlobReader) Close() error {
+	return r.f.UnloadContainer()
+}
+
+// sifBlob returns the content of the OCI blob with the given digest in f.
+func sifBlob(f *sif.FileImage, digest v1.Hash) ([]byte, error) {
+	d, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIBlob), sif.WithOCIBlobDigest(digest))
+	if err != nil {
+		return nil, fmt.Errorf("blob %s: %w", digest, err)
+	}
+	return d.GetData()
+}
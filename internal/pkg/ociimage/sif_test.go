@@ -0,0 +1,235 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ociimage
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apptainer/sif/v2/pkg/sif"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/google/go-containerregistry/pkg/v1/validate"
+)
+
+// writeOCISIF writes a sample OCI-SIF image holding a single image with the
+// given config and layers to path, and returns its manifest.
+func writeOCISIF(t *testing.T, path string, cf v1.ConfigFile, layers [][]byte) v1.Manifest {
+	t.Helper()
+
+	var blobs [][]byte
+	blob := func(v any) (v1.Descriptor, []byte) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("while marshaling %T: %v", v, err)
+		}
+		h, size, err := v1.SHA256(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("while hashing %T: %v", v, err)
+		}
+		return v1.Descriptor{Digest: h, Size: size}, b
+	}
+
+	m := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+	}
+	for _, l := range layers {
+		h, size, err := v1.SHA256(bytes.NewReader(l))
+		if err != nil {
+			t.Fatalf("while hashing layer: %v", err)
+		}
+		m.Layers = append(m.Layers, v1.Descriptor{
+			MediaType: types.OCIUncompressedLayer,
+			Digest:    h,
+			Size:      size,
+		})
+		blobs = append(blobs, l)
+	}
+	cd, cb := blob(cf)
+	cd.MediaType = types.OCIConfigJSON
+	m.Config = cd
+	blobs = append(blobs, cb)
+	md, mb := blob(m)
+	md.MediaType = types.OCIManifestSchema1
+	blobs = append(blobs, mb)
+	_, ib := blob(v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests:     []v1.Descriptor{md},
+	})
+
+	var dis []sif.DescriptorInput
+	di, err := sif.NewDescriptorInput(sif.DataOCIRootIndex, bytes.NewReader(ib))
+	if err != nil {
+		t.Fatalf("while creating root index descriptor: %v", err)
+	}
+	dis = append(dis, di)
+	for _, b := range blobs {
+		di, err := sif.NewDescriptorInput(sif.DataOCIBlob, bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("while creating blob descriptor: %v", err)
+		}
+		dis = append(dis, di)
+	}
+
+	f, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(dis...), sif.OptCreateDeterministic())
+	if err != nil {
+		t.Fatalf("while creating OCI-SIF: %v", err)
+	}
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatalf("while closing OCI-SIF: %v", err)
+	}
+	return m
+}
+
+func TestInspectSIF(t *testing.T) {
+	cf := v1.ConfigFile{
+		Architecture: "arm64",
+		OS:           "linux",
+		Variant:      "v8",
+		Config: v1.Config{
+			Entrypoint: []string{"/bin/entrypoint"},
+			Cmd:        []string{"--help"},
+			Env:        []string{"PATH=/usr/bin:/bin", "FOO=bar"},
+			Labels:     map[string]string{"org.example.name": "test"},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "image.sif")
+	m := writeOCISIF(t, path, cf, [][]byte{[]byte("layer one"), []byte("layer two")})
+
+	if !IsOCISIF(path) {
+		t.Fatalf("%s not identified as an OCI-SIF image", path)
+	}
+
+	img, err := InspectSIF(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if img.Architecture != cf.Architecture || img.OS != cf.OS || img.Variant != cf.Variant {
+		t.Errorf("got platform %s/%s/%s, want %s/%s/%s", img.OS, img.Architecture, img.Variant, cf.OS, cf.Architecture, cf.Variant)
+	}
+	if !reflect.DeepEqual(img.Env, cf.Config.Env) {
+		t.Errorf("got env %v, want %v", img.Env, cf.Config.Env)
+	}
+	if !reflect.DeepEqual(img.Entrypoint, cf.Config.Entrypoint) {
+		t.Errorf("got entrypoint %v, want %v", img.Entrypoint, cf.Config.Entrypoint)
+	}
+	if !reflect.DeepEqual(img.Cmd, cf.Config.Cmd) {
+		t.Errorf("got cmd %v, want %v", img.Cmd, cf.Config.Cmd)
+	}
+	if !reflect.DeepEqual(img.Labels, cf.Config.Labels) {
+		t.Errorf("got labels %v, want %v", img.Labels, cf.Config.Labels)
+	}
+	if !reflect.DeepEqual(img.Layers, m.Layers) {
+		t.Errorf("got layers %v, want %v", img.Layers, m.Layers)
+	}
+
+	// Root index, 2 layers, config and manifest.
+	if len(img.Descriptors) != 5 {
+		t.Fatalf("got %d descriptors, want 5", len(img.Descriptors))
+	}
+	if img.Descriptors[0].DataType != sif.DataOCIRootIndex.String() {
+		t.Errorf("got first descriptor of type %s, want %s", img.Descriptors[0].DataType, sif.DataOCIRootIndex)
+	}
+	for i, l := range m.Layers {
+		if got := img.Descriptors[i+1].Digest; got != l.Digest.String() {
+			t.Errorf("got descriptor digest %s, want %s", got, l.Digest)
+		}
+	}
+	if img.Descriptors[4].Digest != img.Digest {
+		t.Errorf("got manifest descriptor digest %s, want %s", img.Descriptors[4].Digest, img.Digest)
+	}
+}
+
+func TestImageFromSIF(t *testing.T) {
+	layers := [][]byte{[]byte("layer one"), []byte("layer two")}
+	cf := v1.ConfigFile{
+		Architecture: "amd64",
+		OS:           "linux",
+		Config:       v1.Config{Cmd: []string{"/bin/sh"}},
+		RootFS:       v1.RootFS{Type: "layers"},
+	}
+	for _, l := range layers {
+		h, _, err := v1.SHA256(bytes.NewReader(l))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf.RootFS.DiffIDs = append(cf.RootFS.DiffIDs, h)
+	}
+	path := filepath.Join(t.TempDir(), "image.sif")
+	m := writeOCISIF(t, path, cf, layers)
+
+	img, err := ImageFromSIF(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validate.Image(img, validate.Fast); err != nil {
+		t.Errorf("invalid image: %v", err)
+	}
+
+	gotManifest, err := img.Manifest()
+	if err != nil {
+		t.Fatalf("while reading manifest: %v", err)
+	}
+	if !reflect.DeepEqual(gotManifest.Layers, m.Layers) {
+		t.Errorf("got layers %v, want %v", gotManifest.Layers, m.Layers)
+	}
+	gotConfig, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("while reading config: %v", err)
+	}
+	if !reflect.DeepEqual(gotConfig.Config.Cmd, cf.Config.Cmd) {
+		t.Errorf("got cmd %v, want %v", gotConfig.Config.Cmd, cf.Config.Cmd)
+	}
+
+	imgLayers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("while reading layers: %v", err)
+	}
+	rc, err := imgLayers[1].Compressed()
+	if err != nil {
+		t.Fatalf("while opening layer: %v", err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("while reading layer: %v", err)
+	}
+	if string(b) != "layer two" {
+		t.Errorf("got layer content %q, want %q", b, "layer two")
+	}
+}
+
+func TestInspectSIFNotOCI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "native.sif")
+	di, err := sif.NewDescriptorInput(sif.DataGeneric, bytes.NewReader([]byte("data")))
+	if err != nil {
+		t.Fatalf("while creating descriptor: %v", err)
+	}
+	f, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(di))
+	if err != nil {
+		t.Fatalf("while creating SIF: %v", err)
+	}
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatalf("while closing SIF: %v", err)
+	}
+
+	if IsOCISIF(path) {
+		t.Errorf("native SIF identified as an OCI-SIF image")
+	}
+	if _, err := InspectSIF(path); err == nil {
+		t.Errorf("expected error inspecting a native SIF")
+	}
+}
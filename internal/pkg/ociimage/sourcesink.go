@@ -193,6 +193,9 @@ func (ss SourceSink) WriteImage(img v1.Image, dstName string, tOpts *TransportOp
 			remoteOpts = append(remoteOpts,
 				remote.WithPlatform(tOpts.Platform),
 				ociauth.AuthOptn(tOpts.AuthConfig, tOpts.AuthFilePath))
+			if tOpts.Concurrency > 0 {
+				remoteOpts = append(remoteOpts, remote.WithJobs(tOpts.Concurrency))
+			}
 		}
 		return remote.Write(dstRef, img, remoteOpts...)
 
@@ -10,11 +10,15 @@
 package ociimage
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/apptainer/apptainer/pkg/util/slice"
 	"github.com/containers/image/v5/docker"
@@ -41,6 +45,11 @@ func SupportedTransport(transport string) string {
 	return ""
 }
 
+// SupportedTransports returns the list of supported OCI image transports.
+func SupportedTransports() []string {
+	return append([]string(nil), ociTransports...)
+}
+
 // TransportOptions provides authentication, platform etc. configuration for
 // interactions with image transports.
 type TransportOptions struct {
@@ -63,6 +72,22 @@ type TransportOptions struct {
 	UserAgent string
 	// TmpDir is a location in which a transport can create temporary files.
 	TmpDir string
+	// Concurrency limits the number of layers that are transferred in
+	// parallel. A value less than 1 leaves the transport default in place.
+	Concurrency int
+	// Offline retrieves registry images from the cache only, without network
+	// access.
+	Offline bool
+}
+
+// TimeoutContext returns a copy of ctx which is cancelled after timeout, for
+// registry operations bounded by the OCI timeout. No deadline is set if
+// timeout is zero.
+func TimeoutContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // SystemContext returns a containers/image/v5 types.SystemContext struct for
@@ -94,6 +119,16 @@ func (t *TransportOptions) SystemContext() types.SystemContext {
 		sc.OCIInsecureSkipTLSVerify = true
 	}
 
+	// containers/image builds its own registry transport, which would only
+	// take the proxy from the environment. Use the proxy of the default
+	// transport instead, which holds the apptainer.conf proxy directives.
+	if dt, ok := http.DefaultTransport.(*http.Transport); ok && dt.Proxy != nil {
+		proxy := dt.Proxy
+		sc.DockerProxy = func(u *url.URL) (*url.URL, error) {
+			return proxy(&http.Request{URL: u})
+		}
+	}
+
 	if sc.OSChoice == "" || sc.ArchitectureChoice == "" {
 		// set default architecture and variant
 		defaultSys := defaultSysCtx()
@@ -172,29 +207,40 @@ func DefaultPolicy() (*signature.PolicyContext, error) {
 	return signature.NewPolicyContext(policy)
 }
 
+// splitURI splits a uri-like OCI image reference into its transport and the
+// reference within that transport. It is the single place where image URIs
+// are parsed, so that all callers agree on the supported transports.
+func splitURI(imageURI string) (transport, ref string, err error) {
+	transport, ref, ok := strings.Cut(imageURI, ":")
+	if !ok || transport == "" || ref == "" {
+		return "", "", fmt.Errorf("could not parse image ref: %s", imageURI)
+	}
+	if SupportedTransport(transport) == "" {
+		return "", "", fmt.Errorf("%w: %s", errUnsupportedTransport, transport)
+	}
+	return transport, ref, nil
+}
+
 // URIToImageReference parses a uri-like OCI image reference into a containers/image types.ImageReference.
 func URIToImageReference(imageRef string) (types.ImageReference, error) {
-	parts := strings.SplitN(imageRef, ":", 2)
-	if len(parts) < 2 {
-		return nil, fmt.Errorf("could not parse image ref: %s", imageRef)
+	transport, ref, err := splitURI(imageRef)
+	if err != nil {
+		return nil, err
 	}
 
 	var srcRef types.ImageReference
-	var err error
 
-	switch parts[0] {
+	switch transport {
 	case "docker":
-		srcRef, err = docker.ParseReference(parts[1])
+		srcRef, err = docker.ParseReference(ref)
 	case "docker-archive":
-		srcRef, err = dockerarchive.ParseReference(parts[1])
+		srcRef, err = dockerarchive.ParseReference(ref)
 	case "docker-daemon":
-		srcRef, err = dockerdaemon.ParseReference(parts[1])
+		srcRef, err = dockerdaemon.ParseReference(ref)
 	case "oci":
-		srcRef, err = ocilayout.ParseReference(parts[1])
+		srcRef, err = ocilayout.ParseReference(ref)
 	case "oci-archive":
-		srcRef, err = ociarchive.ParseReference(parts[1])
-	default:
-		return nil, errUnsupportedTransport
+		srcRef, err = ociarchive.ParseReference(ref)
 	}
 	if err != nil {
 		return nil, err
@@ -203,27 +249,27 @@ func URIToImageReference(imageRef string) (types.ImageReference, error) {
 	return srcRef, nil
 }
 
-// URItoSourceSinkRef parses a uri-like OCI image reference into a SourceSink and ref
+// URItoSourceSinkRef parses a uri-like OCI image reference into a SourceSink and ref.
+// An oci-archive must first be extracted to an OCI layout, see ociArchiveToLayout.
 func URItoSourceSinkRef(imageURI string) (SourceSink, string, error) {
-	parts := strings.SplitN(imageURI, ":", 2)
-	if len(parts) < 2 {
-		return UnknownSourceSink, "", fmt.Errorf("could not parse image ref: %s", imageURI)
+	transport, ref, err := splitURI(imageURI)
+	if err != nil {
+		return UnknownSourceSink, "", err
 	}
 
-	switch parts[0] {
+	switch transport {
 	case "docker":
 		// Remove slashes from docker:// URI
-		parts[1] = strings.TrimPrefix(parts[1], "//")
-		return RegistrySourceSink, parts[1], nil
+		return RegistrySourceSink, strings.TrimPrefix(ref, "//"), nil
 	case "docker-archive":
-		return TarballSourceSink, parts[1], nil
+		return TarballSourceSink, ref, nil
 	case "docker-daemon":
-		return DaemonSourceSink, parts[1], nil
+		return DaemonSourceSink, ref, nil
 	case "oci":
-		return OCISourceSink, parts[1], nil
+		return OCISourceSink, ref, nil
 	}
 
-	return UnknownSourceSink, "", errUnsupportedTransport
+	return UnknownSourceSink, "", fmt.Errorf("%w: %s must be extracted to an oci layout", errUnsupportedTransport, transport)
 }
 
 func defaultSysCtx() *types.SystemContext {
@@ -10,6 +10,7 @@
 package ociimage
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -46,3 +47,140 @@ func TestSupportedTransport(t *testing.T) {
 		})
 	}
 }
+
+func TestURItoSourceSinkRef(t *testing.T) {
+	tests := []struct {
+		name        string
+		uri         string
+		wantSS      SourceSink
+		wantRef     string
+		wantErr     bool
+		unsupported bool
+	}{
+		{
+			name:    "docker",
+			uri:     "docker://alpine:latest",
+			wantSS:  RegistrySourceSink,
+			wantRef: "alpine:latest",
+		},
+		{
+			name:    "dockerNoSlashes",
+			uri:     "docker:alpine:latest",
+			wantSS:  RegistrySourceSink,
+			wantRef: "alpine:latest",
+		},
+		{
+			name:    "dockerArchive",
+			uri:     "docker-archive:/tmp/alpine.tar",
+			wantSS:  TarballSourceSink,
+			wantRef: "/tmp/alpine.tar",
+		},
+		{
+			name:    "dockerDaemon",
+			uri:     "docker-daemon:alpine:latest",
+			wantSS:  DaemonSourceSink,
+			wantRef: "alpine:latest",
+		},
+		{
+			name:    "oci",
+			uri:     "oci:/tmp/layout:tag",
+			wantSS:  OCISourceSink,
+			wantRef: "/tmp/layout:tag",
+		},
+		{
+			name:        "ociArchive",
+			uri:         "oci-archive:/tmp/alpine.tar",
+			wantErr:     true,
+			unsupported: true,
+		},
+		{
+			name:        "unknownTransport",
+			uri:         "library://alpine:latest",
+			wantErr:     true,
+			unsupported: true,
+		},
+		{
+			name:    "noTransport",
+			uri:     "alpine",
+			wantErr: true,
+		},
+		{
+			name:    "emptyRef",
+			uri:     "docker:",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, ref, err := URItoSourceSinkRef(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if errors.Is(err, errUnsupportedTransport) != tt.unsupported {
+				t.Errorf("expected unsupported transport error %v, got %v", tt.unsupported, err)
+			}
+			if err != nil {
+				return
+			}
+			if ss != tt.wantSS {
+				t.Errorf("expected source sink %v, got %v", tt.wantSS, ss)
+			}
+			if ref != tt.wantRef {
+				t.Errorf("expected ref %q, got %q", tt.wantRef, ref)
+			}
+		})
+	}
+}
+
+func TestURIToImageReference(t *testing.T) {
+	tests := []struct {
+		name          string
+		uri           string
+		wantTransport string
+		wantErr       bool
+	}{
+		{name: "docker", uri: "docker://alpine:latest", wantTransport: "docker"},
+		{name: "dockerArchive", uri: "docker-archive:/tmp/alpine.tar", wantTransport: "docker-archive"},
+		{name: "dockerDaemon", uri: "docker-daemon:alpine:latest", wantTransport: "docker-daemon"},
+		{name: "oci", uri: "oci:/tmp/layout:tag", wantTransport: "oci"},
+		{name: "ociArchive", uri: "oci-archive:/tmp/alpine.tar", wantTransport: "oci-archive"},
+		{name: "unknownTransport", uri: "library://alpine:latest", wantErr: true},
+		{name: "noTransport", uri: "alpine", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := URIToImageReference(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := ref.Transport().Name(); got != tt.wantTransport {
+				t.Errorf("expected transport %s, got %s", tt.wantTransport, got)
+			}
+		})
+	}
+}
+
+func TestSupportedTransports(t *testing.T) {
+	transports := SupportedTransports()
+	if len(transports) != len(ociTransports) {
+		t.Fatalf("expected %d transports, got %d", len(ociTransports), len(transports))
+	}
+
+	// Every listed transport must be handled when parsing an image URI.
+	for _, transport := range transports {
+		if _, err := URIToImageReference(transport + ":/tmp/image"); errors.Is(err, errUnsupportedTransport) {
+			t.Errorf("listed transport %s is not handled", transport)
+		}
+	}
+
+	// The returned list must be a copy.
+	transports[0] = "fake"
+	if SupportedTransport("fake") != "" {
+		t.Errorf("modifying the returned list changed the supported transports")
+	}
+}
@@ -82,3 +82,16 @@ func PlatformFromArch(a string) (*ggcrv1.Platform, error) {
 		Variant:      variant,
 	}, nil
 }
+
+// PlatformFromVariant returns the platform of the host, with the CPU variant
+// set to variant rather than the detected one.
+func PlatformFromVariant(variant string) (*ggcrv1.Platform, error) {
+	plat, err := DefaultPlatform()
+	if err != nil {
+		return nil, err
+	}
+
+	plat.Architecture, plat.Variant = normalizeArch(plat.Architecture, variant)
+
+	return plat, nil
+}
@@ -11,6 +11,7 @@ package ociplatform
 
 import (
 	"reflect"
+	"runtime"
 	"testing"
 
 	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
@@ -73,3 +74,36 @@ func TestPlatformFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestPlatformFromVariant(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant string
+		want    string
+	}{
+		{
+			name:    "Prefixed",
+			variant: "v6",
+			want:    "v6",
+		},
+		{
+			name:    "Uppercase",
+			variant: "V6",
+			want:    "v6",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlatformFromVariant(tt.variant)
+			if err != nil {
+				t.Fatalf("PlatformFromVariant() error = %v", err)
+			}
+			if got.OS != runtime.GOOS || got.Architecture != runtime.GOARCH {
+				t.Errorf("PlatformFromVariant() = %v, want host architecture %s/%s", got, runtime.GOOS, runtime.GOARCH)
+			}
+			if got.Variant != tt.want {
+				t.Errorf("PlatformFromVariant() variant = %q, want %q", got.Variant, tt.want)
+			}
+		})
+	}
+}
@@ -134,11 +134,12 @@ func (h *keyserverHandler) login(u *url.URL, username, password string, insecure
 	}
 
 	if insecure {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true, //nolint:gosec
-			},
+		// clone the default transport to keep its proxy settings
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec
 		}
+		client.Transport = transport
 	}
 
 	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
@@ -138,6 +138,12 @@ func (e *EngineOperations) CleanupContainer(ctx context.Context, _ error, _ sysc
 		}
 	}
 
+	if cidFile := e.EngineConfig.GetCIDFile(); cidFile != "" {
+		if err := os.Remove(cidFile); err != nil && !os.IsNotExist(err) {
+			sylog.Warningf("Could not remove container id file: %s", err)
+		}
+	}
+
 	if e.EngineConfig.GetInstance() {
 		file, err := instance.Get(e.CommonConfig.ContainerID, instance.AppSubDir)
 		if err != nil {
@@ -78,6 +78,13 @@ type lastMount struct {
 	flags uintptr
 }
 
+// etcMount is a default mount point of a file in /etc, which is skipped
+// if the container has no /etc directory.
+type etcMount struct {
+	tag  mount.AuthorizedTag
+	dest string
+}
+
 type container struct {
 	engine        *EngineOperations
 	rpcOps        *client.RPC
@@ -95,6 +102,7 @@ type container struct {
 	suidFlag      uintptr
 	devSourcePath string
 	skipCwd       bool
+	etcMounts     []etcMount
 }
 
 //nolint:maintidx
@@ -212,6 +220,9 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	if err := system.RunBeforeTag(mount.CwdTag, c.addCwdMount); err != nil {
 		return err
 	}
+	if err := system.RunAfterTag(mount.RootfsTag, c.skipEtcMounts); err != nil {
+		return err
+	}
 	if err := system.RunAfterTag(mount.SharedTag, c.addIdentityMount); err != nil {
 		return err
 	}
@@ -249,6 +260,9 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	if err := c.addTmpMount(system); err != nil {
 		return err
 	}
+	if err := c.addRunMount(system); err != nil {
+		return err
+	}
 	if err := c.addScratchMount(system); err != nil {
 		return err
 	}
@@ -706,6 +720,9 @@ func (c *container) mountGeneric(mnt *mount.Point, system *mount.System) (err er
 	bindMount := flags&syscall.MS_BIND != 0
 	remount := mount.HasRemountFlag(flags)
 	propagation := mount.HasPropagationFlag(flags)
+	// read-only remount of a recursive bind, as requested by the mount point
+	// rather than inherited from the source mount flags
+	recursiveReadonly := remount && bindMount && flags&syscall.MS_REC != 0 && flags&syscall.MS_RDONLY != 0
 	source := mnt.Source
 	dest := ""
 
@@ -899,11 +916,26 @@ mount:
 		if err = c.rpcOps.Mount(source, dest, mnt.Type, flags, optsString); err != nil {
 			return fmt.Errorf("while remounting %s: %s", dest, err)
 		}
+	} else if recursiveReadonly {
+		c.setRecursiveReadonly(mnt.Destination, dest)
 	}
 
 	return nil
 }
 
+// setRecursiveReadonly makes the submounts of the read-only recursive bind
+// mount at dest read-only too, as the read-only remount only applies to the
+// top mount point. This requires Linux 5.12 or later, submounts are left
+// writable on older kernels.
+func (c *container) setRecursiveReadonly(destination, dest string) {
+	err := c.rpcOps.RecursiveReadonly(dest)
+	if errors.Is(err, unix.ENOSYS) {
+		sylog.Verbosef("Kernel doesn't support recursive read-only mounts, submounts of %s may be writable", destination)
+	} else if err != nil {
+		sylog.Warningf("Could not make submounts of %s read-only: %s", destination, err)
+	}
+}
+
 // mount image via loop
 func (c *container) mountImage(mnt *mount.Point, system *mount.System) error {
 	var key []byte
@@ -1550,8 +1582,12 @@ func (c *container) addDevMount(system *mount.System) error {
 			return fmt.Errorf("failed to add /dev/shm session directory: %s", err)
 		}
 		devshmPath, _ := c.session.GetPath("/dev/shm")
+		options := "mode=1777"
+		if size := c.engine.EngineConfig.GetShmSize(); size > 0 {
+			options = fmt.Sprintf("mode=1777,size=%dm", size)
+		}
 		flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV)
-		err := system.Points.AddFS(mount.DevTag, devshmPath, c.sessionFsType, flags, "mode=1777")
+		err := system.Points.AddFS(mount.DevTag, devshmPath, c.sessionFsType, flags, options)
 		if err != nil {
 			return fmt.Errorf("failed to add /dev/shm temporary filesystem: %s", err)
 		}
@@ -1703,6 +1739,9 @@ func (c *container) addDevMount(system *mount.System) error {
 			return err
 		}
 	} else if c.engine.EngineConfig.File.MountDev == "yes" {
+		if c.engine.EngineConfig.GetShmSize() > 0 {
+			sylog.Warningf("--shm-size is ignored as /dev is bound from the host, use --contain to apply it")
+		}
 		sylog.Debugf("Adding dev to mount list\n")
 		err := system.Points.AddBind(mount.DevTag, "/dev", "/dev", syscall.MS_BIND|syscall.MS_REC)
 		if err != nil {
@@ -1795,6 +1834,7 @@ func (c *container) addBindsMount(system *mount.System) error {
 			if err := system.Points.AddRemount(mount.BindsTag, hostsPath, flags); err != nil {
 				return fmt.Errorf("unable to add %s for remount: %s", hostsPath, err)
 			}
+			c.etcMounts = append(c.etcMounts, etcMount{mount.BindsTag, hostsPath})
 		}
 		if !skipAllBinds && !slice.ContainsString(skipBinds, localtimePath) {
 			if err := c.copyHostLocaltime(localtimePath); err != nil {
@@ -1807,6 +1847,7 @@ func (c *container) addBindsMount(system *mount.System) error {
 			if err := system.Points.AddRemount(mount.BindsTag, localtimePath, flags); err != nil {
 				return fmt.Errorf("unable to add %s for remount: %s", localtimePath, err)
 			}
+			c.etcMounts = append(c.etcMounts, etcMount{mount.BindsTag, localtimePath})
 		}
 		return nil
 	}
@@ -1846,11 +1887,33 @@ func (c *container) addBindsMount(system *mount.System) error {
 		if err := system.Points.AddRemount(mount.BindsTag, dst, flags); err != nil {
 			return fmt.Errorf("unable to add %s for remount: %s", dst, err)
 		}
+		if dst == hostsPath || dst == localtimePath {
+			c.etcMounts = append(c.etcMounts, etcMount{mount.BindsTag, dst})
+		}
 	}
 
 	return nil
 }
 
+// skipEtcMounts removes the default mount points of files in /etc when the
+// container has no /etc directory, as in some scratch or distroless images,
+// rather than creating an /etc holding only these files.
+func (c *container) skipEtcMounts(system *mount.System) error {
+	if len(c.etcMounts) == 0 || files.HasEtc(c.session.RootFsPath()) {
+		return nil
+	}
+	dns := c.engine.EngineConfig.GetDNS() != "" || len(c.engine.EngineConfig.GetDNSOptions()) > 0
+	for _, m := range c.etcMounts {
+		if m.dest == "/etc/resolv.conf" && dns {
+			sylog.Warningf("Container has no /etc directory, ignoring --dns and --dns-option")
+		} else {
+			sylog.Verbosef("Container has no /etc directory, skipping %s", m.dest)
+		}
+		system.Points.RemoveByTagDest(m.tag, m.dest)
+	}
+	return nil
+}
+
 // copyHostLocaltime creates a bind point in the overlay layer so the bind mount does not overwrite the
 // default timezone in the container, which is likely at a different path due to a symlink.  Rather than creating
 // an empty file, it copies the content from the host filesystem if available, just in case of a bind mount failure
@@ -1894,9 +1957,27 @@ func (c *container) getHomePaths() (source string, dest string, err error) {
 	return source, dest, err
 }
 
+// homeMountOptions returns the home mount options set in apptainer.conf,
+// restricted by the options requested by the user.
+func (c *container) homeMountOptions() (apptainer.HomeMountOptions, error) {
+	homeOpts, err := apptainer.ParseHomeMountOptions(c.engine.EngineConfig.File.HomeMountOptions)
+	if err != nil {
+		return homeOpts, fmt.Errorf("invalid home mount options in apptainer.conf: %s", err)
+	}
+	userOpts, err := apptainer.ParseUserHomeMountOptions(c.engine.EngineConfig.GetHomeOptions())
+	if err != nil {
+		return homeOpts, fmt.Errorf("invalid home mount options: %s", err)
+	}
+	return homeOpts.Restrict(userOpts), nil
+}
+
 // addHomeStagingDir adds and mounts home directory in session staging directory
 func (c *container) addHomeStagingDir(system *mount.System, source string, dest string) (string, error) {
-	flags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
+	homeOpts, err := c.homeMountOptions()
+	if err != nil {
+		return "", err
+	}
+	flags := homeOpts.Flags(uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC))
 	homeStage := ""
 
 	if err := c.session.AddDir(dest); err != nil {
@@ -1920,7 +2001,22 @@ func (c *container) addHomeStagingDir(system *mount.System, source string, dest
 		}
 		system.Points.AddRemount(mount.HomeTag, homeStage, flags)
 		c.session.OverrideDir(dest, source)
+		if homeOpts.Size > 0 {
+			sylog.Verbosef("Ignoring home directory size option, %s is bound from the host", source)
+		}
 	} else {
+		if homeOpts.Size > 0 && c.sessionFsType != "tmpfs" {
+			sylog.Warningf("Ignoring home directory size option, %s doesn't support a size limit", c.sessionFsType)
+			homeOpts.Size = 0
+		}
+		if homeOpts != (apptainer.HomeMountOptions{}) {
+			sylog.Debugf("Mounting temporary filesystem for home directory with options %+v", homeOpts)
+			tmpfsFlags := homeOpts.Flags(uintptr(syscall.MS_NOSUID | syscall.MS_NODEV))
+			options := homeOpts.TmpfsOptions(os.Getuid(), os.Getgid())
+			if err := system.Points.AddFS(mount.PreLayerTag, homeStage, c.sessionFsType, tmpfsFlags, options); err != nil {
+				return "", fmt.Errorf("failed to add home directory temporary filesystem: %s", err)
+			}
+		}
 		sylog.Debugf("Using session directory for home directory")
 		c.session.OverrideDir(dest, homeStage)
 	}
@@ -1930,7 +2026,11 @@ func (c *container) addHomeStagingDir(system *mount.System, source string, dest
 
 // addHomeLayer adds the home mount when using either overlay or underlay
 func (c *container) addHomeLayer(system *mount.System, source, dest string) error {
-	flags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
+	homeOpts, err := c.homeMountOptions()
+	if err != nil {
+		return err
+	}
+	flags := homeOpts.Flags(uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC))
 
 	if len(system.Points.GetByTag(mount.HomeTag)) > 0 {
 		flags = uintptr(syscall.MS_BIND | syscall.MS_REC)
@@ -2196,6 +2296,28 @@ func (c *container) addTmpMount(system *mount.System) error {
 	return system.RunBeforeTag(mount.TmpTag, addBinds)
 }
 
+// addRunMount mounts a memory filesystem on /run for boot instances, as
+// required by systemd and the services it starts. It is sized like the
+// session directory.
+func (c *container) addRunMount(system *mount.System) error {
+	const runPath = "/run"
+
+	if !c.engine.EngineConfig.GetInstance() || !c.engine.EngineConfig.GetBootInstance() {
+		return nil
+	}
+
+	options := "mode=755"
+	if c.sessionSize > 0 {
+		options = fmt.Sprintf("mode=755,size=%dm", c.sessionSize)
+	}
+	flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV)
+	if err := system.Points.AddFS(mount.TmpTag, runPath, c.sessionFsType, flags, options); err != nil {
+		return fmt.Errorf("failed to add %s temporary filesystem: %s", runPath, err)
+	}
+	sylog.Verbosef("Default mount: %s", runPath)
+	return nil
+}
+
 func (c *container) addScratchMount(system *mount.System) error {
 	const scratchSessionDir = "/scratch"
 
@@ -2551,6 +2673,10 @@ func (c *container) addIdentityMount(system *mount.System) error {
 	}
 
 	rootfs := c.session.RootFsPath()
+	if !files.HasEtc(rootfs) {
+		sylog.Verbosef("Container has no /etc directory, skipping /etc/passwd and /etc/group")
+		return nil
+	}
 	defer c.session.Update()
 
 	if c.engine.EngineConfig.File.ConfigPasswd {
@@ -2559,7 +2685,7 @@ func (c *container) addIdentityMount(system *mount.System) error {
 		if err != nil {
 			sylog.Warningf("%s", err)
 		} else {
-			content, err := files.Passwd(passwd, home, uid, c)
+			content, err := files.Passwd(passwd, home, c.engine.EngineConfig.GetShell(), uid, c)
 			if err != nil {
 				sylog.Warningf("%s", err)
 			} else {
@@ -2631,6 +2757,16 @@ func (c *container) addResolvConfMount(system *mount.System) error {
 				return err
 			}
 		}
+		if options := c.engine.EngineConfig.GetDNSOptions(); len(options) > 0 {
+			line, err := files.ResolvConfOptions(options)
+			if err != nil {
+				return err
+			}
+			if len(content) > 0 && content[len(content)-1] != '\n' {
+				content = append(content, '\n')
+			}
+			content = append(content, line...)
+		}
 		if err := c.session.AddFile(resolvConf, content); err != nil {
 			sylog.Warningf("failed to add resolv.conf session file: %s", err)
 		}
@@ -2642,6 +2778,7 @@ func (c *container) addResolvConfMount(system *mount.System) error {
 			return fmt.Errorf("unable to add %s to mount list: %s", resolvConf, err)
 		}
 		sylog.Verbosef("Default mount: /etc/resolv.conf:/etc/resolv.conf")
+		c.etcMounts = append(c.etcMounts, etcMount{mount.FilesTag, resolvConf})
 	} else {
 		sylog.Verbosef("Skipping bind of the host's %s", resolvConf)
 	}
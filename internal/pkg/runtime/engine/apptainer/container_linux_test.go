@@ -0,0 +1,89 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+)
+
+func TestAddRunMount(t *testing.T) {
+	tests := []struct {
+		name        string
+		instance    bool
+		boot        bool
+		sessionSize int
+		wantMount   bool
+		wantOptions []string
+	}{
+		{
+			name: "NotInstance",
+		},
+		{
+			name:     "InstanceWithoutBoot",
+			instance: true,
+		},
+		{
+			name:        "BootInstance",
+			instance:    true,
+			boot:        true,
+			sessionSize: 64,
+			wantMount:   true,
+			wantOptions: []string{"nosuid", "nodev", "mode=755", "size=64m"},
+		},
+		{
+			name:        "BootInstanceUnsized",
+			instance:    true,
+			boot:        true,
+			wantMount:   true,
+			wantOptions: []string{"nosuid", "nodev", "mode=755"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engineConfig := apptainerConfig.NewConfig()
+			engineConfig.SetInstance(tt.instance)
+			engineConfig.SetBootInstance(tt.boot)
+			c := &container{
+				engine:        &EngineOperations{EngineConfig: engineConfig},
+				sessionFsType: "tmpfs",
+				sessionSize:   tt.sessionSize,
+			}
+			system := &mount.System{Points: &mount.Points{}}
+
+			if err := c.addRunMount(system); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			points := system.Points.GetByDest("/run")
+			if !tt.wantMount {
+				if len(points) != 0 {
+					t.Errorf("unexpected /run mount: %+v", points)
+				}
+				return
+			}
+			if len(points) != 1 {
+				t.Fatalf("expected one /run mount, got %+v", points)
+			}
+			if points[0].Type != "tmpfs" {
+				t.Errorf("expected tmpfs /run mount, got %s", points[0].Type)
+			}
+			if !reflect.DeepEqual(points[0].Options, tt.wantOptions) {
+				t.Errorf("expected /run mount options %q, got %q", tt.wantOptions, points[0].Options)
+			}
+			if tags := system.Points.GetByTag(mount.TmpTag); len(tags) != 1 {
+				t.Errorf("expected /run mount with tag %s, got %+v", mount.TmpTag, system.Points.GetAll())
+			}
+		})
+	}
+}
@@ -31,7 +31,6 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/plugin"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/starter"
 	"github.com/apptainer/apptainer/internal/pkg/security"
-	"github.com/apptainer/apptainer/internal/pkg/security/seccomp"
 	"github.com/apptainer/apptainer/internal/pkg/syecl"
 	"github.com/apptainer/apptainer/internal/pkg/sypgp"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
@@ -678,17 +677,9 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 			}
 		}
 
-		getIDRange := fakerootutil.GetIDRange
-
-		callbackType := (fakerootcallback.UserMapping)(nil)
-		callbacks, err := plugin.LoadCallbacks(callbackType)
+		getIDRange, err := idRangeFunc(e.EngineConfig.GetFakerootRange())
 		if err != nil {
-			return fmt.Errorf("while loading plugins callbacks '%T': %s", callbackType, err)
-		}
-		if len(callbacks) > 1 {
-			return fmt.Errorf("multiple plugins have registered hook callback for fakeroot")
-		} else if len(callbacks) == 1 {
-			getIDRange = callbacks[0].(fakerootcallback.UserMapping)
+			return err
 		}
 
 		e.EngineConfig.OciConfig.AddLinuxUIDMapping(uid, 0, 1)
@@ -716,6 +707,10 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 
 		starterConfig.SetTargetUID(0)
 		starterConfig.SetTargetGID([]int{0})
+	} else if e.EngineConfig.GetKeepID() {
+		if err := e.prepareKeepID(starterConfig); err != nil {
+			return err
+		}
 	}
 
 	starterConfig.SetBringLoopbackInterface(true)
@@ -754,7 +749,7 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 	if param != "" {
 		sylog.Debugf("Applying seccomp rule from %s", param)
 		generator := &e.EngineConfig.OciConfig.Generator
-		if err := seccomp.LoadProfileFromFile(param, generator); err != nil {
+		if err := security.SetSeccomp(param, generator); err != nil {
 			return err
 		}
 	}
@@ -763,6 +758,74 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 	return e.prepareAutofs(starterConfig)
 }
 
+// idRangeFunc returns the function determining the subordinate id ranges of
+// a user, which may be provided by a plugin. A non-empty rangeSel selects one
+// of the ranges of the user, as parsed by fakerootutil.ParseRangeSelector.
+func idRangeFunc(rangeSel string) (fakerootcallback.UserMapping, error) {
+	callbackType := (fakerootcallback.UserMapping)(nil)
+	callbacks, err := plugin.LoadCallbacks(callbackType)
+	if err != nil {
+		return nil, fmt.Errorf("while loading plugins callbacks '%T': %s", callbackType, err)
+	}
+	if len(callbacks) > 1 {
+		return nil, fmt.Errorf("multiple plugins have registered hook callback for fakeroot")
+	} else if len(callbacks) == 1 {
+		if rangeSel != "" {
+			return nil, fmt.Errorf("can't select a fakeroot range, id mappings are provided by a plugin")
+		}
+		return callbacks[0].(fakerootcallback.UserMapping), nil
+	}
+	if rangeSel == "" {
+		return fakerootutil.GetIDRange, nil
+	}
+	sel, err := fakerootutil.ParseRangeSelector(rangeSel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fakeroot range: %s", err)
+	}
+	sylog.Debugf("Using subordinate id range %s", sel)
+	return func(path string, uid uint32) (*specs.LinuxIDMapping, error) {
+		return fakerootutil.GetSelectedIDRange(path, uid, sel)
+	}, nil
+}
+
+// prepareKeepID sets the user namespace mappings of --keep-id, preserving
+// the user uid/gid, with the subuid/subgid ranges of the user mapped around
+// them with newuidmap/newgidmap. Unlike fakeroot, the container process runs
+// with the user ids, without privileges in the user namespace.
+func (e *EngineOperations) prepareKeepID(starterConfig *starter.Config) error {
+	uid := uint32(os.Getuid())
+	gid := uint32(os.Getgid())
+
+	sylog.Debugf("Search for newuidmap binary")
+	if err := starterConfig.SetNewUIDMapPath(); err != nil {
+		return err
+	}
+	sylog.Debugf("Search for newgidmap binary")
+	if err := starterConfig.SetNewGIDMapPath(); err != nil {
+		return err
+	}
+
+	getIDRange, err := idRangeFunc(e.EngineConfig.GetFakerootRange())
+	if err != nil {
+		return err
+	}
+	idRange, err := getIDRange(fakerootutil.SubUIDFile, uid)
+	if err != nil {
+		return fmt.Errorf("could not use --keep-id: %s", err)
+	}
+	e.EngineConfig.OciConfig.Linux.UIDMappings = fakerootutil.KeepIDMappings(uid, idRange)
+	idRange, err = getIDRange(fakerootutil.SubGIDFile, uid)
+	if err != nil {
+		return fmt.Errorf("could not use --keep-id: %s", err)
+	}
+	e.EngineConfig.OciConfig.Linux.GIDMappings = fakerootutil.KeepIDMappings(gid, idRange)
+
+	starterConfig.SetAllowSetgroups(true)
+	starterConfig.SetTargetUID(int(uid))
+	starterConfig.SetTargetGID([]int{int(gid)})
+	return nil
+}
+
 // prepareInstanceJoinConfig is responsible for getting and
 // applying configuration to join a running instance.
 //
@@ -1018,7 +1081,7 @@ func (e *EngineOperations) prepareInstanceJoinConfig(starterConfig *starter.Conf
 	if param != "" {
 		sylog.Debugf("Applying seccomp rule from %s", param)
 		generator := &e.EngineConfig.OciConfig.Generator
-		if err := seccomp.LoadProfileFromFile(param, generator); err != nil {
+		if err := security.SetSeccomp(param, generator); err != nil {
 			return err
 		}
 	} else {
@@ -1448,7 +1511,11 @@ func (e *EngineOperations) loadBindImages(starterConfig *starter.Config, userNS
 func (e *EngineOperations) loadImage(path string, writable bool, userNS bool, elevated bool) (*image.Image, error) {
 	const delSuffix = " (deleted)"
 
-	imgObject, imgErr := image.Init(path, writable)
+	initImage := image.Init
+	if e.EngineConfig.GetIgnoreArch() {
+		initImage = image.InitIgnoreArch
+	}
+	imgObject, imgErr := initImage(path, writable)
 	// pass imgObject if not nil for overlay and read-only filesystem error.
 	// Do not remove this line
 	if imgObject == nil {
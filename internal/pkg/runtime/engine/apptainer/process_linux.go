@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,6 +31,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/apptainer/apptainer/internal/pkg/buildcfg"
 	"github.com/apptainer/apptainer/internal/pkg/checkpoint/dmtcp"
 	"github.com/apptainer/apptainer/internal/pkg/fakeroot"
 	"github.com/apptainer/apptainer/internal/pkg/instance"
@@ -41,6 +43,7 @@ import (
 	"github.com/apptainer/apptainer/internal/pkg/util/shell"
 	"github.com/apptainer/apptainer/internal/pkg/util/shell/interpreter"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
+	"github.com/apptainer/apptainer/pkg/image"
 	apptainercallback "github.com/apptainer/apptainer/pkg/plugin/callback/runtime/engine/apptainer"
 	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
 	"github.com/apptainer/apptainer/pkg/sylog"
@@ -442,6 +445,10 @@ func (e *EngineOperations) PostStartProcess(_ context.Context, pid int) error {
 			return err
 		}
 
+		if e.EngineConfig.GetHealthcheck() {
+			e.startHealthcheck(file)
+		}
+
 		if !e.EngineConfig.GetShareNSMode() {
 			// send SIGUSR1 to the parent process in order to tell it
 			// to detach container process and run as instance.
@@ -470,6 +477,67 @@ func (e *EngineOperations) PostStartProcess(_ context.Context, pid int) error {
 	return nil
 }
 
+// startHealthcheck reads the HEALTHCHECK from the OCI configuration of the
+// instance image, and runs it in the background for as long as the master
+// process lives. The health status is recorded in the instance file.
+func (e *EngineOperations) startHealthcheck(file *instance.File) {
+	initImage := image.Init
+	if e.EngineConfig.GetIgnoreArch() {
+		initImage = image.InitIgnoreArch
+	}
+	img, err := initImage(e.EngineConfig.GetImage(), false)
+	if err != nil {
+		sylog.Warningf("Could not open image for healthcheck: %s", err)
+		return
+	}
+	defer img.File.Close()
+
+	reader, err := image.NewSectionReader(img, image.SIFDescOCIConfigJSON, -1)
+	if err == image.ErrNoSection {
+		sylog.Warningf("Image has no OCI configuration, healthcheck disabled")
+		return
+	} else if err != nil {
+		sylog.Warningf("Could not read %s section: %s", image.SIFDescOCIConfigJSON, err)
+		return
+	}
+
+	hc, err := instance.ParseHealthcheck(reader)
+	if err != nil {
+		sylog.Warningf("Healthcheck disabled: %s", err)
+		return
+	} else if hc == nil {
+		sylog.Warningf("Image does not define a healthcheck")
+		return
+	}
+
+	// The probe joins the namespaces of the instance, and runs as the
+	// instance user when a target uid or gid were requested at start.
+	var opts []string
+	if uid := e.EngineConfig.GetTargetUID(); uid != 0 {
+		opts = append(opts, "--security", fmt.Sprintf("uid:%d", uid))
+	}
+	if gids := e.EngineConfig.GetTargetGID(); len(gids) > 0 {
+		ids := make([]string, len(gids))
+		for i, gid := range gids {
+			ids[i] = strconv.Itoa(gid)
+		}
+		opts = append(opts, "--security", "gid:"+strings.Join(ids, ":"))
+	}
+
+	apptainer := filepath.Join(buildcfg.BINDIR, "apptainer")
+	probe := func(ctx context.Context, args []string) error {
+		return instance.ProbeCommand(ctx, apptainer, file.Name, opts, args).Run()
+	}
+	update := func(status string) {
+		file.Health = status
+		if err := file.Update(); err != nil {
+			sylog.Warningf("Could not update health status of instance %s: %s", file.Name, err)
+		}
+	}
+
+	go instance.RunHealthcheck(context.Background(), hc, probe, update)
+}
+
 func (e *EngineOperations) setPathEnv() {
 	env := e.EngineConfig.OciConfig.Process.Env
 	for _, keyval := range env {
@@ -675,7 +743,7 @@ func (b *bufferCloser) Close() error {
 // If noEval is false then exports are double quoted, and their content is evaluated,
 // consuming one level of shell escaping and performing any unescaped var substitution,
 // subshell execution etc (Apptainer historic behavior).
-func injectEnvHandler(senv map[string]string, noEval bool) interpreter.OpenHandler {
+func injectEnvHandler(senv map[string]string, unsetEnv []string, noEval bool) interpreter.OpenHandler {
 	var once sync.Once
 
 	return func(_ string, _ int, _ os.FileMode) (io.ReadWriteCloser, error) {
@@ -695,7 +763,16 @@ func injectEnvHandler(senv map[string]string, noEval bool) interpreter.OpenHandl
 			fi
 			export %[1]s=%[2]s
 			`
-			for key, value := range senv {
+			// export in a stable order, so that the environment script
+			// is the same from one run to the next
+			keys := make([]string, 0, len(senv))
+			for key := range senv {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				value := senv[key]
 				if key == "UID" || key == "GID" {
 					continue
 				}
@@ -711,6 +788,15 @@ func injectEnvHandler(senv map[string]string, noEval bool) interpreter.OpenHandl
 				}
 				b.WriteString(fmt.Sprintf(snippet, key, value))
 			}
+
+			// names were validated by the launcher, as they are not quoted
+			unsetSnippet := `
+			sylog debug "Unsetting %[1]s environment variable"
+			unset %[1]s
+			`
+			for _, key := range unsetEnv {
+				b.WriteString(fmt.Sprintf(unsetSnippet, key))
+			}
 		})
 
 		return b, nil
@@ -890,7 +976,7 @@ func runActionScript(engineConfig *apptainerConfig.EngineConfig) ([]string, []st
 
 	// inject APPTAINERENV_ defined variables
 	senv := engineConfig.GetApptainerEnv()
-	shell.RegisterOpenHandler("/.inject-apptainer-env.sh", injectEnvHandler(senv, engineConfig.GetNoEval()))
+	shell.RegisterOpenHandler("/.inject-apptainer-env.sh", injectEnvHandler(senv, engineConfig.GetUnsetEnv(), engineConfig.GetNoEval()))
 
 	shell.RegisterOpenHandler("/.singularity.d/env/99-runtimevars.sh", runtimeVarsHandler())
 
@@ -0,0 +1,69 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// injectedEnv returns the content of the environment script generated by
+// injectEnvHandler for senv.
+func injectedEnv(t *testing.T, senv map[string]string) string {
+	t.Helper()
+
+	rwc, err := injectEnvHandler(senv, nil, true)("", 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := io.ReadAll(rwc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(b)
+}
+
+func TestInjectEnvHandlerOrder(t *testing.T) {
+	senv := map[string]string{
+		"LD_LIBRARY_PATH": "/opt/lib",
+		"UID":             "1000",
+	}
+	var want []string
+	for i := 0; i < 32; i++ {
+		key := fmt.Sprintf("VAR_%02d", i)
+		senv[key] = "value"
+		want = append(want, key)
+	}
+	want = append(want, "LD_LIBRARY_PATH")
+	sort.Strings(want)
+	// the default PATH is always exported first
+	want = append([]string{"PATH"}, want...)
+
+	first := injectedEnv(t, senv)
+
+	exportRe := regexp.MustCompile(`(?m)^\s*export ([A-Z_0-9]+)=`)
+	var got []string
+	for _, m := range exportRe.FindAllStringSubmatch(first, -1) {
+		got = append(got, m[1])
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("unexpected export order:\n got: %v\nwant: %v", got, want)
+	}
+
+	// map iteration order is randomized, so repeated generation would
+	// differ if the exports followed it
+	for i := 0; i < 10; i++ {
+		if again := injectedEnv(t, senv); again != first {
+			t.Fatalf("environment script differs between runs:\n%s\n---\n%s", first, again)
+		}
+	}
+}
@@ -43,6 +43,12 @@ type MountArgs struct {
 	Data       string
 }
 
+// RecursiveReadonlyArgs defines the arguments to make a mount point, and
+// its submounts, read-only.
+type RecursiveReadonlyArgs struct {
+	Target string
+}
+
 // UnmountArgs defines the arguments to unmount.
 type UnmountArgs struct {
 	Target       string
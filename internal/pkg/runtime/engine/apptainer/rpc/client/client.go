@@ -45,6 +45,24 @@ func (t *RPC) Mount(source string, target string, filesystem string, flags uintp
 	return err
 }
 
+// RecursiveReadonly calls the recursive read-only RPC using the supplied
+// arguments.
+func (t *RPC) RecursiveReadonly(target string) error {
+	arguments := &args.RecursiveReadonlyArgs{
+		Target: target,
+	}
+
+	var readonlyErr error
+
+	err := t.Client.Call(t.Name+".RecursiveReadonly", arguments, &readonlyErr)
+	// RPC communication will take precedence over mount_setattr error
+	if err == nil {
+		err = readonlyErr
+	}
+
+	return err
+}
+
 // Unmount calls the unmount RPC using the supplied arguments.
 func (t *RPC) Unmount(target string, flags int) error {
 	arguments := &args.UnmountArgs{
@@ -20,6 +20,7 @@ import (
 	args "github.com/apptainer/apptainer/internal/pkg/runtime/engine/apptainer/rpc"
 	"github.com/apptainer/apptainer/internal/pkg/util/crypt"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs/mount"
 	"github.com/apptainer/apptainer/internal/pkg/util/gpu"
 	"github.com/apptainer/apptainer/internal/pkg/util/mainthread"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
@@ -73,6 +74,15 @@ func (t *Methods) Mount(arguments *args.MountArgs, mountErr *error) (err error)
 	return
 }
 
+// RecursiveReadonly makes a mount point, and all mount points below it,
+// read-only.
+func (t *Methods) RecursiveReadonly(arguments *args.RecursiveReadonlyArgs, readonlyErr *error) (err error) {
+	mainthread.Execute(func() {
+		*readonlyErr = mount.SetRecursiveReadonly(arguments.Target)
+	})
+	return
+}
+
 // Unmount performs an unmount with the specified arguments.
 func (t *Methods) Unmount(arguments *args.UnmountArgs, unmountErr *error) (err error) {
 	mainthread.Execute(func() {
@@ -34,6 +34,7 @@ type EngineConfig struct {
 	InputStreams   [2]int           `json:"inputStreams"`
 	SyncSocket     string           `json:"syncSocket"`
 	EmptyProcess   bool             `json:"emptyProcess"`
+	Init           bool             `json:"init"`
 	Exec           bool             `json:"exec"`
 	SystemdCgroups bool             `json:"systemdCgroups"`
 	Cgroups        *cgroups.Manager `json:"-"`
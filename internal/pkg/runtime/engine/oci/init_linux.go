@@ -0,0 +1,83 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
+)
+
+// initProcess runs args as a child of the current process, which acts as a
+// minimal init: received signals are forwarded to the child, and all
+// terminated children are reaped, including orphans re-parented to the
+// container PID 1. It exits with the status of the child once it terminates.
+func initProcess(args, env []string, terminal bool) error {
+	// Catch all signals before starting the child, so that no SIGCHLD
+	// can be missed.
+	signals := make(chan os.Signal, 16)
+	signal.Notify(signals)
+
+	attr := &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys: &syscall.SysProcAttr{
+			// Run the child in its own process group, in the foreground
+			// of the terminal if any, so that signals generated by the
+			// terminal are not delivered twice.
+			Setpgid:    true,
+			Foreground: terminal,
+		},
+	}
+	proc, err := os.StartProcess(args[0], args, attr)
+	if err != nil {
+		return fmt.Errorf("exec %s failed: %s", args[0], err)
+	}
+
+	status := reap(proc.Pid, signals)
+	if status.Signaled() {
+		os.Exit(128 + int(status.Signal()))
+	}
+	os.Exit(status.ExitStatus())
+	return nil
+}
+
+// reap forwards signals received on signals to pid, and reaps terminated
+// children, until pid terminates. The wait status of pid is returned.
+func reap(pid int, signals <-chan os.Signal) syscall.WaitStatus {
+	for {
+		s := <-signals
+		switch s {
+		case syscall.SIGCHLD:
+			for {
+				var status syscall.WaitStatus
+
+				wpid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+				if wpid <= 0 || err != nil {
+					break
+				}
+				if wpid == pid {
+					return status
+				}
+				sylog.Debugf("Reaped orphan process %d", wpid)
+			}
+		case syscall.SIGURG:
+			// Ignore SIGURG, which is used for non-cooperative goroutine
+			// preemption starting with Go 1.14. For more information, see
+			// https://github.com/golang/go/issues/24543.
+		default:
+			if err := syscall.Kill(pid, s.(syscall.Signal)); err != nil {
+				sylog.Debugf("While forwarding signal %s to %d: %s", s, pid, err)
+			}
+		}
+	}
+}
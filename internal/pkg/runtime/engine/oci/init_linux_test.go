@@ -0,0 +1,73 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// startReaper makes the test process a child subreaper, so that orphans are
+// re-parented to it as they would be to the container PID 1, and returns the
+// channel on which SIGCHLD is delivered.
+func startReaper(t *testing.T) chan os.Signal {
+	t.Helper()
+
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+		t.Skipf("could not set child subreaper: %v", err)
+	}
+	signals := make(chan os.Signal, 16)
+	signal.Notify(signals, syscall.SIGCHLD)
+
+	t.Cleanup(func() {
+		signal.Stop(signals)
+		unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 0, 0, 0, 0)
+	})
+	return signals
+}
+
+func TestReapOrphans(t *testing.T) {
+	signals := startReaper(t)
+
+	// The shell leaves an orphan, which terminates before the shell itself.
+	cmd := exec.Command("/bin/sh", "-c", "(sh -c 'exit 0' &); sleep 1; exit 3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("while starting process: %v", err)
+	}
+
+	status := reap(cmd.Process.Pid, signals)
+	if !status.Exited() || status.ExitStatus() != 3 {
+		t.Errorf("expected exit status 3, got %v", status)
+	}
+
+	var ws syscall.WaitStatus
+	if pid, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil); err != syscall.ECHILD {
+		t.Errorf("expected all children to be reaped, found process %d", pid)
+	}
+}
+
+func TestReapForwardSignal(t *testing.T) {
+	signals := startReaper(t)
+
+	cmd := exec.Command("/bin/sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("while starting process: %v", err)
+	}
+
+	signals <- syscall.SIGTERM
+	status := reap(cmd.Process.Pid, signals)
+	if !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Errorf("expected process to be terminated by SIGTERM, got %v", status)
+	}
+}
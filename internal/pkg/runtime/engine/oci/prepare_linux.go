@@ -158,6 +158,8 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 		e.EngineConfig.SetLogFormat("kubernetes")
 	}
 
+	// An exec process is not attached to the container streams, it keeps the
+	// standard streams of the caller, whether they are a terminal or pipes.
 	if !e.EngineConfig.Exec {
 		if e.EngineConfig.OciConfig.Process.Terminal {
 			var err error
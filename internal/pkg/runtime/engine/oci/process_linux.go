@@ -157,6 +157,10 @@ func (e *EngineOperations) StartProcess(masterConnFd int) error {
 		return fmt.Errorf("failed to apply security configuration: %s", err)
 	}
 
+	if e.EngineConfig.Init && !e.EngineConfig.Exec {
+		return initProcess(args, env, e.EngineConfig.MasterPts != -1)
+	}
+
 	err = syscall.Exec(args[0], args, env)
 	return fmt.Errorf("exec %s failed: %s", args[0], err)
 }
@@ -0,0 +1,41 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeCIDFile writes the container id to the file at path, as with the
+// --cidfile option of docker, and returns the absolute path of the file. An
+// existing file is not overwritten, as it may belong to a running container.
+func writeCIDFile(path, containerID string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("while getting absolute path of %s: %w", path, err)
+	}
+	f, err := os.OpenFile(abs, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if os.IsExist(err) {
+		return "", fmt.Errorf("container id file %s already exists, make sure the container it refers to is not running, and remove it", abs)
+	} else if err != nil {
+		return "", fmt.Errorf("while creating container id file: %w", err)
+	}
+	if _, err := f.WriteString(containerID); err != nil {
+		f.Close()
+		os.Remove(abs)
+		return "", fmt.Errorf("while writing container id file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(abs)
+		return "", fmt.Errorf("while writing container id file: %w", err)
+	}
+	return abs, nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCIDFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := writeCIDFile(filepath.Join(dir, "sub", "..", "container.id"), "c0ffee")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := filepath.Join(dir, "container.id"); path != want {
+		t.Errorf("expected path %s, got %s", want, path)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "c0ffee" {
+		t.Errorf("expected container id c0ffee, got %q", b)
+	}
+
+	// an existing file may belong to a running container
+	if _, err := writeCIDFile(path, "other"); err == nil {
+		t.Errorf("unexpected success overwriting %s", path)
+	}
+	b, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "c0ffee" {
+		t.Errorf("existing container id file was modified: %q", b)
+	}
+
+	if _, err := writeCIDFile(filepath.Join(dir, "missing", "container.id"), "c0ffee"); err == nil {
+		t.Errorf("unexpected success in missing directory")
+	}
+}
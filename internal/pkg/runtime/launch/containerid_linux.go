@@ -0,0 +1,86 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apptainer/apptainer/internal/pkg/instance"
+	"golang.org/x/sys/unix"
+)
+
+// instanceList lists the instance files, replaced in tests.
+var instanceList = instance.List
+
+// containerIDLockPath returns the path of the file locked to reserve a
+// container id, next to the instance directories, replaced in tests.
+var containerIDLockPath = func(id string) (string, error) {
+	dir, err := instance.GetDir(id, instance.AppSubDir)
+	if err != nil {
+		return "", err
+	}
+	return dir + ".lock", nil
+}
+
+// checkContainerID checks that a user requested container id only holds
+// the characters allowed in an instance name, and that it is not the name of
+// a running instance, or of a container created with apptainer oci.
+func checkContainerID(id string) error {
+	if err := instance.CheckName(id); err != nil {
+		return fmt.Errorf("invalid container id %q: only letters, digits, '.', '_' and '-' are allowed", id)
+	}
+	for _, subDir := range []string{instance.AppSubDir, instance.OciSubDir} {
+		files, err := instanceList("", id, subDir, true)
+		if err != nil {
+			return fmt.Errorf("while checking container id %s: %w", id, err)
+		}
+		if len(files) > 0 {
+			kind := "instance"
+			if subDir == instance.OciSubDir {
+				kind = "OCI container"
+			}
+			return fmt.Errorf("container id %s is already in use by %s %s", id, kind, files[0].Name)
+		}
+	}
+	return nil
+}
+
+// reserveContainerID reserves a user requested container id, so that another
+// non-instance container can't be started with the same id. The returned file
+// holds an exclusive lock and is inherited by the starter, which keeps it
+// open until the container exits. Closing the file releases the id.
+func reserveContainerID(id string) (*os.File, error) {
+	path, err := containerIDLockPath(id)
+	if err != nil {
+		return nil, fmt.Errorf("while reserving container id %s: %w", id, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("while reserving container id %s: %w", id, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("while reserving container id %s: %w", id, err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, fmt.Errorf("container id %s is already in use by another container", id)
+		}
+		return nil, fmt.Errorf("while reserving container id %s: %w", id, err)
+	}
+	// the lock must be held by the starter once executed
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_SETFD, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("while reserving container id %s: %w", id, err)
+	}
+	return f, nil
+}
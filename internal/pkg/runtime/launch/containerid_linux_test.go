@@ -0,0 +1,101 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/instance"
+	"golang.org/x/sys/unix"
+)
+
+func TestCheckContainerID(t *testing.T) {
+	defer func(f func(string, string, string, bool) ([]*instance.File, error)) {
+		instanceList = f
+	}(instanceList)
+
+	// a running instance "web" and an OCI container "db"
+	instanceList = func(_ string, name string, subDir string, _ bool) ([]*instance.File, error) {
+		if (subDir == instance.AppSubDir && name == "web") || (subDir == instance.OciSubDir && name == "db") {
+			return []*instance.File{{Name: name}}, nil
+		}
+		return nil, nil
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		wantErr string
+	}{
+		{name: "Valid", id: "my-job_1.0"},
+		{name: "Empty", id: "", wantErr: "invalid container id"},
+		{name: "Slash", id: "../job", wantErr: "invalid container id"},
+		{name: "Space", id: "my job", wantErr: "invalid container id"},
+		{name: "Glob", id: "job*", wantErr: "invalid container id"},
+		{name: "InstanceCollision", id: "web", wantErr: "already in use by instance web"},
+		{name: "OCICollision", id: "db", wantErr: "already in use by OCI container db"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkContainerID(tt.id)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReserveContainerID(t *testing.T) {
+	defer func(f func(string) (string, error)) {
+		containerIDLockPath = f
+	}(containerIDLockPath)
+
+	dir := filepath.Join(t.TempDir(), "instances")
+	containerIDLockPath = func(id string) (string, error) {
+		return filepath.Join(dir, id+".lock"), nil
+	}
+
+	f, err := reserveContainerID("job")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the lock must survive the execution of the starter
+	flags, err := unix.FcntlInt(f.Fd(), unix.F_GETFD, 0)
+	if err != nil {
+		t.Fatalf("while getting file descriptor flags: %s", err)
+	}
+	if flags&unix.FD_CLOEXEC != 0 {
+		t.Errorf("reservation file descriptor is closed on exec")
+	}
+
+	if _, err := reserveContainerID("job"); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("expected error reserving an id in use, got %v", err)
+	}
+	other, err := reserveContainerID("other")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	other.Close()
+
+	f.Close()
+	f, err = reserveContainerID("job")
+	if err != nil {
+		t.Fatalf("unexpected error reserving a released id: %s", err)
+	}
+	f.Close()
+}
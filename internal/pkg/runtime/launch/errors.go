@@ -0,0 +1,52 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import "errors"
+
+// Categories of errors returned by the Launcher. An error returned by Exec
+// matches one of these with errors.Is, while its message, and the underlying
+// cause, are preserved.
+var (
+	// ErrImageNotRunnable is returned when the image cannot be prepared to run,
+	// e.g. it cannot be extracted to a temporary sandbox.
+	ErrImageNotRunnable = errors.New("image not runnable")
+	// ErrInstanceExists is returned when starting an instance with the name
+	// of an instance that is already running.
+	ErrInstanceExists = errors.New("instance already exists")
+	// ErrUnsupportedOption is returned when an option, or combination of
+	// options, is not supported in the current configuration.
+	ErrUnsupportedOption = errors.New("unsupported option")
+	// ErrPrivilegeRequired is returned when an option requires root
+	// privileges.
+	ErrPrivilegeRequired = errors.New("root privileges required")
+	// ErrStarterFailed is returned when the starter binary, which runs the
+	// container, fails.
+	ErrStarterFailed = errors.New("starter failed")
+)
+
+// launchError associates an error with its category, keeping the message of
+// the error itself.
+type launchError struct {
+	category error
+	err      error
+}
+
+func (e *launchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *launchError) Unwrap() []error {
+	return []error{e.category, e.err}
+}
+
+// categorize wraps err so that it matches category with errors.Is.
+func categorize(category, err error) error {
+	return &launchError{category: category, err: err}
+}
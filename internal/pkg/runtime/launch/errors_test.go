@@ -0,0 +1,76 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	categories := []error{
+		ErrImageNotRunnable,
+		ErrInstanceExists,
+		ErrUnsupportedOption,
+		ErrPrivilegeRequired,
+		ErrStarterFailed,
+	}
+
+	for _, category := range categories {
+		t.Run(category.Error(), func(t *testing.T) {
+			cause := fmt.Errorf("while doing something: %w", os.ErrNotExist)
+			err := categorize(category, cause)
+
+			if err.Error() != cause.Error() {
+				t.Errorf("expected message %q, got %q", cause.Error(), err.Error())
+			}
+			if !errors.Is(err, category) {
+				t.Errorf("expected error to match %v", category)
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected error to match underlying cause")
+			}
+			for _, other := range categories {
+				if other != category && errors.Is(err, other) {
+					t.Errorf("error unexpectedly matches %v", other)
+				}
+			}
+		})
+	}
+}
+
+func TestWithPrivilege(t *testing.T) {
+	called := false
+	fn := func() error {
+		called = true
+		return nil
+	}
+
+	if err := withPrivilege(0, true, "--boot", fn); err != nil || !called {
+		t.Errorf("expected fn to be called as root, got err=%v", err)
+	}
+
+	called = false
+	err := withPrivilege(1000, true, "--boot", fn)
+	if !errors.Is(err, ErrPrivilegeRequired) {
+		t.Errorf("expected ErrPrivilegeRequired, got %v", err)
+	}
+	if err != nil && err.Error() != "--boot requires root privileges" {
+		t.Errorf("unexpected error message %q", err.Error())
+	}
+	if called {
+		t.Errorf("fn unexpectedly called without privileges")
+	}
+
+	if err := withPrivilege(1000, false, "--boot", fn); err != nil {
+		t.Errorf("unexpected error when option is not set: %v", err)
+	}
+}
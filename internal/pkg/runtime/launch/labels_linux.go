@@ -0,0 +1,111 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	imgutil "github.com/apptainer/apptainer/pkg/image"
+	"github.com/apptainer/apptainer/pkg/inspect"
+	imageSpecs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// labelEnvPrefix is the prefix of the environment variables holding image
+// labels selected with --label-env.
+const labelEnvPrefix = "APPTAINER_LABEL_"
+
+// imageLabels returns the labels of the image: those of a SIF image OCI
+// configuration and inspect metadata, or those recorded in the labels.json
+// file of a sandbox.
+func imageLabels(img *imgutil.Image) (map[string]string, error) {
+	labels := map[string]string{}
+
+	if img.Type == imgutil.SANDBOX {
+		b, err := os.ReadFile(filepath.Join(img.Path, ".singularity.d", "labels.json"))
+		if os.IsNotExist(err) {
+			return labels, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &labels); err != nil {
+			return nil, fmt.Errorf("while decoding labels.json: %w", err)
+		}
+		return labels, nil
+	} else if img.Type != imgutil.SIF {
+		return labels, nil
+	}
+
+	if r, err := imgutil.NewSectionReader(img, imgutil.SIFDescInspectMetadataJSON, -1); err == nil {
+		var metadata inspect.Metadata
+		if err := json.NewDecoder(r).Decode(&metadata); err != nil {
+			return nil, fmt.Errorf("while decoding %s: %w", imgutil.SIFDescInspectMetadataJSON, err)
+		}
+		for k, v := range metadata.Attributes.Labels {
+			labels[k] = v
+		}
+	} else if err != imgutil.ErrNoSection {
+		return nil, err
+	}
+
+	// labels of the OCI configuration take precedence, as the inspect
+	// metadata of an image built from an OCI image holds a copy of them
+	if r, err := imgutil.NewSectionReader(img, imgutil.SIFDescOCIConfigJSON, -1); err == nil {
+		var config imageSpecs.ImageConfig
+		if err := json.NewDecoder(r).Decode(&config); err != nil {
+			return nil, fmt.Errorf("while decoding %s: %w", imgutil.SIFDescOCIConfigJSON, err)
+		}
+		for k, v := range config.Labels {
+			labels[k] = v
+		}
+	} else if err != imgutil.ErrNoSection {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// labelEnvVars returns the environment variables exposing the labels whose
+// name matches one of patterns, e.g. "org.opencontainers.image.*". A label
+// is exposed as APPTAINER_LABEL_<NAME>, with NAME the label name in upper
+// case, where characters not allowed in a variable name are replaced by _.
+func labelEnvVars(labels map[string]string, patterns []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid label pattern %q: %w", pattern, err)
+		}
+	}
+	for name, value := range labels {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				vars[labelEnvPrefix+labelEnvName(name)] = value
+				break
+			}
+		}
+	}
+	return vars, nil
+}
+
+func labelEnvName(label string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, label)
+}
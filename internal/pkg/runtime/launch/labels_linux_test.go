@@ -0,0 +1,84 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	imgutil "github.com/apptainer/apptainer/pkg/image"
+)
+
+func TestLabelEnvVars(t *testing.T) {
+	sandbox := t.TempDir()
+	if err := os.Mkdir(filepath.Join(sandbox, ".singularity.d"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	labelsJSON := `{
+		"org.opencontainers.image.version": "1.2.3",
+		"org.opencontainers.image.vendor": "Apptainer",
+		"maintainer": "someone@example.com"
+	}`
+	if err := os.WriteFile(filepath.Join(sandbox, ".singularity.d", "labels.json"), []byte(labelsJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	labels, err := imageLabels(&imgutil.Image{Type: imgutil.SANDBOX, Path: sandbox})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(labels) != 3 {
+		t.Fatalf("expected 3 labels, got %v", labels)
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "Name",
+			patterns: []string{"maintainer"},
+			want:     map[string]string{"APPTAINER_LABEL_MAINTAINER": "someone@example.com"},
+		},
+		{
+			name:     "Pattern",
+			patterns: []string{"org.opencontainers.image.*"},
+			want: map[string]string{
+				"APPTAINER_LABEL_ORG_OPENCONTAINERS_IMAGE_VERSION": "1.2.3",
+				"APPTAINER_LABEL_ORG_OPENCONTAINERS_IMAGE_VENDOR":  "Apptainer",
+			},
+		},
+		{
+			name:     "NoMatch",
+			patterns: []string{"com.example.*"},
+			want:     map[string]string{},
+		},
+		{
+			name:     "BadPattern",
+			patterns: []string{"org.["},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := labelEnvVars(labels, tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, expected error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
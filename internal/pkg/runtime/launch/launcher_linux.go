@@ -16,6 +16,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
@@ -43,6 +44,7 @@ import (
 	imgutil "github.com/apptainer/apptainer/pkg/image"
 	clicallback "github.com/apptainer/apptainer/pkg/plugin/callback/cli"
 	apptainercallback "github.com/apptainer/apptainer/pkg/plugin/callback/runtime/engine/apptainer"
+	fakerootcallback "github.com/apptainer/apptainer/pkg/plugin/callback/runtime/fakeroot"
 	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
 	"github.com/apptainer/apptainer/pkg/runtime/engine/config"
 	"github.com/apptainer/apptainer/pkg/syfs"
@@ -53,6 +55,7 @@ import (
 	"github.com/apptainer/apptainer/pkg/util/fs/proc"
 	"github.com/apptainer/apptainer/pkg/util/namespaces"
 	"github.com/apptainer/apptainer/pkg/util/rlimit"
+	"github.com/google/uuid"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
@@ -96,6 +99,27 @@ func NewLauncher(opts ...Option) (*Launcher, error) {
 func (l *Launcher) Exec(ctx context.Context, image string, args []string, instanceName string) error {
 	var err error
 
+	// An instance name is the container id of an instance.
+	if l.cfg.ContainerID != "" {
+		if instanceName != "" {
+			return fmt.Errorf("--container-id can't be used with instances, the instance name is the container id")
+		}
+		if err := checkContainerID(l.cfg.ContainerID); err != nil {
+			return err
+		}
+		// The starter replaces this process, holding the reservation
+		// until the container exits.
+		idLock, err := reserveContainerID(l.cfg.ContainerID)
+		if err != nil {
+			return err
+		}
+		defer idLock.Close()
+	}
+
+	if err := l.checkFakerootRange(); err != nil {
+		return err
+	}
+
 	var fakerootPath string
 	if l.cfg.Fakeroot {
 		if (l.uid == 0) && namespaces.IsUnprivileged() {
@@ -161,6 +185,25 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 		}
 	}
 
+	// --keep-id needs a user namespace, with the subordinate ids of the user
+	// mapped by newuidmap/newgidmap around its own ids.
+	if l.cfg.KeepID {
+		if l.cfg.Fakeroot {
+			return fmt.Errorf("--keep-id and --fakeroot are mutually exclusive")
+		}
+		if l.uid == 0 {
+			return fmt.Errorf("--keep-id can only be used by a non-root user")
+		}
+		if l.cfg.IgnoreSubuid {
+			return fmt.Errorf("--keep-id maps subordinate ids, it can't be used with --ignore-subuid")
+		}
+		// a plugin may provide subordinate ids not listed in /etc/subuid
+		if !fakeroot.IsUIDMapped(l.uid) && !hasUserMappingPlugin() {
+			return fmt.Errorf("--keep-id requires a subordinate id range for your user in %s", fakeroot.SubUIDFile)
+		}
+		l.cfg.Namespaces.User = true
+	}
+
 	// Set arguments to pass to contained process.
 	l.generator.SetProcessArgs(args)
 
@@ -181,6 +224,15 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	// IgnoreUserns is a hidden control flag
 	l.cfg.Namespaces.User = l.cfg.Namespaces.User && !l.cfg.IgnoreUserns
 
+	// Without the suid starter, the subordinate ids of --keep-id, and of
+	// --fakeroot for a user in /etc/subuid, are mapped with newuidmap and
+	// newgidmap, check for them now rather than failing in the starter.
+	if !useSuid && (l.cfg.KeepID || (l.cfg.Fakeroot && !l.cfg.IgnoreSubuid && fakeroot.IsUIDMapped(l.uid))) {
+		if err := fakeroot.CheckIDMapBinaries(); err != nil {
+			return err
+		}
+	}
+
 	// Get our effective uid and gid for container execution.
 	// If user requests a target uid, gid via --security options, handle them now.
 	err = l.setTargetIDs(useSuid)
@@ -200,9 +252,15 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	// Prefer underlay for bind
 	l.engineConfig.SetUnderlay(l.cfg.Underlay)
 
-	// Check key is available for encrypted image, if applicable.
-	// If we are joining an instance, then any encrypted image is already mounted.
+	// Check the image can run on the host, and key is available for encrypted
+	// image, if applicable. If we are joining an instance, then the image is
+	// already running.
+	l.engineConfig.SetIgnoreArch(l.cfg.IgnoreArch)
 	if !l.engineConfig.GetInstanceJoin() {
+		err = l.checkArchitecture()
+		if err != nil {
+			sylog.Fatalf("%s", err)
+		}
 		err = l.checkEncryptionKey()
 		if err != nil {
 			sylog.Fatalf("While checking container encryption: %s", err)
@@ -265,6 +323,7 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	// Container networking configuration.
 	l.engineConfig.SetNetwork(l.cfg.Network)
 	l.engineConfig.SetDNS(l.cfg.DNS)
+	l.engineConfig.SetDNSOptions(l.cfg.DNSOptions)
 	l.engineConfig.SetNetworkArgs(l.cfg.NetworkArgs)
 
 	// If user wants to set a hostname, it requires the UTS namespace.
@@ -317,6 +376,9 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 	if l.cfg.Fakeroot {
 		l.cfg.Namespaces.User = !l.cfg.IgnoreUserns
 	}
+	// Or preserving the user ids with subuid / subgid mapping?
+	l.engineConfig.SetKeepID(l.cfg.KeepID && l.cfg.Namespaces.User)
+	l.engineConfig.SetFakerootRange(l.cfg.FakerootRange)
 
 	err = l.setCgroups(instanceName)
 	if err != nil {
@@ -339,6 +401,7 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 			l.cfg.CleanEnv = true
 		}
 	}
+	l.engineConfig.SetShmSize(l.cfg.ShmSize)
 
 	// Setup instance specific configuration if required.
 	if instanceName != "" {
@@ -352,14 +415,15 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 		l.cfg.Namespaces.PID = pidNamespace
 		l.engineConfig.SetInstance(true)
 		l.engineConfig.SetBootInstance(l.cfg.Boot)
+		l.engineConfig.SetHealthcheck(l.cfg.Healthcheck)
 
 		if useSuid && !l.cfg.Namespaces.User && hidepidProc() {
-			return fmt.Errorf("hidepid option set on /proc mount, require 'hidepid=0' to start instance with setuid workflow")
+			return categorize(ErrUnsupportedOption, fmt.Errorf("hidepid option set on /proc mount, require 'hidepid=0' to start instance with setuid workflow"))
 		}
 
 		_, err := instance.Get(instanceName, instance.AppSubDir)
 		if err == nil {
-			return fmt.Errorf("instance %s already exists", instanceName)
+			return categorize(ErrInstanceExists, fmt.Errorf("instance %s already exists", instanceName))
 		}
 
 		if l.cfg.Boot {
@@ -399,7 +463,7 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 
 	// Get image ready to run, if needed, via FUSE mount / extraction / image driver handling.
 	if err := l.prepareImage(ctx, insideUserNs, image); err != nil {
-		return fmt.Errorf("while preparing image: %s", err)
+		return categorize(ErrImageNotRunnable, fmt.Errorf("while preparing image: %w", err))
 	}
 
 	loadOverlay := false
@@ -413,9 +477,30 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 		}
 	}
 
+	// Write the container id, requested or generated for non-instance
+	// containers, to the --cidfile, which the engine removes when a
+	// non-instance container exits.
+	containerID := instanceName
+	if l.cfg.ContainerID != "" {
+		containerID = l.cfg.ContainerID
+	}
+	cidFile := ""
+	if l.cfg.CIDFile != "" {
+		if containerID == "" {
+			containerID = uuid.NewString()
+		}
+		cidFile, err = writeCIDFile(l.cfg.CIDFile, containerID)
+		if err != nil {
+			return err
+		}
+		if !l.engineConfig.GetInstance() {
+			l.engineConfig.SetCIDFile(cidFile)
+		}
+	}
+
 	cfg := &config.Common{
 		EngineName:   apptainerConfig.Name,
-		ContainerID:  instanceName,
+		ContainerID:  containerID,
 		EngineConfig: l.engineConfig,
 	}
 
@@ -437,7 +522,10 @@ func (l *Launcher) Exec(ctx context.Context, image string, args []string, instan
 
 	// Execution is finished.
 	if err != nil {
-		return fmt.Errorf("while executing starter: %s", err)
+		if cidFile != "" {
+			os.Remove(cidFile)
+		}
+		return categorize(ErrStarterFailed, fmt.Errorf("while executing starter: %w", err))
 	}
 	return nil
 }
@@ -515,7 +603,7 @@ func (l *Launcher) setTargetIDs(useSuid bool) (err error) {
 func (l *Launcher) setImageOrInstance(image string, name string) error {
 	if strings.HasPrefix(image, "instance://") {
 		if name != "" {
-			return fmt.Errorf("starting an instance from another is not allowed")
+			return categorize(ErrUnsupportedOption, fmt.Errorf("starting an instance from another is not allowed"))
 		}
 		instanceName := instance.ExtractName(image)
 		file, err := instance.Get(instanceName, instance.AppSubDir)
@@ -575,11 +663,38 @@ func (l *Launcher) setImageOrInstance(image string, name string) error {
 	return nil
 }
 
+// checkArchitecture verifies that the image targets an architecture which the
+// host can run, unless disabled with --ignore-arch, so that a mismatch is
+// reported clearly rather than as an exec format error in the container.
+func (l *Launcher) checkArchitecture() error {
+	if l.cfg.IgnoreArch {
+		return nil
+	}
+	img, err := l.initImage(l.engineConfig.GetImage())
+	if err != nil {
+		var archErr *imgutil.ArchitectureError
+		if errors.As(err, &archErr) {
+			return err
+		}
+		return fmt.Errorf("could not open image %s: %w", l.engineConfig.GetImage(), err)
+	}
+	return img.File.Close()
+}
+
+// initImage opens the image at path, without refusing an image targeting an
+// architecture which the host can't run when --ignore-arch is set.
+func (l *Launcher) initImage(path string) (*imgutil.Image, error) {
+	if l.cfg.IgnoreArch {
+		return imgutil.InitIgnoreArch(path, false)
+	}
+	return imgutil.Init(path, false)
+}
+
 // checkEncryptionKey verifies key material is available if the image is encrypted.
 // Allows us to fail fast if required key material is not available / usable.
 func (l *Launcher) checkEncryptionKey() error {
 	sylog.Debugf("Checking for encrypted system partition")
-	img, err := imgutil.Init(l.engineConfig.GetImage(), false)
+	img, err := l.initImage(l.engineConfig.GetImage())
 	if err != nil {
 		return fmt.Errorf("could not open image %s: %w", l.engineConfig.GetImage(), err)
 	}
@@ -611,6 +726,36 @@ func (l *Launcher) checkEncryptionKey() error {
 	return nil
 }
 
+// checkFakerootRange returns an error if a subordinate id range is selected
+// with --fakeroot-range while no subordinate ids would be mapped: without
+// --fakeroot or --keep-id, or when --fakeroot falls back to a root-mapped
+// namespace.
+func (l *Launcher) checkFakerootRange() error {
+	if l.cfg.FakerootRange == "" {
+		return nil
+	}
+	if !l.cfg.Fakeroot && !l.cfg.KeepID {
+		return fmt.Errorf("--fakeroot-range requires --fakeroot or --keep-id")
+	}
+	// --keep-id checks for the subordinate ids of the user itself.
+	if !l.cfg.Fakeroot {
+		return nil
+	}
+	if l.uid == 0 {
+		if namespaces.IsUnprivileged() {
+			return fmt.Errorf("--fakeroot-range can't be used when already running root-mapped in a user namespace")
+		}
+		return nil
+	}
+	if l.cfg.IgnoreSubuid {
+		return fmt.Errorf("--fakeroot-range selects a subordinate id range, it can't be used with --ignore-subuid")
+	}
+	if !fakeroot.IsUIDMapped(l.uid) {
+		return fmt.Errorf("--fakeroot-range requires a subordinate id range for your user in %s", fakeroot.SubUIDFile)
+	}
+	return nil
+}
+
 // useSuid checks whether to use the setuid starter binary, and if we need to force the user namespace.
 func (l *Launcher) useSuid(insideUserNs bool) (useSuid bool) {
 	// privileged installation by default
@@ -660,6 +805,19 @@ func (l *Launcher) setBinds(fakerootPath string) error {
 	if err != nil {
 		return fmt.Errorf("while parsing bind path: %w", err)
 	}
+	if !l.cfg.NoBindExpand {
+		for i := range binds {
+			if binds[i].Source, err = expandTilde(binds[i].Source); err != nil {
+				return fmt.Errorf("while parsing bind path: %w", err)
+			}
+			if binds[i].Destination, err = expandTilde(binds[i].Destination); err != nil {
+				return fmt.Errorf("while parsing bind path: %w", err)
+			}
+		}
+		if err := apptainerConfig.ExpandBindPaths(binds); err != nil {
+			return fmt.Errorf("while parsing bind path: %w", err)
+		}
+	}
 	// Now add binds from one or more --mount and env var.
 	// Note that these do not get exported for nested containers
 	for _, m := range l.cfg.Mounts {
@@ -744,6 +902,33 @@ func (l *Launcher) setNoMountFlags() {
 	l.engineConfig.SetSkipBinds(skipBinds)
 }
 
+// getPwUID and getPwNam are variables so they can be replaced by tests.
+var (
+	getPwUID = user.GetPwUID
+	getPwNam = user.GetPwNam
+)
+
+// expandTilde expands a leading ~ or ~user in path to the home directory of
+// the user running apptainer, or of user, from the host passwd database.
+func expandTilde(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+
+	name, rest, _ := strings.Cut(path[1:], "/")
+	var pw *user.User
+	var err error
+	if name == "" {
+		pw, err = getPwUID(uint32(os.Getuid()))
+	} else {
+		pw, err = getPwNam(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("while expanding %q: %w", path, err)
+	}
+	return filepath.Join(pw.Dir, rest), nil
+}
+
 // setHome sets the correct home directory configuration for our circumstance.
 // If it is not possible to mount a home directory then the mount will be disabled.
 func (l *Launcher) setHome() error {
@@ -786,6 +971,23 @@ func (l *Launcher) setHome() error {
 	if len(homeSlice) > 2 || len(homeSlice) == 0 {
 		return fmt.Errorf("home argument has incorrect number of elements: %v", len(homeSlice))
 	}
+	for i := range homeSlice {
+		var err error
+		if homeSlice[i], err = expandTilde(homeSlice[i]); err != nil {
+			return fmt.Errorf("while parsing home argument: %w", err)
+		}
+	}
+	userHomeOpts, err := apptainerConfig.ParseUserHomeMountOptions(l.cfg.HomeOptions)
+	if err != nil {
+		return fmt.Errorf("while parsing --home-options: %w", err)
+	}
+	if len(l.cfg.HomeOptions) > 0 && !userHomeOpts.NoExec {
+		if homeOpts, err := apptainerConfig.ParseHomeMountOptions(l.engineConfig.File.HomeMountOptions); err == nil && homeOpts.NoExec {
+			sylog.Warningf("Ignoring --home-options exec, home mount options in apptainer.conf set noexec")
+		}
+	}
+	l.engineConfig.SetHomeOptions(l.cfg.HomeOptions)
+
 	l.engineConfig.SetHomeSource(homeSlice[0])
 	if len(homeSlice) == 1 {
 		l.engineConfig.SetHomeDest(homeSlice[0])
@@ -806,6 +1008,20 @@ func (l *Launcher) SetGPUConfig() error {
 		sylog.Verbosef("'always use rocm = yes' found in apptainer.conf")
 	}
 
+	if l.cfg.GPUs != "" {
+		devices, err := gpu.ParseGPUs(l.cfg.GPUs)
+		if err != nil {
+			return fmt.Errorf("while parsing --gpus: %w", err)
+		}
+		if env, ok := os.LookupEnv("NVIDIA_VISIBLE_DEVICES"); ok && env != devices {
+			sylog.Warningf("--gpus overrides NVIDIA_VISIBLE_DEVICES=%s from the environment", env)
+		}
+		// Only nvidia-container-cli can restrict the devices made available.
+		sylog.Debugf("implying --nvccli from --gpus, with NVIDIA_VISIBLE_DEVICES=%s", devices)
+		os.Setenv("NVIDIA_VISIBLE_DEVICES", devices)
+		l.cfg.NvCCLI = true
+	}
+
 	if l.cfg.NvCCLI && !l.cfg.Nvidia {
 		sylog.Debugf("implying --nv from --nvccli")
 		l.cfg.Nvidia = true
@@ -831,7 +1047,7 @@ func (l *Launcher) SetGPUConfig() error {
 		if !fakeRootPriv {
 			return l.setNvCCLIConfig()
 		}
-		return fmt.Errorf("--fakeroot does not support --nvccli in set-uid installations")
+		return categorize(ErrUnsupportedOption, fmt.Errorf("--fakeroot does not support --nvccli in set-uid installations"))
 	}
 	return nil
 }
@@ -998,15 +1214,73 @@ func (l *Launcher) setNamespaces() {
 	}
 	if l.cfg.Namespaces.User {
 		l.generator.AddOrReplaceLinuxNamespace("user", "")
-		if !l.cfg.Fakeroot {
+		// fakeroot and keep-id mappings are set by the engine
+		if !l.cfg.Fakeroot && !l.engineConfig.GetKeepID() {
 			l.generator.AddLinuxUIDMapping(uint32(os.Getuid()), l.uid, 1)
 			l.generator.AddLinuxGIDMapping(uint32(os.Getgid()), l.gid, 1)
 		}
 	}
 }
 
+// addEnvVars adds the variables from vars, read from source, to env, unless
+// they are already set there by a source with higher precedence. sources
+// holds the source of each variable of env, and is updated with the source of
+// the variables added.
+func addEnvVars(env, sources, vars map[string]string, source string) {
+	for k, v := range vars {
+		if _, ok := env[k]; ok {
+			sylog.Warningf("Ignored %s variable %s: override from %s", source, k, sources[k])
+			continue
+		}
+		env[k] = v
+		sources[k] = source
+	}
+}
+
+// envNameRegexp matches valid environment variable names, which are written
+// unquoted to the environment script sourced in the container.
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkUnsetEnv checks that names can be removed from the container
+// environment.
+func checkUnsetEnv(names []string) error {
+	for _, name := range names {
+		if !envNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if env.ReadOnlyVars[name] {
+			return fmt.Errorf("environment variable %s cannot be unset", name)
+		}
+	}
+	return nil
+}
+
 // setEnvVars sets the environment for the container, from the host environment, glads, env-file.
 func (l *Launcher) setEnvVars(ctx context.Context, args []string) error {
+	if l.cfg.Env == nil {
+		l.cfg.Env = map[string]string{}
+	}
+	// envSources records where each variable of l.cfg.Env is set from, to
+	// report it when a variable from another source is ignored.
+	envSources := make(map[string]string, len(l.cfg.Env))
+	for k := range l.cfg.Env {
+		envSources[k] = "--env"
+	}
+	if err := env.CheckPassEnv(l.cfg.EnvPass); err != nil {
+		return fmt.Errorf("while processing --env-pass: %w", err)
+	}
+
+	// --env-json variables have the same precedence as --env, which wins if a
+	// variable is set by both, and are applied before the environment files.
+	if l.cfg.EnvJSON != "" {
+		jsonMap, err := env.JSONMap(l.cfg.EnvJSON)
+		if err != nil {
+			return fmt.Errorf("while processing --env-json: %w", err)
+		}
+		sylog.Debugf("Setting environment variables from --env-json")
+		addEnvVars(l.cfg.Env, envSources, jsonMap, "--env-json")
+	}
+
 	if len(l.cfg.EnvFiles) > 0 {
 		currentEnv := append(
 			os.Environ(),
@@ -1027,14 +1301,16 @@ func (l *Launcher) setEnvVars(ctx context.Context, args []string) error {
 
 		// --env variables will take precedence over variables defined by the environment files
 		// Update Env with those from file
-		for k, v := range envFilesMap {
-			// Ensure we don't overwrite --env variables with environment file
-			if _, ok := l.cfg.Env[k]; ok {
-				sylog.Warningf("Ignored environment file variable %s: override from --env", k)
-			} else {
-				l.cfg.Env[k] = v
-			}
+		addEnvVars(l.cfg.Env, envSources, envFilesMap, "--env-file")
+	}
+
+	// --label-env variables have the lowest precedence of the env options
+	if len(l.cfg.LabelEnv) > 0 {
+		labelVars, err := l.labelEnvVars()
+		if err != nil {
+			return fmt.Errorf("while processing --label-env: %w", err)
 		}
+		addEnvVars(l.cfg.Env, envSources, labelVars, "--label-env")
 	}
 
 	// process --env and --env-file variables for injection
@@ -1047,14 +1323,47 @@ func (l *Launcher) setEnvVars(ctx context.Context, args []string) error {
 		}
 		os.Setenv("APPTAINERENV_"+envName, envValue)
 	}
+
+	// --unsetenv variables are removed from the host environment here, and
+	// from the image environment by the engine
+	if err := checkUnsetEnv(l.cfg.UnsetEnv); err != nil {
+		return fmt.Errorf("while processing --unsetenv: %w", err)
+	}
+	for _, name := range l.cfg.UnsetEnv {
+		if _, ok := l.cfg.Env[name]; ok {
+			sylog.Warningf("Ignored environment variable %s: removed by --unsetenv", name)
+		}
+		os.Unsetenv(name)
+		for _, prefix := range env.ApptainerEnvPrefixes {
+			os.Unsetenv(prefix + name)
+		}
+	}
+	l.engineConfig.SetUnsetEnv(l.cfg.UnsetEnv)
+
 	// Copy and cache environment
 	environment := os.Environ()
 	// Clean environment
-	apptainerEnv := env.SetContainerEnv(l.generator, environment, l.cfg.CleanEnv, l.engineConfig.GetHomeDest())
+	apptainerEnv := env.SetContainerEnv(l.generator, environment, l.cfg.CleanEnv, l.engineConfig.GetHomeDest(), l.cfg.EnvPass)
 	l.engineConfig.SetApptainerEnv(apptainerEnv)
 	return nil
 }
 
+// labelEnvVars returns the environment variables exposing the image labels
+// selected with --label-env.
+func (l *Launcher) labelEnvVars() (map[string]string, error) {
+	img, err := l.initImage(l.engineConfig.GetImage())
+	if err != nil {
+		return nil, fmt.Errorf("could not open image %s: %w", l.engineConfig.GetImage(), err)
+	}
+	defer img.File.Close()
+
+	labels, err := imageLabels(img)
+	if err != nil {
+		return nil, fmt.Errorf("while reading labels of %s: %w", l.engineConfig.GetImage(), err)
+	}
+	return labelEnvVars(labels, l.cfg.LabelEnv)
+}
+
 // setProcessCwd sets the container process working directory
 func (l *Launcher) setProcessCwd() {
 	if cwd, err := os.Getwd(); err == nil {
@@ -1182,7 +1491,7 @@ func (l *Launcher) prepareImage(_ context.Context, insideUserNs bool, image stri
 				sylog.Fatalf("while extracting %s: %s", image, err)
 			}
 			sylog.Infof("Converting SIF file to temporary sandbox...")
-			rootfsDir, imageDir, err := convertImage(image, unsquashfsPath, l.cfg.TmpDir)
+			rootfsDir, imageDir, err := l.convertImage(image, unsquashfsPath, l.cfg.TmpDir)
 			if err != nil {
 				sylog.Fatalf("while extracting %s: %s", image, err)
 			}
@@ -1283,13 +1592,25 @@ func runPluginCallbacks(cfg *config.Common) error {
 	return nil
 }
 
+// hasUserMappingPlugin reports whether a plugin provides the subordinate id
+// ranges of users, in place of the subuid and subgid files.
+func hasUserMappingPlugin() bool {
+	callbackType := (fakerootcallback.UserMapping)(nil)
+	callbacks, err := plugin.LoadCallbacks(callbackType)
+	if err != nil {
+		sylog.Debugf("Loading plugins callbacks '%T' failed: %s", callbackType, err)
+		return false
+	}
+	return len(callbacks) > 0
+}
+
 // withPrivilege calls fn if cond is satisfied, and we are uid 0
 func withPrivilege(uid uint32, cond bool, desc string, fn func() error) error {
 	if !cond {
 		return nil
 	}
 	if uid != 0 {
-		return fmt.Errorf("%s requires root privileges", desc)
+		return categorize(ErrPrivilegeRequired, fmt.Errorf("%s requires root privileges", desc))
 	}
 	return fn()
 }
@@ -1318,8 +1639,8 @@ func hidepidProc() bool {
 // convertImage extracts the image found at filename to directory dir within a temporary directory
 // tempDir. If the unsquashfs binary is not located, the binary at unsquashfsPath is used. It is
 // the caller's responsibility to remove rootfsDir when no longer needed.
-func convertImage(filename string, unsquashfsPath string, tmpDir string) (rootfsDir string, imageDir string, err error) {
-	img, err := imgutil.Init(filename, false)
+func (l *Launcher) convertImage(filename string, unsquashfsPath string, tmpDir string) (rootfsDir string, imageDir string, err error) {
+	img, err := l.initImage(filename)
 	if err != nil {
 		return "", "", fmt.Errorf("could not open image %s: %s", filename, err)
 	}
@@ -0,0 +1,211 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package launch
+
+import (
+	"bytes"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/env"
+	"github.com/apptainer/apptainer/internal/pkg/util/machine"
+	apptainerUser "github.com/apptainer/apptainer/internal/pkg/util/user"
+	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
+	"github.com/apptainer/sif/v2/pkg/sif"
+)
+
+func TestAddEnvVarsJSONPrecedence(t *testing.T) {
+	jsonMap, err := env.JSONMap(`{"FOO": "json", "BAR": 1, "BAZ": false}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	envFileMap := map[string]string{"BAR": "file", "QUX": "file"}
+
+	// --env wins over --env-json, which wins over the environment files.
+	envMap := map[string]string{"FOO": "env"}
+	sources := map[string]string{"FOO": "--env"}
+	addEnvVars(envMap, sources, jsonMap, "--env-json")
+	addEnvVars(envMap, sources, envFileMap, "--env-file")
+
+	want := map[string]string{
+		"FOO": "env",
+		"BAR": "1",
+		"BAZ": "false",
+		"QUX": "file",
+	}
+	if !reflect.DeepEqual(envMap, want) {
+		t.Errorf("expected %v, got %v", want, envMap)
+	}
+
+	// The source of each variable is recorded, to be named when a variable
+	// of a lower precedence source is ignored.
+	wantSources := map[string]string{
+		"FOO": "--env",
+		"BAR": "--env-json",
+		"BAZ": "--env-json",
+		"QUX": "--env-file",
+	}
+	if !reflect.DeepEqual(sources, wantSources) {
+		t.Errorf("expected sources %v, got %v", wantSources, sources)
+	}
+}
+
+func TestCheckUnsetEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		wantErr bool
+	}{
+		{name: "None"},
+		{name: "Valid", names: []string{"LD_PRELOAD", "_foo", "VAR1"}},
+		{name: "Empty", names: []string{""}, wantErr: true},
+		{name: "Assignment", names: []string{"FOO="}, wantErr: true},
+		{name: "LeadingDigit", names: []string{"1FOO"}, wantErr: true},
+		{name: "ShellInjection", names: []string{"FOO;rm"}, wantErr: true},
+		{name: "ReadOnly", names: []string{"HOME"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkUnsetEnv(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpandTilde(t *testing.T) {
+	oldGetPwUID, oldGetPwNam := getPwUID, getPwNam
+	defer func() { getPwUID, getPwNam = oldGetPwUID, oldGetPwNam }()
+
+	getPwUID = func(uint32) (*apptainerUser.User, error) {
+		return &apptainerUser.User{Name: "me", Dir: "/home/me"}, nil
+	}
+	getPwNam = func(name string) (*apptainerUser.User, error) {
+		if name == "other" {
+			return &apptainerUser.User{Name: "other", Dir: "/home/other"}, nil
+		}
+		return nil, user.UnknownUserError(name)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Tilde", path: "~", want: "/home/me"},
+		{name: "TildeSub", path: "~/data/sub", want: "/home/me/data/sub"},
+		{name: "OtherUser", path: "~other", want: "/home/other"},
+		{name: "OtherUserSub", path: "~other/data", want: "/home/other/data"},
+		{name: "UnknownUser", path: "~nobody-here/data", wantErr: true},
+		{name: "NoTilde", path: "/data/~me", want: "/data/~me"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandTilde(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckFakerootRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		uid     uint32
+		cfg     launchOptions
+		wantErr bool
+	}{
+		{
+			name: "NoRange",
+			uid:  1000,
+		},
+		{
+			name:    "RangeWithoutFakeroot",
+			uid:     1000,
+			cfg:     launchOptions{FakerootRange: "1"},
+			wantErr: true,
+		},
+		{
+			name: "RangeWithKeepID",
+			uid:  1000,
+			cfg:  launchOptions{FakerootRange: "1", KeepID: true},
+		},
+		{
+			name:    "RangeWithIgnoreSubuid",
+			uid:     1000,
+			cfg:     launchOptions{FakerootRange: "1", Fakeroot: true, IgnoreSubuid: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Launcher{uid: tt.uid, cfg: tt.cfg}
+			err := l.checkFakerootRange()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckEncryptionKeyIgnoreArch(t *testing.T) {
+	otherArch := "s390x"
+	if runtime.GOARCH == otherArch {
+		otherArch = "amd64"
+	}
+	if machine.CompatibleWith(otherArch) {
+		t.Skipf("host can run %s images through emulation", otherArch)
+	}
+
+	b, err := os.ReadFile("../../../../pkg/image/testdata/squashfs.v4")
+	if err != nil {
+		t.Fatalf("failed to read squashfs image: %s", err)
+	}
+	di, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(b),
+		sif.OptPartitionMetadata(sif.FsSquash, sif.PartPrimSys, otherArch),
+	)
+	if err != nil {
+		t.Fatalf("failed to get DescriptorInput: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "image.sif")
+	fp, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(di))
+	if err != nil {
+		t.Fatalf("failed to create SIF: %s", err)
+	}
+	fp.UnloadContainer()
+
+	engineConfig := apptainerConfig.NewConfig()
+	engineConfig.SetImage(path)
+
+	l := &Launcher{engineConfig: engineConfig}
+	if err := l.checkArchitecture(); err == nil {
+		t.Errorf("expected an architecture error without --ignore-arch")
+	}
+
+	l.cfg.IgnoreArch = true
+	if err := l.checkArchitecture(); err != nil {
+		t.Errorf("unexpected error from checkArchitecture: %s", err)
+	}
+	if err := l.checkEncryptionKey(); err != nil {
+		t.Errorf("unexpected error from checkEncryptionKey: %s", err)
+	}
+}
@@ -20,7 +20,11 @@
 package launch
 
 import (
+	"fmt"
+
+	"github.com/apptainer/apptainer/internal/pkg/fakeroot"
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	apptainerConfig "github.com/apptainer/apptainer/pkg/runtime/engine/apptainer/config"
 	"github.com/apptainer/apptainer/pkg/util/cryptkey"
 )
@@ -47,6 +51,9 @@ type launchOptions struct {
 	CustomHome bool
 	// NoHome disables automatic mounting of the home directory into the container.
 	NoHome bool
+	// HomeOptions lists user options of the home directory mount, restricting
+	// those set in apptainer.conf.
+	HomeOptions []string
 
 	// BindPaths lists paths to bind from host to container, which may be <src>:<dest> pairs.
 	BindPaths []string
@@ -56,6 +63,8 @@ type launchOptions struct {
 	Mounts []string
 	// NoMount is a list of automatic / configured mounts to disable.
 	NoMount []string
+	// NoBindExpand disables the expansion of host environment variables in BindPaths.
+	NoBindExpand bool
 
 	// Nvidia enables NVIDIA GPU support.
 	Nvidia bool
@@ -63,6 +72,8 @@ type launchOptions struct {
 	NvCCLI bool
 	// NoNvidia disables NVIDIA GPU support when set default in apptainer.conf.
 	NoNvidia bool
+	// GPUs selects the NVIDIA GPUs to make available, in docker --gpus format.
+	GPUs string
 	// Rocm enables Rocm GPU support.
 	Rocm bool
 	// NoRocm disable Rocm GPU support when set default in apptainer.conf.
@@ -75,6 +86,18 @@ type launchOptions struct {
 	Env map[string]string
 	// EnvFiles contains filenames to read container env vars from.
 	EnvFiles []string
+	// EnvPass contains names, or glob patterns, of host env vars to pass to
+	// the container, even when CleanEnv is set.
+	EnvPass []string
+	// UnsetEnv contains names of env vars to remove from the container
+	// environment, including those set by the image.
+	UnsetEnv []string
+	// LabelEnv contains names, or glob patterns, of image labels to expose
+	// as APPTAINER_LABEL_* env vars in the container.
+	LabelEnv []string
+	// EnvJSON is a JSON object, or the path of a file holding one, of env
+	// vars to set in the container.
+	EnvJSON string
 	// CleanEnv starts the container with a clean environment, excluding host env vars.
 	CleanEnv bool
 	// NoEval instructs Apptainer not to shell evaluate args and env vars.
@@ -95,6 +118,8 @@ type launchOptions struct {
 	Hostname string
 	// DNS is the comma separated list of DNS servers to be set in the container's resolv.conf.
 	DNS string
+	// DNSOptions are resolver options to be set in the container's resolv.conf.
+	DNSOptions []string
 
 	// AddCaps is the list of capabilities to Add to the container process.
 	AddCaps string
@@ -124,6 +149,8 @@ type launchOptions struct {
 
 	// Fakeroot enables the fake root mode, using user namespaces and subuid / subgid mapping.
 	Fakeroot bool
+	// FakerootRange selects the subuid / subgid range to map, by index or as start=<id>.
+	FakerootRange string
 	// Boot enables execution of /sbin/init on startup of an instance container.
 	Boot bool
 	// NoInit disables shim process when PID namespace is used.
@@ -132,6 +159,8 @@ type launchOptions struct {
 	Contain bool
 	// ContainAll infers Contain, and adds PID, IPC namespaces, and CleanEnv.
 	ContainAll bool
+	// ShmSize is the size, in MiB, of the /dev/shm tmpfs of a contained /dev.
+	ShmSize int64
 
 	// AppName sets a SCIF application name to run.
 	AppName string
@@ -158,6 +187,11 @@ type launchOptions struct {
 	ShareNSMode       bool   // whether running in sharens mode
 	ShareNSFd         int    // fd opened in sharens mode
 	RunscriptTimeout  string // runscript timeout
+	Healthcheck       bool   // whether to run the image healthcheck for an instance
+	IgnoreArch        bool   // whether to run an image of an architecture the host can't run
+	CIDFile           string // file to write the container id to
+	ContainerID       string // user requested container id
+	KeepID            bool   // whether to preserve the host uid/gid in a user namespace
 }
 
 type Launcher struct {
@@ -236,6 +270,18 @@ func OptHome(homeDir string, custom bool, disable bool) Option {
 	}
 }
 
+// OptHomeOptions sets the user options of the home directory mount, exec or
+// noexec. They can't lift noexec set in apptainer.conf.
+func OptHomeOptions(options []string) Option {
+	return func(lo *launchOptions) error {
+		if _, err := apptainerConfig.ParseUserHomeMountOptions(options); err != nil {
+			return fmt.Errorf("invalid --home-options: %w", err)
+		}
+		lo.HomeOptions = options
+		return nil
+	}
+}
+
 // OptMounts sets user-requested mounts to propagate into the container.
 //
 // binds lists bind mount specifications in Apptainer's <src>:<dst>[:<opts>] format.
@@ -258,6 +304,15 @@ func OptNoMount(nm []string) Option {
 	}
 }
 
+// OptNoBindExpand disables the expansion of host environment variables in
+// the source and destination of bind mount specifications.
+func OptNoBindExpand(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.NoBindExpand = b
+		return nil
+	}
+}
+
 // OptNvidia enables NVIDIA GPU support.
 //
 // nvccli sets whether to use the nvidia-container-runtime (true), or legacy bind mounts (false).
@@ -277,6 +332,15 @@ func OptNoNvidia(b bool) Option {
 	}
 }
 
+// OptGPUs selects the NVIDIA GPUs to make available in the container, as
+// 'all', a number of GPUs, or a list of devices. It implies nvccli.
+func OptGPUs(gpus string) Option {
+	return func(lo *launchOptions) error {
+		lo.GPUs = gpus
+		return nil
+	}
+}
+
 // OptRocm enable Rocm GPU support.
 func OptRocm(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -315,6 +379,42 @@ func OptEnv(env map[string]string, envFiles []string, clean bool) Option {
 	}
 }
 
+// OptEnvJSON sets container environment variables from a JSON object, given
+// inline or as a file path. They take precedence over env files, but not env.
+func OptEnvJSON(envJSON string) Option {
+	return func(lo *launchOptions) error {
+		lo.EnvJSON = envJSON
+		return nil
+	}
+}
+
+// OptEnvPass passes the host variables matching the names or glob patterns
+// to the container, even with a clean environment.
+func OptEnvPass(patterns []string) Option {
+	return func(lo *launchOptions) error {
+		lo.EnvPass = patterns
+		return nil
+	}
+}
+
+// OptUnsetEnv removes the named variables from the container environment,
+// whether they are set by the host, the image, or env options.
+func OptUnsetEnv(names []string) Option {
+	return func(lo *launchOptions) error {
+		lo.UnsetEnv = names
+		return nil
+	}
+}
+
+// OptLabelEnv exposes the image labels matching patterns as
+// APPTAINER_LABEL_* variables in the container environment.
+func OptLabelEnv(patterns []string) Option {
+	return func(lo *launchOptions) error {
+		lo.LabelEnv = patterns
+		return nil
+	}
+}
+
 // OptNoEval disables shell evaluation of args and env vars.
 func OptNoEval(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -367,6 +467,14 @@ func OptDNS(d string) Option {
 	}
 }
 
+// OptDNSOptions sets resolver options for the container resolv.conf.
+func OptDNSOptions(o []string) Option {
+	return func(lo *launchOptions) error {
+		lo.DNSOptions = o
+		return nil
+	}
+}
+
 // OptCaps sets capabilities to add and drop.
 func OptCaps(add, drop string) Option {
 	return func(lo *launchOptions) error {
@@ -456,6 +564,30 @@ func OptFakeroot(b bool) Option {
 	}
 }
 
+// OptFakerootRange selects the subuid / subgid range mapped with fakeroot or
+// keep-id, when the user has several ranges, either by index or as start=<id>.
+func OptFakerootRange(sel string) Option {
+	return func(lo *launchOptions) error {
+		if sel == "" {
+			return nil
+		}
+		if _, err := fakeroot.ParseRangeSelector(sel); err != nil {
+			return fmt.Errorf("invalid --fakeroot-range: %w", err)
+		}
+		lo.FakerootRange = sel
+		return nil
+	}
+}
+
+// OptKeepID preserves the host uid/gid in a user namespace, with the other
+// container ids mapped to the subuid / subgid ranges of the user.
+func OptKeepID(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.KeepID = b
+		return nil
+	}
+}
+
 // OptBoot enables execution of /sbin/init on startup of an instance container.
 func OptBoot(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -480,6 +612,22 @@ func OptContain(b bool) Option {
 	}
 }
 
+// OptShmSize sets the size of the /dev/shm tmpfs of a contained /dev, such as
+// "512m" or "2g".
+func OptShmSize(size string) Option {
+	return func(lo *launchOptions) error {
+		if size == "" {
+			return nil
+		}
+		s, err := fs.ParseSizeMiB(size)
+		if err != nil {
+			return fmt.Errorf("invalid --shm-size: %w", err)
+		}
+		lo.ShmSize = s
+		return nil
+	}
+}
+
 // OptContainAll infers Contain, and adds PID, IPC namespaces, and CleanEnv.
 func OptContainAll(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -584,6 +732,41 @@ func OptUnderlay(b bool) Option {
 	}
 }
 
+// OptHealthcheck sets whether the image healthcheck is run for an instance.
+func OptHealthcheck(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.Healthcheck = b
+		return nil
+	}
+}
+
+// OptIgnoreArch sets whether an image targeting an architecture which the host
+// can't run, even with emulation, is run anyway.
+func OptIgnoreArch(b bool) Option {
+	return func(lo *launchOptions) error {
+		lo.IgnoreArch = b
+		return nil
+	}
+}
+
+// OptContainerID sets the container id of a non-instance container, instead
+// of a generated one.
+func OptContainerID(id string) Option {
+	return func(lo *launchOptions) error {
+		lo.ContainerID = id
+		return nil
+	}
+}
+
+// OptCIDFile sets the path of a file to write the container id to. The
+// file is removed when a non-instance container exits.
+func OptCIDFile(path string) Option {
+	return func(lo *launchOptions) error {
+		lo.CIDFile = path
+		return nil
+	}
+}
+
 // OptShareNSMode
 func OptShareNSMode(b bool) Option {
 	return func(lo *launchOptions) error {
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
 	"github.com/apptainer/apptainer/internal/pkg/security/apparmor"
 	"github.com/apptainer/apptainer/internal/pkg/security/seccomp"
 	"github.com/apptainer/apptainer/internal/pkg/security/selinux"
@@ -70,3 +71,21 @@ func GetParam(security []string, feature string) string {
 	}
 	return ""
 }
+
+// SeccompUnconfined is the seccomp parameter of the security options running
+// a container without seccomp filter, e.g. for debugging tools like strace.
+const SeccompUnconfined = "unconfined"
+
+// SetSeccomp applies the seccomp parameter of the security options to the
+// configuration of generator. SeccompUnconfined removes any seccomp filter,
+// including a default profile, otherwise param is the path of a seccomp
+// profile file.
+func SetSeccomp(param string, generator *generate.Generator) error {
+	if param != SeccompUnconfined {
+		return seccomp.LoadProfileFromFile(param, generator)
+	}
+	if generator.Config.Linux != nil {
+		generator.Config.Linux.Seccomp = nil
+	}
+	return nil
+}
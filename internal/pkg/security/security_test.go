@@ -14,6 +14,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
 	"github.com/apptainer/apptainer/internal/pkg/security/apparmor"
 	"github.com/apptainer/apptainer/internal/pkg/security/selinux"
 	"github.com/apptainer/apptainer/internal/pkg/test"
@@ -51,6 +52,29 @@ func TestGetParam(t *testing.T) {
 	}
 }
 
+func TestSetSeccomp(t *testing.T) {
+	spec := &specs.Spec{
+		Linux: &specs.Linux{
+			Seccomp: &specs.LinuxSeccomp{DefaultAction: specs.ActErrno},
+		},
+	}
+	if err := SetSeccomp(SeccompUnconfined, generate.New(spec)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if spec.Linux.Seccomp != nil {
+		t.Errorf("seccomp configuration not cleared: %+v", spec.Linux.Seccomp)
+	}
+
+	// no linux section to clear
+	spec = &specs.Spec{}
+	if err := SetSeccomp(SeccompUnconfined, generate.New(spec)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if spec.Linux != nil && spec.Linux.Seccomp != nil {
+		t.Errorf("unexpected seccomp configuration: %+v", spec.Linux.Seccomp)
+	}
+}
+
 func TestConfigure(t *testing.T) {
 	test.EnsurePrivilege(t)
 
@@ -10,6 +10,9 @@
 package env
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
@@ -125,7 +128,9 @@ func warnDeprecatedEnvUsage(hostEnvs []string) {
 }
 
 // SetContainerEnv cleans environment variables before running the container.
-func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, homeDest string) map[string]string {
+// Host variables matching one of the passEnv glob patterns are forwarded even
+// if cleanEnv is set.
+func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, homeDest string, passEnv []string) map[string]string {
 	// allow override with APPTAINERENV_LANG
 	if cleanEnv {
 		g.SetProcessEnv("LANG", "C")
@@ -162,7 +167,7 @@ EnvKeys:
 		}
 
 		// non prefixed environment variables
-		if mustAddToHostEnv(e[0], cleanEnv) {
+		if mustAddToHostEnv(e[0], cleanEnv, passEnv) {
 			if value, ok := envKeys[e[0]]; ok {
 				if value != e[1] {
 					sylog.Warningf("Environment variable %s already has value [%s], will not forward new value [%s] from parent process environment", e[0], value, e[1])
@@ -187,12 +192,38 @@ EnvKeys:
 
 // mustAddToHostEnv processes given key and returns if the environment
 // variable should be added to the container or not.
-func mustAddToHostEnv(key string, cleanEnv bool) bool {
+func mustAddToHostEnv(key string, cleanEnv bool, passEnv []string) bool {
 	if _, ok := alwaysPassKeys[key]; ok {
 		return true
 	}
-	if _, ok := alwaysOmitKeys[key]; ok || cleanEnv {
+	if _, ok := alwaysOmitKeys[key]; ok {
 		return false
 	}
+	if cleanEnv {
+		return matchPassEnv(key, passEnv)
+	}
 	return true
 }
+
+// matchPassEnv returns whether key matches one of the passEnv glob patterns.
+func matchPassEnv(key string, passEnv []string) bool {
+	for _, pattern := range passEnv {
+		if ok, _ := filepath.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPassEnv checks that the passEnv glob patterns are well formed.
+func CheckPassEnv(passEnv []string) error {
+	for _, pattern := range passEnv {
+		if pattern == "" {
+			return errors.New("empty environment variable pattern")
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid environment variable pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
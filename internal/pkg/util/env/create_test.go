@@ -32,6 +32,7 @@ func TestSetContainerEnv(t *testing.T) {
 	tt := []struct {
 		name            string
 		cleanEnv        bool
+		passEnv         []string
 		homeDest        string
 		env             []string
 		processEnv      map[string]string
@@ -699,6 +700,46 @@ func TestSetContainerEnv(t *testing.T) {
 				"Environment variable SINGULARITYENV_PS1 is set, but APPTAINERENV_PS1 is preferred",
 			},
 		},
+		{
+			name:     "clean envs with pass through",
+			cleanEnv: true,
+			passEnv:  []string{"FOO", "SLURM_*", "PATH"},
+			homeDest: "/home/tester",
+			env: []string{
+				"HOME=/home/john",
+				"TERM=xterm-256color",
+				"PATH=/usr/games:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+				"FOO=foo",
+				"FOOBAR=foobar",
+				"SLURM_JOB_ID=42",
+				"SLURM_NTASKS=4",
+				"MY_SLURM_VAR=no",
+			},
+			resultEnv: []string{
+				"LANG=C",
+				"TERM=xterm-256color",
+				"FOO=foo",
+				"SLURM_JOB_ID=42",
+				"SLURM_NTASKS=4",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+		},
+		{
+			name:     "pass through without clean envs",
+			passEnv:  []string{"FOO"},
+			homeDest: "/home/tester",
+			env: []string{
+				"FOO=foo",
+				"BAR=bar",
+			},
+			resultEnv: []string{
+				"FOO=foo",
+				"BAR=bar",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+		},
 		{
 			name:     "should print warning message if legacy and new env vars have different values",
 			cleanEnv: false,
@@ -737,14 +778,14 @@ func TestSetContainerEnv(t *testing.T) {
 			var senv map[string]string
 			func() {
 				oldWriter := sylog.SetWriter(&output)
-				oldLevel := sylog.GetLevel()
-				sylog.SetLevel(int(sylog.DebugLevel), true)
+				oldLevel := sylog.GetMessageLevel()
+				sylog.SetMessageLevel(sylog.DebugLevel, true)
 				defer func() {
 					oldWriter.Write(output.Bytes())
 					sylog.SetWriter(oldWriter)
-					sylog.SetLevel(oldLevel, true)
+					sylog.SetMessageLevel(oldLevel, true)
 				}()
-				senv = SetContainerEnv(generator, tc.env, tc.cleanEnv, tc.homeDest)
+				senv = SetContainerEnv(generator, tc.env, tc.cleanEnv, tc.homeDest, tc.passEnv)
 			}()
 			for _, requiredOutput := range tc.outputNeeded {
 				if !strings.Contains(output.String(), requiredOutput) {
@@ -779,3 +820,26 @@ func equal(_ *testing.T, a, b []string) bool {
 	}
 	return true
 }
+
+func TestCheckPassEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		passEnv []string
+		wantErr bool
+	}{
+		{name: "None"},
+		{name: "Names", passEnv: []string{"http_proxy", "FOO"}},
+		{name: "Globs", passEnv: []string{"SLURM_*", "OMP_?", "[AB]_VAR"}},
+		{name: "Empty", passEnv: []string{""}, wantErr: true},
+		{name: "BadPattern", passEnv: []string{"FOO["}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPassEnv(tt.passEnv)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
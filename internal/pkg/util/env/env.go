@@ -10,9 +10,13 @@
 package env
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/shell/interpreter"
@@ -129,6 +133,48 @@ func FileMap(ctx context.Context, f string, args []string, hostEnv []string) (ma
 	return envMap, nil
 }
 
+// JSONMap returns a map of KEY=VAL env vars from a JSON object, given inline
+// in value, or read from the file at path value if it does not start with a
+// '{'. Number and boolean values are converted to their string form.
+func JSONMap(value string) (map[string]string, error) {
+	content := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		var err error
+		content, err = os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not read environment JSON file %q: %w", value, err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("while decoding environment JSON: %w", err)
+	}
+	if obj == nil {
+		return nil, errors.New("environment JSON must be an object")
+	}
+
+	envMap := make(map[string]string, len(obj))
+	for k, v := range obj {
+		if k == "" || strings.Contains(k, "=") {
+			return nil, fmt.Errorf("invalid environment variable name %q", k)
+		}
+		switch val := v.(type) {
+		case string:
+			envMap[k] = val
+		case json.Number:
+			envMap[k] = val.String()
+		case bool:
+			envMap[k] = strconv.FormatBool(val)
+		default:
+			return nil, fmt.Errorf("environment variable %s must be a string, number or boolean", k)
+		}
+	}
+	return envMap, nil
+}
+
 // MergeMap merges two maps of environment variables, with values in b replacing
 // values also set in a.
 func MergeMap(a map[string]string, b map[string]string) map[string]string {
@@ -251,3 +251,94 @@ func TestEnvFileMap(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "Empty",
+			json: `{}`,
+			want: map[string]string{},
+		},
+		{
+			name: "Strings",
+			json: `{"FOO": "bar", "EMPTY": "", "SPACES": "a b=c"}`,
+			want: map[string]string{
+				"FOO":    "bar",
+				"EMPTY":  "",
+				"SPACES": "a b=c",
+			},
+		},
+		{
+			name: "Coercion",
+			json: ` {"INT": 42, "FLOAT": 1.5, "BIG": 12345678901234567890, "BOOL": true}`,
+			want: map[string]string{
+				"INT":   "42",
+				"FLOAT": "1.5",
+				"BIG":   "12345678901234567890",
+				"BOOL":  "true",
+			},
+		},
+		{
+			name:    "Null",
+			json:    `{"FOO": null}`,
+			wantErr: true,
+		},
+		{
+			name:    "Nested",
+			json:    `{"FOO": {"BAR": "baz"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Array",
+			json:    `{"FOO": ["bar"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidName",
+			json:    `{"FOO=BAR": "baz"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid",
+			json:    `{"FOO": `,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONMap(tt.json)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env.json")
+	if err := os.WriteFile(path, []byte(`{"FOO": "bar", "N": 1}`), 0o644); err != nil {
+		t.Fatalf("could not write env JSON file: %v", err)
+	}
+
+	got, err := JSONMap(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"FOO": "bar", "N": "1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if _, err := JSONMap(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
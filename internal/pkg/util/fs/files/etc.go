@@ -0,0 +1,23 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package files
+
+import (
+	"path/filepath"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+)
+
+// HasEtc returns whether the container root filesystem at rootfs has an /etc
+// directory. Scratch and distroless images may not have one, in which case
+// the default /etc files, such as /etc/passwd or /etc/resolv.conf, should not
+// be provided, rather than a stray /etc holding only these files.
+func HasEtc(rootfs string) bool {
+	return fs.IsDir(filepath.Join(rootfs, "etc"))
+}
@@ -12,6 +12,7 @@ package files
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
@@ -88,3 +89,168 @@ func TestResolvConf(t *testing.T) {
 		t.Errorf("ResolvConf returns a bad content")
 	}
 }
+
+func TestResolvConfIPv6(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	tests := []struct {
+		name    string
+		dns     []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "IPv4",
+			dns:  []string{"192.0.2.1"},
+			want: "nameserver 192.0.2.1\n",
+		},
+		{
+			name: "GlobalIPv6",
+			dns:  []string{"2001:DB8:0:0::53"},
+			want: "nameserver 2001:db8::53\n",
+		},
+		{
+			name: "LinkLocalIPv6WithZone",
+			dns:  []string{"fe80::1%eth0"},
+			want: "nameserver fe80::1%eth0\n",
+		},
+		{
+			name: "IPv4MappedIPv6",
+			dns:  []string{"::ffff:192.0.2.1"},
+			want: "nameserver 192.0.2.1\n",
+		},
+		{
+			name: "Mixed",
+			dns:  []string{"192.0.2.1", "2001:db8::53", "fe80::1%eth0"},
+			want: "nameserver 192.0.2.1\nnameserver 2001:db8::53\nnameserver fe80::1%eth0\n",
+		},
+		{
+			name:    "LinkLocalIPv6WithoutZone",
+			dns:     []string{"fe80::1"},
+			wantErr: true,
+		},
+		{
+			name:    "IPv4WithZone",
+			dns:     []string{"192.0.2.1%eth0"},
+			wantErr: true,
+		},
+		{
+			name:    "EmptyZone",
+			dns:     []string{"fe80::1%"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := ResolvConf(tt.dns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if string(content) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, string(content))
+			}
+		})
+	}
+}
+
+func TestResolvConfOptions(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	tests := []struct {
+		name    string
+		options []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Values",
+			options: []string{"ndots:2", "timeout:1"},
+			want:    "options ndots:2 timeout:1\n",
+		},
+		{
+			name:    "Flags",
+			options: []string{"rotate", "single-request-reopen"},
+			want:    "options rotate single-request-reopen\n",
+		},
+		{
+			name:    "None",
+			options: []string{},
+			wantErr: true,
+		},
+		{
+			name:    "MissingValue",
+			options: []string{"ndots"},
+			wantErr: true,
+		},
+		{
+			name:    "BadValue",
+			options: []string{"timeout:one"},
+			wantErr: true,
+		},
+		{
+			name:    "Whitespace",
+			options: []string{"ndots:2 rotate"},
+			wantErr: true,
+		},
+		{
+			name:    "Newline",
+			options: []string{"rotate\nnameserver 192.0.2.1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := ResolvConfOptions(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if string(content) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, string(content))
+			}
+		})
+	}
+
+	// the options line is appended after the nameservers
+	content, err := ResolvConf([]string{"192.0.2.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	options, err := ResolvConfOptions([]string{"ndots:2", "timeout:1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content = append(content, options...)
+	if want := "nameserver 192.0.2.1\noptions ndots:2 timeout:1\n"; string(content) != want {
+		t.Errorf("expected %q, got %q", want, string(content))
+	}
+}
+
+func TestHasEtc(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	rootfs := t.TempDir()
+	if HasEtc(rootfs) {
+		t.Errorf("rootfs without /etc reported as having one")
+	}
+
+	// a file named etc is not an /etc directory
+	if err := os.WriteFile(filepath.Join(rootfs, "etc"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if HasEtc(rootfs) {
+		t.Errorf("rootfs with an etc file reported as having /etc")
+	}
+
+	rootfs = t.TempDir()
+	if err := os.Mkdir(filepath.Join(rootfs, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if !HasEtc(rootfs) {
+		t.Errorf("rootfs with /etc reported as not having one")
+	}
+}
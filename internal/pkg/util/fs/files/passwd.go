@@ -13,15 +13,22 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	pwd "github.com/astromechza/etcpwdparse"
+	securejoin "github.com/cyphar/filepath-securejoin"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
 	"github.com/apptainer/apptainer/internal/pkg/util/user"
 	"github.com/apptainer/apptainer/pkg/sylog"
 )
 
+// defaultPasswdShell is the login shell recorded for a user added to the
+// container passwd file, when no shell was requested and neither the root
+// entry nor the host user has a login shell found in the container.
+const defaultPasswdShell = "/bin/sh"
+
 type UserGroupLookup interface {
 	GetPwUID(uint32) (*user.User, error)
 	GetGrGID(uint32) (*user.Group, error)
@@ -29,8 +36,13 @@ type UserGroupLookup interface {
 }
 
 // Passwd creates a passwd template based on content of file provided in path,
-// updates content with current user information and returns content.
-func Passwd(path string, home string, uid int, customLookup UserGroupLookup) (content []byte, err error) {
+// updates content with current user information and returns content. path is
+// the /etc/passwd file of the container root filesystem. If shell is set, it
+// is recorded as the login shell of the user. Otherwise, the shell of an
+// existing container entry is kept, and an added entry uses the first login
+// shell found in the container from the container root entry, then the host
+// user, falling back to /bin/sh.
+func Passwd(path string, home string, shell string, uid int, customLookup UserGroupLookup) (content []byte, err error) {
 	sylog.Verbosef("Checking for template passwd file: %s", path)
 	if !fs.IsFile(path) {
 		return content, fmt.Errorf("passwd file doesn't exist in container, not updating")
@@ -66,6 +78,7 @@ func Passwd(path string, home string, uid int, customLookup UserGroupLookup) (co
 
 	sylog.Verbosef("Creating template passwd file and injecting user data: %s", path)
 	userExists := false
+	rootShell := ""
 	for i, line := range lines {
 		if line == "" {
 			continue
@@ -75,15 +88,26 @@ func Passwd(path string, home string, uid int, customLookup UserGroupLookup) (co
 		if err != nil {
 			return content, fmt.Errorf("failed to parse this /etc/passwd line in container: %#v (%s)", line, err)
 		}
+		if entry.Uid() == 0 && rootShell == "" {
+			rootShell = entry.Shell()
+		}
 		if entry.Uid() == uid {
 			userExists = true
 			// If user already exists in container, update their homedir
-			lines[i] = makePasswdLine(entry.Username(), uint32(entry.Uid()), uint32(entry.Gid()), entry.Info(), homeDir, entry.Shell())
+			entryShell := entry.Shell()
+			if shell != "" {
+				entryShell = shell
+			}
+			lines[i] = makePasswdLine(entry.Username(), uint32(entry.Uid()), uint32(entry.Gid()), entry.Info(), homeDir, entryShell)
 			break
 		}
 	}
 	if !userExists {
-		lines = append(lines, makePasswdLine(pwInfo.Name, pwInfo.UID, pwInfo.GID, pwInfo.Gecos, homeDir, pwInfo.Shell))
+		if shell == "" {
+			rootfs := filepath.Dir(filepath.Dir(path))
+			shell = containerShell(rootfs, rootShell, pwInfo.Shell)
+		}
+		lines = append(lines, makePasswdLine(pwInfo.Name, pwInfo.UID, pwInfo.GID, pwInfo.Gecos, homeDir, shell))
 	}
 
 	// Add this so that the following strings.Join call will result in text that ends in a newline
@@ -92,6 +116,25 @@ func Passwd(path string, home string, uid int, customLookup UserGroupLookup) (co
 	return []byte(strings.Join(lines, "\n")), nil
 }
 
+// containerShell returns the first of shells which is an executable file in
+// the container root filesystem rootfs, or defaultPasswdShell.
+func containerShell(rootfs string, shells ...string) string {
+	for _, shell := range shells {
+		if !filepath.IsAbs(shell) {
+			continue
+		}
+		path, err := securejoin.SecureJoin(rootfs, shell)
+		if err != nil {
+			continue
+		}
+		if fs.IsFile(path) && fs.IsExec(path) {
+			return shell
+		}
+		sylog.Debugf("Login shell %s not found in container", shell)
+	}
+	return defaultPasswdShell
+}
+
 func makePasswdLine(name string, uid uint32, gid uint32, gecos string, homedir string, shell string) string {
 	return fmt.Sprintf("%s:x:%d:%d:%s:%s:%s", name, uid, gid, gecos, homedir, shell)
 }
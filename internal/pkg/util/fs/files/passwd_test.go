@@ -10,11 +10,14 @@
 package files
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
+	"github.com/apptainer/apptainer/internal/pkg/util/user"
 	"gotest.tools/v3/golden"
 )
 
@@ -25,7 +28,7 @@ func TestPasswd(t *testing.T) {
 	uid := os.Getuid()
 
 	// Test how Passwd() works with a bad passwd file
-	_, err := Passwd("/fake", "/fake", uid, nil)
+	_, err := Passwd("/fake", "/fake", "", uid, nil)
 	if err == nil {
 		t.Errorf("should have failed with bad passwd file")
 	}
@@ -38,7 +41,7 @@ func TestPasswd(t *testing.T) {
 	emptyPasswd := f.Name()
 	defer os.Remove(emptyPasswd)
 	f.Close()
-	_, err = Passwd(emptyPasswd, "/home", uid, nil)
+	_, err = Passwd(emptyPasswd, "/home", "", uid, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -47,7 +50,7 @@ func TestPasswd(t *testing.T) {
 	testUID := 0
 	testHomeDir := "/tmp"
 	testGoldenFile := "passwd.root.customhome.golden"
-	bytes, err := Passwd(inputPasswdFilePath, testHomeDir, testUID, nil)
+	bytes, err := Passwd(inputPasswdFilePath, testHomeDir, "", testUID, nil)
 	if err != nil {
 		t.Errorf("Unexpected error encountered calling Passwd(): %v", err)
 		return
@@ -55,3 +58,113 @@ func TestPasswd(t *testing.T) {
 
 	golden.Assert(t, string(bytes), testGoldenFile, "mismatch in Passwd() invocation (uid: %d; requested homeDir: %#v)", testUID, testHomeDir)
 }
+
+// stubLookup returns a fixed user for GetPwUID.
+type stubLookup struct {
+	user *user.User
+}
+
+func (s stubLookup) GetPwUID(uint32) (*user.User, error) {
+	return s.user, nil
+}
+
+func (s stubLookup) GetGrGID(gid uint32) (*user.Group, error) {
+	return &user.Group{Name: s.user.Name, GID: gid}, nil
+}
+
+func (s stubLookup) Getgroups() ([]int, error) {
+	return []int{int(s.user.GID)}, nil
+}
+
+func TestPasswdShell(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join(".", "testdata", "passwd.in"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUser := &user.User{Name: "bender", UID: 3729, GID: 3729, Gecos: "Bender", Dir: "/home/bender", Shell: "/usr/bin/fish"}
+	existingUser := &user.User{Name: "leela", UID: 3727, GID: 3727, Gecos: "Turanga Leela", Dir: "/home/leela", Shell: "/usr/bin/zsh"}
+
+	tests := []struct {
+		name  string
+		user  *user.User
+		shell string
+		// execs are executable files created in the container root
+		// filesystem, where /bin is a symlink to usr/bin.
+		execs []string
+		want  string
+	}{
+		{
+			name: "NewUserDefault",
+			user: newUser,
+			want: "bender:x:3729:3729:Bender:/home/bender:/bin/sh",
+		},
+		{
+			name:  "NewUserRootShell",
+			user:  newUser,
+			execs: []string{"/usr/bin/ash", "/usr/bin/fish"},
+			want:  "bender:x:3729:3729:Bender:/home/bender:/bin/ash",
+		},
+		{
+			name:  "NewUserHostShell",
+			user:  newUser,
+			execs: []string{"/usr/bin/fish"},
+			want:  "bender:x:3729:3729:Bender:/home/bender:/usr/bin/fish",
+		},
+		{
+			name:  "NewUserShell",
+			user:  newUser,
+			shell: "/bin/bash",
+			execs: []string{"/usr/bin/ash"},
+			want:  "bender:x:3729:3729:Bender:/home/bender:/bin/bash",
+		},
+		{
+			name: "ExistingUserDefault",
+			user: existingUser,
+			want: "leela:x:3727:3727:Turanga Leela:/home/leela:/usr/bin/zsh",
+		},
+		{
+			name:  "ExistingUserShell",
+			user:  existingUser,
+			shell: "/bin/bash",
+			want:  "leela:x:3727:3727:Turanga Leela:/home/leela:/bin/bash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootfs := t.TempDir()
+			for _, dir := range []string{"etc", "usr/bin"} {
+				if err := os.MkdirAll(filepath.Join(rootfs, dir), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.Symlink("usr/bin", filepath.Join(rootfs, "bin")); err != nil {
+				t.Fatal(err)
+			}
+			passwd := filepath.Join(rootfs, "etc", "passwd")
+			if err := os.WriteFile(passwd, input, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for _, exec := range tt.execs {
+				if err := os.WriteFile(filepath.Join(rootfs, exec), nil, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			content, err := Passwd(passwd, "", tt.shell, int(tt.user.UID), stubLookup{user: tt.user})
+			if err != nil {
+				t.Fatalf("Unexpected error encountered calling Passwd(): %v", err)
+			}
+			prefix := fmt.Sprintf("%s:", tt.user.Name)
+			for _, line := range strings.Split(string(content), "\n") {
+				if strings.HasPrefix(line, prefix) {
+					if line != tt.want {
+						t.Errorf("expected passwd line %q, got %q", tt.want, line)
+					}
+					return
+				}
+			}
+			t.Errorf("no passwd line found for %s", tt.user.Name)
+		})
+	}
+}
@@ -11,7 +11,9 @@ package files
 
 import (
 	"fmt"
-	"net"
+	"net/netip"
+	"regexp"
+	"strings"
 
 	"github.com/apptainer/apptainer/pkg/sylog"
 )
@@ -23,11 +25,59 @@ func ResolvConf(dns []string) (content []byte, err error) {
 		return content, fmt.Errorf("no dns ip provided")
 	}
 	for _, ip := range dns {
-		if net.ParseIP(ip) == nil {
-			return content, fmt.Errorf("dns ip %s is not a valid IP address", ip)
+		addr, err := parseNameserver(ip)
+		if err != nil {
+			return content, err
 		}
-		line := fmt.Sprintf("nameserver %s\n", ip)
+		line := fmt.Sprintf("nameserver %s\n", addr)
 		content = append(content, line...)
 	}
 	return content, nil
 }
+
+// parseNameserver validates a nameserver IP address, returning it in its
+// canonical form. IPv6 addresses may carry a zone identifier, which is
+// required for a link-local address to be usable, e.g. fe80::1%eth0.
+func parseNameserver(ip string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return addr, fmt.Errorf("dns ip %s is not a valid IP address", ip)
+	}
+	if addr.Is4In6() {
+		addr = addr.Unmap()
+	}
+	if addr.Is6() && addr.IsLinkLocalUnicast() && addr.Zone() == "" {
+		return addr, fmt.Errorf("dns ip %s is a link-local IPv6 address without a zone identifier (e.g. %s%%eth0)", ip, ip)
+	}
+	return addr, nil
+}
+
+var (
+	// resolvOptionRe matches a resolver option, either a flag such as rotate,
+	// or a name:value pair such as ndots:2.
+	resolvOptionRe = regexp.MustCompile(`^[a-z][a-z0-9-]*(:[0-9]+)?$`)
+	// resolvValueOptions are the resolver options requiring a value.
+	resolvValueOptions = map[string]bool{
+		"ndots":    true,
+		"timeout":  true,
+		"attempts": true,
+	}
+)
+
+// ResolvConfOptions returns a resolv.conf options line holding the provided
+// resolver options, e.g. ndots:2 timeout:1.
+func ResolvConfOptions(options []string) (content []byte, err error) {
+	if len(options) == 0 {
+		return content, fmt.Errorf("no resolver option provided")
+	}
+	for _, o := range options {
+		if !resolvOptionRe.MatchString(o) {
+			return content, fmt.Errorf("resolver option %q is not valid", o)
+		}
+		name, _, hasValue := strings.Cut(o, ":")
+		if resolvValueOptions[name] && !hasValue {
+			return content, fmt.Errorf("resolver option %q requires a value, e.g. %s:1", o, name)
+		}
+	}
+	return []byte(fmt.Sprintf("options %s\n", strings.Join(options, " "))), nil
+}
@@ -8,6 +8,9 @@
 # LICENSE.md file distributed with the sources of this project regarding your
 # rights to use or distribute this software.
 
+# PATH precedence, once the image environment has been sourced: the image
+# PATH gets APPTAINERENV_PREPEND_PATH and APPTAINERENV_APPEND_PATH added, and
+# APPTAINERENV_PATH, when set, replaces the result entirely.
 
 if test -n "${SING_USER_DEFINED_PREPEND_PATH:-}"; then
     PATH="${SING_USER_DEFINED_PREPEND_PATH}:${PATH}"
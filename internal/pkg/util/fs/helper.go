@@ -17,8 +17,10 @@ import (
 	"sort"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/apptainer/apptainer/pkg/sylog"
+	units "github.com/docker/go-units"
 	"golang.org/x/sys/unix"
 )
 
@@ -480,6 +482,74 @@ func ForceRemoveAll(path string) error {
 	return os.RemoveAll(path)
 }
 
+// RemoveStaleTmpDirs removes directories directly within basedir whose names
+// start with one of prefixes, which are owned by the current user, and in
+// which nothing was modified, or read for files, within maxAge. It cleans up
+// temporary directories left behind when an operation was killed before it
+// could remove them. The number of directories removed is returned.
+func RemoveStaleTmpDirs(basedir string, prefixes []string, maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(basedir)
+	if err != nil {
+		return 0, err
+	}
+
+	uid := uint32(os.Getuid())
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, e := range entries {
+		if !e.IsDir() || !hasAnyPrefix(e.Name(), prefixes) {
+			continue
+		}
+		path := filepath.Join(basedir, e.Name())
+		info, err := e.Info()
+		if err != nil || !IsOwner(path, uid) || !lastUsedUnder(path, info, cutoff).Before(cutoff) {
+			continue
+		}
+		sylog.Debugf("Removing stale temporary directory %s", path)
+		if err := ForceRemoveAll(path); err != nil {
+			sylog.Warningf("Unable to remove stale temporary directory %s: %s", path, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// lastUsedUnder returns the latest modification time of the directory tree
+// at path, with root information info, or access time of its files. The walk
+// stops at the first time after cutoff. Access times of directories are
+// ignored, as walking the tree updates them.
+func lastUsedUnder(path string, info os.FileInfo, cutoff time.Time) time.Time {
+	used := info.ModTime()
+	filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if t := fi.ModTime(); t.After(used) {
+			used = t
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && fi.Mode().IsRegular() {
+			if t := time.Unix(st.Atim.Sec, st.Atim.Nsec); t.After(used) {
+				used = t
+			}
+		}
+		if used.After(cutoff) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return used
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
 // PermWalk is similar to filepath.Walk - but:
 //  1. The skipDir checks are removed (we never want to skip anything here)
 //  2. Our walk will call walkFn on a directory *before* attempting to look
@@ -610,3 +680,17 @@ func FindSize(size int64) string {
 	}
 	return fmt.Sprintf("%.2f %s", float64(size)/factor, unit)
 }
+
+// ParseSizeMiB parses a human-readable size, such as "512m" or "2g", with a
+// base-2 k, m, g or t suffix, or a number of bytes without suffix. The size is
+// returned in MiB, rounded up, for use in the size option of a tmpfs mount.
+func ParseSizeMiB(size string) (int64, error) {
+	b, err := units.RAMInBytes(size)
+	if err != nil {
+		return 0, err
+	}
+	if b <= 0 {
+		return 0, fmt.Errorf("invalid size: %q: size must be positive", size)
+	}
+	return (b + miB - 1) / miB, nil
+}
@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
 )
@@ -756,3 +757,107 @@ func TestForceRemoveAll(t *testing.T) {
 		t.Errorf("ForceRemoveAll failed to remove %s", testDir)
 	}
 }
+
+func TestRemoveStaleTmpDirs(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	baseDir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+
+	// mkdir creates a directory holding sub/file, with the times of the
+	// file, and the modification time of the directories, set to mtime.
+	mkdir := func(name string, mtime time.Time) string {
+		path := filepath.Join(baseDir, name)
+		sub := filepath.Join(path, "sub")
+		if err := os.MkdirAll(sub, 0o755); err != nil {
+			t.Fatalf("failed to create %s: %s", sub, err)
+		}
+		file := filepath.Join(sub, "file")
+		if err := os.WriteFile(file, []byte("data"), 0o644); err != nil {
+			t.Fatalf("failed to create file in %s: %s", path, err)
+		}
+		for _, p := range []string{file, sub, path} {
+			if err := os.Chtimes(p, mtime, mtime); err != nil {
+				t.Fatalf("failed to set times on %s: %s", p, err)
+			}
+		}
+		return path
+	}
+
+	staleOCI := mkdir("temp-oci-stale", old)
+	staleOras := mkdir("oras-tmp-stale", old)
+	recent := mkdir("temp-oci-recent", time.Now())
+	other := mkdir("other-stale", old)
+	// a directory whose top level is stale, but with a file read recently
+	recentFile := mkdir("temp-oci-recent-file", old)
+	if err := os.Chtimes(filepath.Join(recentFile, "sub", "file"), time.Now(), old); err != nil {
+		t.Fatalf("failed to set times in %s: %s", recentFile, err)
+	}
+	file := filepath.Join(baseDir, "temp-oci-file")
+	if err := os.WriteFile(file, []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to create %s: %s", file, err)
+	}
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatalf("failed to set times on %s: %s", file, err)
+	}
+
+	n, err := RemoveStaleTmpDirs(baseDir, []string{"temp-oci-", "oras-tmp-"}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 directories removed, got %d", n)
+	}
+
+	for _, path := range []string{staleOCI, staleOras} {
+		if ok, _ := PathExists(path); ok {
+			t.Errorf("stale directory %s was not removed", path)
+		}
+	}
+	for _, path := range []string{recent, recentFile, other, file} {
+		if ok, _ := PathExists(path); !ok {
+			t.Errorf("%s was unexpectedly removed", path)
+		}
+	}
+
+	if _, err := RemoveStaleTmpDirs(filepath.Join(baseDir, "missing"), []string{"temp-oci-"}, time.Hour); err == nil {
+		t.Errorf("expected error for non-existent base directory")
+	}
+}
+
+func TestParseSizeMiB(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "2g", want: 2048},
+		{size: "2G", want: 2048},
+		{size: "512M", want: 512},
+		{size: "512mb", want: 512},
+		{size: "1.5g", want: 1536},
+		{size: "64k", want: 1},
+		{size: "1024", want: 1},
+		{size: "1048576", want: 1},
+		{size: "1048577", want: 2},
+		{size: "", wantErr: true},
+		{size: "0", wantErr: true},
+		{size: "-1g", wantErr: true},
+		{size: "2x", wantErr: true},
+		{size: "g", wantErr: true},
+		{size: "big", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := ParseSizeMiB(tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state, got err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expected %d MiB, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -18,9 +18,11 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/fs/proc"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 )
 
 type mountError string
@@ -79,6 +81,15 @@ var mountFlags = []struct {
 	{"unbindable", syscall.MS_UNBINDABLE},
 }
 
+// conflictingOptions are pairs of mount options which cannot be set on the
+// same mount point. The first, more restrictive, option of a pair wins.
+var conflictingOptions = [][2]string{
+	{"ro", "rw"},
+	{"nosuid", "suid"},
+	{"nodev", "dev"},
+	{"noexec", "exec"},
+}
+
 type fsContext struct {
 	context bool
 }
@@ -463,6 +474,7 @@ func (p *Points) add(tag AuthorizedTag, source string, dest string, fstype strin
 		context := fmt.Sprintf("context=%q", p.context)
 		mountOpts = append(mountOpts, context)
 	}
+	mountOpts = normalizeOptions(dest, mountOpts)
 	p.points[tag] = append(p.points[tag], Point{
 		Mount: specs.Mount{
 			Source:      source,
@@ -475,6 +487,33 @@ func (p *Points) add(tag AuthorizedTag, source string, dest string, fstype strin
 	return nil
 }
 
+// normalizeOptions removes duplicate mount options of the mount point dest,
+// keeping the first occurrence of each. When conflicting options are set, a
+// warning is displayed and only the more restrictive one is kept.
+func normalizeOptions(dest string, options []string) []string {
+	seen := make(map[string]bool, len(options))
+	for _, o := range options {
+		seen[o] = true
+	}
+	skip := make(map[string]bool)
+	for _, c := range conflictingOptions {
+		if seen[c[0]] && seen[c[1]] {
+			sylog.Warningf("Mount point %s has both %s and %s options, using %s", dest, c[0], c[1], c[0])
+			skip[c[1]] = true
+		}
+	}
+	normalized := make([]string, 0, len(options))
+	for _, o := range options {
+		if skip[o] {
+			continue
+		}
+		// keep only the first occurrence of each option
+		skip[o] = true
+		normalized = append(normalized, o)
+	}
+	return normalized
+}
+
 // GetAll returns all registered mount points
 func (p *Points) GetAll() map[AuthorizedTag]PointList {
 	p.init()
@@ -535,6 +574,17 @@ func (p *Points) RemoveByDest(dest string) {
 	}
 }
 
+// RemoveByTagDest removes mount points attached to a tag and identified by
+// destination
+func (p *Points) RemoveByTagDest(tag AuthorizedTag, dest string) {
+	p.init()
+	for d := len(p.points[tag]) - 1; d >= 0; d-- {
+		if p.points[tag][d].Destination == dest {
+			p.points[tag] = append(p.points[tag][:d], p.points[tag][d+1:]...)
+		}
+	}
+}
+
 // RemoveBySource removes mount points identified by source
 func (p *Points) RemoveBySource(source string) {
 	p.init()
@@ -818,3 +868,16 @@ func (p *Points) SetContext(context string) error {
 func (p *Points) GetContext() string {
 	return p.context
 }
+
+// SetRecursiveReadonly makes the mount point at path, and all mount points
+// below it, read-only. A read-only remount of a recursive bind mount only
+// applies to the top mount point, leaving the submounts writable. This uses
+// mount_setattr, which returns an error wrapping ENOSYS on kernels older than
+// 5.12.
+func SetRecursiveReadonly(path string) error {
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}
+	if err := unix.MountSetattr(unix.AT_FDCWD, path, unix.AT_RECURSIVE, attr); err != nil {
+		return &os.PathError{Op: "mount_setattr", Path: path, Err: err}
+	}
+	return nil
+}
@@ -10,13 +10,18 @@
 package mount
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"syscall"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
 	"github.com/apptainer/apptainer/internal/pkg/test/tool/require"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 )
 
 func TestImage(t *testing.T) {
@@ -281,6 +286,90 @@ func TestBind(t *testing.T) {
 	if !hasBind {
 		t.Errorf("option rbind not applied for /mnt")
 	}
+	points.RemoveAll()
+
+	if err := points.AddBind(BindsTag, "/etc/hosts", "/etc/hosts", syscall.MS_BIND); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := points.AddBind(UserbindsTag, "/tmp/hosts", "/etc/hosts", syscall.MS_BIND); err != nil {
+		t.Fatalf("%s", err)
+	}
+	points.RemoveByTagDest(BindsTag, "/etc/hosts")
+	if len(points.GetByTag(BindsTag)) != 0 {
+		t.Errorf("failed to remove /etc/hosts bind mount point")
+	}
+	if len(points.GetByTag(UserbindsTag)) != 1 {
+		t.Errorf("/etc/hosts user bind mount point removed with another tag")
+	}
+}
+
+func TestBindOptions(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	tests := []struct {
+		name    string
+		flags   uintptr
+		options []string
+		want    []string
+	}{
+		{
+			name:    "FlagsAndOptions",
+			flags:   syscall.MS_NOSUID | syscall.MS_NODEV,
+			options: []string{"mode=755"},
+			want:    []string{"nosuid", "nodev", "bind", "mode=755"},
+		},
+		{
+			name:    "DuplicateFlagOption",
+			flags:   syscall.MS_NOSUID | syscall.MS_NODEV,
+			options: []string{"nosuid", "nodev"},
+			want:    []string{"nosuid", "nodev", "bind"},
+		},
+		{
+			name:    "DuplicateOptions",
+			options: []string{"mode=755", "uid=0", "mode=755"},
+			want:    []string{"bind", "mode=755", "uid=0"},
+		},
+		{
+			name:    "ReadOnlyReadWrite",
+			flags:   syscall.MS_RDONLY,
+			options: []string{"rw"},
+			want:    []string{"ro", "bind"},
+		},
+		{
+			name:    "NoSuidSuid",
+			flags:   syscall.MS_NOSUID,
+			options: []string{"suid"},
+			want:    []string{"nosuid", "bind"},
+		},
+		{
+			name:    "NoDevDev",
+			options: []string{"dev", "nodev"},
+			want:    []string{"bind", "nodev"},
+		},
+		{
+			name:    "NoExecExec",
+			flags:   syscall.MS_NOEXEC,
+			options: []string{"exec"},
+			want:    []string{"noexec", "bind"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := &Points{}
+			if err := points.AddBind(UserbindsTag, "/", "/mnt", tt.flags, tt.options...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			bind := points.GetByDest("/mnt")
+			if len(bind) != 1 {
+				t.Fatalf("expected one mount point for /mnt, got %d", len(bind))
+			}
+			if !reflect.DeepEqual(bind[0].Options, tt.want) {
+				t.Errorf("expected options %v, got %v", tt.want, bind[0].Options)
+			}
+		})
+	}
 }
 
 func TestRemount(t *testing.T) {
@@ -630,3 +719,50 @@ func TestTag(t *testing.T) {
 		}
 	}
 }
+
+func TestSetRecursiveReadonly(t *testing.T) {
+	test.EnsurePrivilege(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	for _, d := range []string{src, dst} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatalf("while creating %s: %s", d, err)
+		}
+	}
+
+	// src holds a submount, which is bind mounted recursively on dst
+	if err := syscall.Mount("tmpfs", src, "tmpfs", 0, ""); err != nil {
+		t.Fatalf("while mounting tmpfs on %s: %s", src, err)
+	}
+	defer syscall.Unmount(src, syscall.MNT_DETACH)
+	sub := filepath.Join(src, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("while creating %s: %s", sub, err)
+	}
+	if err := syscall.Mount("tmpfs", sub, "tmpfs", 0, ""); err != nil {
+		t.Fatalf("while mounting tmpfs on %s: %s", sub, err)
+	}
+	if err := syscall.Mount(src, dst, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		t.Fatalf("while bind mounting %s on %s: %s", src, dst, err)
+	}
+	defer syscall.Unmount(dst, syscall.MNT_DETACH)
+
+	if err := SetRecursiveReadonly(dst); errors.Is(err, unix.ENOSYS) {
+		t.Skipf("recursive read-only mounts are not supported by the kernel: %s", err)
+	} else if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, d := range []string{dst, filepath.Join(dst, "sub")} {
+		err := os.WriteFile(filepath.Join(d, "file"), []byte("data"), 0o644)
+		if !errors.Is(err, syscall.EROFS) {
+			t.Errorf("expected %s to be read-only, got err=%v", d, err)
+		}
+	}
+	// the source mount points are not affected
+	if err := os.WriteFile(filepath.Join(sub, "file"), []byte("data"), 0o644); err != nil {
+		t.Errorf("unexpected error writing to %s: %s", sub, err)
+	}
+}
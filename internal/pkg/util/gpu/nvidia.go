@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -158,6 +159,39 @@ func NVCLIConfigure(nvidiaEnv []string, rootfs string, userNS bool) error {
 	return nil
 }
 
+// ParseGPUs converts a docker style --gpus value to the list of devices to
+// set in NVIDIA_VISIBLE_DEVICES. The value may be 'all', a number of GPUs, or
+// a comma separated list of GPU indexes or UUIDs, optionally prefixed with
+// 'device='.
+func ParseGPUs(value string) (string, error) {
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	if value == "all" {
+		return value, nil
+	}
+
+	list, isList := strings.CutPrefix(value, "device=")
+	if !isList {
+		if count, err := strconv.Atoi(value); err == nil {
+			if count < 1 {
+				return "", fmt.Errorf("invalid GPU count %d, must be at least 1", count)
+			}
+			devices := make([]string, count)
+			for i := range devices {
+				devices[i] = strconv.Itoa(i)
+			}
+			return strings.Join(devices, ","), nil
+		}
+	}
+
+	devices := strings.Split(list, ",")
+	for _, d := range devices {
+		if d == "" || strings.ContainsAny(d, " \t=") {
+			return "", fmt.Errorf("invalid GPU device %q in %q", d, value)
+		}
+	}
+	return strings.Join(devices, ","), nil
+}
+
 // NVCLIEnvToFlags reads the passed in NVIDIA_ environment variables supported
 // by nvidia-container-runtime and converts them to flags for
 // nvidia-container-cli. See:
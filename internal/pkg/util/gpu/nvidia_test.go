@@ -163,3 +163,36 @@ func TestNVCLIEnvToFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestParseGPUs(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "all", value: "all", want: "all"},
+		{name: "quoted all", value: `"all"`, want: "all"},
+		{name: "count", value: "2", want: "0,1"},
+		{name: "count one", value: "1", want: "0"},
+		{name: "count zero", value: "0", wantErr: true},
+		{name: "negative count", value: "-1", wantErr: true},
+		{name: "list", value: "0,1", want: "0,1"},
+		{name: "device list", value: "device=1,3", want: "1,3"},
+		{name: "single device", value: `"device=0"`, want: "0"},
+		{name: "uuid", value: "GPU-3a23c669-1f69-c64e-cf85-44e9b07e7a2a", want: "GPU-3a23c669-1f69-c64e-cf85-44e9b07e7a2a"},
+		{name: "empty", value: "", wantErr: true},
+		{name: "empty device", value: "0,,1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGPUs(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGPUs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseGPUs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -36,6 +36,7 @@ type format struct {
 	Class      elf.Class
 	Endianness binary.ByteOrder
 	ElfMagic   []byte
+	Qemu       string
 }
 
 var formats = []format{
@@ -46,6 +47,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS32,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x03, 0x00},
+		Qemu:       "i386",
 	},
 	{
 		Arch:       "386",
@@ -54,6 +56,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS32,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x06, 0x00},
+		Qemu:       "i386",
 	},
 	{
 		Arch:       "amd64",
@@ -61,6 +64,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x3e, 0x00},
+		Qemu:       "x86_64",
 	},
 	{
 		Arch:       "arm",
@@ -69,6 +73,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS32,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x28, 0x00},
+		Qemu:       "arm",
 	},
 	{
 		Arch:       "armbe",
@@ -77,6 +82,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS32,
 		Endianness: binary.BigEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x01, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x28},
+		Qemu:       "armeb",
 	},
 	{
 		Arch:       "arm64",
@@ -84,6 +90,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0xb7, 0x00},
+		Qemu:       "aarch64",
 	},
 	{
 		Arch:       "arm64be",
@@ -91,6 +98,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.BigEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0xb7},
+		Qemu:       "aarch64_be",
 	},
 	{
 		Arch:       "s390x",
@@ -98,6 +106,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.BigEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x16},
+		Qemu:       "s390x",
 	},
 	{
 		Arch:       "ppc64",
@@ -105,6 +114,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS32,
 		Endianness: binary.BigEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x15},
+		Qemu:       "ppc64",
 	},
 	{
 		Arch:       "ppc64le",
@@ -112,6 +122,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x15, 0x00},
+		Qemu:       "ppc64le",
 	},
 	{
 		Arch:       "mips",
@@ -120,6 +131,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS32,
 		Endianness: binary.BigEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x01, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x08},
+		Qemu:       "mips",
 	},
 	{
 		Arch:       "mipsle",
@@ -128,6 +140,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS32,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x08, 0x00},
+		Qemu:       "mipsel",
 	},
 	{
 		Arch:       "mips64",
@@ -135,6 +148,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.BigEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x08},
+		Qemu:       "mips64",
 	},
 	{
 		Arch:       "mips64le",
@@ -142,6 +156,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x08, 0x00},
+		Qemu:       "mips64el",
 	},
 	{
 		Arch:       "riscv64",
@@ -149,6 +164,7 @@ var formats = []format{
 		Class:      elf.ELFCLASS64,
 		Endianness: binary.LittleEndian,
 		ElfMagic:   []byte{0x7F, 0x45, 0x4C, 0x46, 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0xf3, 0x00},
+		Qemu:       "riscv64",
 	},
 }
 
@@ -218,42 +234,64 @@ func ArchFromContainer(container string) string {
 	return arch
 }
 
-const binfmtMisc = "/proc/sys/fs/binfmt_misc"
+// binfmtMisc is a variable so it can be changed by tests.
+var binfmtMisc = "/proc/sys/fs/binfmt_misc"
+
+// ErrNoEmulation is the error returned when binfmt_misc has no enabled
+// registration to run binaries of an architecture.
+var ErrNoEmulation = errors.New("no binfmt_misc registration found")
 
 type binfmtEntry struct {
-	magic      string
-	enabled    bool
-	persistent bool
+	magic       string
+	interpreter string
+	enabled     bool
+	persistent  bool
 }
 
-func canEmulate(arch string) bool {
-	var format format
-
+func getFormat(arch string) (format, error) {
 	for _, f := range formats {
 		if arch == f.Arch {
-			format = f
-			break
+			return f, nil
 		}
 	}
+	return format{}, ErrUnknownArch
+}
+
+// QemuBinary returns the name of the static qemu user mode emulator for the
+// architecture passed in argument, e.g. qemu-aarch64-static for arm64.
+func QemuBinary(arch string) (string, error) {
+	format, err := getFormat(arch)
+	if err != nil {
+		return "", err
+	}
+	return "qemu-" + format.Qemu + "-static", nil
+}
 
-	// no architecture format found
-	if format.Arch == "" {
-		return false
+// Emulator returns the interpreter registered in /proc/sys/fs/binfmt_misc
+// to run binaries of the architecture passed in argument, and whether it is
+// registered with the F (fix binary) flag. Without this flag the interpreter
+// is looked up when a binary is executed, so it must be present at the same
+// path in the container.
+func Emulator(arch string) (interpreter string, persistent bool, err error) {
+	format, err := getFormat(arch)
+	if err != nil {
+		return "", false, err
 	}
 
-	// look at /proc/sys/fs/binfmt_misc
 	content, _ := os.ReadFile(filepath.Join(binfmtMisc, "status"))
 	if string(content) != "enabled\n" {
-		return false
+		return "", false, fmt.Errorf("%w: binfmt_misc is not enabled", ErrNoEmulation)
 	}
 
 	entries, err := os.ReadDir(binfmtMisc)
 	if err != nil {
-		return false
+		return "", false, fmt.Errorf("%w: %s", ErrNoEmulation, err)
 	}
 
 	archMagic := hex.EncodeToString(format.ElfMagic)
 
+	var found *binfmtEntry
+
 	for _, entry := range entries {
 		f := filepath.Join(binfmtMisc, entry.Name())
 		b, err := os.ReadFile(f)
@@ -269,6 +307,11 @@ func canEmulate(arch string) bool {
 
 			if t == "enabled" {
 				entry.enabled = true
+			} else if strings.HasPrefix(t, "interpreter") {
+				splitted := strings.Split(t, " ")
+				if len(splitted) > 1 {
+					entry.interpreter = splitted[1]
+				}
 			} else if strings.HasPrefix(t, "magic") {
 				splitted := strings.Split(t, " ")
 				if len(splitted) > 1 {
@@ -282,12 +325,26 @@ func canEmulate(arch string) bool {
 			}
 		}
 
-		if entry.enabled && entry.persistent && entry.magic == archMagic {
-			return true
+		if !entry.enabled || entry.magic != archMagic {
+			continue
+		}
+		// prefer a persistent registration, which works in any container
+		if entry.persistent {
+			return entry.interpreter, true, nil
+		} else if found == nil {
+			found = entry
 		}
 	}
 
-	return false
+	if found == nil {
+		return "", false, fmt.Errorf("%w for %s binaries", ErrNoEmulation, arch)
+	}
+	return found.interpreter, false, nil
+}
+
+func canEmulate(arch string) bool {
+	_, persistent, err := Emulator(arch)
+	return err == nil && persistent
 }
 
 // CompatibleWith returns if the current machine architecture is
@@ -193,6 +193,16 @@ func getCredentialsFromFile(reqAuthFile string) (*configfile.ConfigFile, error)
 	return cf, nil
 }
 
+// Authenticator returns the authenticator to use for target. As for AuthOptn,
+// credentials in ociAuth take precedence over any found in the auth file.
+func Authenticator(ociAuth *authn.AuthConfig, reqAuthFile string, target authn.Resource) (authn.Authenticator, error) {
+	if ociAuth != nil {
+		return authn.FromConfig(*ociAuth), nil
+	}
+
+	return (&apptainerKeychain{reqAuthFile: reqAuthFile}).Resolve(target)
+}
+
 func AuthOptn(ociAuth *authn.AuthConfig, reqAuthFile string) remote.Option {
 	if ociAuth != nil {
 		return remote.WithAuth(authn.FromConfig(*ociAuth))
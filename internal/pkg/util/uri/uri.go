@@ -70,7 +70,8 @@ func GetName(uri string) string {
 	refSplit := strings.Split(ref, "/") // Split ref into parts
 
 	if transport == HTTP || transport == HTTPS {
-		imageName := refSplit[len(refSplit)-1]
+		// Drop a URL fragment, such as a #sha256= checksum
+		imageName, _, _ := strings.Cut(refSplit[len(refSplit)-1], "#")
 		return imageName
 	}
 
@@ -23,6 +23,8 @@ func Test_GetName(t *testing.T) {
 		{"docker scoped", "docker://user/image", "image_latest.sif"},
 		{"dave's magical lolcow", "docker://sylabs.io/lolcow", "lolcow_latest.sif"},
 		{"docker w/ tags", "docker://sylabs.io/lolcow:3.7", "lolcow_3.7.sif"},
+		{"https", "https://example.com/images/lolcow.sif", "lolcow.sif"},
+		{"https w/ checksum", "https://example.com/images/lolcow.sif#sha256=abc", "lolcow.sif"},
 	}
 
 	for _, tt := range tests {
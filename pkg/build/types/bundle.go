@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/apptainer/apptainer/internal/pkg/cache"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
@@ -92,8 +93,24 @@ type Options struct {
 	Unprivilege bool
 	// Arch info
 	Arch string
+	// ArchVariant selects the CPU variant of the OCI image to fetch, in place
+	// of the variant of the host.
+	ArchVariant string
 	// Authentication file for registry credentials
 	ReqAuthFile string
+	// Concurrency limits the number of OCI layers fetched in parallel.
+	// Zero leaves the transport default in place.
+	Concurrency int
+	// OCITimeout is the overall deadline for fetching an OCI image, no
+	// deadline is set if zero.
+	OCITimeout time.Duration
+	// OCIOffline fetches OCI registry images from the cache only, without
+	// network access.
+	OCIOffline bool
+	// FakerootIDs maps the ownership of files extracted from OCI images onto
+	// the full id range of a fakeroot user namespace, instead of collapsing it
+	// to the building user.
+	FakerootIDs bool
 }
 
 // NewEncryptedBundle creates an Encrypted Bundle environment.
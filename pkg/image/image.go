@@ -131,16 +131,20 @@ type Section struct {
 // image format like SIF contains descriptors pointing to chunk of
 // data, chunks position and size are stored as image sections.
 type Image struct {
-	Partitions []Section `json:"partitions"`
-	Sections   []Section `json:"sections"`
-	Path       string    `json:"path"`
-	Name       string    `json:"name"`
-	Source     string    `json:"source"`
-	Type       int       `json:"type"`
-	File       *os.File  `json:"-"`
-	Fd         uintptr   `json:"fd"`
-	Writable   bool      `json:"writable"`
-	Usage      Usage     `json:"usage"`
+	Partitions   []Section `json:"partitions"`
+	Sections     []Section `json:"sections"`
+	Path         string    `json:"path"`
+	Name         string    `json:"name"`
+	Source       string    `json:"source"`
+	Type         int       `json:"type"`
+	File         *os.File  `json:"-"`
+	Fd           uintptr   `json:"fd"`
+	Writable     bool      `json:"writable"`
+	Usage        Usage     `json:"usage"`
+	Architecture string    `json:"architecture,omitempty"`
+
+	// ignoreArch disables the architecture check of SIF images
+	ignoreArch bool
 }
 
 // ReInit fills in the File object if needed.  This function should be
@@ -369,8 +373,21 @@ func ResolvePath(path string) (string, error) {
 	return resolvedPath, nil
 }
 
-// Init initializes an image object based on given path.
+// Init initializes an image object based on given path. A SIF image
+// targeting an architecture which the host can't run is refused with an
+// *ArchitectureError.
 func Init(path string, writable bool) (*Image, error) {
+	return initImage(path, writable, false)
+}
+
+// InitIgnoreArch initializes an image object based on given path, like Init,
+// but doesn't check the architecture targeted by a SIF image. The caller can
+// check it with CheckArchitecture.
+func InitIgnoreArch(path string, writable bool) (*Image, error) {
+	return initImage(path, writable, true)
+}
+
+func initImage(path string, writable bool, ignoreArch bool) (*Image, error) {
 	sylog.Debugf("Image format detection")
 
 	resolvedPath, err := ResolvePath(path)
@@ -387,6 +404,8 @@ func Init(path string, writable bool) (*Image, error) {
 		Name:  filepath.Base(resolvedPath),
 		Fd:    emptyFd,
 		Usage: RootFsUsage,
+
+		ignoreArch: ignoreArch,
 	}
 
 	for _, rf := range registeredFormats {
@@ -103,8 +103,13 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 		// CompatibleWith call will also check that the current machine
 		// has persistent emulation enabled in /proc/sys/fs/binfmt_misc to
 		// be able to execute container process correctly
-		if goArch != "unknown" && !machine.CompatibleWith(goArch) {
-			return fmt.Errorf("the image's architecture (%s) could not run on the host's (%s)", goArch, runtime.GOARCH)
+		if goArch != "unknown" {
+			img.Architecture = goArch
+			if !img.ignoreArch {
+				if err := img.CheckArchitecture(); err != nil {
+					return err
+				}
+			}
 		}
 
 		groupID = desc.GroupID()
@@ -192,3 +197,31 @@ func (f *sifFormat) lock(img *Image) error {
 	}
 	return nil
 }
+
+// ArchitectureError is returned by CheckArchitecture when the image targets
+// an architecture which can't run on the host.
+type ArchitectureError struct {
+	// Image is the architecture targeted by the image.
+	Image string
+	// Host is the architecture of the host.
+	Host string
+}
+
+func (e *ArchitectureError) Error() string {
+	return fmt.Sprintf(
+		"the image's architecture (%[1]s) could not run on the host's (%[2]s): use an image for %[2]s, e.g. pulled with --arch %[2]s, "+
+			"or if emulation of %[1]s is set up with binfmt_misc and qemu, use --ignore-arch",
+		e.Image, e.Host,
+	)
+}
+
+// CheckArchitecture returns an *ArchitectureError if the image targets an
+// architecture which the host can't run, natively or with emulation enabled
+// persistently in /proc/sys/fs/binfmt_misc. Images without a known
+// architecture, such as sandboxes, are not checked.
+func (i *Image) CheckArchitecture() error {
+	if i.Architecture == "" || machine.CompatibleWith(i.Architecture) {
+		return nil
+	}
+	return &ArchitectureError{Image: i.Architecture, Host: runtime.GOARCH}
+}
@@ -11,11 +11,14 @@ package image
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/apptainer/apptainer/internal/pkg/util/machine"
 	"github.com/apptainer/sif/v2/pkg/sif"
 )
 
@@ -219,3 +222,73 @@ func TestSIFOpenMode(t *testing.T) {
 		t.Fatal("openMode(false) returned the wrong value")
 	}
 }
+
+func TestCheckArchitecture(t *testing.T) {
+	otherArch := "s390x"
+	if runtime.GOARCH == otherArch {
+		otherArch = "amd64"
+	}
+
+	if err := (&Image{}).CheckArchitecture(); err != nil {
+		t.Errorf("unexpected error for image without architecture: %s", err)
+	}
+	if err := (&Image{Architecture: runtime.GOARCH}).CheckArchitecture(); err != nil {
+		t.Errorf("unexpected error for image of host architecture: %s", err)
+	}
+
+	if machine.CompatibleWith(otherArch) {
+		t.Skipf("host can run %s images through emulation", otherArch)
+	}
+	err := (&Image{Architecture: otherArch}).CheckArchitecture()
+	var archErr *ArchitectureError
+	if !errors.As(err, &archErr) {
+		t.Fatalf("expected an architecture error, got %v", err)
+	}
+	if archErr.Image != otherArch || archErr.Host != runtime.GOARCH {
+		t.Errorf("unexpected architectures in error: %+v", archErr)
+	}
+	if !strings.Contains(err.Error(), "--arch "+runtime.GOARCH) {
+		t.Errorf("error %q doesn't suggest --arch", err)
+	}
+}
+
+func TestInitIgnoreArch(t *testing.T) {
+	otherArch := "s390x"
+	if runtime.GOARCH == otherArch {
+		otherArch = "amd64"
+	}
+	if machine.CompatibleWith(otherArch) {
+		t.Skipf("host can run %s images through emulation", otherArch)
+	}
+
+	b, err := os.ReadFile(testSquash)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", testSquash, err)
+	}
+	path := createSIF(t, false, func() (sif.DescriptorInput, error) {
+		return sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(b),
+			sif.OptPartitionMetadata(sif.FsSquash, sif.PartPrimSys, otherArch),
+		)
+	})
+	defer os.Remove(path)
+
+	var archErr *ArchitectureError
+	if _, err := Init(path, false); !errors.As(err, &archErr) {
+		t.Errorf("expected an architecture error from Init, got %v", err)
+	}
+
+	img, err := InitIgnoreArch(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error from InitIgnoreArch: %s", err)
+	}
+	defer img.File.Close()
+	if img.Architecture != otherArch {
+		t.Errorf("expected architecture %s, got %q", otherArch, img.Architecture)
+	}
+	if len(img.Partitions) != 1 {
+		t.Errorf("expected 1 partition, got %d", len(img.Partitions))
+	}
+	if err := img.CheckArchitecture(); !errors.As(err, &archErr) {
+		t.Errorf("expected an architecture error from CheckArchitecture, got %v", err)
+	}
+}
@@ -13,27 +13,235 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
+	"github.com/apptainer/apptainer/internal/pkg/util/machine"
+	securejoin "github.com/cyphar/filepath-securejoin"
 	imageSpecs "github.com/opencontainers/image-spec/specs-go/v1"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 
 	"github.com/apptainer/apptainer/pkg/image"
 	"github.com/apptainer/apptainer/pkg/ocibundle"
 	"github.com/apptainer/apptainer/pkg/ocibundle/tools"
+	"github.com/apptainer/apptainer/pkg/ociruntime"
+	"github.com/apptainer/apptainer/pkg/sylog"
 )
 
 type sifBundle struct {
 	image      string
 	bundlePath string
 	writable   bool
+	emulate    bool
+	// entrypoint replaces the image ENTRYPOINT if overrideEntrypoint is set.
+	entrypoint         []string
+	overrideEntrypoint bool
+	// scratchDirs are container paths where a tmpfs of scratchSize MiB is
+	// mounted, or a directory under workDir is bound if set.
+	scratchDirs []string
+	scratchSize int64
+	workDir     string
 	ocibundle.Bundle
 }
 
+// Option is a functional option for FromSif.
+type Option func(s *sifBundle)
+
+// OptEmulate sets whether an image targeting an architecture which the host
+// can't run natively is run with a qemu user mode emulator, rather than
+// returning an error.
+func OptEmulate(b bool) Option {
+	return func(s *sifBundle) {
+		s.emulate = b
+	}
+}
+
+// OptEntrypoint replaces the ENTRYPOINT of the image with entrypoint, as
+// docker run --entrypoint does. An empty entrypoint resets it, so that the
+// image CMD, or the process args of the container config, run without it.
+func OptEntrypoint(entrypoint []string) Option {
+	return func(s *sifBundle) {
+		s.entrypoint = entrypoint
+		s.overrideEntrypoint = true
+	}
+}
+
+// OptScratchDirs mounts a scratch tmpfs at each of dirs in the container, as
+// the native --scratch option does. A size greater than 0 limits each tmpfs
+// to size MiB.
+func OptScratchDirs(dirs []string, size int64) Option {
+	return func(s *sifBundle) {
+		s.scratchDirs = dirs
+		s.scratchSize = size
+	}
+}
+
+// OptWorkDir sets a host directory holding the scratch directories, rather
+// than tmpfs mounts, as the native --workdir option does.
+func OptWorkDir(dir string) Option {
+	return func(s *sifBundle) {
+		s.workDir = dir
+	}
+}
+
+// emulator is a variable so it can be replaced by tests.
+var emulator = machine.Emulator
+
+// addEmulator adds a bind mount of the qemu user mode emulator for arch to
+// the container, at the interpreter path registered in binfmt_misc. This is
+// the path the kernel resolves in the container when executing a binary,
+// unless the registration has the F flag. If no registration is found a
+// warning is printed and the emulator is bound at /usr/bin.
+func addEmulator(g *generate.Generator, arch string) error {
+	qemu, err := machine.QemuBinary(arch)
+	if err != nil {
+		return fmt.Errorf("no emulator known for %s: %w", arch, err)
+	}
+
+	interpreter, _, err := emulator(arch)
+	if err != nil {
+		sylog.Warningf("%s, the container will likely fail with 'exec format error'", err)
+		sylog.Warningf("Install qemu-user-static, or register %s in binfmt_misc, to run %s images", qemu, arch)
+		interpreter = filepath.Join("/usr/bin", qemu)
+	}
+
+	src := interpreter
+	if _, err := os.Stat(src); err != nil {
+		src, err = exec.LookPath(qemu)
+		if err != nil {
+			return fmt.Errorf("%s is required to run %s images: %w", qemu, arch, err)
+		}
+	}
+
+	sylog.Verbosef("Running %s image with %s emulation, binding %s to %s", arch, qemu, src, interpreter)
+	g.AddMount(specs.Mount{
+		Source:      src,
+		Destination: interpreter,
+		Type:        "none",
+		Options:     []string{"bind", "ro", "nosuid"},
+	})
+	return nil
+}
+
+// addScratchMounts adds a tmpfs mount at each of dirs to the container,
+// writable by any user like /tmp, limited to size MiB if size is greater
+// than 0. If workdir is set, the scratch directories are instead created
+// under workdir/scratch and bound into the container.
+func addScratchMounts(g *generate.Generator, dirs []string, size int64, workdir string) error {
+	if workdir != "" {
+		var err error
+		workdir, err = filepath.Abs(filepath.Clean(workdir))
+		if err != nil {
+			return fmt.Errorf("can't determine absolute path of workdir %s: %s", workdir, err)
+		}
+	}
+
+	options := []string{"nosuid", "nodev", "mode=1777"}
+	if size > 0 {
+		options = append(options, fmt.Sprintf("size=%dm", size))
+	}
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("scratch directory %s must be an absolute path", dir)
+		}
+		dir = filepath.Clean(dir)
+
+		if workdir == "" {
+			sylog.Debugf("Adding scratch directory %s", dir)
+			g.AddMount(specs.Mount{
+				Source:      "tmpfs",
+				Destination: dir,
+				Type:        "tmpfs",
+				Options:     options,
+			})
+			continue
+		}
+
+		src, err := createScratchWorkDir(workdir, dir)
+		if err != nil {
+			return err
+		}
+		sylog.Debugf("Adding scratch directory %s from %s", dir, src)
+		g.AddMount(specs.Mount{
+			Source:      src,
+			Destination: dir,
+			Type:        "none",
+			Options:     []string{"bind", "rw", "nosuid", "nodev"},
+		})
+	}
+	return nil
+}
+
+// createScratchWorkDir creates the scratch directory dir under workdir/scratch,
+// writable by any user like /tmp, and returns its path. The path is walked
+// from / without following symbolic links, and each directory on the way must
+// be owned by root or the caller and not be writable by other users, unless
+// sticky. This prevents another user from redirecting the bind mount, or the
+// permission change, to a directory of their choice.
+func createScratchWorkDir(workdir, dir string) (string, error) {
+	fd, err := unix.Open("/", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return "", fmt.Errorf("could not open /: %s", err)
+	}
+	defer func() { unix.Close(fd) }()
+
+	workdirParts := strings.Split(strings.TrimPrefix(workdir, "/"), "/")
+	scratchParts := strings.Split(strings.TrimPrefix(filepath.Join("scratch", dir), "/"), "/")
+
+	path := "/"
+	for i, name := range append(workdirParts, scratchParts...) {
+		if name == "" {
+			continue
+		}
+		path = filepath.Join(path, name)
+		// only the scratch directories are created, the workdir must exist
+		if i >= len(workdirParts) {
+			if err := unix.Mkdirat(fd, name, 0o755); err != nil && err != unix.EEXIST {
+				return "", fmt.Errorf("could not create scratch working directory %s: %s", path, err)
+			}
+		}
+		nfd, err := unix.Openat(fd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err == unix.ELOOP || err == unix.ENOTDIR {
+			return "", fmt.Errorf("refusing scratch working directory %s: %s is not a directory or is a symbolic link", filepath.Join(workdir, "scratch", dir), path)
+		} else if err != nil {
+			return "", fmt.Errorf("could not open %s: %s", path, err)
+		}
+		unix.Close(fd)
+		fd = nfd
+
+		var st unix.Stat_t
+		if err := unix.Fstat(fd, &st); err != nil {
+			return "", fmt.Errorf("could not get status of %s: %s", path, err)
+		}
+		if st.Uid != 0 && int(st.Uid) != os.Geteuid() {
+			return "", fmt.Errorf("refusing scratch working directory under %s, owned by uid %d", path, st.Uid)
+		}
+		if st.Mode&0o022 != 0 && st.Mode&unix.S_ISVTX == 0 {
+			return "", fmt.Errorf("refusing scratch working directory under %s, writable by other users", path)
+		}
+	}
+
+	if err := unix.Fchmod(fd, unix.S_ISVTX|0o777); err != nil {
+		return "", fmt.Errorf("could not set permissions of scratch working directory %s: %s", path, err)
+	}
+	return path, nil
+}
+
 func (s *sifBundle) writeConfig(img *image.Image, g *generate.Generator) error {
+	rootfs := tools.RootFs(s.bundlePath).Path()
+	if err := checkCwd(g, rootfs); err != nil {
+		return err
+	}
+
+	if g.Config.Annotations == nil {
+		g.Config.Annotations = make(map[string]string)
+	}
+	g.Config.Annotations[ociruntime.AnnotationImage] = s.image
+
 	// check if SIF file contain an OCI image configuration
 	reader, err := image.NewSectionReader(img, image.SIFDescOCIConfigJSON, -1)
 	if err != nil && err != image.ErrNoSection {
@@ -48,16 +256,82 @@ func (s *sifBundle) writeConfig(img *image.Image, g *generate.Generator) error {
 		return fmt.Errorf("failed to decode %s: %s", image.SIFDescOCIConfigJSON, err)
 	}
 
+	if s.overrideEntrypoint {
+		if err := overrideEntrypoint(g, &imgConfig, s.entrypoint); err != nil {
+			return err
+		}
+	}
+
+	applyImageConfig(g, imgConfig, rootfs)
+
+	// the image user applies unless the configuration sets a non-root user
+	if imgConfig.User != "" && g.Config.Process.User.UID == 0 && g.Config.Process.User.GID == 0 {
+		user, err := tools.BundleUser(s.bundlePath, imgConfig.User)
+		if err != nil {
+			sylog.Warningf("Could not resolve image user %q, running container process as root: %v", imgConfig.User, err)
+		} else {
+			sylog.Debugf("Running container process as image user %q (uid %d, gid %d)", imgConfig.User, user.UID, user.GID)
+			g.Config.Process.User = user
+		}
+	}
+
+	volumes := tools.Volumes(s.bundlePath).Path()
+	for dst := range imgConfig.Volumes {
+		replacer := strings.NewReplacer(string(os.PathSeparator), "_")
+		src := filepath.Join(volumes, replacer.Replace(dst))
+		if err := os.MkdirAll(src, 0o755); err != nil {
+			return fmt.Errorf("failed to create volume directory %s: %s", src, err)
+		}
+		g.AddMount(specs.Mount{
+			Source:      src,
+			Destination: dst,
+			Type:        "none",
+			Options:     []string{"bind", "rw"},
+		})
+	}
+
+	return tools.SaveBundleConfig(s.bundlePath, g)
+}
+
+// shellMetachars are characters which indicate that a command string must be
+// interpreted by a shell. Blanks are not included, as an executable path may
+// hold spaces.
+const shellMetachars = "\n|&;<>()$`\\\"'*?[]#~"
+
+// shellFormArgs wraps args with /bin/sh -c if it is a single string holding a
+// shell command, rather than an executable path, e.g. `["echo hi && echo bye"]`.
+// This is the case for images built by tools that record a shell-form
+// ENTRYPOINT / CMD without the /bin/sh -c prefix that Docker adds. A single
+// string without shell metacharacters, e.g. `["/opt/my app/run"]`, and
+// exec-form args are returned unchanged: they are never split on blanks.
+func shellFormArgs(args []string) []string {
+	if len(args) != 1 || !strings.ContainsAny(args[0], shellMetachars) {
+		return args
+	}
+	sylog.Debugf("Running shell-form command %q with /bin/sh -c", args[0])
+	return []string{"/bin/sh", "-c", args[0]}
+}
+
+// applyImageConfig sets the container process arguments, working directory
+// and environment from the image config, where they have not been set
+// explicitly. The image working directory is only used if it exists as a
+// directory in rootfs. The resulting process arguments are logged at verbose
+// level.
+func applyImageConfig(g *generate.Generator, imgConfig imageSpecs.ImageConfig, rootfs string) {
 	if len(g.Config.Process.Args) == 1 && g.Config.Process.Args[0] == tools.RunScript {
 		args := imgConfig.Entrypoint
 		args = append(args, imgConfig.Cmd...)
 		if len(args) > 0 {
-			g.SetProcessArgs(args)
+			g.SetProcessArgs(shellFormArgs(args))
 		}
 	}
 
 	if g.Config.Process.Cwd == "" && imgConfig.WorkingDir != "" {
-		g.SetProcessCwd(imgConfig.WorkingDir)
+		if isRootfsDir(rootfs, imgConfig.WorkingDir) {
+			g.SetProcessCwd(imgConfig.WorkingDir)
+		} else {
+			sylog.Warningf("Image working directory %s does not exist in the container, ignoring", imgConfig.WorkingDir)
+		}
 	}
 	for _, e := range imgConfig.Env {
 		found := false
@@ -73,22 +347,71 @@ func (s *sifBundle) writeConfig(img *image.Image, g *generate.Generator) error {
 		}
 	}
 
-	volumes := tools.Volumes(s.bundlePath).Path()
-	for dst := range imgConfig.Volumes {
-		replacer := strings.NewReplacer(string(os.PathSeparator), "_")
-		src := filepath.Join(volumes, replacer.Replace(dst))
-		if err := os.MkdirAll(src, 0o755); err != nil {
-			return fmt.Errorf("failed to create volume directory %s: %s", src, err)
+	if imgConfig.StopSignal != "" {
+		if g.Config.Annotations == nil {
+			g.Config.Annotations = make(map[string]string)
 		}
-		g.AddMount(specs.Mount{
-			Source:      src,
-			Destination: dst,
-			Type:        "none",
-			Options:     []string{"bind", "rw"},
-		})
+		g.Config.Annotations[ociruntime.AnnotationStopSignal] = imgConfig.StopSignal
 	}
 
-	return tools.SaveBundleConfig(s.bundlePath, g)
+	sylog.Verbosef("Container process args: %q (%d environment variables)", g.Config.Process.Args, len(g.Config.Process.Env))
+}
+
+// overrideEntrypoint replaces the ENTRYPOINT of imgConfig with entrypoint.
+// As with docker, the image CMD is only kept when the entrypoint is reset to
+// empty. An error is returned if an empty entrypoint leaves no command to run,
+// as the process args of the container config are the default runscript and
+// the image has no CMD.
+func overrideEntrypoint(g *generate.Generator, imgConfig *imageSpecs.ImageConfig, entrypoint []string) error {
+	if len(entrypoint) > 0 {
+		sylog.Debugf("Replacing image entrypoint %q with %q", imgConfig.Entrypoint, entrypoint)
+		imgConfig.Entrypoint = entrypoint
+		imgConfig.Cmd = nil
+		return nil
+	}
+
+	sylog.Debugf("Resetting image entrypoint %q", imgConfig.Entrypoint)
+	imgConfig.Entrypoint = nil
+	defaultArgs := len(g.Config.Process.Args) == 1 && g.Config.Process.Args[0] == tools.RunScript
+	if defaultArgs && len(imgConfig.Cmd) == 0 {
+		return fmt.Errorf("no command to run: the entrypoint was reset and the image has no CMD")
+	}
+	return nil
+}
+
+// isRootfsDir returns true if path is a directory within rootfs. Symlinks are
+// resolved within rootfs.
+func isRootfsDir(rootfs, path string) bool {
+	fullPath, err := securejoin.SecureJoin(rootfs, path)
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(fullPath)
+	return err == nil && fi.IsDir()
+}
+
+// checkCwd returns a descriptive error if the working directory requested in
+// the container process config does not exist as a directory in rootfs. A
+// working directory at, or below, a mount destination is not checked, as it
+// will be provided by the mount.
+func checkCwd(g *generate.Generator, rootfs string) error {
+	if g.Config.Process == nil {
+		return nil
+	}
+	cwd := filepath.Clean(g.Config.Process.Cwd)
+	if g.Config.Process.Cwd == "" || cwd == "/" {
+		return nil
+	}
+	for _, m := range g.Config.Mounts {
+		dest := filepath.Clean(m.Destination)
+		if cwd == dest || strings.HasPrefix(cwd, dest+"/") {
+			return nil
+		}
+	}
+	if !isRootfsDir(rootfs, cwd) {
+		return fmt.Errorf("working directory %s does not exist in the container", cwd)
+	}
+	return nil
 }
 
 // Create creates an OCI bundle from a SIF image
@@ -97,7 +420,8 @@ func (s *sifBundle) Create(ociConfig *specs.Spec) error {
 		return fmt.Errorf("image wasn't set, need one to create bundle")
 	}
 
-	img, err := image.Init(s.image, s.writable)
+	// the architecture is checked below, as it can be emulated
+	img, err := image.InitIgnoreArch(s.image, s.writable)
 	if err != nil {
 		return fmt.Errorf("failed to load SIF image %s: %s", s.image, err)
 	}
@@ -107,6 +431,14 @@ func (s *sifBundle) Create(ociConfig *specs.Spec) error {
 		return fmt.Errorf("%s is not a SIF image", s.image)
 	}
 
+	emulateArch := ""
+	if err := img.CheckArchitecture(); err != nil {
+		if !s.emulate {
+			return err
+		}
+		emulateArch = img.Architecture
+	}
+
 	part, err := img.GetRootFsPartition()
 	if err != nil {
 		return fmt.Errorf("while getting root filesystem in SIF %s: %s", s.image, err)
@@ -124,6 +456,18 @@ func (s *sifBundle) Create(ociConfig *specs.Spec) error {
 		return fmt.Errorf("failed to generate OCI bundle/config: %s", err)
 	}
 
+	if emulateArch != "" {
+		if err := addEmulator(g, emulateArch); err != nil {
+			tools.DeleteBundle(s.bundlePath)
+			return err
+		}
+	}
+
+	if err := addScratchMounts(g, s.scratchDirs, s.scratchSize, s.workDir); err != nil {
+		tools.DeleteBundle(s.bundlePath)
+		return err
+	}
+
 	// associate SIF image with a block
 	loop, loopCloser, err := tools.CreateLoop(img.File, offset, size)
 	if err != nil {
@@ -173,12 +517,15 @@ func (s *sifBundle) Delete() error {
 }
 
 // FromSif returns a bundle interface to create/delete OCI bundle from SIF image
-func FromSif(image, bundle string, writable bool) (ocibundle.Bundle, error) {
+func FromSif(image, bundle string, writable bool, opts ...Option) (ocibundle.Bundle, error) {
 	var err error
 
 	s := &sifBundle{
 		writable: writable,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	s.bundlePath, err = filepath.Abs(bundle)
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine bundle path: %s", err)
@@ -10,15 +10,26 @@
 package sifbundle
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci"
+	"github.com/apptainer/apptainer/internal/pkg/runtime/engine/config/oci/generate"
 	"github.com/apptainer/apptainer/internal/pkg/test"
 	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+	"github.com/apptainer/apptainer/internal/pkg/util/machine"
 	"github.com/apptainer/apptainer/pkg/ocibundle/tools"
+	"github.com/apptainer/apptainer/pkg/ociruntime"
+	"github.com/apptainer/apptainer/pkg/sylog"
 	"github.com/apptainer/apptainer/pkg/util/fs/proc"
+	imageSpecs "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/opencontainers/runtime-tools/validate"
 )
 
@@ -103,6 +114,601 @@ func TestFromSif(t *testing.T) {
 	}
 }
 
+func TestApplyImageConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		imgConfig imageSpecs.ImageConfig
+		wantArgs  []string
+	}{
+		{
+			name: "EntrypointAndCmd",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Entrypoint: []string{"/bin/sh", "-c"},
+				Cmd:        []string{"date"},
+			},
+			wantArgs: []string{"/bin/sh", "-c", "date"},
+		},
+		{
+			name: "CmdOnly",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Cmd: []string{"/bin/date", "-u"},
+			},
+			wantArgs: []string{"/bin/date", "-u"},
+		},
+		{
+			name: "NoEntrypointOrCmd",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Env: []string{"FOO=bar"},
+			},
+			wantArgs: []string{tools.RunScript},
+		},
+		{
+			name: "ShellFormCmd",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Cmd: []string{"echo hi && echo bye"},
+			},
+			wantArgs: []string{"/bin/sh", "-c", "echo hi && echo bye"},
+		},
+		{
+			name: "ShellFormEntrypoint",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Entrypoint: []string{"exec /app/server --port $PORT"},
+			},
+			wantArgs: []string{"/bin/sh", "-c", "exec /app/server --port $PORT"},
+		},
+		{
+			name: "ShellFormQuotedArgs",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Cmd: []string{`/app/server --name "my app" --debug`},
+			},
+			wantArgs: []string{"/bin/sh", "-c", `/app/server --name "my app" --debug`},
+		},
+		{
+			name: "ShellFormGlob",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Cmd: []string{"cat /etc/*.conf"},
+			},
+			wantArgs: []string{"/bin/sh", "-c", "cat /etc/*.conf"},
+		},
+		{
+			name: "ShellFormMultiline",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Cmd: []string{"date\nuptime"},
+			},
+			wantArgs: []string{"/bin/sh", "-c", "date\nuptime"},
+		},
+		{
+			name: "ExecFormSingleWithSpace",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Entrypoint: []string{"/opt/my app/run"},
+			},
+			wantArgs: []string{"/opt/my app/run"},
+		},
+		{
+			name: "ExecFormSingle",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Cmd: []string{"/usr/local/bin/server"},
+			},
+			wantArgs: []string{"/usr/local/bin/server"},
+		},
+		{
+			name: "ExecFormMultiple",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Cmd: []string{"echo", "hi && echo bye"},
+			},
+			wantArgs: []string{"echo", "hi && echo bye"},
+		},
+		{
+			name: "UserArgsOverride",
+			args: []string{"/bin/true"},
+			imgConfig: imageSpecs.ImageConfig{
+				Entrypoint: []string{"/bin/sh"},
+				Cmd:        []string{"-c", "date"},
+			},
+			wantArgs: []string{"/bin/true"},
+		},
+	}
+
+	oldLevel := sylog.GetLevel()
+	defer sylog.SetLevel(oldLevel, true)
+	sylog.SetLevel(int(sylog.VerboseLevel), true)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			oldWriter := sylog.SetWriter(&buf)
+			defer sylog.SetWriter(oldWriter)
+
+			g := generate.New(&specs.Spec{
+				Process: &specs.Process{
+					Args: tt.args,
+					Env:  []string{"PATH=/bin"},
+				},
+			})
+			applyImageConfig(g, tt.imgConfig, t.TempDir())
+
+			if !reflect.DeepEqual(g.Config.Process.Args, tt.wantArgs) {
+				t.Errorf("expected args %q, got %q", tt.wantArgs, g.Config.Process.Args)
+			}
+			wantLog := fmt.Sprintf("Container process args: %q (%d environment variables)", tt.wantArgs, len(g.Config.Process.Env))
+			if !strings.Contains(buf.String(), wantLog) {
+				t.Errorf("expected log message %q, got %q", wantLog, buf.String())
+			}
+		})
+	}
+}
+
+func TestApplyImageConfigWorkingDir(t *testing.T) {
+	rootfs := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(rootfs, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/app", filepath.Join(rootfs, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rootfs, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		cwd        string
+		workingDir string
+		wantCwd    string
+	}{
+		{
+			name:       "ImageWorkingDir",
+			workingDir: "/app",
+			wantCwd:    "/app",
+		},
+		{
+			name:       "SymlinkWorkingDir",
+			workingDir: "/link",
+			wantCwd:    "/link",
+		},
+		{
+			name:       "PwdOverride",
+			cwd:        "/tmp",
+			workingDir: "/app",
+			wantCwd:    "/tmp",
+		},
+		{
+			name:       "NoWorkingDir",
+			workingDir: "",
+			wantCwd:    "",
+		},
+		{
+			name:       "MissingWorkingDir",
+			workingDir: "/missing",
+			wantCwd:    "",
+		},
+		{
+			name:       "FileWorkingDir",
+			workingDir: "/file",
+			wantCwd:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := generate.New(&specs.Spec{
+				Process: &specs.Process{
+					Args: []string{tools.RunScript},
+					Cwd:  tt.cwd,
+				},
+			})
+			applyImageConfig(g, imageSpecs.ImageConfig{WorkingDir: tt.workingDir}, rootfs)
+
+			if g.Config.Process.Cwd != tt.wantCwd {
+				t.Errorf("expected cwd %q, got %q", tt.wantCwd, g.Config.Process.Cwd)
+			}
+		})
+	}
+}
+
+func TestOverrideEntrypoint(t *testing.T) {
+	imgConfig := imageSpecs.ImageConfig{
+		Entrypoint: []string{"/docker-entrypoint.sh"},
+		Cmd:        []string{"/bin/date", "-u"},
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		imgConfig  imageSpecs.ImageConfig
+		entrypoint []string
+		wantArgs   []string
+		wantErr    bool
+	}{
+		{
+			name:      "ResetRunsCmd",
+			args:      []string{tools.RunScript},
+			imgConfig: imgConfig,
+			wantArgs:  []string{"/bin/date", "-u"},
+		},
+		{
+			name:      "ResetRunsUserArgs",
+			args:      []string{"/bin/true"},
+			imgConfig: imgConfig,
+			wantArgs:  []string{"/bin/true"},
+		},
+		{
+			name: "ResetNoCmd",
+			args: []string{tools.RunScript},
+			imgConfig: imageSpecs.ImageConfig{
+				Entrypoint: []string{"/docker-entrypoint.sh"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ResetNoCmdUserArgs",
+			args: []string{"/bin/true"},
+			imgConfig: imageSpecs.ImageConfig{
+				Entrypoint: []string{"/docker-entrypoint.sh"},
+			},
+			wantArgs: []string{"/bin/true"},
+		},
+		{
+			name:       "ReplaceClearsCmd",
+			args:       []string{tools.RunScript},
+			imgConfig:  imgConfig,
+			entrypoint: []string{"/bin/echo", "hi"},
+			wantArgs:   []string{"/bin/echo", "hi"},
+		},
+		{
+			name:       "ReplaceUserArgs",
+			args:       []string{"/bin/true"},
+			imgConfig:  imgConfig,
+			entrypoint: []string{"/bin/echo"},
+			wantArgs:   []string{"/bin/true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := generate.New(&specs.Spec{
+				Process: &specs.Process{
+					Args: tt.args,
+				},
+			})
+			cfg := tt.imgConfig
+			err := overrideEntrypoint(g, &cfg, tt.entrypoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			applyImageConfig(g, cfg, t.TempDir())
+
+			if !reflect.DeepEqual(g.Config.Process.Args, tt.wantArgs) {
+				t.Errorf("expected args %q, got %q", tt.wantArgs, g.Config.Process.Args)
+			}
+		})
+	}
+}
+
+func TestCheckCwd(t *testing.T) {
+	rootfs := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(rootfs, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cwd     string
+		mounts  []specs.Mount
+		wantErr string
+	}{
+		{
+			name: "Unset",
+			cwd:  "",
+		},
+		{
+			name: "Root",
+			cwd:  "/",
+		},
+		{
+			name: "Exists",
+			cwd:  "/app",
+		},
+		{
+			name:    "Missing",
+			cwd:     "/missing/dir",
+			wantErr: "working directory /missing/dir does not exist in the container",
+		},
+		{
+			name:   "BelowMount",
+			cwd:    "/data/sub",
+			mounts: []specs.Mount{{Destination: "/data"}},
+		},
+		{
+			name:    "NotBelowMount",
+			cwd:     "/database",
+			mounts:  []specs.Mount{{Destination: "/data"}},
+			wantErr: "working directory /database does not exist in the container",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := generate.New(&specs.Spec{
+				Process: &specs.Process{Cwd: tt.cwd},
+				Mounts:  tt.mounts,
+			})
+			err := checkCwd(g, rootfs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyImageConfigStopSignal(t *testing.T) {
+	tests := []struct {
+		name       string
+		stopSignal string
+		want       string
+		wantSet    bool
+	}{
+		{name: "StopSignal", stopSignal: "SIGQUIT", want: "SIGQUIT", wantSet: true},
+		{name: "NoStopSignal", stopSignal: "", wantSet: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := generate.New(&specs.Spec{
+				Process: &specs.Process{Args: []string{tools.RunScript}},
+			})
+			applyImageConfig(g, imageSpecs.ImageConfig{StopSignal: tt.stopSignal}, t.TempDir())
+
+			got, ok := g.Config.Annotations[ociruntime.AnnotationStopSignal]
+			if ok != tt.wantSet || got != tt.want {
+				t.Errorf("expected stop signal annotation %q (set: %v), got %q (set: %v)", tt.want, tt.wantSet, got, ok)
+			}
+		})
+	}
+}
+
+func TestAddEmulator(t *testing.T) {
+	// a fake emulator, found in PATH when the registered interpreter
+	// doesn't exist on the host
+	binDir := t.TempDir()
+	qemu := filepath.Join(binDir, "qemu-aarch64-static")
+	if err := os.WriteFile(qemu, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	origEmulator := emulator
+	defer func() { emulator = origEmulator }()
+
+	tests := []struct {
+		name        string
+		interpreter string
+		err         error
+		wantDest    string
+	}{
+		{
+			name:        "Registered",
+			interpreter: "/usr/libexec/qemu-binfmt/aarch64-binfmt-P",
+			wantDest:    "/usr/libexec/qemu-binfmt/aarch64-binfmt-P",
+		},
+		{
+			name:     "NotRegistered",
+			err:      machine.ErrNoEmulation,
+			wantDest: "/usr/bin/qemu-aarch64-static",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emulator = func(arch string) (string, bool, error) {
+				if arch != "arm64" {
+					t.Errorf("unexpected emulated architecture %s", arch)
+				}
+				return tt.interpreter, false, tt.err
+			}
+
+			g := generate.New(&specs.Spec{})
+			if err := addEmulator(g, "arm64"); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// the interpreter is bound itself if present on the host
+			wantSource := qemu
+			if _, err := os.Stat(tt.wantDest); err == nil {
+				wantSource = tt.wantDest
+			}
+			want := specs.Mount{
+				Source:      wantSource,
+				Destination: tt.wantDest,
+				Type:        "none",
+				Options:     []string{"bind", "ro", "nosuid"},
+			}
+			if len(g.Config.Mounts) != 1 || !reflect.DeepEqual(g.Config.Mounts[0], want) {
+				t.Errorf("expected mounts [%+v], got %+v", want, g.Config.Mounts)
+			}
+		})
+	}
+
+	t.Run("NoEmulator", func(t *testing.T) {
+		if _, err := os.Stat("/usr/bin/qemu-aarch64-static"); err == nil {
+			t.Skip("qemu-aarch64-static is installed in /usr/bin")
+		}
+		t.Setenv("PATH", t.TempDir())
+		emulator = func(string) (string, bool, error) {
+			return "", false, machine.ErrNoEmulation
+		}
+		if err := addEmulator(generate.New(&specs.Spec{}), "arm64"); err == nil {
+			t.Errorf("unexpected success without qemu-aarch64-static")
+		}
+	})
+}
+
+func TestAddScratchMounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirs    []string
+		size    int64
+		want    []specs.Mount
+		wantErr bool
+	}{
+		{
+			name: "None",
+		},
+		{
+			name: "DefaultSize",
+			dirs: []string{"/scratch"},
+			want: []specs.Mount{
+				{
+					Source:      "tmpfs",
+					Destination: "/scratch",
+					Type:        "tmpfs",
+					Options:     []string{"nosuid", "nodev", "mode=1777"},
+				},
+			},
+		},
+		{
+			name: "SessionSize",
+			dirs: []string{"/scratch", "/data/tmp/"},
+			size: 64,
+			want: []specs.Mount{
+				{
+					Source:      "tmpfs",
+					Destination: "/scratch",
+					Type:        "tmpfs",
+					Options:     []string{"nosuid", "nodev", "mode=1777", "size=64m"},
+				},
+				{
+					Source:      "tmpfs",
+					Destination: "/data/tmp",
+					Type:        "tmpfs",
+					Options:     []string{"nosuid", "nodev", "mode=1777", "size=64m"},
+				},
+			},
+		},
+		{
+			name:    "Relative",
+			dirs:    []string{"scratch"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := generate.New(&specs.Spec{})
+			err := addScratchMounts(g, tt.dirs, tt.size, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addScratchMounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(g.Config.Mounts, tt.want) {
+				t.Errorf("expected mounts %+v, got %+v", tt.want, g.Config.Mounts)
+			}
+		})
+	}
+
+	t.Run("WorkDir", func(t *testing.T) {
+		workdir := t.TempDir()
+		g := generate.New(&specs.Spec{})
+		if err := addScratchMounts(g, []string{"/scratch", "/data/tmp"}, 64, workdir); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var want []specs.Mount
+		for _, dir := range []string{"/scratch", "/data/tmp"} {
+			src := filepath.Join(workdir, "scratch", dir)
+			fi, err := os.Stat(src)
+			if err != nil {
+				t.Fatalf("scratch directory not created under workdir: %s", err)
+			}
+			if !fi.IsDir() || fi.Mode()&os.ModeSticky == 0 || fi.Mode().Perm() != 0o777 {
+				t.Errorf("unexpected mode %s for %s", fi.Mode(), src)
+			}
+			want = append(want, specs.Mount{
+				Source:      src,
+				Destination: dir,
+				Type:        "none",
+				Options:     []string{"bind", "rw", "nosuid", "nodev"},
+			})
+		}
+		if !reflect.DeepEqual(g.Config.Mounts, want) {
+			t.Errorf("expected mounts %+v, got %+v", want, g.Config.Mounts)
+		}
+	})
+
+	t.Run("WorkDirSymlink", func(t *testing.T) {
+		workdir := t.TempDir()
+		target := t.TempDir()
+		before, err := os.Stat(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(workdir, "scratch")); err != nil {
+			t.Fatal(err)
+		}
+		g := generate.New(&specs.Spec{})
+		if err := addScratchMounts(g, []string{"/scratch"}, 0, workdir); err == nil {
+			t.Fatalf("unexpected success with a symlinked scratch working directory")
+		}
+		if fi, err := os.Stat(target); err != nil || fi.Mode() != before.Mode() {
+			t.Errorf("symlink target mode was changed from %v: %v", before.Mode(), fi.Mode())
+		}
+		if _, err := os.Stat(filepath.Join(target, "scratch")); !os.IsNotExist(err) {
+			t.Errorf("scratch directory created through symlink")
+		}
+	})
+
+	t.Run("WorkDirWritable", func(t *testing.T) {
+		workdir := t.TempDir()
+		if err := os.Chmod(workdir, 0o777); err != nil {
+			t.Fatal(err)
+		}
+		g := generate.New(&specs.Spec{})
+		if err := addScratchMounts(g, []string{"/scratch"}, 0, workdir); err == nil {
+			t.Fatalf("unexpected success with a world writable working directory")
+		}
+	})
+
+	t.Run("WorkDirOtherOwner", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			t.Skip("changing ownership requires root")
+		}
+		workdir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(workdir, "scratch"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chown(filepath.Join(workdir, "scratch"), 65534, 65534); err != nil {
+			t.Fatal(err)
+		}
+		g := generate.New(&specs.Spec{})
+		if err := addScratchMounts(g, []string{"/scratch"}, 0, workdir); err == nil {
+			t.Fatalf("unexpected success with a directory owned by another user")
+		}
+	})
+}
+
 // TODO: This is a duplicate from internal/pkg/test/tool/require
 // in order avoid needing buildcfg for this unit test, such that
 // it can be run directly from the source tree without compilation.
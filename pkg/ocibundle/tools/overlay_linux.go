@@ -16,6 +16,12 @@ import (
 	"syscall"
 )
 
+// OverlayUpperDir returns the upper directory of the writable overlay
+// created by CreateOverlay, which holds the changes to the root filesystem.
+func OverlayUpperDir(bundlePath string) string {
+	return filepath.Join(bundlePath, "overlay", "upper")
+}
+
 // CreateOverlay creates a writable overlay
 func CreateOverlay(bundlePath string) error {
 	var err error
@@ -49,7 +55,7 @@ func CreateOverlay(bundlePath string) error {
 		return fmt.Errorf("failed to remount %s: %s", overlayDir, err)
 	}
 
-	upperDir := filepath.Join(overlayDir, "upper")
+	upperDir := OverlayUpperDir(bundlePath)
 	if err = os.Mkdir(upperDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create %s: %s", upperDir, err)
 	}
@@ -0,0 +1,151 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// BundleUser returns the container process user for an image USER value,
+// which is a user name or uid, optionally followed by a group name or gid,
+// e.g. "nobody", "1001" or "1001:1001". A numeric uid:gid is returned as is,
+// without reading any file. Otherwise, names, the primary group and the
+// supplementary groups of the user are resolved from the /etc/passwd and
+// /etc/group files of the bundle root filesystem. As with Docker, a uid
+// without a passwd entry runs with gid 0.
+func BundleUser(bundlePath, user string) (specs.User, error) {
+	userPart, groupPart, hasGroup := strings.Cut(user, ":")
+	if userPart == "" || (hasGroup && groupPart == "") {
+		return specs.User{}, fmt.Errorf("invalid user %q", user)
+	}
+	uid, uidErr := parseID(userPart)
+	gid, gidErr := parseID(groupPart)
+	if uidErr == nil && hasGroup && gidErr == nil {
+		return specs.User{UID: uid, GID: gid}, nil
+	}
+
+	rootfs := RootFs(bundlePath).Path()
+
+	u := specs.User{UID: uid}
+	name := ""
+	found, err := scanEntries(rootfs, "/etc/passwd", func(fields []string) bool {
+		if len(fields) < 4 || (fields[0] != userPart && fields[2] != userPart) {
+			return false
+		}
+		id, err := parseID(fields[2])
+		if err != nil {
+			return false
+		}
+		pgid, err := parseID(fields[3])
+		if err != nil {
+			return false
+		}
+		name = fields[0]
+		u.UID, u.GID = id, pgid
+		return true
+	})
+	if err != nil {
+		return specs.User{}, err
+	} else if !found && uidErr != nil {
+		return specs.User{}, fmt.Errorf("user %s not found in container /etc/passwd", userPart)
+	}
+
+	if hasGroup && gidErr == nil {
+		u.GID = gid
+		return u, nil
+	} else if hasGroup {
+		found, err := scanEntries(rootfs, "/etc/group", func(fields []string) bool {
+			if len(fields) < 3 || fields[0] != groupPart {
+				return false
+			}
+			id, err := parseID(fields[2])
+			if err != nil {
+				return false
+			}
+			u.GID = id
+			return true
+		})
+		if err != nil {
+			return specs.User{}, err
+		} else if !found {
+			return specs.User{}, fmt.Errorf("group %s not found in container /etc/group", groupPart)
+		}
+		return u, nil
+	}
+
+	// without an explicit group, the user is a member of the groups listing
+	// its name in /etc/group, as with Docker
+	if name == "" {
+		return u, nil
+	}
+	_, err = scanEntries(rootfs, "/etc/group", func(fields []string) bool {
+		if len(fields) < 4 {
+			return false
+		}
+		id, err := parseID(fields[2])
+		if err != nil || id == u.GID {
+			return false
+		}
+		for _, member := range strings.Split(fields[3], ",") {
+			if member == name {
+				u.AdditionalGids = append(u.AdditionalGids, id)
+				break
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return specs.User{}, err
+	}
+	return u, nil
+}
+
+func parseID(id string) (uint32, error) {
+	v, err := strconv.ParseUint(id, 10, 32)
+	return uint32(v), err
+}
+
+// scanEntries calls match with the colon separated fields of each line of
+// the file at path in rootfs, until it returns true. It returns whether an
+// entry matched. A missing file has no entries.
+func scanEntries(rootfs, path string, match func(fields []string) bool) (bool, error) {
+	fullPath, err := securejoin.SecureJoin(rootfs, path)
+	if err != nil {
+		return false, fmt.Errorf("while resolving container %s: %w", path, err)
+	}
+	f, err := os.Open(fullPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("while opening container %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match(strings.Split(line, ":")) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("while reading container %s: %w", path, err)
+	}
+	return false, nil
+}
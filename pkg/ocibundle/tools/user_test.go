@@ -0,0 +1,83 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const testPasswd = `root:x:0:0:root:/root:/bin/sh
+# comment
+app:x:1001:1002:app user:/home/app:/bin/sh
+nginx:x:101:101:nginx:/nonexistent:/bin/false
+`
+
+const testGroup = `root:x:0:
+app:x:1002:
+staff:x:50:app,other
+wheel:x:10:other
+nginx:x:101:
+`
+
+func TestBundleUser(t *testing.T) {
+	bundle := t.TempDir()
+	etc := filepath.Join(RootFs(bundle).Path(), "etc")
+	if err := os.MkdirAll(etc, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(etc, "passwd"), []byte(testPasswd), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(etc, "group"), []byte(testGroup), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	emptyBundle := t.TempDir()
+
+	tests := []struct {
+		name    string
+		bundle  string
+		user    string
+		want    specs.User
+		wantErr bool
+	}{
+		{name: "NumericUIDGID", bundle: emptyBundle, user: "1001:1001", want: specs.User{UID: 1001, GID: 1001}},
+		{name: "NumericUIDGIDInPasswd", bundle: bundle, user: "1001:1002", want: specs.User{UID: 1001, GID: 1002}},
+		{name: "NumericUIDNoPasswd", bundle: emptyBundle, user: "1003", want: specs.User{UID: 1003}},
+		{name: "NumericUIDInPasswd", bundle: bundle, user: "1001", want: specs.User{UID: 1001, GID: 1002, AdditionalGids: []uint32{50}}},
+		{name: "NumericUIDNotInPasswd", bundle: bundle, user: "1003", want: specs.User{UID: 1003}},
+		{name: "Name", bundle: bundle, user: "app", want: specs.User{UID: 1001, GID: 1002, AdditionalGids: []uint32{50}}},
+		{name: "NameOnly", bundle: bundle, user: "nginx", want: specs.User{UID: 101, GID: 101}},
+		{name: "NumericUIDOnly", bundle: bundle, user: "101", want: specs.User{UID: 101, GID: 101}},
+		{name: "Root", bundle: bundle, user: "root", want: specs.User{}},
+		{name: "NameGID", bundle: bundle, user: "app:10", want: specs.User{UID: 1001, GID: 10}},
+		{name: "NameGroup", bundle: bundle, user: "app:wheel", want: specs.User{UID: 1001, GID: 10}},
+		{name: "NumericUIDGroup", bundle: bundle, user: "1003:staff", want: specs.User{UID: 1003, GID: 50}},
+		{name: "UnknownName", bundle: bundle, user: "nobody", wantErr: true},
+		{name: "UnknownNameNoPasswd", bundle: emptyBundle, user: "app", wantErr: true},
+		{name: "UnknownGroup", bundle: bundle, user: "app:nogroup", wantErr: true},
+		{name: "EmptyGroup", bundle: bundle, user: "app:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BundleUser(tt.bundle, tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, expected error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
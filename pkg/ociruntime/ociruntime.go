@@ -26,6 +26,14 @@ const (
 	Paused = "paused"
 )
 
+// AnnotationStopSignal is the container annotation holding the signal used to
+// stop the container, set from the StopSignal of the image config.
+const AnnotationStopSignal = "org.opencontainers.image.stopSignal"
+
+// AnnotationImage is the container annotation holding the path of the SIF
+// image that the bundle was mounted from.
+const AnnotationImage = "org.apptainer.oci.image"
+
 // State represents the state of the container
 type State struct {
 	specs.State
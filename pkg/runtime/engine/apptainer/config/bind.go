@@ -7,8 +7,11 @@ package apptainer
 
 import (
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
+
+	"github.com/apptainer/apptainer/pkg/sylog"
 )
 
 // BindOption represents a bind option with its associated
@@ -202,6 +205,43 @@ func ParseBindPath(paths []string) ([]BindPath, error) {
 	return binds, nil
 }
 
+// ExpandBindPaths expands the host environment variables referenced as $VAR
+// or ${VAR} in the source and destination of binds, once they have been
+// parsed, so that variable values may hold colons and commas. $$ stands for a
+// literal $. An error is returned if a referenced variable is not set.
+func ExpandBindPaths(binds []BindPath) error {
+	for i := range binds {
+		src, err := expandEnv(binds[i].Source)
+		if err != nil {
+			return fmt.Errorf("while expanding bind source %q: %w", binds[i].Source, err)
+		}
+		dst, err := expandEnv(binds[i].Destination)
+		if err != nil {
+			return fmt.Errorf("while expanding bind destination %q: %w", binds[i].Destination, err)
+		}
+		binds[i].Source = src
+		binds[i].Destination = dst
+	}
+	return nil
+}
+
+// expandEnv expands the host environment variables referenced in s, returning
+// an error for the first one which is not set.
+func expandEnv(s string) (string, error) {
+	var err error
+	expanded := os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	return expanded, err
+}
+
 func splitBy(str string, sep byte) []string {
 	re := regexp.MustCompile(fmt.Sprintf(`(?m)([^\\]%c)`, sep))
 	indexes := re.FindAllStringIndex(str, -1)
@@ -267,6 +307,9 @@ func newBindPath(bind string) (BindPath, error) {
 				return bp, fmt.Errorf("%s is not a valid bind option", value)
 			}
 		}
+		if bp.Options["ro"] != nil && bp.Options["rw"] != nil {
+			sylog.Warningf("Bind path %q has both ro and rw options, mounting it read-only", bind)
+		}
 	}
 
 	return bp, nil
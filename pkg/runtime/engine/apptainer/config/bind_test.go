@@ -114,6 +114,19 @@ func TestParseBindPath(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "srcDstRODuplicate",
+			bindpaths: []string{"/opt:/other:ro,ro"},
+			want: []BindPath{
+				{
+					Source:      "/opt",
+					Destination: "/other",
+					Options: map[string]*BindOption{
+						"ro": {},
+					},
+				},
+			},
+		},
 		{
 			// This doesn't make functional sense (ro & rw), but is testing
 			// parsing multiple binds, with multiple options each. Note the
@@ -200,3 +213,83 @@ func TestParseBindPath(t *testing.T) {
 		})
 	}
 }
+
+func TestExpandBindPaths(t *testing.T) {
+	t.Setenv("BIND_TEST_SCRATCH", "/scratch/user")
+	t.Setenv("BIND_TEST_DST", "/data")
+
+	tests := []struct {
+		name      string
+		bindpaths []string
+		want      []BindPath
+		wantErr   bool
+	}{
+		{
+			name:      "source",
+			bindpaths: []string{"$BIND_TEST_SCRATCH:/scratch"},
+			want: []BindPath{
+				{
+					Source:      "/scratch/user",
+					Destination: "/scratch",
+				},
+			},
+		},
+		{
+			name:      "sourceOnly",
+			bindpaths: []string{"${BIND_TEST_SCRATCH}/run"},
+			want: []BindPath{
+				{
+					Source:      "/scratch/user/run",
+					Destination: "/scratch/user/run",
+				},
+			},
+		},
+		{
+			name:      "sourceDestOptions",
+			bindpaths: []string{"$BIND_TEST_SCRATCH:$BIND_TEST_DST:ro"},
+			want: []BindPath{
+				{
+					Source:      "/scratch/user",
+					Destination: "/data",
+					Options: map[string]*BindOption{
+						"ro": {},
+					},
+				},
+			},
+		},
+		{
+			name:      "literalDollar",
+			bindpaths: []string{"/opt/$$BIND_TEST_SCRATCH:/opt"},
+			want: []BindPath{
+				{
+					Source:      "/opt/$BIND_TEST_SCRATCH",
+					Destination: "/opt",
+				},
+			},
+		},
+		{
+			name:      "undefined",
+			bindpaths: []string{"$BIND_TEST_UNDEFINED:/scratch"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBindPath(tt.bindpaths)
+			if err != nil {
+				t.Fatalf("unexpected error parsing %v: %v", tt.bindpaths, err)
+			}
+			err = ExpandBindPaths(got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandBindPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandBindPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
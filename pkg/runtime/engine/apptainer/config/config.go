@@ -110,6 +110,7 @@ type JSONConfig struct {
 	Hostname              string            `json:"hostname,omitempty"`
 	Network               string            `json:"network,omitempty"`
 	DNS                   string            `json:"dns,omitempty"`
+	DNSOptions            []string          `json:"dnsOptions,omitempty"`
 	Cwd                   string            `json:"cwd,omitempty"`
 	SessionLayer          string            `json:"sessionLayer,omitempty"`
 	ConfigurationFile     string            `json:"configurationFile,omitempty"`
@@ -157,6 +158,14 @@ type JSONConfig struct {
 	ShareNSMode           bool              `json:"sharensMode,omitempty"`
 	ShareNSFd             int               `json:"sharensFd,omitempty"`
 	RunscriptTimeout      string            `json:"runscriptTimeout,omitempty"`
+	Healthcheck           bool              `json:"healthcheck,omitempty"`
+	UnsetEnv              []string          `json:"unsetEnv,omitempty"`
+	ShmSize               int64             `json:"shmSize,omitempty"`
+	HomeOptions           []string          `json:"homeOptions,omitempty"`
+	IgnoreArch            bool              `json:"ignoreArch,omitempty"`
+	CIDFile               string            `json:"cidFile,omitempty"`
+	KeepID                bool              `json:"keepID,omitempty"`
+	FakerootRange         string            `json:"fakerootRange,omitempty"`
 }
 
 // SetImage sets the container image path to be used by EngineConfig.JSON.
@@ -590,6 +599,16 @@ func (e *EngineConfig) GetDNS() string {
 	return e.JSON.DNS
 }
 
+// SetDNSOptions sets the list of resolver options to add in resolv.conf.
+func (e *EngineConfig) SetDNSOptions(options []string) {
+	e.JSON.DNSOptions = options
+}
+
+// GetDNSOptions retrieves the list of resolver options.
+func (e *EngineConfig) GetDNSOptions() []string {
+	return e.JSON.DNSOptions
+}
+
 // SetImageList sets image list containing opened images.
 func (e *EngineConfig) SetImageList(list []image.Image) {
 	e.JSON.ImageList = list
@@ -958,6 +977,101 @@ func (e *EngineConfig) GetOverlayImplied() bool {
 	return e.JSON.OverlayImplied
 }
 
+// SetHealthcheck sets whether the image healthcheck should be run for an
+// instance.
+func (e *EngineConfig) SetHealthcheck(b bool) {
+	e.JSON.Healthcheck = b
+}
+
+// GetHealthcheck returns whether the image healthcheck should be run for an
+// instance.
+func (e *EngineConfig) GetHealthcheck() bool {
+	return e.JSON.Healthcheck
+}
+
+// SetUnsetEnv sets the environment variables to remove from the container
+// environment, after the image environment scripts have been sourced.
+func (e *EngineConfig) SetUnsetEnv(names []string) {
+	e.JSON.UnsetEnv = names
+}
+
+// GetUnsetEnv returns the environment variables to remove from the container
+// environment.
+func (e *EngineConfig) GetUnsetEnv() []string {
+	return e.JSON.UnsetEnv
+}
+
+// SetShmSize sets the size, in MiB, of the /dev/shm tmpfs of a contained
+// /dev.
+func (e *EngineConfig) SetShmSize(size int64) {
+	e.JSON.ShmSize = size
+}
+
+// GetShmSize returns the size, in MiB, of the /dev/shm tmpfs of a contained
+// /dev, or 0 for the default size.
+func (e *EngineConfig) GetShmSize() int64 {
+	return e.JSON.ShmSize
+}
+
+// SetHomeOptions sets the user options of the home directory mount, as parsed
+// by ParseUserHomeMountOptions.
+func (e *EngineConfig) SetHomeOptions(options []string) {
+	e.JSON.HomeOptions = options
+}
+
+// GetHomeOptions returns the user options of the home directory mount.
+func (e *EngineConfig) GetHomeOptions() []string {
+	return e.JSON.HomeOptions
+}
+
+// SetIgnoreArch sets whether images targeting an architecture which the host
+// can't run are run anyway.
+func (e *EngineConfig) SetIgnoreArch(ignore bool) {
+	e.JSON.IgnoreArch = ignore
+}
+
+// GetIgnoreArch returns whether images targeting an architecture which the
+// host can't run are run anyway.
+func (e *EngineConfig) GetIgnoreArch() bool {
+	return e.JSON.IgnoreArch
+}
+
+// SetCIDFile sets the path of the container id file to remove when the
+// container exits.
+func (e *EngineConfig) SetCIDFile(path string) {
+	e.JSON.CIDFile = path
+}
+
+// GetCIDFile returns the path of the container id file to remove when the
+// container exits.
+func (e *EngineConfig) GetCIDFile() string {
+	return e.JSON.CIDFile
+}
+
+// SetKeepID sets whether to preserve the host uid/gid in a user namespace,
+// with subordinate ids mapped around them.
+func (e *EngineConfig) SetKeepID(keepID bool) {
+	e.JSON.KeepID = keepID
+}
+
+// GetKeepID returns whether to preserve the host uid/gid in a user namespace,
+// with subordinate ids mapped around them.
+func (e *EngineConfig) GetKeepID() bool {
+	return e.JSON.KeepID
+}
+
+// SetFakerootRange sets the selection of the subuid/subgid range mapped in
+// the user namespace, when the user has several ranges.
+func (e *EngineConfig) SetFakerootRange(sel string) {
+	e.JSON.FakerootRange = sel
+}
+
+// GetFakerootRange returns the selection of the subuid/subgid range mapped in
+// the user namespace, or an empty string for the default range.
+func (e *EngineConfig) GetFakerootRange() string {
+	return e.JSON.FakerootRange
+}
+
 // SetShareNSMode sets whether container should run in shared namespace mode
 func (e *EngineConfig) SetShareNSMode(mode bool) {
 	e.JSON.ShareNSMode = mode
@@ -0,0 +1,99 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/apptainer/apptainer/internal/pkg/util/fs"
+)
+
+// HomeMountOptions holds the user configurable options of the home directory
+// mount.
+type HomeMountOptions struct {
+	// NoExec prevents the execution of programs from the home directory.
+	NoExec bool
+	// Size is the size, in MiB, of the tmpfs used as home directory when it
+	// is not bound from the host, or 0 for the default size.
+	Size int64
+}
+
+// ParseHomeMountOptions parses a list of home mount options. Supported
+// options are exec, noexec and size=<size>, where size is a value such as
+// "512m" or "2g". Each element may hold several comma separated options, and
+// later options override earlier ones.
+func ParseHomeMountOptions(options []string) (HomeMountOptions, error) {
+	var ho HomeMountOptions
+
+	for _, o := range options {
+		for _, opt := range strings.Split(o, ",") {
+			opt = strings.TrimSpace(opt)
+			key, val, _ := strings.Cut(opt, "=")
+			switch key {
+			case "":
+			case "exec":
+				ho.NoExec = false
+			case "noexec":
+				ho.NoExec = true
+			case "size":
+				size, err := fs.ParseSizeMiB(val)
+				if err != nil {
+					return HomeMountOptions{}, fmt.Errorf("invalid home mount option %q: %w", opt, err)
+				}
+				ho.Size = size
+			default:
+				return HomeMountOptions{}, fmt.Errorf("unsupported home mount option %q", opt)
+			}
+		}
+	}
+
+	return ho, nil
+}
+
+// ParseUserHomeMountOptions parses the home mount options requested by a
+// user, which can only be exec or noexec. The size of the home directory is
+// only set by the administrator.
+func ParseUserHomeMountOptions(options []string) (HomeMountOptions, error) {
+	ho, err := ParseHomeMountOptions(options)
+	if err != nil {
+		return HomeMountOptions{}, err
+	}
+	if ho.Size != 0 {
+		return HomeMountOptions{}, fmt.Errorf("the home directory size can only be set with 'home mount options' in apptainer.conf")
+	}
+	return ho, nil
+}
+
+// Restrict returns the home mount options ho, set by the administrator, with
+// the user options applied. A user can add noexec, but can't lift noexec set
+// by the administrator.
+func (ho HomeMountOptions) Restrict(user HomeMountOptions) HomeMountOptions {
+	ho.NoExec = ho.NoExec || user.NoExec
+	return ho
+}
+
+// Flags returns the mount flags with the home mount options applied.
+func (ho HomeMountOptions) Flags(flags uintptr) uintptr {
+	if ho.NoExec {
+		return flags | syscall.MS_NOEXEC
+	}
+	return flags &^ syscall.MS_NOEXEC
+}
+
+// TmpfsOptions returns the data options of the tmpfs used as home directory
+// when it is not bound from the host, owned by the given uid and gid.
+func (ho HomeMountOptions) TmpfsOptions(uid, gid int) string {
+	options := fmt.Sprintf("mode=755,uid=%d,gid=%d", uid, gid)
+	if ho.Size > 0 {
+		options = fmt.Sprintf("%s,size=%dm", options, ho.Size)
+	}
+	return options
+}
@@ -0,0 +1,163 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainer
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseHomeMountOptions(t *testing.T) {
+	const base = uintptr(syscall.MS_BIND | syscall.MS_NOSUID | syscall.MS_NODEV)
+
+	tests := []struct {
+		name      string
+		options   []string
+		wantFlags uintptr
+		wantTmpfs string
+		wantErr   bool
+	}{
+		{
+			name:      "Default",
+			wantFlags: base,
+			wantTmpfs: "mode=755,uid=1000,gid=1000",
+		},
+		{
+			name:      "NoExec",
+			options:   []string{"noexec"},
+			wantFlags: base | syscall.MS_NOEXEC,
+			wantTmpfs: "mode=755,uid=1000,gid=1000",
+		},
+		{
+			name:      "Size",
+			options:   []string{"size=512m"},
+			wantFlags: base,
+			wantTmpfs: "mode=755,uid=1000,gid=1000,size=512m",
+		},
+		{
+			name:      "SizeRoundedUp",
+			options:   []string{"size=1500k"},
+			wantFlags: base,
+			wantTmpfs: "mode=755,uid=1000,gid=1000,size=2m",
+		},
+		{
+			name:      "CommaSeparated",
+			options:   []string{"noexec,size=1g"},
+			wantFlags: base | syscall.MS_NOEXEC,
+			wantTmpfs: "mode=755,uid=1000,gid=1000,size=1024m",
+		},
+		{
+			name:      "LaterExecOverrides",
+			options:   []string{"noexec,size=1g", "exec"},
+			wantFlags: base,
+			wantTmpfs: "mode=755,uid=1000,gid=1000,size=1024m",
+		},
+		{
+			name:      "LaterSizeOverrides",
+			options:   []string{"size=1g", "noexec, size=256m"},
+			wantFlags: base | syscall.MS_NOEXEC,
+			wantTmpfs: "mode=755,uid=1000,gid=1000,size=256m",
+		},
+		{
+			name:    "InvalidSize",
+			options: []string{"size=lots"},
+			wantErr: true,
+		},
+		{
+			name:    "NegativeSize",
+			options: []string{"size=-1m"},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported",
+			options: []string{"suid"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ho, err := ParseHomeMountOptions(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHomeMountOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := ho.Flags(base); got != tt.wantFlags {
+				t.Errorf("Flags() = %#x, want %#x", got, tt.wantFlags)
+			}
+			if got := ho.TmpfsOptions(1000, 1000); got != tt.wantTmpfs {
+				t.Errorf("TmpfsOptions() = %q, want %q", got, tt.wantTmpfs)
+			}
+		})
+	}
+}
+
+func TestRestrictHomeMountOptions(t *testing.T) {
+	const base = uintptr(syscall.MS_BIND | syscall.MS_NOSUID | syscall.MS_NODEV)
+
+	tests := []struct {
+		name      string
+		config    []string
+		user      []string
+		wantFlags uintptr
+		wantTmpfs string
+		wantErr   bool
+	}{
+		{
+			name:      "NoOptions",
+			wantFlags: base,
+			wantTmpfs: "mode=755,uid=1000,gid=1000",
+		},
+		{
+			name:      "UserNoExec",
+			config:    []string{"size=1g"},
+			user:      []string{"noexec"},
+			wantFlags: base | syscall.MS_NOEXEC,
+			wantTmpfs: "mode=755,uid=1000,gid=1000,size=1024m",
+		},
+		{
+			name:      "UserExecCannotLiftConfigNoExec",
+			config:    []string{"noexec,size=1g"},
+			user:      []string{"exec"},
+			wantFlags: base | syscall.MS_NOEXEC,
+			wantTmpfs: "mode=755,uid=1000,gid=1000,size=1024m",
+		},
+		{
+			name:    "UserSize",
+			config:  []string{"size=1g"},
+			user:    []string{"size=8g"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseHomeMountOptions(tt.config)
+			if err != nil {
+				t.Fatalf("ParseHomeMountOptions() error = %v", err)
+			}
+			user, err := ParseUserHomeMountOptions(tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUserHomeMountOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			ho := config.Restrict(user)
+			if got := ho.Flags(base); got != tt.wantFlags {
+				t.Errorf("Flags() = %#x, want %#x", got, tt.wantFlags)
+			}
+			if got := ho.TmpfsOptions(1000, 1000); got != tt.wantTmpfs {
+				t.Errorf("TmpfsOptions() = %q, want %q", got, tt.wantTmpfs)
+			}
+		})
+	}
+}
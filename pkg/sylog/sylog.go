@@ -18,9 +18,10 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
-var messageColors = map[messageLevel]string{
+var messageColors = map[MessageLevel]string{
 	FatalLevel: "\x1b[31m",
 	ErrorLevel: "\x1b[31m",
 	WarnLevel:  "\x1b[33m",
@@ -28,20 +29,46 @@ var messageColors = map[messageLevel]string{
 }
 
 var (
-	noColorLevel messageLevel = 90
+	noColorLevel MessageLevel = 90
 	loggerLevel               = InfoLevel
+	// debugScopes are the scopes, set by APPTAINER_DEBUG_SCOPES, for which
+	// debug messages are logged regardless of loggerLevel.
+	debugScopes []string
 )
 
 var logWriter = (io.Writer)(os.Stderr)
 
+// logMutex serializes writes to logWriter, and changes of logWriter, so that
+// messages logged concurrently are not interleaved.
+var logMutex sync.Mutex
+
+// progressRegions is the number of active progress regions, during which log
+// lines are held in progressLines rather than written, see StartProgress.
+var (
+	progressRegions int
+	progressLines   []string
+)
+
 func init() {
 	l, err := strconv.Atoi(os.Getenv("APPTAINER_MESSAGELEVEL"))
 	if err == nil {
-		loggerLevel = messageLevel(l)
+		loggerLevel = MessageLevel(l)
+	}
+	debugScopes = parseScopes(os.Getenv("APPTAINER_DEBUG_SCOPES"))
+}
+
+// parseScopes returns the scopes from a comma separated list.
+func parseScopes(value string) []string {
+	var scopes []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.Trim(strings.TrimSpace(s), "/"); s != "" {
+			scopes = append(scopes, s)
+		}
 	}
+	return scopes
 }
 
-func prefix(logLevel, msgLevel messageLevel) string {
+func prefix(logLevel, msgLevel MessageLevel) string {
 	colorReset := "\x1b[0m"
 	messageColor, ok := messageColors[msgLevel]
 	if !ok || logLevel != loggerLevel {
@@ -72,7 +99,7 @@ func prefix(logLevel, msgLevel messageLevel) string {
 	return fmt.Sprintf("%s%-8s%s%-19s%-30s", messageColor, msgLevel, colorReset, uidStr, funcName)
 }
 
-func writef(msgLevel messageLevel, format string, a ...interface{}) {
+func writef(msgLevel MessageLevel, format string, a ...interface{}) {
 	logLevel := getLoggerLevel()
 	if logLevel < msgLevel {
 		return
@@ -80,11 +107,45 @@ func writef(msgLevel messageLevel, format string, a ...interface{}) {
 
 	message := fmt.Sprintf(format, a...)
 	message = strings.TrimRight(message, "\n")
+	line := prefix(logLevel, msgLevel) + message + "\n"
+
+	writeLine(msgLevel, line)
+}
+
+// writeLine writes line to logWriter at once, so that it is not interleaved
+// with lines logged concurrently. During a progress region, info, verbose and
+// debug lines are held, while warnings and errors are written immediately
+// after the held lines, so that they are not missed, and as the process may
+// exit before the region ends.
+func writeLine(msgLevel MessageLevel, line string) {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	if progressRegions > 0 && msgLevel > WarnLevel {
+		progressLines = append(progressLines, line)
+		return
+	}
+	writeProgressLines()
+	io.WriteString(logWriter, line)
+}
+
+// writeProgressLines writes the log lines held during progress regions. The
+// caller must hold logMutex.
+func writeProgressLines() {
+	for _, line := range progressLines {
+		io.WriteString(logWriter, line)
+	}
+	progressLines = nil
+}
 
-	fmt.Fprintf(logWriter, "%s%s\n", prefix(logLevel, msgLevel), message)
+// flushProgress writes the log lines held during progress regions, before
+// exiting on a fatal error.
+func flushProgress() {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	writeProgressLines()
 }
 
-func getLoggerLevel() messageLevel {
+func getLoggerLevel() MessageLevel {
 	if loggerLevel <= -noColorLevel {
 		return loggerLevel + noColorLevel
 	} else if loggerLevel >= noColorLevel {
@@ -93,11 +154,12 @@ func getLoggerLevel() messageLevel {
 	return loggerLevel
 }
 
-// Fatalf is equivalent to a call to Errorf followed by os.Exit(255). Code that
-// may be imported by other projects should NOT use Fatalf.
+// Fatalf is equivalent to a call to Errorf followed by os.Exit(255), unless
+// disabled with SetExitOnFatal. Code that may be imported by other projects
+// should NOT use Fatalf.
 func Fatalf(format string, a ...interface{}) {
 	writef(FatalLevel, format, a...)
-	os.Exit(255)
+	fatal(format, a...)
 }
 
 // Errorf writes an ERROR level message to the log but does not exit. This
@@ -128,9 +190,15 @@ func Debugf(format string, a ...interface{}) {
 	writef(DebugLevel, format, a...)
 }
 
-// SetLevel explicitly sets the loggerLevel
+// SetLevel explicitly sets the loggerLevel. SetMessageLevel should be
+// preferred, to not pass levels as raw integers.
 func SetLevel(l int, color bool) {
-	loggerLevel = messageLevel(l)
+	SetMessageLevel(MessageLevel(l), color)
+}
+
+// SetMessageLevel explicitly sets the loggerLevel
+func SetMessageLevel(l MessageLevel, color bool) {
+	loggerLevel = l
 	if !color {
 		if loggerLevel >= InfoLevel {
 			loggerLevel = loggerLevel + noColorLevel
@@ -145,6 +213,11 @@ func GetLevel() int {
 	return int(getLoggerLevel())
 }
 
+// GetMessageLevel returns the current log level
+func GetMessageLevel() MessageLevel {
+	return getLoggerLevel()
+}
+
 // GetEnvVar returns a formatted environment variable string which
 // can later be interpreted by init() in a child proc
 func GetEnvVar() string {
@@ -158,6 +231,8 @@ func Writer() io.Writer {
 		return io.Discard
 	}
 
+	logMutex.Lock()
+	defer logMutex.Unlock()
 	return logWriter
 }
 
@@ -179,9 +254,105 @@ func (t DebugLogger) Logf(format string, v ...interface{}) {
 // returns the previous writer so that it may be restored by the caller
 // useful to capture log output during unit tests
 func SetWriter(writer io.Writer) io.Writer {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+
 	oldWriter := logWriter
 	if nil != writer {
 		logWriter = writer
 	}
 	return oldWriter
 }
+
+// StartProgress starts a progress region, while a progress bar is drawn on
+// the terminal. Info, verbose and debug lines are held during the region,
+// rather than being written in the middle of the bar, and are written in
+// order once the returned function has been called to end all active regions.
+// Warnings and errors are written immediately. The returned function may be
+// called more than once.
+func StartProgress() func() {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	progressRegions++
+
+	var once sync.Once
+	return func() {
+		once.Do(endProgress)
+	}
+}
+
+// endProgress ends a progress region, and writes the held log lines if no
+// other region is active.
+func endProgress() {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	progressRegions--
+	if progressRegions > 0 {
+		return
+	}
+	writeProgressLines()
+}
+
+// Logger logs messages for a scope, such as "oci/mounts", at the global log
+// level, or at debug level if the scope is enabled with a comma separated
+// list of scopes in APPTAINER_DEBUG_SCOPES. A scope is enabled by itself or
+// by any of its parents, e.g. "oci" enables "oci/mounts".
+type Logger struct {
+	scope string
+}
+
+// For returns a Logger for scope.
+func For(scope string) *Logger {
+	return &Logger{scope: strings.Trim(scope, "/")}
+}
+
+// enabled returns whether debug messages are enabled for the scope.
+func (l *Logger) enabled() bool {
+	for _, s := range debugScopes {
+		if l.scope == s || strings.HasPrefix(l.scope, s+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Logger) writef(msgLevel MessageLevel, format string, a ...interface{}) {
+	logLevel := getLoggerLevel()
+	if logLevel < DebugLevel && l.enabled() {
+		logLevel = DebugLevel
+	}
+	if logLevel < msgLevel {
+		return
+	}
+
+	message := fmt.Sprintf(format, a...)
+	message = strings.TrimRight(message, "\n")
+
+	writeLine(msgLevel, prefix(logLevel, msgLevel)+message+"\n")
+}
+
+// Errorf writes an ERROR level message to the log.
+func (l *Logger) Errorf(format string, a ...interface{}) {
+	l.writef(ErrorLevel, format, a...)
+}
+
+// Warningf writes a WARNING level message to the log.
+func (l *Logger) Warningf(format string, a ...interface{}) {
+	l.writef(WarnLevel, format, a...)
+}
+
+// Infof writes an INFO level message to the log.
+func (l *Logger) Infof(format string, a ...interface{}) {
+	l.writef(InfoLevel, format, a...)
+}
+
+// Verbosef writes a VERBOSE level message to the log.
+func (l *Logger) Verbosef(format string, a ...interface{}) {
+	l.writef(VerboseLevel, format, a...)
+}
+
+// Debugf writes a DEBUG level message to the log, if the global log level is
+// debug or the scope is enabled.
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	l.writef(DebugLevel, format, a...)
+}
@@ -9,11 +9,19 @@
 
 package sylog
 
-type messageLevel int
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// MessageLevel is the level of a log message, or of the logger. It should be
+// used with the Log levels constants rather than as a raw integer.
+type MessageLevel int
 
 // Log levels.
 const (
-	FatalLevel    messageLevel = iota - 4 // FatalLevel    : -4
+	FatalLevel    MessageLevel = iota - 4 // FatalLevel    : -4
 	ErrorLevel                            // ErrorLevel    : -3
 	WarnLevel                             // WarnLevel     : -2
 	LogLevel                              // LogLevel      : -1
@@ -25,7 +33,7 @@ const (
 	DebugLevel                            // DebugLevel    : 5
 )
 
-func (l messageLevel) String() string {
+func (l MessageLevel) String() string {
 	str, ok := messageLabels[l]
 
 	if !ok {
@@ -35,7 +43,7 @@ func (l messageLevel) String() string {
 	return str
 }
 
-var messageLabels = map[messageLevel]string{
+var messageLabels = map[MessageLevel]string{
 	FatalLevel:    "FATAL",
 	ErrorLevel:    "ERROR",
 	WarnLevel:     "WARNING",
@@ -46,3 +54,51 @@ var messageLabels = map[messageLevel]string{
 	Verbose3Level: "VERBOSE",
 	DebugLevel:    "DEBUG",
 }
+
+// noExitOnFatal is whether Fatalf panics rather than exiting the process.
+var noExitOnFatal atomic.Bool
+
+// FatalError is the value Fatalf panics with when it does not exit the
+// process, see SetExitOnFatal.
+type FatalError struct {
+	Message string
+}
+
+func (e *FatalError) Error() string {
+	return e.Message
+}
+
+// SetExitOnFatal sets whether Fatalf exits the process with code 255, which
+// is the default. Projects embedding Apptainer may disable it, so that Fatalf
+// panics with a *FatalError instead, which can be recovered with CatchFatal.
+// The previous setting is returned.
+func SetExitOnFatal(exit bool) bool {
+	return !noExitOnFatal.Swap(!exit)
+}
+
+// CatchFatal calls f, and returns the *FatalError if f called Fatalf while
+// exiting on fatal errors is disabled. Other panics are propagated.
+func CatchFatal(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fe, ok := r.(*FatalError)
+			if !ok {
+				panic(r)
+			}
+			err = fe
+		}
+	}()
+	f()
+	return nil
+}
+
+// fatal exits the process with code 255, or panics with a *FatalError
+// holding the message if exiting on fatal errors is disabled. Log lines held
+// during a progress region are written first, as the region won't end.
+func fatal(format string, a ...interface{}) {
+	flushProgress()
+	if noExitOnFatal.Load() {
+		panic(&FatalError{Message: fmt.Sprintf(format, a...)})
+	}
+	os.Exit(255)
+}
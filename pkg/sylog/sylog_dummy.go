@@ -18,18 +18,18 @@ import (
 )
 
 var (
-	noColorLevel messageLevel = 90
+	noColorLevel MessageLevel = 90
 	loggerLevel               = InfoLevel
 )
 
 func init() {
 	l, err := strconv.Atoi(os.Getenv("APPTAINER_MESSAGELEVEL"))
 	if err == nil {
-		loggerLevel = messageLevel(l)
+		loggerLevel = MessageLevel(l)
 	}
 }
 
-func getLoggerLevel() messageLevel {
+func getLoggerLevel() MessageLevel {
 	if loggerLevel <= -noColorLevel {
 		return loggerLevel + noColorLevel
 	} else if loggerLevel >= noColorLevel {
@@ -38,10 +38,10 @@ func getLoggerLevel() messageLevel {
 	return loggerLevel
 }
 
-// Fatalf is a dummy function exiting with code 255. This
-// function must not be used in public packages.
+// Fatalf is a dummy function exiting with code 255, unless disabled with
+// SetExitOnFatal. This function must not be used in public packages.
 func Fatalf(format string, a ...interface{}) {
-	os.Exit(255)
+	fatal(format, a...)
 }
 
 // Errorf is a dummy function doing nothing.
@@ -61,8 +61,13 @@ func Debugf(format string, a ...interface{}) {}
 
 // SetLevel is a dummy function doing nothing.
 func SetLevel(l int, color bool) {
+	SetMessageLevel(MessageLevel(l), color)
+}
+
+// SetMessageLevel is a dummy function doing nothing.
+func SetMessageLevel(l MessageLevel, color bool) {
 	// Here we do not check term.IsTerminal to explicitly control the color
-	loggerLevel = messageLevel(l)
+	loggerLevel = l
 	if !color {
 		if loggerLevel >= InfoLevel {
 			loggerLevel = loggerLevel + noColorLevel
@@ -80,6 +85,11 @@ func GetLevel() int {
 	return int(getLoggerLevel())
 }
 
+// GetMessageLevel is a dummy function returning lowest message level.
+func GetMessageLevel() MessageLevel {
+	return getLoggerLevel()
+}
+
 // GetEnvVar is a dummy function returning environment variable
 // with lowest message level.
 func GetEnvVar() string {
@@ -100,3 +110,34 @@ func (t DebugLogger) Log(v ...interface{}) {}
 
 // Logf is a dummy function doing nothing.
 func (t DebugLogger) Logf(format string, v ...interface{}) {}
+
+// StartProgress is a dummy function returning a function doing nothing.
+func StartProgress() func() {
+	return func() {}
+}
+
+// flushProgress is a dummy function doing nothing.
+func flushProgress() {}
+
+// Logger is a dummy scoped logger doing nothing.
+type Logger struct{}
+
+// For is a dummy function returning a Logger doing nothing.
+func For(scope string) *Logger {
+	return &Logger{}
+}
+
+// Errorf is a dummy function doing nothing.
+func (l *Logger) Errorf(format string, a ...interface{}) {}
+
+// Warningf is a dummy function doing nothing.
+func (l *Logger) Warningf(format string, a ...interface{}) {}
+
+// Infof is a dummy function doing nothing.
+func (l *Logger) Infof(format string, a ...interface{}) {}
+
+// Verbosef is a dummy function doing nothing.
+func (l *Logger) Verbosef(format string, a ...interface{}) {}
+
+// Debugf is a dummy function doing nothing.
+func (l *Logger) Debugf(format string, a ...interface{}) {}
@@ -13,11 +13,14 @@ package sylog
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/apptainer/apptainer/internal/pkg/test"
@@ -37,13 +40,13 @@ func TestPrefix(t *testing.T) {
 
 	tests := []struct {
 		name     string
-		lvl      messageLevel
+		lvl      MessageLevel
 		msgColor string
 		levelStr string
 	}{
 		{
 			name:     "invalid",
-			lvl:      messageLevel(FatalLevel - 1),
+			lvl:      MessageLevel(FatalLevel - 1),
 			msgColor: "",
 			levelStr: "????",
 		},
@@ -174,7 +177,7 @@ func TestWritef(t *testing.T) {
 
 	tests := []struct {
 		name string
-		lvl  messageLevel
+		lvl  MessageLevel
 	}{
 		{
 			name: "info",
@@ -220,7 +223,7 @@ func TestWritef(t *testing.T) {
 func TestGetLevel(t *testing.T) {
 	tests := []struct {
 		name           string
-		lvl            messageLevel
+		lvl            MessageLevel
 		expectedResult int
 	}{
 		{
@@ -265,7 +268,7 @@ func TestGetLevel(t *testing.T) {
 		},
 		{
 			name:           "invalid",
-			lvl:            messageLevel(-10),
+			lvl:            MessageLevel(-10),
 			expectedResult: -4,
 		},
 	}
@@ -280,6 +283,43 @@ func TestGetLevel(t *testing.T) {
 	}
 }
 
+func TestMessageLevelForms(t *testing.T) {
+	defer SetLevel(0, true)
+
+	levels := []MessageLevel{
+		FatalLevel,
+		ErrorLevel,
+		WarnLevel,
+		LogLevel,
+		InfoLevel,
+		VerboseLevel,
+		Verbose2Level,
+		Verbose3Level,
+		DebugLevel,
+	}
+	for _, lvl := range levels {
+		for _, color := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%d/color=%v", lvl, color), func(t *testing.T) {
+				SetMessageLevel(lvl, color)
+				typedLevel, typedEnv := GetMessageLevel(), GetEnvVar()
+
+				SetLevel(int(lvl), color)
+				intLevel, intEnv := GetLevel(), GetEnvVar()
+
+				if typedLevel != lvl {
+					t.Errorf("GetMessageLevel returned %d instead of %d", typedLevel, lvl)
+				}
+				if intLevel != int(typedLevel) {
+					t.Errorf("GetLevel returned %d instead of %d", intLevel, typedLevel)
+				}
+				if intEnv != typedEnv {
+					t.Errorf("SetLevel set %q instead of %q", intEnv, typedEnv)
+				}
+			})
+		}
+	}
+}
+
 func TestGetenv(t *testing.T) {
 	str := GetEnvVar()
 	expectedResult := "APPTAINER_MESSAGELEVEL="
@@ -363,3 +403,311 @@ func TestStderrOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestWritefConcurrent(t *testing.T) {
+	const (
+		goroutines = 50
+		messages   = 200
+	)
+
+	var buf bytes.Buffer
+	oldWriter := SetWriter(&buf)
+	defer SetWriter(oldWriter)
+
+	SetLevel(int(InfoLevel), false)
+	defer SetLevel(0, true)
+
+	// a long message makes interleaving of partial writes more likely
+	msg := strings.Repeat("x", 1024)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				writef(InfoLevel, "%d %s", i, msg)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lineRegexp := regexp.MustCompile(`^INFO: +\d+ x+$`)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != goroutines*messages {
+		t.Fatalf("expected %d lines, got %d", goroutines*messages, len(lines))
+	}
+	for _, line := range lines {
+		if !lineRegexp.MatchString(line) || !strings.HasSuffix(line, " "+msg) {
+			t.Fatalf("corrupted log line: %q", line)
+		}
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "", want: nil},
+		{value: "oci/mounts", want: []string{"oci/mounts"}},
+		{value: "oci/mounts, cache,,/ociimage/", want: []string{"oci/mounts", "cache", "ociimage"}},
+	}
+	for _, tt := range tests {
+		if got := parseScopes(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseScopes(%q): expected %v, got %v", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestLoggerScopes(t *testing.T) {
+	var buf bytes.Buffer
+	oldWriter := SetWriter(&buf)
+	defer SetWriter(oldWriter)
+
+	oldScopes := debugScopes
+	debugScopes = []string{"oci", "cache/blob"}
+	defer func() { debugScopes = oldScopes }()
+
+	tests := []struct {
+		name      string
+		level     MessageLevel
+		scope     string
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{name: "Enabled", level: InfoLevel, scope: "oci", wantDebug: true, wantInfo: true},
+		{name: "EnabledByParent", level: InfoLevel, scope: "oci/mounts", wantDebug: true, wantInfo: true},
+		{name: "EnabledNested", level: InfoLevel, scope: "cache/blob", wantDebug: true, wantInfo: true},
+		{name: "ParentNotEnabledByChild", level: InfoLevel, scope: "cache", wantDebug: false, wantInfo: true},
+		{name: "SamePrefix", level: InfoLevel, scope: "ocisif", wantDebug: false, wantInfo: true},
+		{name: "Disabled", level: InfoLevel, scope: "pull", wantDebug: false, wantInfo: true},
+		{name: "QuietDisabled", level: WarnLevel, scope: "pull", wantDebug: false, wantInfo: false},
+		{name: "GlobalDebug", level: DebugLevel, scope: "pull", wantDebug: true, wantInfo: true},
+	}
+
+	defer SetLevel(0, true)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLevel(int(tt.level), false)
+			l := For(tt.scope)
+
+			buf.Reset()
+			l.Debugf("debug message")
+			if got := strings.Contains(buf.String(), "debug message"); got != tt.wantDebug {
+				t.Errorf("expected debug message logged %v, got %q", tt.wantDebug, buf.String())
+			}
+
+			buf.Reset()
+			l.Infof("info message")
+			if got := strings.Contains(buf.String(), "info message"); got != tt.wantInfo {
+				t.Errorf("expected info message logged %v, got %q", tt.wantInfo, buf.String())
+			}
+		})
+	}
+
+	// package level functions are not affected by the scopes
+	SetLevel(int(InfoLevel), false)
+	buf.Reset()
+	Debugf("debug message")
+	if buf.Len() != 0 {
+		t.Errorf("unexpected package level debug message: %q", buf.String())
+	}
+}
+
+func TestLoggerDebugPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	oldWriter := SetWriter(&buf)
+	defer SetWriter(oldWriter)
+
+	oldScopes := debugScopes
+	debugScopes = []string{"oci"}
+	defer func() { debugScopes = oldScopes }()
+
+	SetLevel(int(InfoLevel), false)
+	defer SetLevel(0, true)
+
+	For("oci").Debugf("%s", testStr)
+	// the caller is reported as for package level functions
+	if !strings.Contains(buf.String(), "TestLoggerDebugPrefix()") {
+		t.Errorf("expected caller in debug message prefix, got %q", buf.String())
+	}
+}
+
+func TestStartProgress(t *testing.T) {
+	var buf bytes.Buffer
+	oldWriter := SetWriter(&buf)
+	defer SetWriter(oldWriter)
+
+	SetLevel(int(InfoLevel), false)
+	defer SetLevel(0, true)
+
+	Infof("before")
+	stop := StartProgress()
+
+	// the bar is drawn directly to the log writer, while messages are logged
+	bar := Writer()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			Infof("during %d", i)
+		}(i)
+	}
+	for i := 0; i <= 10; i++ {
+		fmt.Fprintf(bar, "\r[%-10s]", strings.Repeat("=", i))
+	}
+	wg.Wait()
+	io.WriteString(bar, "\n")
+
+	if strings.Contains(buf.String(), "during") {
+		t.Fatalf("log lines written during progress region: %q", buf.String())
+	}
+
+	// nested regions hold log lines until the outer one is done
+	nestedStop := StartProgress()
+	nestedStop()
+	nestedStop()
+	if strings.Contains(buf.String(), "during") {
+		t.Fatalf("log lines written before end of progress region: %q", buf.String())
+	}
+
+	stop()
+	Infof("after")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 13 {
+		t.Fatalf("expected 13 lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], "before") {
+		t.Errorf("expected first line to be the message before the progress region, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "[==========]") {
+		t.Errorf("expected the progress bar uninterrupted, got %q", lines[1])
+	}
+	for i, line := range lines[2:12] {
+		if !strings.HasPrefix(line, "INFO:") || !strings.Contains(line, "during") {
+			t.Errorf("expected held log line %d, got %q", i, line)
+		}
+	}
+	if !strings.HasSuffix(lines[12], "after") {
+		t.Errorf("expected last line to be the message after the progress region, got %q", lines[12])
+	}
+}
+
+func TestWarningInProgress(t *testing.T) {
+	var buf bytes.Buffer
+	oldWriter := SetWriter(&buf)
+	defer SetWriter(oldWriter)
+
+	SetLevel(int(InfoLevel), false)
+	defer SetLevel(0, true)
+
+	stop := StartProgress()
+	defer stop()
+
+	Infof("held")
+	if buf.Len() != 0 {
+		t.Fatalf("log line written during progress region: %q", buf.String())
+	}
+
+	// warnings are written at once, after the lines held before them
+	Warningf("warning")
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], "held") {
+		t.Errorf("expected the held line first, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "WARNING:") || !strings.HasSuffix(lines[1], "warning") {
+		t.Errorf("expected the warning, got %q", lines[1])
+	}
+
+	Infof("held again")
+	if strings.Contains(buf.String(), "held again") {
+		t.Errorf("log line written during progress region: %q", buf.String())
+	}
+}
+
+func TestFatalfNoExit(t *testing.T) {
+	var buf bytes.Buffer
+	oldWriter := SetWriter(&buf)
+	defer SetWriter(oldWriter)
+
+	if prev := SetExitOnFatal(false); !prev {
+		t.Errorf("exit on fatal was not enabled by default")
+	}
+	defer SetExitOnFatal(true)
+
+	err := CatchFatal(func() {
+		Fatalf("fatal %s", "message")
+		t.Errorf("Fatalf returned")
+	})
+	var fe *FatalError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected a *FatalError, got %v", err)
+	}
+	if fe.Message != "fatal message" {
+		t.Errorf("expected message %q, got %q", "fatal message", fe.Message)
+	}
+	if !strings.Contains(buf.String(), "fatal message") {
+		t.Errorf("fatal message was not logged: %q", buf.String())
+	}
+
+	if err := CatchFatal(func() {}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// other panics are propagated
+	func() {
+		defer func() {
+			if r := recover(); r != "other" {
+				t.Errorf("expected other panic to be propagated, got %v", r)
+			}
+		}()
+		CatchFatal(func() { panic("other") })
+	}()
+}
+
+func TestFatalfInProgress(t *testing.T) {
+	var buf bytes.Buffer
+	oldWriter := SetWriter(&buf)
+	defer SetWriter(oldWriter)
+
+	SetLevel(int(InfoLevel), false)
+	defer SetLevel(0, true)
+
+	SetExitOnFatal(false)
+	defer SetExitOnFatal(true)
+
+	// the region is never ended, as on an early return before the bar is
+	// shut down
+	stop := StartProgress()
+	defer stop()
+
+	Infof("held")
+	if buf.Len() != 0 {
+		t.Fatalf("log line written during progress region: %q", buf.String())
+	}
+
+	err := CatchFatal(func() {
+		Fatalf("fatal %s", "message")
+	})
+	if err == nil {
+		t.Fatalf("expected a fatal error")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], "held") {
+		t.Errorf("expected the held line first, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "FATAL:") || !strings.HasSuffix(lines[1], "fatal message") {
+		t.Errorf("expected the fatal message, got %q", lines[1])
+	}
+}
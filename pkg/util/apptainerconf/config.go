@@ -10,11 +10,14 @@
 package apptainerconf
 
 import (
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/apptainer/apptainer/pkg/sylog"
+	"golang.org/x/net/http/httpproxy"
 )
 
 // currentConfig corresponds to the current configuration, may
@@ -41,6 +44,27 @@ func ApplyBuildConfig(config *File) {
 	config.MountDevPts = false
 }
 
+// ProxyFunc returns a function, to be set as the Proxy of an http.Transport,
+// which selects the proxy for a request from the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables, or from the proxy directives of config for
+// those which are not set in the environment.
+func ProxyFunc(config *File) func(*http.Request) (*url.URL, error) {
+	proxy := httpproxy.FromEnvironment()
+	if proxy.HTTPProxy == "" {
+		proxy.HTTPProxy = config.HTTPProxy
+	}
+	if proxy.HTTPSProxy == "" {
+		proxy.HTTPSProxy = config.HTTPSProxy
+	}
+	if proxy.NoProxy == "" {
+		proxy.NoProxy = config.NoProxy
+	}
+	proxyURL := proxy.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyURL(req.URL)
+	}
+}
+
 // SetBinaryPath sets the value of the binary path, substituting the
 // user's $PATH plus ":" for "$PATH:" in BinaryPath.  If nonSuid is true,
 // then SuidBinaryPath gets the same value as BinaryPath, otherwise
@@ -88,6 +112,7 @@ type File struct {
 	MountSys                  bool     `default:"yes" authorized:"yes,no" directive:"mount sys"`
 	MountDevPts               bool     `default:"yes" authorized:"yes,no" directive:"mount devpts"`
 	MountHome                 bool     `default:"yes" authorized:"yes,no" directive:"mount home"`
+	HomeMountOptions          []string `directive:"home mount options"`
 	MountTmp                  bool     `default:"yes" authorized:"yes,no" directive:"mount tmp"`
 	MountHostfs               bool     `default:"no" authorized:"yes,no" directive:"mount hostfs"`
 	UserBindControl           bool     `default:"yes" authorized:"yes,no" directive:"user bind control"`
@@ -131,11 +156,21 @@ type File struct {
 	DownloadConcurrency uint   `default:"3" directive:"download concurrency"`
 	DownloadPartSize    uint   `default:"5242880" directive:"download part size"`
 	DownloadBufferSize  uint   `default:"32768" directive:"download buffer size"`
+	OCIConcurrency      uint   `default:"4" directive:"oci concurrency"`
+	OCITimeout          uint   `default:"0" directive:"oci timeout"`
+	HTTPProxy           string `directive:"http proxy"`
+	HTTPSProxy          string `directive:"https proxy"`
+	NoProxy             string `directive:"no proxy"`
 	SystemdCgroups      bool   `default:"yes" authorized:"yes,no" directive:"systemd cgroups"`
 	// apptheus unix socket
 	ApptheusSocketPath string `default:"/run/apptheus/gateway.sock" directive:"apptheus communication socket path"`
 	// Allow monitoring by apptheus, default is `no` because it requires an additional tool, i.e. apptheus
 	AllowMonitoring bool `default:"no" authorized:"yes,no" directive:"allow monitoring"`
+	// OCI hooks added to containers created with the apptainer oci commands
+	OCICreateRuntimeHooks  []string `directive:"oci createruntime hooks"`
+	OCIStartContainerHooks []string `directive:"oci startcontainer hooks"`
+	OCIPoststartHooks      []string `directive:"oci poststart hooks"`
+	OCIPoststopHooks       []string `directive:"oci poststop hooks"`
 }
 
 // NOTE: if you think that we may want to change the default for any
@@ -211,7 +246,9 @@ config group = {{ if eq .ConfigGroup true }}yes{{ else }}no{{ end }}
 # CONFIG RESOLV_CONF: [BOOL]
 # DEFAULT: yes
 # If there is a bind point within the container, use the host's
-# /etc/resolv.conf.
+# /etc/resolv.conf. This only applies to containers run by the native runtime.
+# Bundles run with 'apptainer oci' keep any /etc/resolv.conf of the image, or
+# the mounts set in their config.json.
 config resolv_conf = {{ if eq .ConfigResolvConf true }}yes{{ else }}no{{ end }}
 
 # MOUNT PROC: [BOOL]
@@ -248,6 +285,19 @@ mount devpts = {{ if eq .MountDevPts true }}yes{{ else }}no{{ end }}
 # environment variables (or their corresponding command line options).
 mount home = {{ if eq .MountHome true }}yes{{ else }}no{{ end }}
 
+# HOME MOUNT OPTIONS: [STRING]
+# DEFAULT: NULL
+# Options applied to the home directory mount, as a comma separated list.
+# Supported options are exec, noexec and size=<size>. The size, such as 512m
+# or 2g, only applies when the home directory is created within the session
+# directory rather than bound from the host, and memory fs type is tmpfs.
+# Users can add noexec with the --home-options command line option, but
+# can't lift noexec set here, nor change the size.
+#home mount options = noexec, size=1g
+{{ range $index, $opt := .HomeMountOptions }}
+{{- if eq $index 0 }}home mount options = {{ else }}, {{ end }}{{$opt}}
+{{- end }}
+
 # MOUNT TMP: [BOOL]
 # DEFAULT: yes
 # Should we automatically bind mount /tmp and /var/tmp into the container? If
@@ -616,6 +666,37 @@ download part size = {{ .DownloadPartSize }}
 # are enabled.
 download buffer size = {{ .DownloadBufferSize }}
 
+# OCI CONCURRENCY: [UINT]
+# DEFAULT: 4
+# This option specifies how many layers are transferred in parallel when
+# pulling an image from, or pushing an image to, an OCI registry. It can be
+# overridden with the --concurrency option of the pull and push commands.
+oci concurrency = {{ .OCIConcurrency }}
+
+# OCI TIMEOUT: [UINT]
+# DEFAULT: 0
+# This option specifies the time, in seconds, after which pulling an image
+# from, or pushing an image to, an OCI registry is aborted, including when
+# fetching the image of a docker or oci build source. The conversion of the
+# image to SIF is not included. Setting it prevents a hung registry
+# connection from blocking indefinitely. 0 disables the timeout.
+oci timeout = {{ .OCITimeout }}
+
+# HTTP PROXY: [STRING]
+# HTTPS PROXY: [STRING]
+# NO PROXY: [STRING]
+# DEFAULT: Undefined
+# These options set the proxies used to retrieve images and contact remote
+# endpoints, for hosts not matched by 'no proxy'. They have the same format
+# as the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, which
+# take precedence when they are set.
+# http proxy = http://proxy.example.com:3128
+# https proxy = http://proxy.example.com:3128
+# no proxy = localhost,.example.com
+{{ if ne .HTTPProxy "" }}http proxy = {{ .HTTPProxy }}{{ end }}
+{{ if ne .HTTPSProxy "" }}https proxy = {{ .HTTPSProxy }}{{ end }}
+{{ if ne .NoProxy "" }}no proxy = {{ .NoProxy }}{{ end }}
+
 # SYSTEMD CGROUPS: [BOOL]
 # DEFAULT: yes
 # Whether to use systemd to manage container cgroups. Required for rootless cgroups
@@ -632,4 +713,28 @@ systemd cgroups = {{ if eq .SystemdCgroups true }}yes{{ else }}no{{ end }}
 # Allow to monitor the system resource usage of apptainer. To enable this option
 # additional tool, i.e. apptheus, is required.
 allow monitoring = {{ if eq .AllowMonitoring true }}yes{{ else }}no{{ end }}
+
+# OCI CREATERUNTIME HOOKS: [STRING]
+# OCI STARTCONTAINER HOOKS: [STRING]
+# OCI POSTSTART HOOKS: [STRING]
+# OCI POSTSTOP HOOKS: [STRING]
+# DEFAULT: NULL
+# Absolute paths of executables added as hooks, in the corresponding phase,
+# to containers created with the 'apptainer oci' commands. They run after any
+# hooks of the same phase defined in the bundle config.json, and receive the
+# container state on their standard input, as described by the OCI runtime
+# specification. This can be used to register containers with a scheduler.
+#oci poststart hooks = /usr/local/libexec/register-container
+{{ range $index, $path := .OCICreateRuntimeHooks }}
+{{- if eq $index 0 }}oci createruntime hooks = {{ else }}, {{ end }}{{$path}}
+{{- end }}
+{{ range $index, $path := .OCIStartContainerHooks }}
+{{- if eq $index 0 }}oci startcontainer hooks = {{ else }}, {{ end }}{{$path}}
+{{- end }}
+{{ range $index, $path := .OCIPoststartHooks }}
+{{- if eq $index 0 }}oci poststart hooks = {{ else }}, {{ end }}{{$path}}
+{{- end }}
+{{ range $index, $path := .OCIPoststopHooks }}
+{{- if eq $index 0 }}oci poststop hooks = {{ else }}, {{ end }}{{$path}}
+{{- end }}
 `
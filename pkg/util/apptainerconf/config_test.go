@@ -0,0 +1,85 @@
+// Copyright (c) Contributors to the Apptainer project, established as
+//   Apptainer a Series of LF Projects LLC.
+//   For website terms of use, trademark policy, privacy policy and other
+//   project policies see https://lfprojects.org/policies
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apptainerconf
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestProxyFunc(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+	// An explicit environment setting takes precedence over the configuration.
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	// The proxy answers all requests itself, recording the requested host.
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	config := &File{
+		HTTPProxy:  proxy.URL,
+		HTTPSProxy: proxy.URL,
+		NoProxy:    "images.example.com",
+	}
+	proxyFunc := ProxyFunc(config)
+
+	// The configuration is passed to the transport, not to the environment.
+	if got := os.Getenv("HTTP_PROXY"); got != "" {
+		t.Errorf("expected HTTP_PROXY to be left unset, got %q", got)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://images.example.com/image.sif", want: proxy.URL},
+		{url: "https://images.example.com/image.sif", want: proxy.URL},
+		{url: "https://internal.example.com/image.sif", want: ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatalf("while creating request: %v", err)
+		}
+		u, err := proxyFunc(req)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.url, err)
+		}
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		if got != tt.want {
+			t.Errorf("expected proxy %q for %s, got %q", tt.want, tt.url, got)
+		}
+	}
+
+	// A request through a transport using the proxy function must be routed
+	// through the proxy.
+	client := &http.Client{Transport: &http.Transport{Proxy: proxyFunc}}
+	resp, err := client.Get("http://images.example.com/image.sif")
+	if err != nil {
+		t.Fatalf("while requesting through proxy: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "proxied" || proxiedHost != "images.example.com" {
+		t.Errorf("request was not routed through the proxy (host %q, body %q)", proxiedHost, body)
+	}
+}